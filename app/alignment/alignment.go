@@ -0,0 +1,342 @@
+// Package alignment ищет линии — группы из трёх и более сущностей, лежащих на одном большом круге
+// с заданным угловым допуском.
+//
+// Каждая сущность по очереди становится опорной: соседи не дальше наибольшей длины линии
+// находятся по индексу ячеек S2, и каждый сосед c на угловом расстоянии d задаёт интервал азимутов
+// большого круга через опорную точку, для которых c отклоняется от круга не больше допуска tol:
+// |θ − θc| ≤ asin(sin tol / sin d). Наибольшие группы пересекающихся интервалов находятся
+// одним проходом по отсортированным концам, так что опорная точка обрабатывается за O(k log k)
+// для k соседей. Геометрия поиска сферическая, длина и азимут линии считаются на эллипсоиде WGS 84.
+package alignment
+
+import (
+	"context"
+	"fmt"
+	"github.com/audetv/datasets-parser/app/repos/entity"
+	"github.com/audetv/datasets-parser/dataset/geodesy"
+	"github.com/golang/geo/r3"
+	"github.com/golang/geo/s1"
+	"github.com/golang/geo/s2"
+	"math"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	// DefaultTolerance угловой допуск по умолчанию, 0,01° — около 1,1 км
+	DefaultTolerance = 0.01
+	// DefaultMaxLength наибольшая длина линии по умолчанию в метрах
+	DefaultMaxLength = 100000
+	// DefaultMinSpacing расстояние по умолчанию в метрах, ближе которого сущности считаются одним местом
+	DefaultMinSpacing = 1000
+	// DefaultLimit сколько лучших линий возвращается по умолчанию
+	DefaultLimit = 100
+)
+
+// Config параметры поиска линий
+type Config struct {
+	// Tolerance наибольшее угловое отклонение сущности от большого круга в градусах
+	Tolerance float64
+	// MaxLength наибольшее расстояние между крайними сущностями линии в метрах
+	MaxLength float64
+	// MinMembers наименьшее число сущностей линии, не меньше 3
+	MinMembers int
+	// MinSpacing сущности ближе этого расстояния в метрах считаются одним местом,
+	// например одна точка из разных наборов данных, и в линии остаётся первая из них
+	MinSpacing float64
+	// Limit сколько лучших линий вернуть
+	Limit int
+	// Workers сколько опорных точек обрабатывается параллельно, 0 — по числу процессоров
+	Workers int
+}
+
+// Validate проверяет параметры
+func (c Config) Validate() error {
+	if !(c.Tolerance > 0 && c.Tolerance < 10) {
+		return fmt.Errorf("alignment: tolerance %v must be in range (0, 10) degrees", c.Tolerance)
+	}
+	if !(c.MaxLength > 0) {
+		return fmt.Errorf("alignment: max length %v must be positive", c.MaxLength)
+	}
+	if c.MinMembers < 3 {
+		return fmt.Errorf("alignment: min members %d must be at least 3", c.MinMembers)
+	}
+	if !(c.MinSpacing >= 0) {
+		return fmt.Errorf("alignment: min spacing %v must not be negative", c.MinSpacing)
+	}
+	if c.Limit < 1 {
+		return fmt.Errorf("alignment: limit %d must be positive", c.Limit)
+	}
+	return nil
+}
+
+// Alignment линия: сущности у одного большого круга
+type Alignment struct {
+	// Members сущности в порядке вдоль линии
+	Members []Member
+	// Length расстояние между крайними сущностями по эллипсоиду WGS 84 в метрах
+	Length float64
+	// Azimuth азимут из первой сущности на последнюю от 0 до 360
+	Azimuth float64
+	// Deviation наибольшее отклонение сущности от большого круга в градусах
+	Deviation float64
+	// Path дуга большого круга от проекции первой сущности до проекции последней, точки «долгота, широта».
+	// Долгота меняется без скачков, на 180-м меридиане может выйти за ±180.
+	Path [][2]float64
+	key  string
+}
+
+// Member сущность линии. Description и DescriptionJson не читаются.
+type Member struct {
+	entity.Entity
+	// Offset расстояние вдоль большого круга от проекции первой сущности в метрах
+	Offset float64
+	// Deviation отклонение от большого круга в градусах, положительное — справа по ходу линии
+	Deviation float64
+}
+
+// Find ищет линии среди сущностей store, подходящих под filter, и возвращает Limit лучших:
+// длинные раньше коротких, при равной длине — с меньшим отклонением, затем с большим числом сущностей.
+// Линия, все сущности которой входят в линию выше, не возвращается.
+func Find(ctx context.Context, store entity.ReadStore, filter entity.Filter, c Config) ([]Alignment, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	var sites []site
+	err := store.All(ctx, filter, func(e entity.Entity) error {
+		e.Description, e.DescriptionJson = "", nil
+		ll := s2.LatLngFromDegrees(e.Latitude, e.Longitude)
+		sites = append(sites, site{Entity: e, cell: s2.CellIDFromLatLng(ll), point: s2.PointFromLatLng(ll)})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	ix := newIndex(sites)
+
+	workers := c.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	anchors := make(chan int)
+	found := make(chan Alignment)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			f := finder{ix: ix, config: c}
+			for a := range anchors {
+				f.anchor(a, found)
+			}
+		}()
+	}
+	go func() {
+		defer close(anchors)
+		for a := range ix.sites {
+			select {
+			case anchors <- a:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(found)
+	}()
+
+	// лучшие линии с запасом на повторы и вложенные линии
+	best := newRanking(c.Limit*8, s1.Angle(c.MinSpacing/geodesy.Earth.Radius))
+	for a := range found {
+		best.add(a)
+	}
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+	return best.top(c.Limit), nil
+}
+
+// finder ищет линии через опорные точки, его буферы используются одной горутиной
+type finder struct {
+	ix        *index
+	config    Config
+	events    []event
+	neighbors []neighbor
+}
+
+// neighbor сосед опорной точки
+type neighbor struct {
+	site int
+	// azimuth азимут на соседа в радианах, distance угловое расстояние
+	azimuth  float64
+	distance float64
+}
+
+// event конец интервала азимутов соседа
+type event struct {
+	angle    float64
+	start    bool
+	neighbor int
+}
+
+// anchor находит линии через опорную точку a и отправляет в found
+func (f *finder) anchor(a int, found chan<- Alignment) {
+	ix := f.ix
+	p := ix.sites[a].point
+	tol := f.config.Tolerance * math.Pi / 180
+	maxLength := s1.Angle(f.config.MaxLength / geodesy.Earth.Radius)
+	minSpacing := f.config.MinSpacing / geodesy.Earth.Radius
+	north, east := tangent(p)
+
+	f.neighbors = f.neighbors[:0]
+	f.events = f.events[:0]
+	ix.within(p, maxLength, func(i int) {
+		q := ix.sites[i].point
+		d := p.Distance(q).Radians()
+		if d <= tol || d < minSpacing {
+			// соседи у самой опорной точки лежат на любом круге через неё
+			return
+		}
+		t := q.Sub(p.Mul(p.Dot(q.Vector)))
+		azi := math.Atan2(t.Dot(east), t.Dot(north))
+		half := math.Asin(math.Min(1, math.Sin(tol)/math.Sin(d)))
+		n := len(f.neighbors)
+		f.neighbors = append(f.neighbors, neighbor{site: i, azimuth: azi, distance: d})
+		// направления круга берутся от 0 до π: круг с азимутом θ + π тот же
+		lo := math.Mod(azi-half+2*math.Pi, math.Pi)
+		for _, shift := range []float64{-math.Pi, 0, math.Pi} {
+			start, end := lo+shift, lo+shift+2*half
+			if end >= 0 && start < math.Pi {
+				f.events = append(f.events, event{angle: start, start: true, neighbor: n}, event{angle: end, neighbor: n})
+			}
+		}
+	})
+	if len(f.neighbors) < f.config.MinMembers-1 {
+		return
+	}
+
+	sort.Slice(f.events, func(i, j int) bool {
+		if f.events[i].angle != f.events[j].angle {
+			return f.events[i].angle < f.events[j].angle
+		}
+		return f.events[i].start && !f.events[j].start
+	})
+	active := make(map[int]bool)
+	lastStart, rising := 0.0, false
+	for _, e := range f.events {
+		if e.start {
+			active[e.neighbor] = true
+			lastStart, rising = e.angle, true
+			continue
+		}
+		if rising && len(active) >= f.config.MinMembers-1 {
+			// набор активных интервалов наибольший: общий их участок от lastStart до e.angle
+			if phi := (lastStart + e.angle) / 2; phi >= 0 && phi < math.Pi {
+				if al, ok := f.build(a, north, east, phi, active); ok {
+					found <- al
+				}
+			}
+		}
+		delete(active, e.neighbor)
+		rising = false
+	}
+}
+
+// tangent возвращает направления на север и восток в точке p
+func tangent(p s2.Point) (north r3.Vector, east r3.Vector) {
+	east = r3.Vector{Z: 1}.Cross(p.Vector)
+	if east.Norm() < 1e-15 {
+		// на полюсе север не определён, берётся направление меридиана 0
+		east = r3.Vector{Y: 1}
+	}
+	east = east.Normalize()
+	return p.Cross(east).Normalize(), east
+}
+
+// build собирает линию через опорную точку a с азимутом phi из активных соседей
+func (f *finder) build(a int, north, east r3.Vector, phi float64, active map[int]bool) (Alignment, bool) {
+	ix := f.ix
+	p := ix.sites[a].point.Vector
+	u := north.Mul(math.Cos(phi)).Add(east.Mul(math.Sin(phi)))
+	pole := p.Cross(u)
+
+	type position struct {
+		site   int
+		along  float64
+		across float64
+	}
+	positions := []position{{site: a}}
+	for n := range active {
+		q := ix.sites[f.neighbors[n].site].point.Vector
+		positions = append(positions, position{
+			site:   f.neighbors[n].site,
+			along:  math.Atan2(q.Dot(u), q.Dot(p)),
+			across: -math.Asin(math.Max(-1, math.Min(1, q.Dot(pole)))),
+		})
+	}
+	sort.Slice(positions, func(i, j int) bool {
+		if positions[i].along != positions[j].along {
+			return positions[i].along < positions[j].along
+		}
+		return ix.sites[positions[i].site].ID.String() < ix.sites[positions[j].site].ID.String()
+	})
+	// сущности у одного места считаются одной
+	minSpacing := f.config.MinSpacing / geodesy.Earth.Radius
+	kept := positions[:1]
+	for _, pos := range positions[1:] {
+		last := kept[len(kept)-1]
+		if ix.sites[last.site].point.Distance(ix.sites[pos.site].point).Radians() >= minSpacing {
+			kept = append(kept, pos)
+		}
+	}
+	if len(kept) < f.config.MinMembers {
+		return Alignment{}, false
+	}
+
+	first, last := ix.sites[kept[0].site], ix.sites[kept[len(kept)-1].site]
+	l := geodesy.WGS84.Inverse(first.Latitude, first.Longitude, last.Latitude, last.Longitude)
+	if l.Distance > f.config.MaxLength {
+		return Alignment{}, false
+	}
+	al := Alignment{Length: l.Distance, Azimuth: geodesy.Azimuth360(l.Azimuth1)}
+	ids := make([]string, len(kept))
+	t0 := kept[0].along
+	for i, pos := range kept {
+		s := ix.sites[pos.site]
+		deviation := pos.across * 180 / math.Pi
+		al.Members = append(al.Members, Member{
+			Entity:    s.Entity,
+			Offset:    (pos.along - t0) * geodesy.Earth.Radius,
+			Deviation: deviation,
+		})
+		al.Deviation = math.Max(al.Deviation, math.Abs(deviation))
+		ids[i] = s.ID.String()
+	}
+	sort.Strings(ids)
+	al.key = strings.Join(ids, ",")
+	al.Path = arc(p, u, t0, kept[len(kept)-1].along)
+	return al, true
+}
+
+// arc возвращает точки дуги большого круга p·cos t + u·sin t от t0 до t1 не реже чем через градус
+func arc(p, u r3.Vector, t0, t1 float64) [][2]float64 {
+	segments := int(math.Ceil((t1 - t0) * 180 / math.Pi))
+	if segments < 1 {
+		segments = 1
+	}
+	path := make([][2]float64, segments+1)
+	for i := range path {
+		t := t0 + (t1-t0)*float64(i)/float64(segments)
+		ll := s2.LatLngFromPoint(s2.Point{Vector: p.Mul(math.Cos(t)).Add(u.Mul(math.Sin(t)))})
+		lon := ll.Lng.Degrees()
+		if i > 0 {
+			// долгота без скачка на 180-м меридиане
+			lon = path[i-1][0] + geodesy.Azimuth360(lon-path[i-1][0]+180) - 180
+		}
+		path[i] = [2]float64{lon, ll.Lat.Degrees()}
+	}
+	return path
+}
@@ -0,0 +1,188 @@
+package alignment
+
+import (
+	"context"
+	"github.com/audetv/datasets-parser/app/repos/entity"
+	"github.com/audetv/datasets-parser/dataset/geodesy"
+	"github.com/google/uuid"
+	"math"
+	"testing"
+)
+
+// sliceStore хранилище сущностей в памяти
+type sliceStore []entity.Entity
+
+func (s sliceStore) All(ctx context.Context, filter entity.Filter, fn func(e entity.Entity) error) error {
+	for _, e := range s {
+		if filter.Match(e) {
+			if err := fn(e); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s sliceStore) Get(ctx context.Context, id uuid.UUID) (entity.Entity, error) {
+	return entity.Entity{}, entity.ErrNotFound
+}
+
+func (s sliceStore) Near(ctx context.Context, q entity.NearQuery) ([]entity.Neighbour, error) {
+	return nil, nil
+}
+
+// at сущность на расстоянии distance метров от точки по азимуту azimuth
+func at(name string, lat, lon, azimuth, distance float64) entity.Entity {
+	p := geodesy.WGS84.Direct(lat, lon, azimuth, distance)
+	return entity.Entity{
+		ID:        uuid.NewSHA1(uuid.NameSpaceOID, []byte(name)),
+		Name:      name,
+		Latitude:  p.Lat,
+		Longitude: p.Lon,
+	}
+}
+
+// config параметры поиска по умолчанию, как у команды alignments
+var config = Config{
+	Tolerance:  DefaultTolerance,
+	MaxLength:  DefaultMaxLength,
+	MinMembers: 3,
+	MinSpacing: DefaultMinSpacing,
+	Limit:      DefaultLimit,
+	Workers:    2,
+}
+
+func find(t *testing.T, store sliceStore, c Config) []Alignment {
+	t.Helper()
+	alignments, err := Find(context.Background(), store, entity.Filter{}, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return alignments
+}
+
+func TestFindCollinear(t *testing.T) {
+	// четыре сущности на геодезической линии с азимутом 40° и одна в стороне
+	lat, lon := 55.75, 37.62
+	store := sliceStore{
+		at("a", lat, lon, 40, 0),
+		at("c", lat, lon, 40, 30000),
+		at("b", lat, lon, 40, 12000),
+		at("d", lat, lon, 40, 50000),
+		at("в стороне", lat, lon, 130, 20000),
+	}
+	alignments := find(t, store, config)
+	if len(alignments) != 1 {
+		t.Fatalf("alignments %d, want 1: %+v", len(alignments), alignments)
+	}
+	a := alignments[0]
+	var names string
+	for _, m := range a.Members {
+		names += m.Name
+	}
+	// сущности упорядочены вдоль линии, направление линии не важно
+	if names != "abcd" && names != "dcba" {
+		t.Errorf("members %q, want abcd", names)
+	}
+	if math.Abs(a.Length-50000) > 10 {
+		t.Errorf("length %v, want 50000", a.Length)
+	}
+	if azi := math.Mod(a.Azimuth, 180); math.Abs(azi-40) > 0.1 {
+		t.Errorf("azimuth %v, want 40 or 220", a.Azimuth)
+	}
+	if a.Deviation > 1e-3 {
+		t.Errorf("deviation %v of a geodesic", a.Deviation)
+	}
+	for i := 1; i < len(a.Members); i++ {
+		if a.Members[i].Offset < a.Members[i-1].Offset {
+			t.Errorf("offsets not increasing: %v, %v", a.Members[i-1].Offset, a.Members[i].Offset)
+		}
+	}
+	if len(a.Path) < 2 {
+		t.Errorf("path %v", a.Path)
+	}
+}
+
+func TestFindNonCollinear(t *testing.T) {
+	// треугольник и точки, отклонённые от линии больше допуска 0,01° (около 1,1 км)
+	lat, lon := 55.75, 37.62
+	store := sliceStore{
+		at("a", lat, lon, 0, 0),
+		at("b", lat, lon, 0, 20000),
+		at("c", lat, lon, 60, 20000),
+	}
+	if alignments := find(t, store, config); len(alignments) != 0 {
+		t.Errorf("triangle: %+v", alignments)
+	}
+
+	mid := geodesy.WGS84.Direct(lat, lon, 90, 20000)
+	store = sliceStore{
+		at("a", lat, lon, 90, 0),
+		at("b", mid.Lat, mid.Lon, 0, 3000),
+		at("c", lat, lon, 90, 40000),
+	}
+	if alignments := find(t, store, config); len(alignments) != 0 {
+		t.Errorf("3 km off the line: %+v", alignments)
+	}
+	// с допуском 0,05° (около 5,5 км) та же точка попадает в линию
+	wide := config
+	wide.Tolerance = 0.05
+	if alignments := find(t, store, wide); len(alignments) != 1 || len(alignments[0].Members) != 3 {
+		t.Errorf("tolerance 0.05: %+v", alignments)
+	}
+}
+
+func TestFindMinSpacing(t *testing.T) {
+	// одна точка из двух наборов данных считается одним местом, и линии из двух мест нет
+	lat, lon := 55.75, 37.62
+	store := sliceStore{
+		at("a", lat, lon, 40, 0),
+		at("a2", lat, lon, 40, 300),
+		at("b", lat, lon, 40, 20000),
+	}
+	if alignments := find(t, store, config); len(alignments) != 0 {
+		t.Errorf("two places: %+v", alignments)
+	}
+	near := config
+	near.MinSpacing = 100
+	if alignments := find(t, store, near); len(alignments) != 1 {
+		t.Errorf("min spacing 100: %+v", alignments)
+	}
+}
+
+func TestFindMaxLength(t *testing.T) {
+	lat, lon := 55.75, 37.62
+	store := sliceStore{
+		at("a", lat, lon, 40, 0),
+		at("b", lat, lon, 40, 60000),
+		at("c", lat, lon, 40, 120000),
+	}
+	if alignments := find(t, store, config); len(alignments) != 0 {
+		t.Errorf("longer than max length: %+v", alignments)
+	}
+	long := config
+	long.MaxLength = 150000
+	if alignments := find(t, store, long); len(alignments) != 1 {
+		t.Errorf("max length 150 km: %+v", alignments)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	if err := config.Validate(); err != nil {
+		t.Error(err)
+	}
+	for _, change := range []func(c *Config){
+		func(c *Config) { c.Tolerance = 0 },
+		func(c *Config) { c.Tolerance = 10 },
+		func(c *Config) { c.MaxLength = 0 },
+		func(c *Config) { c.MinMembers = 2 },
+		func(c *Config) { c.MinSpacing = -1 },
+		func(c *Config) { c.Limit = 0 },
+	} {
+		c := config
+		change(&c)
+		if err := c.Validate(); err == nil {
+			t.Errorf("%+v accepted", c)
+		}
+	}
+}
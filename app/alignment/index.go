@@ -0,0 +1,46 @@
+package alignment
+
+import (
+	"github.com/audetv/datasets-parser/app/repos/entity"
+	"github.com/golang/geo/s1"
+	"github.com/golang/geo/s2"
+	"sort"
+)
+
+// site сущность с точкой на единичной сфере
+type site struct {
+	entity.Entity
+	cell  s2.CellID
+	point s2.Point
+}
+
+// index точки, упорядоченные по ячейкам S2 30-го уровня: точки ячейки любого уровня
+// занимают непрерывный отрезок, его границы находятся двоичным поиском
+type index struct {
+	sites   []site
+	coverer *s2.RegionCoverer
+}
+
+func newIndex(sites []site) *index {
+	sort.Slice(sites, func(i, j int) bool {
+		if sites[i].cell != sites[j].cell {
+			return sites[i].cell < sites[j].cell
+		}
+		return sites[i].ID.String() < sites[j].ID.String()
+	})
+	return &index{sites: sites, coverer: &s2.RegionCoverer{MaxLevel: s2.MaxLevel, MaxCells: 8}}
+}
+
+// within вызывает fn для точек не дальше radius от точки p
+func (ix *index) within(p s2.Point, radius s1.Angle, fn func(i int)) {
+	c := s2.CapFromCenterAngle(p, radius)
+	for _, cell := range ix.coverer.Covering(c) {
+		lo, hi := cell.RangeMin(), cell.RangeMax()
+		i := sort.Search(len(ix.sites), func(i int) bool { return ix.sites[i].cell >= lo })
+		for ; i < len(ix.sites) && ix.sites[i].cell <= hi; i++ {
+			if p.Distance(ix.sites[i].point) <= radius {
+				fn(i)
+			}
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package alignment
+
+import (
+	"github.com/golang/geo/s1"
+	"github.com/golang/geo/s2"
+	"github.com/google/uuid"
+	"sort"
+)
+
+// ranking хранит лучшие линии без повторов: одна и та же линия находится через каждую свою сущность
+type ranking struct {
+	size  int
+	items map[string]Alignment
+	// spacing угловое расстояние, ближе которого сущности считаются одним местом
+	spacing s1.Angle
+}
+
+func newRanking(size int, spacing s1.Angle) *ranking {
+	return &ranking{size: size, items: make(map[string]Alignment), spacing: spacing}
+}
+
+// better сравнивает линии: длиннее, с меньшим отклонением, с большим числом сущностей,
+// при равенстве — по составу, чтобы порядок не зависел от порядка поиска
+func better(a, b Alignment) bool {
+	if a.Length != b.Length {
+		return a.Length > b.Length
+	}
+	if a.Deviation != b.Deviation {
+		return a.Deviation < b.Deviation
+	}
+	if len(a.Members) != len(b.Members) {
+		return len(a.Members) > len(b.Members)
+	}
+	return a.key < b.key
+}
+
+func (r *ranking) add(a Alignment) {
+	if old, ok := r.items[a.key]; ok && !better(a, old) {
+		return
+	}
+	r.items[a.key] = a
+	if len(r.items) > 2*r.size {
+		r.items = make(map[string]Alignment, 2*r.size)
+		for _, a := range r.sorted() {
+			r.items[a.key] = a
+		}
+	}
+}
+
+// sorted возвращает size лучших линий по порядку
+func (r *ranking) sorted() []Alignment {
+	all := make([]Alignment, 0, len(r.items))
+	for _, a := range r.items {
+		all = append(all, a)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return better(all[i], all[j])
+	})
+	if len(all) > r.size {
+		all = all[:r.size]
+	}
+	return all
+}
+
+// top возвращает limit лучших линий, пропуская линии, все сущности которых или их места
+// входят в линию выше: та же линия через дубликат сущности из другого набора данных — повтор
+func (r *ranking) top(limit int) []Alignment {
+	var result []Alignment
+	// в каких из уже выбранных линий есть сущность
+	in := make(map[uuid.UUID][]int)
+	for _, a := range r.sorted() {
+		if len(result) == limit {
+			break
+		}
+		if r.covered(a, result, in) {
+			continue
+		}
+		for _, m := range a.Members {
+			in[m.ID] = append(in[m.ID], len(result))
+		}
+		result = append(result, a)
+	}
+	return result
+}
+
+// covered сообщает, что каждая сущность a или её место есть в одной из выбранных линий
+func (r *ranking) covered(a Alignment, chosen []Alignment, in map[uuid.UUID][]int) bool {
+	checked := make(map[int]bool)
+	for _, m := range a.Members {
+		for _, k := range in[m.ID] {
+			if checked[k] {
+				continue
+			}
+			checked[k] = true
+			if r.contains(chosen[k], a) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// contains сообщает, что у каждой сущности b есть сущность a в том же месте
+func (r *ranking) contains(a Alignment, b Alignment) bool {
+	for _, mb := range b.Members {
+		found := false
+		pb := s2.LatLngFromDegrees(mb.Latitude, mb.Longitude)
+		for _, ma := range a.Members {
+			if ma.ID == mb.ID || s2.LatLngFromDegrees(ma.Latitude, ma.Longitude).Distance(pb) < r.spacing {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,210 @@
+// Package dedup находит дубликаты — сущности разных наборов данных, описывающие одно место, —
+// и объединяет их в канонические сущности.
+//
+// Пары-кандидаты находятся по ячейкам S2 уровня, ячейка которого не уже порога расстояния:
+// точка на расстоянии не больше порога лежит в той же или в соседней ячейке. Пара совпадает,
+// если расстояние по эллипсоиду WGS 84 не больше порога, а сходство имён, записанных латиницей,
+// не меньше порога. Совпавшие пары объединяются в кластеры от ближайших к дальним.
+package dedup
+
+import (
+	"context"
+	"fmt"
+	"github.com/audetv/datasets-parser/app/repos/entity"
+	"github.com/audetv/datasets-parser/dataset/geodesy"
+	"github.com/golang/geo/s1"
+	"github.com/golang/geo/s2"
+	"sort"
+)
+
+const (
+	// DefaultDistance порог расстояния по умолчанию в метрах
+	DefaultDistance = 1000
+	// DefaultSimilarity порог сходства имён по умолчанию
+	DefaultSimilarity = 0.8
+	// Filename имя набора данных канонических сущностей
+	Filename = "dedup"
+	// maxDistance наибольший порог расстояния в метрах, при большем соседних ячеек слишком много
+	maxDistance = 100000
+	// distanceMargin запас порога на сфере: расстояние по эллипсоиду отличается от сферического до 0,5 %
+	distanceMargin = 1.01
+)
+
+// Config пороги поиска дубликатов
+type Config struct {
+	// Distance наибольшее расстояние между дубликатами в метрах
+	Distance float64
+	// Similarity наименьшее сходство имён дубликатов от 0 до 1, см. Similarity
+	Similarity float64
+	// SameFile искать дубликаты и внутри одного набора данных. Без него в кластере
+	// не больше одной сущности каждого файла.
+	SameFile bool
+}
+
+// Validate проверяет пороги
+func (c Config) Validate() error {
+	if !(c.Distance > 0 && c.Distance <= maxDistance) {
+		return fmt.Errorf("dedup: distance %v must be in range (0, %v] meters", c.Distance, maxDistance)
+	}
+	if !(c.Similarity > 0 && c.Similarity <= 1) {
+		return fmt.Errorf("dedup: similarity %v must be in range (0, 1]", c.Similarity)
+	}
+	return nil
+}
+
+// site сущность с именем для сравнения
+type site struct {
+	entity.Entity
+	name  string
+	point s2.Point
+	cell  s2.CellID
+}
+
+// pair совпавшая пара сущностей
+type pair struct {
+	a, b     int
+	distance float64
+}
+
+// Find ищет дубликаты среди сущностей store, подходящих под filter, и возвращает кластеры
+// из двух и более сущностей с каноническими сущностями, как их строит Canonical,
+// в порядке первой сущности кластера. Description и DescriptionJson не читаются,
+// поэтому Canonical.DescriptionJson содержит только имена и координаты.
+func Find(ctx context.Context, store entity.ReadStore, filter entity.Filter, c Config) ([]entity.Merge, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	radius := s1.Angle(c.Distance * distanceMargin / geodesy.Earth.Radius)
+	level := s2.MinWidthMetric.MaxLevel(radius.Radians())
+
+	var sites []site
+	cells := make(map[s2.CellID][]int)
+	err := store.All(ctx, filter, func(e entity.Entity) error {
+		e.Description, e.DescriptionJson = "", nil
+		ll := s2.LatLngFromDegrees(e.Latitude, e.Longitude)
+		s := site{Entity: e, name: Normalize(e.Name), point: s2.PointFromLatLng(ll), cell: s2.CellIDFromLatLng(ll).Parent(level)}
+		if s.name != "" {
+			cells[s.cell] = append(cells[s.cell], len(sites))
+		}
+		sites = append(sites, s)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var pairs []pair
+	for i, a := range sites {
+		if i%1000 == 0 {
+			if err = ctx.Err(); err != nil {
+				return nil, err
+			}
+		}
+		if a.name == "" {
+			continue
+		}
+		for _, cell := range append(a.cell.AllNeighbors(level), a.cell) {
+			for _, j := range cells[cell] {
+				b := sites[j]
+				if j <= i || (!c.SameFile && a.Filename == b.Filename) || a.point.Distance(b.point) > radius {
+					continue
+				}
+				if Similarity(a.name, b.name) < c.Similarity {
+					continue
+				}
+				if d := geodesy.WGS84.Inverse(a.Latitude, a.Longitude, b.Latitude, b.Longitude).Distance; d <= c.Distance {
+					pairs = append(pairs, pair{a: i, b: j, distance: d})
+				}
+			}
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].distance != pairs[j].distance {
+			return pairs[i].distance < pairs[j].distance
+		}
+		if pairs[i].a != pairs[j].a {
+			return pairs[i].a < pairs[j].a
+		}
+		return pairs[i].b < pairs[j].b
+	})
+
+	cl := newClusters(sites, c.SameFile)
+	for _, p := range pairs {
+		cl.union(p.a, p.b)
+	}
+	groups := make(map[int][]int)
+	var roots []int
+	for i := range sites {
+		r := cl.find(i)
+		if cl.size[r] < 2 {
+			continue
+		}
+		if len(groups[r]) == 0 {
+			roots = append(roots, r)
+		}
+		groups[r] = append(groups[r], i)
+	}
+	merges := make([]entity.Merge, len(roots))
+	for k, r := range roots {
+		es := make([]entity.Entity, len(groups[r]))
+		for n, i := range groups[r] {
+			es[n] = sites[i].Entity
+		}
+		merges[k] = Canonical(es)
+	}
+	return merges, nil
+}
+
+// clusters непересекающиеся множества сущностей
+type clusters struct {
+	parent []int
+	size   []int
+	// files файлы сущностей кластера по корню, если в кластере не может быть двух сущностей одного файла
+	files []map[string]bool
+}
+
+func newClusters(sites []site, sameFile bool) *clusters {
+	c := &clusters{parent: make([]int, len(sites)), size: make([]int, len(sites))}
+	if !sameFile {
+		c.files = make([]map[string]bool, len(sites))
+	}
+	for i, s := range sites {
+		c.parent[i], c.size[i] = i, 1
+		if c.files != nil {
+			c.files[i] = map[string]bool{s.Filename: true}
+		}
+	}
+	return c
+}
+
+func (c *clusters) find(i int) int {
+	for c.parent[i] != i {
+		c.parent[i] = c.parent[c.parent[i]]
+		i = c.parent[i]
+	}
+	return i
+}
+
+// union объединяет кластеры a и b, если в них нет сущностей одного файла
+func (c *clusters) union(a, b int) {
+	a, b = c.find(a), c.find(b)
+	if a == b {
+		return
+	}
+	if c.size[a] < c.size[b] {
+		a, b = b, a
+	}
+	if c.files != nil {
+		for f := range c.files[b] {
+			if c.files[a][f] {
+				return
+			}
+		}
+		for f := range c.files[b] {
+			c.files[a][f] = true
+		}
+		c.files[b] = nil
+	}
+	c.parent[b] = a
+	c.size[a] += c.size[b]
+}
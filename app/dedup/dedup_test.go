@@ -0,0 +1,305 @@
+package dedup
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/audetv/datasets-parser/app/repos/entity"
+	"github.com/audetv/datasets-parser/dataset/geodesy"
+	"github.com/google/uuid"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// sliceStore хранилище сущностей в памяти
+type sliceStore []entity.Entity
+
+func (s sliceStore) All(ctx context.Context, filter entity.Filter, fn func(e entity.Entity) error) error {
+	for _, e := range s {
+		if filter.Match(e) {
+			if err := fn(e); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s sliceStore) Get(ctx context.Context, id uuid.UUID) (entity.Entity, error) {
+	return entity.Entity{}, entity.ErrNotFound
+}
+
+func (s sliceStore) Near(ctx context.Context, q entity.NearQuery) ([]entity.Neighbour, error) {
+	return nil, nil
+}
+
+// kremlin точка, от которой откладываются сущности тестов
+const kremlinLat, kremlinLon = 55.7520, 37.6175
+
+// at сущность файла filename на расстоянии distance метров от Кремля по азимуту azimuth
+func at(filename, name string, azimuth, distance float64) entity.Entity {
+	p := geodesy.WGS84.Direct(kremlinLat, kremlinLon, azimuth, distance)
+	return entity.Entity{
+		ID:        uuid.NewSHA1(uuid.NameSpaceOID, []byte(fmt.Sprint(filename, name, azimuth, distance))),
+		Filename:  filename,
+		Name:      name,
+		Latitude:  p.Lat,
+		Longitude: p.Lon,
+	}
+}
+
+var config = Config{Distance: DefaultDistance, Similarity: DefaultSimilarity}
+
+// clusterNames возвращает кластеры как отсортированные «файл:имя» через запятую
+func clusterNames(merges []entity.Merge) []string {
+	var out []string
+	for _, m := range merges {
+		var names []string
+		for _, mm := range m.Members {
+			names = append(names, mm.Filename+":"+mm.Name)
+		}
+		sort.Strings(names)
+		out = append(out, strings.Join(names, ","))
+	}
+	sort.Strings(out)
+	return out
+}
+
+func find(t *testing.T, store sliceStore, c Config) []entity.Merge {
+	t.Helper()
+	merges, err := Find(context.Background(), store, entity.Filter{}, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return merges
+}
+
+func TestFind(t *testing.T) {
+	store := sliceStore{
+		at("cities.csv", "Москва", 0, 0),
+		at("osm.pbf", "Moskva", 90, 200),
+		at("temples.kml", "МОСКВА", 180, 300),
+		// другое имя рядом
+		at("osm.pbf", "Большой театр", 0, 50),
+		// то же имя, но дальше порога расстояния
+		at("geonames.csv", "Москва", 270, 5000),
+		// похожие, но разные имена
+		at("temples.kml", "Успенский собор", 45, 10),
+		at("osm.pbf", "Успенский храм", 45, 20),
+		// разные записи одного имени
+		at("temples.kml", "Ясная Поляна", 135, 5000),
+		at("osm.pbf", "Yasnaya Polyana", 135, 5400),
+		// сущности без имени не объединяются
+		at("cities.csv", "", 225, 5000),
+		at("osm.pbf", "", 225, 5000),
+	}
+	got := clusterNames(find(t, store, config))
+	want := []string{
+		"cities.csv:Москва,osm.pbf:Moskva,temples.kml:МОСКВА",
+		"osm.pbf:Yasnaya Polyana,temples.kml:Ясная Поляна",
+	}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("clusters %q, want %q", got, want)
+	}
+
+	// с порогом расстояния 6 км к Москве присоединяется сущность geonames.csv
+	far := config
+	far.Distance = 6000
+	got = clusterNames(find(t, store, far))
+	if len(got) != 2 || got[0] != "cities.csv:Москва,geonames.csv:Москва,osm.pbf:Moskva,temples.kml:МОСКВА" {
+		t.Errorf("distance 6 km: %q", got)
+	}
+	// с порогом сходства 0,6 объединяются и соборы
+	loose := config
+	loose.Similarity = 0.6
+	got = clusterNames(find(t, store, loose))
+	if len(got) != 3 || got[2] != "osm.pbf:Успенский храм,temples.kml:Успенский собор" {
+		t.Errorf("similarity 0.6: %q", got)
+	}
+}
+
+func TestFindSameFile(t *testing.T) {
+	store := sliceStore{
+		at("cities.csv", "Тверь", 0, 0),
+		at("cities.csv", "Тверь", 0, 10),
+	}
+	if merges := find(t, store, config); len(merges) != 0 {
+		t.Errorf("same file: %q", clusterNames(merges))
+	}
+	same := config
+	same.SameFile = true
+	if merges := find(t, store, same); len(merges) != 1 || len(merges[0].Members) != 2 {
+		t.Errorf("--same-file: %q", clusterNames(merges))
+	}
+}
+
+func TestFindNearestFirst(t *testing.T) {
+	// в кластере не больше одной сущности файла: к Москве из cities.csv присоединяется
+	// ближайшая Москва из osm.pbf, дальняя остаётся одна
+	store := sliceStore{
+		at("cities.csv", "Москва", 0, 0),
+		at("osm.pbf", "Москва", 90, 600),
+		at("osm.pbf", "Москва", 270, 100),
+	}
+	merges := find(t, store, config)
+	if len(merges) != 1 || len(merges[0].Members) != 2 {
+		t.Fatalf("clusters %q", clusterNames(merges))
+	}
+	for _, mm := range merges[0].Members {
+		if mm.ID == store[1].ID {
+			t.Errorf("farther entity %v joined the cluster", mm.Name)
+		}
+	}
+}
+
+func TestClusters(t *testing.T) {
+	sites := []site{
+		{Entity: entity.Entity{Filename: "a"}},
+		{Entity: entity.Entity{Filename: "a"}},
+		{Entity: entity.Entity{Filename: "b"}},
+		{Entity: entity.Entity{Filename: "c"}},
+	}
+	c := newClusters(sites, false)
+	c.union(0, 2)
+	c.union(2, 3)
+	// 1 из того же файла, что и 0, кластер его не принимает
+	c.union(1, 3)
+	if c.find(0) != c.find(3) || c.find(1) == c.find(0) || c.size[c.find(0)] != 3 {
+		t.Errorf("clusters parent %v, size %v", c.parent, c.size)
+	}
+	// повторное объединение ничего не меняет
+	c.union(3, 0)
+	if c.size[c.find(0)] != 3 {
+		t.Errorf("size %v after repeated union", c.size[c.find(0)])
+	}
+
+	c = newClusters(sites, true)
+	c.union(0, 1)
+	c.union(2, 3)
+	c.union(1, 2)
+	root := c.find(0)
+	for i := range sites {
+		if c.find(i) != root {
+			t.Errorf("site %d not in cluster, parent %v", i, c.parent)
+		}
+	}
+	if c.size[root] != 4 {
+		t.Errorf("size %d, want 4", c.size[root])
+	}
+}
+
+func TestCanonical(t *testing.T) {
+	es := []entity.Entity{
+		at("osm.pbf", "Moskva", 90, 200),
+		at("cities.csv", "Москва", 0, 0),
+		at("temples.kml", "Москва", 180, 300),
+	}
+	es[0].Description = "столица"
+	es[1].Description = "столица"
+	es[1].Height = 156
+	es[2].Description = "город"
+	es[2].DescriptionJson = map[string]interface{}{"population": 13010112}
+
+	m := Canonical(es)
+	c := m.Canonical
+	// самое частое имя, а не первое
+	if c.Name != "Москва" || c.Filename != Filename || c.ID == uuid.Nil || c.Height != 156 {
+		t.Errorf("canonical %+v", c)
+	}
+	if c.Description != "столица\n\nгород" {
+		t.Errorf("description %q", c.Description)
+	}
+	// средняя точка на сфере не дальше 300 м от каждой сущности
+	for _, mm := range m.Members {
+		if mm.Distance > 300 {
+			t.Errorf("%v %v m from canonical point", mm.Name, mm.Distance)
+		}
+		if mm.Similarity != 1 {
+			t.Errorf("%v similarity %v", mm.Name, mm.Similarity)
+		}
+	}
+	sources, ok := c.DescriptionJson.(map[string][]interface{})
+	if !ok || len(sources) != 3 || len(sources["temples.kml"]) != 1 {
+		t.Fatalf("description json %#v", c.DescriptionJson)
+	}
+	if s := sources["temples.kml"][0].(source); s.ID != es[2].ID || s.Attributes == nil {
+		t.Errorf("temples source %+v", s)
+	}
+
+	// каноническая сущность в новом кластере: её сущности переносятся без вложенности
+	prior := c
+	prior.DescriptionJson = map[string]interface{}{
+		"cities.csv": []interface{}{map[string]interface{}{"id": es[1].ID.String(), "name": "Москва"}},
+		"osm.pbf":    []interface{}{map[string]interface{}{"id": es[0].ID.String(), "name": "Moskva"}},
+	}
+	next := Canonical([]entity.Entity{prior, at("geonames.csv", "Moscow", 0, 100)})
+	sources = next.Canonical.DescriptionJson.(map[string][]interface{})
+	if len(sources) != 3 || len(sources["cities.csv"]) != 1 || len(sources[Filename]) != 0 {
+		t.Errorf("nested description json %#v", sources)
+	}
+	if next.Canonical.Description != "столица\n\nгород" {
+		t.Errorf("nested description %q", next.Canonical.Description)
+	}
+}
+
+func TestReport(t *testing.T) {
+	store := sliceStore{
+		at("cities.csv", "Москва", 0, 0),
+		at("osm.pbf", "Moskva", 90, 200),
+		at("temples.kml", "Ясная Поляна", 135, 5000),
+		at("osm.pbf", "Yasnaya Polyana", 135, 5400),
+	}
+	merges := find(t, store, config)
+	var b bytes.Buffer
+	if err := WriteReport(&b, merges); err != nil {
+		t.Fatal(err)
+	}
+	clusters, err := ReadReport(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(clusters) != len(merges) {
+		t.Fatalf("clusters %v", clusters)
+	}
+	for k, m := range merges {
+		if len(clusters[k]) != len(m.Members) {
+			t.Errorf("cluster %d: %v", k+1, clusters[k])
+			continue
+		}
+		for i, mm := range m.Members {
+			if clusters[k][i] != mm.ID {
+				t.Errorf("cluster %d member %d: %v, want %v", k+1, i, clusters[k][i], mm.ID)
+			}
+		}
+	}
+
+	// строки исправленного отчёта: кластер 1 разделён, id указан дважды
+	split := "cluster;id\n1;\n1;" + store[0].ID.String() + "\n3;" + store[1].ID.String() + "\n"
+	if clusters, err = ReadReport(strings.NewReader(split)); err != nil || len(clusters) != 2 {
+		t.Errorf("split report %v, %v", clusters, err)
+	}
+	twice := "cluster;id\n1;" + store[0].ID.String() + "\n2;" + store[0].ID.String() + "\n"
+	if _, err = ReadReport(strings.NewReader(twice)); err == nil {
+		t.Error("entity listed twice accepted")
+	}
+	if _, err = ReadReport(strings.NewReader("id;cluster\n")); err == nil {
+		t.Error("wrong header accepted")
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	for _, c := range []Config{
+		{Distance: 0, Similarity: 0.8},
+		{Distance: maxDistance + 1, Similarity: 0.8},
+		{Distance: 1000, Similarity: 0},
+		{Distance: 1000, Similarity: 1.1},
+	} {
+		if err := c.Validate(); err == nil {
+			t.Errorf("%+v accepted", c)
+		}
+	}
+	if err := config.Validate(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,100 @@
+package dedup
+
+import (
+	"github.com/audetv/datasets-parser/app/repos/entity"
+	"github.com/audetv/datasets-parser/dataset/geodesy"
+	"github.com/golang/geo/r3"
+	"github.com/golang/geo/s2"
+	"github.com/google/uuid"
+	"strings"
+)
+
+// descriptionSeparator разделяет описания сущностей в описании канонической сущности
+const descriptionSeparator = "\n\n"
+
+// source сущность набора данных в DescriptionJson канонической сущности
+type source struct {
+	ID          uuid.UUID   `json:"id"`
+	Name        string      `json:"name"`
+	Description string      `json:"description,omitempty"`
+	Longitude   float64     `json:"longitude"`
+	Latitude    float64     `json:"latitude"`
+	Height      float64     `json:"height,omitempty"`
+	Attributes  interface{} `json:"attributes,omitempty"`
+}
+
+// Canonical строит каноническую сущность кластера es с новым ID и Filename:
+//   - имя — самое частое среди сущностей, при равенстве — первое;
+//   - точка — среднее точек на сфере, высота — первая ненулевая;
+//   - описание — различные описания через пустую строку;
+//   - DescriptionJson — сущности по именам файлов: {"файл": [{"id", "name", "description",
+//     "longitude", "latitude", "height", "attributes"}]}, в attributes — DescriptionJson сущности.
+//
+// Сущности прежних канонических сущностей кластера переносятся в DescriptionJson как есть.
+// CellID, geohash, ячейки и тайлы не вычисляются, их заполняет entity.Indexer, например geoindex.Indexer.
+func Canonical(es []entity.Entity) entity.Merge {
+	c := entity.Entity{ID: uuid.New(), Filename: Filename}
+
+	counts := make(map[string]int)
+	for _, e := range es {
+		counts[strings.TrimSpace(e.Name)]++
+	}
+	best := 0
+	for _, e := range es {
+		if name := strings.TrimSpace(e.Name); name != "" && counts[name] > best {
+			c.Name, best = name, counts[name]
+		}
+	}
+
+	var sum r3.Vector
+	var descriptions []string
+	seen := make(map[string]bool)
+	sources := make(map[string][]interface{})
+	for _, e := range es {
+		sum = sum.Add(s2.PointFromLatLng(s2.LatLngFromDegrees(e.Latitude, e.Longitude)).Vector)
+		if c.Height == 0 {
+			c.Height = e.Height
+		}
+		parts := []string{e.Description}
+		if e.Filename == Filename {
+			parts = strings.Split(e.Description, descriptionSeparator)
+		}
+		for _, d := range parts {
+			if d = strings.TrimSpace(d); d != "" && !seen[d] {
+				seen[d] = true
+				descriptions = append(descriptions, d)
+			}
+		}
+		if prior, ok := e.DescriptionJson.(map[string]interface{}); ok && e.Filename == Filename {
+			for file, v := range prior {
+				if list, ok := v.([]interface{}); ok {
+					sources[file] = append(sources[file], list...)
+				}
+			}
+			continue
+		}
+		sources[e.Filename] = append(sources[e.Filename], source{
+			ID: e.ID, Name: e.Name, Description: e.Description, Longitude: e.Longitude, Latitude: e.Latitude,
+			Height: e.Height, Attributes: e.DescriptionJson,
+		})
+	}
+	ll := s2.LatLngFromPoint(s2.Point{Vector: sum.Normalize()})
+	if sum.Norm() == 0 {
+		// точки уравновешивают друг друга только на противоположных сторонах Земли
+		ll = s2.LatLngFromDegrees(es[0].Latitude, es[0].Longitude)
+	}
+	c.Latitude, c.Longitude = ll.Lat.Degrees(), ll.Lng.Degrees()
+	c.Description = strings.Join(descriptions, descriptionSeparator)
+	c.DescriptionJson = sources
+
+	m := entity.Merge{Canonical: c, Members: make([]entity.MergeMember, len(es))}
+	name := Normalize(c.Name)
+	for i, e := range es {
+		m.Members[i] = entity.MergeMember{
+			Entity:     e,
+			Distance:   geodesy.WGS84.Inverse(c.Latitude, c.Longitude, e.Latitude, e.Longitude).Distance,
+			Similarity: Similarity(name, Normalize(e.Name)),
+		}
+	}
+	return m
+}
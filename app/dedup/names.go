@@ -0,0 +1,125 @@
+package dedup
+
+import (
+	"golang.org/x/text/unicode/norm"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// translit латинская запись кириллических букв, как в загранпаспортах, с украинскими и белорусскими буквами
+var translit = map[rune]string{
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'д': "d", 'е': "e", 'ё': "e", 'ж': "zh", 'з': "z",
+	'и': "i", 'й': "i", 'к': "k", 'л': "l", 'м': "m", 'н': "n", 'о': "o", 'п': "p", 'р': "r",
+	'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh", 'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch",
+	'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "iu", 'я': "ia",
+	'і': "i", 'ї': "i", 'є': "ie", 'ґ': "g", 'ў': "u",
+}
+
+// fold сводит разные латинские записи одних звуков к одной: «Khabarovsk» и «Habarovsk»,
+// «Yasnaya» и «Iasnaia», «Tsaritsyn» и «Caricyn»
+var fold = strings.NewReplacer(
+	"shch", "sh", "sch", "sh", "kh", "h", "ck", "k", "ph", "f", "ts", "c", "tz", "c",
+	"w", "v", "x", "ks", "q", "k", "j", "i", "y", "i",
+)
+
+// Normalize приводит имя к виду для сравнения: строчные латинские буквы без диакритики и цифры,
+// кириллица записывается латиницей, слова разделены одним пробелом, повторы букв убраны
+func Normalize(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(norm.NFC.String(name)) {
+		if s, ok := translit[r]; ok {
+			b.WriteString(s)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	var words []string
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, string(word))
+			word = word[:0]
+		}
+	}
+	for _, r := range norm.NFD.String(b.String()) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			word = append(word, r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	for i, w := range words {
+		words[i] = squeeze(fold.Replace(w))
+	}
+	return strings.Join(words, " ")
+}
+
+// squeeze убирает повторы букв подряд: «Таллинн» и «Tallin»
+func squeeze(s string) string {
+	rs := []rune(s)
+	out := rs[:0]
+	for i, r := range rs {
+		if i == 0 || r != rs[i-1] {
+			out = append(out, r)
+		}
+	}
+	return string(out)
+}
+
+// Similarity сходство имён, приведённых Normalize, от 0 до 1: доля совпадающих букв по расстоянию
+// Левенштейна, для имён целиком или для их слов по алфавиту, если так больше.
+// Пустое имя ни на что не похоже.
+func Similarity(a, b string) float64 {
+	if a == "" || b == "" {
+		return 0
+	}
+	if a == b {
+		return 1
+	}
+	s := ratio(a, b)
+	if t := ratio(sortWords(a), sortWords(b)); t > s {
+		s = t
+	}
+	return s
+}
+
+func sortWords(s string) string {
+	words := strings.Fields(s)
+	sort.Strings(words)
+	return strings.Join(words, " ")
+}
+
+// ratio 1 - расстояние Левенштейна, делённое на длину большей строки
+func ratio(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	n := len(ra)
+	if len(rb) > n {
+		n = len(rb)
+	}
+	return 1 - float64(levenshtein(ra, rb))/float64(n)
+}
+
+func levenshtein(a, b []rune) int {
+	row := make([]int, len(b)+1)
+	for j := range row {
+		row[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		prev := row[0]
+		row[0] = i
+		for j := 1; j <= len(b); j++ {
+			cur := row[j]
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			row[j] = min(row[j]+1, row[j-1]+1, prev+cost)
+			prev = cur
+		}
+	}
+	return row[len(b)]
+}
@@ -0,0 +1,92 @@
+package dedup
+
+import (
+	"math"
+	"testing"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"Москва", "moskva"},
+		{"  MOSKVA ", "moskva"},
+		// кириллица записывается латиницей, разные записи одних звуков совпадают
+		{"Ясная Поляна", "iasnaia poliana"},
+		{"Yasnaya Polyana", "iasnaia poliana"},
+		{"Хабаровск", "habarovsk"},
+		{"Khabarovsk", "habarovsk"},
+		{"Царицын", "caricin"},
+		{"Tsaritsyn", "caricin"},
+		{"Щёлково", "shelkovo"},
+		// мягкий и твёрдый знаки не записываются
+		{"Кремль", "kreml"},
+		{"Подъезд", "podezd"},
+		// повторы букв убираются
+		{"Таллинн", "talin"},
+		{"Tallin", "talin"},
+		// украинские буквы, «ii» сводится к одной букве
+		{"Київ", "kiv"},
+		// диакритика и знаки препинания
+		{"Café «Procópio»", "cafe procopio"},
+		{"Храм Спаса-на-Крови", "hram spasa na krovi"},
+		{"№ 12", "12"},
+		{"", ""},
+		{"---", ""},
+	}
+	for _, tt := range tests {
+		if got := Normalize(tt.name); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLevenshtein(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"", "abc", 3},
+		{"kitten", "sitting", 3},
+		{"flaw", "lawn", 2},
+		{"moskva", "moskov", 2},
+		{"кремль", "кремл", 1},
+	}
+	for _, tt := range tests {
+		if got := levenshtein([]rune(tt.a), []rune(tt.b)); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := levenshtein([]rune(tt.b), []rune(tt.a)); got != tt.want {
+			t.Errorf("levenshtein(%q, %q) = %d, want %d", tt.b, tt.a, got, tt.want)
+		}
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"Москва", "Moskva", 1},
+		{"Ясная Поляна", "Поляна Ясная", 1},
+		// одна буква из шести: 5/6
+		{"Москва", "Moskvy", 5. / 6},
+		// «moskva» и «moscov»: три буквы из шести, ниже порога по умолчанию 0,8
+		{"Москва", "Moscow", 0.5},
+		// «uspenski sobor» и «uspenski hram»: пять правок на 14 букв
+		{"Успенский собор", "Успенский храм", 1 - 5./14},
+		{"Москва", "", 0},
+		{"", "", 0},
+	}
+	for _, tt := range tests {
+		got := Similarity(Normalize(tt.a), Normalize(tt.b))
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Similarity(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+	if Similarity("moskva", "moskvy") < DefaultSimilarity || Similarity("moskva", "moskov") >= DefaultSimilarity {
+		t.Error("default similarity threshold")
+	}
+}
@@ -0,0 +1,93 @@
+package dedup
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/audetv/datasets-parser/app/repos/entity"
+	"github.com/google/uuid"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// reportHeader колонки отчёта о дубликатах
+var reportHeader = []string{"cluster", "id", "filename", "name", "longitude", "latitude", "distance", "similarity"}
+
+// WriteReport записывает кластеры в csv с разделителем «;» и колонками reportHeader для проверки
+// перед объединением: первая строка кластера — каноническая сущность без id, затем его сущности
+// с расстоянием до канонической точки в метрах и сходством имени с каноническим
+func WriteReport(w io.Writer, merges []entity.Merge) error {
+	c := csv.NewWriter(w)
+	c.Comma = ';'
+	c.Write(reportHeader)
+	for i, m := range merges {
+		cluster := strconv.Itoa(i + 1)
+		e := m.Canonical
+		c.Write([]string{cluster, "", e.Filename, e.Name, formatFloat(e.Longitude), formatFloat(e.Latitude), "", ""})
+		for _, mm := range m.Members {
+			c.Write([]string{
+				cluster, mm.ID.String(), mm.Filename, mm.Name, formatFloat(mm.Longitude), formatFloat(mm.Latitude),
+				strconv.FormatFloat(mm.Distance, 'f', 1, 64), strconv.FormatFloat(mm.Similarity, 'f', 3, 64),
+			})
+		}
+	}
+	c.Flush()
+	return c.Error()
+}
+
+// ReadReport читает отчёт WriteReport, возможно исправленный: строки удалены или номера кластеров
+// изменены. Возвращает идентификаторы сущностей кластеров в порядке их первых строк,
+// строки без id пропускаются, остальные колонки не читаются.
+func ReadReport(r io.Reader) ([][]uuid.UUID, error) {
+	c := csv.NewReader(r)
+	c.Comma = ';'
+	c.FieldsPerRecord = -1
+	header, err := c.Read()
+	if err == io.EOF {
+		return nil, errors.New("dedup report is empty")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(header) < 2 || strings.TrimPrefix(header[0], "\ufeff") != reportHeader[0] || header[1] != reportHeader[1] {
+		return nil, fmt.Errorf("dedup report must start with columns %v;%v", reportHeader[0], reportHeader[1])
+	}
+
+	var clusters [][]uuid.UUID
+	index := make(map[string]int)
+	seen := make(map[uuid.UUID]bool)
+	for {
+		record, err := c.Read()
+		if err == io.EOF {
+			return clusters, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		line, _ := c.FieldPos(0)
+		if len(record) < 2 || strings.TrimSpace(record[1]) == "" {
+			continue
+		}
+		id, err := uuid.Parse(strings.TrimSpace(record[1]))
+		if err != nil {
+			return nil, fmt.Errorf("dedup report line %d: %w", line, err)
+		}
+		if seen[id] {
+			return nil, fmt.Errorf("dedup report line %d: entity %v is listed twice", line, id)
+		}
+		seen[id] = true
+		cluster := strings.TrimSpace(record[0])
+		k, ok := index[cluster]
+		if !ok {
+			k = len(clusters)
+			index[cluster] = k
+			clusters = append(clusters, nil)
+		}
+		clusters[k] = append(clusters[k], id)
+	}
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
@@ -0,0 +1,109 @@
+package exporter
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"github.com/audetv/datasets-parser/app/alignment"
+	"io"
+	"math"
+	"strconv"
+)
+
+type alignmentFeature struct {
+	Type       string              `json:"type"`
+	Geometry   lineString          `json:"geometry"`
+	Properties alignmentProperties `json:"properties"`
+}
+
+type lineString struct {
+	Type        string      `json:"type"`
+	Coordinates interface{} `json:"coordinates"`
+}
+
+// newLineString возвращает LineString или, если путь пересекает 180-й меридиан,
+// MultiLineString из частей по обе стороны от него (RFC 7946, 3.1.9)
+func newLineString(path [][2]float64) lineString {
+	var parts [][][2]float64
+	var part [][2]float64
+	shift := 0.0
+	for i, p := range path {
+		lon := p[0] + shift
+		if lon > 180 || lon < -180 {
+			// отрезок пересекает меридиан: точка пересечения завершает часть и начинает следующую
+			prev := path[i-1]
+			edge := math.Copysign(180, lon)
+			lat := prev[1] + (p[1]-prev[1])*(edge-(prev[0]+shift))/(lon-(prev[0]+shift))
+			part = append(part, [2]float64{edge, lat})
+			parts = append(parts, part)
+			shift -= 2 * edge
+			part = [][2]float64{{-edge, lat}}
+			lon = p[0] + shift
+		}
+		part = append(part, [2]float64{lon, p[1]})
+	}
+	if len(parts) == 0 {
+		return lineString{Type: "LineString", Coordinates: part}
+	}
+	return lineString{Type: "MultiLineString", Coordinates: append(parts, part)}
+}
+
+type alignmentProperties struct {
+	Rank      int      `json:"rank"`
+	Count     int      `json:"count"`
+	Length    float64  `json:"length"`
+	Azimuth   float64  `json:"azimuth"`
+	Deviation float64  `json:"deviation"`
+	Members   []string `json:"members"`
+	Names     []string `json:"names"`
+}
+
+// WriteAlignments записывает линии в w FeatureCollection линий LineString по дугам больших кругов
+// со свойствами rank, count — число сущностей, length в метрах, azimuth, deviation в градусах
+// и ID и названиями сущностей members и names
+func WriteAlignments(w io.Writer, alignments []alignment.Alignment) error {
+	features := make([]alignmentFeature, len(alignments))
+	for i, a := range alignments {
+		p := alignmentProperties{
+			Rank: i + 1, Count: len(a.Members), Length: a.Length, Azimuth: a.Azimuth, Deviation: a.Deviation,
+		}
+		for _, m := range a.Members {
+			p.Members = append(p.Members, m.ID.String())
+			p.Names = append(p.Names, m.Name)
+		}
+		features[i] = alignmentFeature{
+			Type:       "Feature",
+			Geometry:   newLineString(a.Path),
+			Properties: p,
+		}
+	}
+	e := json.NewEncoder(w)
+	e.SetEscapeHTML(false)
+	return e.Encode(struct {
+		Type     string             `json:"type"`
+		Features []alignmentFeature `json:"features"`
+	}{Type: "FeatureCollection", Features: features})
+}
+
+// alignmentMembersHeader колонки csv сущностей линий
+var alignmentMembersHeader = []string{
+	"rank", "position", "id", "filename", "name", "longitude", "latitude", "offset", "deviation",
+}
+
+// WriteAlignmentMembers записывает в w сущности линий csv с разделителем «;»: rank — номер линии,
+// position — номер сущности вдоль линии, offset — расстояние вдоль линии в метрах, deviation — отклонение в градусах
+func WriteAlignmentMembers(w io.Writer, alignments []alignment.Alignment) error {
+	c := csv.NewWriter(w)
+	c.Comma = ';'
+	c.Write(alignmentMembersHeader)
+	for i, a := range alignments {
+		for j, m := range a.Members {
+			c.Write([]string{
+				strconv.Itoa(i + 1), strconv.Itoa(j + 1), m.ID.String(), m.Filename, m.Name,
+				formatFloat(m.Longitude), formatFloat(m.Latitude),
+				strconv.FormatFloat(m.Offset, 'f', 1, 64), strconv.FormatFloat(m.Deviation, 'f', 6, 64),
+			})
+		}
+	}
+	c.Flush()
+	return c.Error()
+}
@@ -0,0 +1,153 @@
+// Package exporter выгружает сущности из хранилища в файлы форматов
+// GeoJSON, GeoJSONSeq, KML, CSV, NDJSON, shapefile и geomatrix_marks.
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"github.com/audetv/datasets-parser/app/repos/entity"
+	"io"
+	"os"
+	"strings"
+)
+
+// Format формат файла выгрузки
+type Format string
+
+const (
+	FormatGeoJSON    Format = "geojson"
+	FormatGeoJSONSeq Format = "geojsonseq"
+	FormatKML        Format = "kml"
+	FormatCSV        Format = "csv"
+	FormatNDJSON     Format = "ndjson"
+	FormatShapefile  Format = "shp"
+	FormatGeomatrix  Format = "geomatrix"
+)
+
+// Formats все форматы выгрузки
+var Formats = []Format{FormatGeoJSON, FormatGeoJSONSeq, FormatKML, FormatCSV, FormatNDJSON, FormatShapefile, FormatGeomatrix}
+
+// aliases другие названия форматов
+var aliases = map[string]Format{
+	"shapefile":       FormatShapefile,
+	"geomatrix_marks": FormatGeomatrix,
+	"geojsonl":        FormatGeoJSONSeq,
+	"jsonl":           FormatNDJSON,
+}
+
+// ParseFormat разбирает название формата выгрузки
+func ParseFormat(s string) (Format, error) {
+	name := strings.ToLower(strings.TrimSpace(s))
+	if f, ok := aliases[name]; ok {
+		return f, nil
+	}
+	for _, f := range Formats {
+		if string(f) == name {
+			return f, nil
+		}
+	}
+	names := make([]string, len(Formats))
+	for i, f := range Formats {
+		names[i] = string(f)
+	}
+	return "", fmt.Errorf("export: unsupported format %q, expected %v", s, strings.Join(names, ", "))
+}
+
+// Writer записывает сущности в файл выгрузки, Close дописывает окончание файла
+type Writer interface {
+	Write(e entity.Entity) error
+	Close() error
+}
+
+// Stdout имя файла выгрузки, означающее стандартный вывод
+const Stdout = "-"
+
+// Create создаёт файл output и Writer формата format.
+// Все форматы, кроме shapefile, можно выгрузить в стандартный вывод, указав Stdout.
+func Create(format Format, output string) (Writer, error) {
+	if output == "" {
+		return nil, fmt.Errorf("export: output file is not set")
+	}
+	if format == FormatShapefile {
+		if output == Stdout {
+			return nil, fmt.Errorf("export: shapefile cannot be written to stdout")
+		}
+		return createShapefile(output)
+	}
+
+	var w io.WriteCloser = nopCloser{os.Stdout}
+	if output != Stdout {
+		f, err := os.Create(output)
+		if err != nil {
+			return nil, err
+		}
+		w = f
+	}
+	switch format {
+	case FormatGeoJSON:
+		return newGeoJSONWriter(w, false), nil
+	case FormatGeoJSONSeq:
+		return newGeoJSONWriter(w, true), nil
+	case FormatKML:
+		return newKMLWriter(w), nil
+	case FormatCSV:
+		return newCSVWriter(w), nil
+	case FormatNDJSON:
+		return newNDJSONWriter(w), nil
+	case FormatGeomatrix:
+		return newGeomatrixWriter(w), nil
+	}
+	w.Close()
+	return nil, fmt.Errorf("export: unsupported format %q", format)
+}
+
+// Export записывает в w сущности хранилища, подходящие под filter,
+// закрывает w и возвращает количество выгруженных сущностей
+func Export(ctx context.Context, store entity.ReadStore, filter entity.Filter, w Writer) (int, error) {
+	n := 0
+	err := store.All(ctx, filter, func(e entity.Entity) error {
+		if err := w.Write(e); err != nil {
+			return err
+		}
+		n++
+		return nil
+	})
+	if cerr := w.Close(); err == nil {
+		err = cerr
+	}
+	return n, err
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+// properties поля сущности для форматов, в которых координаты
+// записываются отдельно от свойств
+type properties struct {
+	Filename        string      `json:"filename"`
+	Name            string      `json:"name"`
+	Description     string      `json:"description,omitempty"`
+	Height          float64     `json:"height,omitempty"`
+	CellID          uint64      `json:"cell_id,string"`
+	S2Token         string      `json:"s2_token,omitempty"`
+	Geohash         string      `json:"geohash,omitempty"`
+	DescriptionJson interface{} `json:"description_json,omitempty"`
+}
+
+func propertiesOf(e entity.Entity) properties {
+	return properties{
+		Filename:        e.Filename,
+		Name:            e.Name,
+		Description:     e.Description,
+		Height:          e.Height,
+		CellID:          e.CellID,
+		S2Token:         e.S2Token,
+		Geohash:         e.Geohash,
+		DescriptionJson: e.DescriptionJson,
+	}
+}
@@ -0,0 +1,249 @@
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"github.com/audetv/datasets-parser/app/repos/entity"
+	"github.com/google/uuid"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// entities сущности двух файлов набора данных в порядке хранилища
+var entities = []entity.Entity{
+	{ID: uuid.MustParse("0f8fad5b-d9cb-469f-a165-70867728950e"), Filename: "cities.csv", Name: "Москва",
+		Description: "столица", Longitude: 37.62, Latitude: 55.75, Height: 156, CellID: 1,
+		DescriptionJson: map[string]interface{}{"population": 13010112}},
+	{ID: uuid.MustParse("7c9e6679-7425-40de-944b-e07fc1f90ae7"), Filename: "cities.csv", Name: "Тверь",
+		Longitude: 35.9, Latitude: 56.86, CellID: 2},
+	{ID: uuid.MustParse("16fd2706-8baf-433b-82eb-8c7fada847da"), Filename: "temples.kml", Name: "Храм <Спаса>",
+		Longitude: 37.6, Latitude: 55.74, CellID: 3},
+}
+
+// sliceStore хранилище сущностей в памяти
+type sliceStore []entity.Entity
+
+func (s sliceStore) All(ctx context.Context, filter entity.Filter, fn func(e entity.Entity) error) error {
+	for _, e := range s {
+		if !filter.Match(e) {
+			continue
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s sliceStore) Get(ctx context.Context, id uuid.UUID) (entity.Entity, error) {
+	for _, e := range s {
+		if e.ID == id {
+			return e, nil
+		}
+	}
+	return entity.Entity{}, entity.ErrNotFound
+}
+
+func (s sliceStore) Near(ctx context.Context, q entity.NearQuery) ([]entity.Neighbour, error) {
+	return nil, nil
+}
+
+// export выгружает сущности в файл формата format и возвращает его содержимое
+func export(t *testing.T, format Format, filter entity.Filter) (int, []byte) {
+	t.Helper()
+	output := filepath.Join(t.TempDir(), "export."+string(format))
+	w, err := Create(format, output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := Export(context.Background(), sliceStore(entities), filter, w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n, data
+}
+
+func TestParseFormat(t *testing.T) {
+	for s, want := range map[string]Format{"GeoJSON": FormatGeoJSON, " kml ": FormatKML, "shapefile": FormatShapefile,
+		"geomatrix_marks": FormatGeomatrix, "jsonl": FormatNDJSON} {
+		if got, err := ParseFormat(s); err != nil || got != want {
+			t.Errorf("%q: %v, %v, want %v", s, got, err, want)
+		}
+	}
+	if _, err := ParseFormat("xlsx"); err == nil {
+		t.Error("xlsx accepted")
+	}
+}
+
+func TestCreate(t *testing.T) {
+	if _, err := Create(FormatGeoJSON, ""); err == nil {
+		t.Error("empty output accepted")
+	}
+	if _, err := Create(FormatShapefile, Stdout); err == nil {
+		t.Error("shapefile to stdout accepted")
+	}
+}
+
+func TestGeoJSON(t *testing.T) {
+	n, data := export(t, FormatGeoJSON, entity.Filter{})
+	var fc struct {
+		Type     string
+		Features []feature
+	}
+	if err := json.Unmarshal(data, &fc); err != nil {
+		t.Fatalf("%v\n%s", err, data)
+	}
+	if n != 3 || fc.Type != "FeatureCollection" || len(fc.Features) != 3 {
+		t.Fatalf("exported %d, collection %+v", n, fc)
+	}
+	moscow := fc.Features[0]
+	if moscow.ID != entities[0].ID.String() || len(moscow.Geometry.Coordinates) != 3 ||
+		moscow.Geometry.Coordinates[0] != 37.62 || moscow.Properties.Filename != "cities.csv" {
+		t.Errorf("moscow %+v", moscow)
+	}
+	// нулевая высота не записывается
+	if len(fc.Features[1].Geometry.Coordinates) != 2 {
+		t.Errorf("tver coordinates %v", fc.Features[1].Geometry.Coordinates)
+	}
+
+	// пустая выборка — пустая коллекция, а не пустой файл
+	_, data = export(t, FormatGeoJSON, entity.Filter{Filenames: []string{"rivers.csv"}})
+	if err := json.Unmarshal(data, &fc); err != nil || len(fc.Features) != 0 {
+		t.Errorf("empty collection %s, %v", data, err)
+	}
+}
+
+func TestGeoJSONSeq(t *testing.T) {
+	_, data := export(t, FormatGeoJSONSeq, entity.Filter{Filenames: []string{"cities.csv"}})
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("lines %q", lines)
+	}
+	for _, line := range lines {
+		var f feature
+		if line[0] != 0x1e || json.Unmarshal([]byte(line[1:]), &f) != nil || f.Type != "Feature" {
+			t.Errorf("line %q", line)
+		}
+	}
+}
+
+func TestCSV(t *testing.T) {
+	_, data := export(t, FormatCSV, entity.Filter{BBox: &entity.BBox{West: 37, South: 55, East: 38, North: 56}})
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comma = ';'
+	records, err := r.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 || records[0][0] != "id" || records[1][2] != "Москва" || records[2][2] != "Храм <Спаса>" {
+		t.Fatalf("records %q", records)
+	}
+	if records[1][10] != `{"population":13010112}` || records[2][10] != "" {
+		t.Errorf("description_json %q, %q", records[1][10], records[2][10])
+	}
+}
+
+func TestNDJSON(t *testing.T) {
+	_, data := export(t, FormatNDJSON, entity.Filter{Filenames: []string{"temples.kml"}})
+	var r record
+	if err := json.Unmarshal(data, &r); err != nil || r.Name != "Храм <Спаса>" || r.Latitude != 55.74 {
+		t.Errorf("record %s, %v", data, err)
+	}
+}
+
+func TestKML(t *testing.T) {
+	_, data := export(t, FormatKML, entity.Filter{})
+	var doc struct {
+		Folders []struct {
+			Name       string `xml:"name"`
+			Placemarks []struct {
+				Name string `xml:"name"`
+			} `xml:"Placemark"`
+		} `xml:"Document>Folder"`
+	}
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("%v\n%s", err, data)
+	}
+	// сущности каждого файла — в своей папке
+	if len(doc.Folders) != 2 || doc.Folders[0].Name != "cities.csv" || len(doc.Folders[0].Placemarks) != 2 ||
+		doc.Folders[1].Placemarks[0].Name != "Храм <Спаса>" {
+		t.Errorf("folders %+v", doc.Folders)
+	}
+}
+
+func TestGeomatrix(t *testing.T) {
+	_, data := export(t, FormatGeomatrix, entity.Filter{Filenames: []string{"cities.csv"}})
+	r := csv.NewReader(bytes.NewReader(data))
+	r.Comma = ';'
+	records, err := r.ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 || len(records[0]) != len(geomatrixHeader) {
+		t.Fatalf("records %q", records)
+	}
+	if moscow := records[1]; moscow[0] != "1" || moscow[1] != "55.75" || moscow[2] != "37.62" ||
+		moscow[7] != entities[0].ID.String() {
+		t.Errorf("moscow %q", moscow)
+	}
+	// geohash сущностей без geohash вычисляется по координатам, gh4 — его начало
+	if moscow := records[1]; len(moscow[6]) != geomatrixPrecision || moscow[8] != moscow[6][:4] ||
+		moscow[9] == "" || moscow[16] == "" {
+		t.Errorf("moscow geohash %q", moscow[6:])
+	}
+	if records[2][0] != "2" {
+		t.Errorf("tver %q", records[2])
+	}
+}
+
+func TestWriteNeighbours(t *testing.T) {
+	neighbours := []entity.Neighbour{
+		{Entity: entities[2], Distance: 1234.5, Azimuth: 200},
+		{Entity: entities[1], Distance: 160000, Azimuth: 305.5},
+	}
+
+	var b bytes.Buffer
+	if err := WriteNeighbours(&b, FormatGeoJSON, neighbours); err != nil {
+		t.Fatal(err)
+	}
+	var fc struct {
+		Features []struct {
+			ID         string
+			Properties struct {
+				Rank     int
+				Distance float64
+				Name     string
+			}
+		}
+	}
+	if err := json.Unmarshal(b.Bytes(), &fc); err != nil {
+		t.Fatal(err)
+	}
+	if len(fc.Features) != 2 || fc.Features[0].Properties.Rank != 1 || fc.Features[0].Properties.Distance != 1234.5 ||
+		fc.Features[0].Properties.Name != "Храм <Спаса>" || fc.Features[1].ID != entities[1].ID.String() {
+		t.Errorf("features %+v", fc.Features)
+	}
+
+	b.Reset()
+	if err := WriteNeighbours(&b, FormatCSV, neighbours); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 3 || lines[0] != strings.Join(neighbourHeader, ";") ||
+		!strings.HasPrefix(lines[2], "2;160000.000;305.500000;"+entities[1].ID.String()+";cities.csv;Тверь;") {
+		t.Errorf("csv %q", lines)
+	}
+
+	if err := WriteNeighbours(&b, FormatKML, neighbours); err == nil {
+		t.Error("kml accepted")
+	}
+}
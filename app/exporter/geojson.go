@@ -0,0 +1,79 @@
+package exporter
+
+import (
+	"bufio"
+	"encoding/json"
+	"github.com/audetv/datasets-parser/app/repos/entity"
+	"io"
+)
+
+// geojsonWriter пишет FeatureCollection потоком, не собирая её в памяти,
+// или, если seq, последовательность объектов GeoJSONSeq (RFC 8142)
+type geojsonWriter struct {
+	f   io.WriteCloser
+	w   *bufio.Writer
+	seq bool
+	n   int
+}
+
+func newGeoJSONWriter(f io.WriteCloser, seq bool) *geojsonWriter {
+	return &geojsonWriter{f: f, w: bufio.NewWriter(f), seq: seq}
+}
+
+type feature struct {
+	Type       string     `json:"type"`
+	ID         string     `json:"id"`
+	Geometry   point      `json:"geometry"`
+	Properties properties `json:"properties"`
+}
+
+type point struct {
+	Type        string    `json:"type"`
+	Coordinates []float64 `json:"coordinates"`
+}
+
+func (g *geojsonWriter) Write(e entity.Entity) error {
+	coordinates := []float64{e.Longitude, e.Latitude}
+	if e.Height != 0 {
+		coordinates = append(coordinates, e.Height)
+	}
+	data, err := json.Marshal(feature{
+		Type:       "Feature",
+		ID:         e.ID.String(),
+		Geometry:   point{Type: "Point", Coordinates: coordinates},
+		Properties: propertiesOf(e),
+	})
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case g.seq:
+		// каждый объект GeoJSONSeq начинается с символа RS
+		g.w.WriteByte(0x1e)
+	case g.n == 0:
+		g.w.WriteString(`{"type":"FeatureCollection","features":[` + "\n")
+	default:
+		g.w.WriteString(",\n")
+	}
+	g.w.Write(data)
+	if g.seq {
+		g.w.WriteByte('\n')
+	}
+	g.n++
+	return nil
+}
+
+func (g *geojsonWriter) Close() error {
+	if !g.seq {
+		if g.n == 0 {
+			g.w.WriteString(`{"type":"FeatureCollection","features":[`)
+		}
+		g.w.WriteString("\n]}\n")
+	}
+	err := g.w.Flush()
+	if cerr := g.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
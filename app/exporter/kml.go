@@ -0,0 +1,139 @@
+package exporter
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"github.com/audetv/datasets-parser/app/repos/entity"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// kmlColors цвета значков наборов данных в формате KML aabbggrr
+var kmlColors = []string{
+	"ff3c14dc", "ff0080ff", "ff00d7ff", "ff32cd32", "ffd0e040", "ffff901e",
+	"ffe22b8a", "ffb469ff", "ff2d52a0", "ff808000", "ff000080", "ff808080",
+}
+
+// kmlWriter пишет KML документ: сущности каждого файла набора данных — в своей папке
+// и со своим стилем значка. Хранилище отдаёт сущности по порядку файлов,
+// поэтому папка закрывается, когда начинается следующий файл.
+type kmlWriter struct {
+	f io.WriteCloser
+	w *bufio.Writer
+	// styles номера стилей файлов наборов данных
+	styles map[string]int
+	// folder файл открытой папки
+	folder string
+	open   bool
+}
+
+func newKMLWriter(f io.WriteCloser) *kmlWriter {
+	w := bufio.NewWriter(f)
+	w.WriteString(xml.Header)
+	w.WriteString(`<kml xmlns="http://www.opengis.net/kml/2.2">` + "\n<Document>\n<name>geomatrix</name>\n")
+	return &kmlWriter{f: f, w: w, styles: make(map[string]int)}
+}
+
+func (k *kmlWriter) Write(e entity.Entity) error {
+	if !k.open || e.Filename != k.folder {
+		k.startFolder(e.Filename)
+	}
+
+	k.w.WriteString("<Placemark>\n<name>")
+	k.text(e.Name)
+	k.w.WriteString("</name>\n")
+	if e.Description != "" {
+		k.w.WriteString("<description>")
+		k.text(e.Description)
+		k.w.WriteString("</description>\n")
+	}
+	fmt.Fprintf(k.w, "<styleUrl>#dataset-%d</styleUrl>\n<ExtendedData>\n", k.styles[e.Filename])
+	k.data("id", e.ID.String())
+	k.data("cell_id", strconv.FormatUint(e.CellID, 10))
+	k.data("s2_token", e.S2Token)
+	k.data("geohash", e.Geohash)
+	if err := k.descriptionJson(e.DescriptionJson); err != nil {
+		return err
+	}
+	k.w.WriteString("</ExtendedData>\n<Point><coordinates>")
+	k.w.WriteString(formatFloat(e.Longitude) + "," + formatFloat(e.Latitude))
+	if e.Height != 0 {
+		k.w.WriteString("," + formatFloat(e.Height))
+	}
+	k.w.WriteString("</coordinates></Point>\n</Placemark>\n")
+	return nil
+}
+
+// startFolder закрывает открытую папку и открывает папку файла filename.
+// Стиль набора данных объявляется в первой его папке.
+func (k *kmlWriter) startFolder(filename string) {
+	if k.open {
+		k.w.WriteString("</Folder>\n")
+	}
+	k.folder, k.open = filename, true
+	k.w.WriteString("<Folder>\n<name>")
+	k.text(filename)
+	k.w.WriteString("</name>\n")
+
+	if _, ok := k.styles[filename]; ok {
+		return
+	}
+	n := len(k.styles) + 1
+	k.styles[filename] = n
+	fmt.Fprintf(k.w, `<Style id="dataset-%d"><IconStyle><color>%v</color><scale>0.8</scale>`+
+		`<Icon><href>http://maps.google.com/mapfiles/kml/shapes/placemark_circle.png</href></Icon>`+
+		"</IconStyle></Style>\n", n, kmlColors[(n-1)%len(kmlColors)])
+}
+
+// descriptionJson записывает ключи верхнего уровня DescriptionJson в ExtendedData,
+// строки — как есть, остальные значения — в json
+func (k *kmlWriter) descriptionJson(dj interface{}) error {
+	m, ok := dj.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if s, ok := m[key].(string); ok {
+			k.data(key, s)
+			continue
+		}
+		data, err := json.Marshal(m[key])
+		if err != nil {
+			return err
+		}
+		k.data(key, string(data))
+	}
+	return nil
+}
+
+func (k *kmlWriter) data(name string, value string) {
+	k.w.WriteString(`<Data name="`)
+	k.text(name)
+	k.w.WriteString(`"><value>`)
+	k.text(value)
+	k.w.WriteString("</value></Data>\n")
+}
+
+func (k *kmlWriter) text(s string) {
+	xml.EscapeText(k.w, []byte(s))
+}
+
+func (k *kmlWriter) Close() error {
+	if k.open {
+		k.w.WriteString("</Folder>\n")
+	}
+	k.w.WriteString("</Document>\n</kml>\n")
+	err := k.w.Flush()
+	if cerr := k.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
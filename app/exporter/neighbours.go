@@ -0,0 +1,67 @@
+package exporter
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"github.com/audetv/datasets-parser/app/repos/entity"
+	"io"
+	"strconv"
+)
+
+// neighbourHeader колонки csv соседей
+var neighbourHeader = []string{"rank", "distance", "azimuth", "id", "filename", "name", "longitude", "latitude"}
+
+// neighbourProperties свойства сущности с местом, расстоянием в метрах и азимутом
+type neighbourProperties struct {
+	Rank     int     `json:"rank"`
+	Distance float64 `json:"distance"`
+	Azimuth  float64 `json:"azimuth"`
+	properties
+}
+
+type neighbourFeature struct {
+	Type       string              `json:"type"`
+	ID         string              `json:"id"`
+	Geometry   point               `json:"geometry"`
+	Properties neighbourProperties `json:"properties"`
+}
+
+// WriteNeighbours записывает в w соседей, найденных запросом entity.NearQuery, в порядке расстояния:
+// FormatGeoJSON — FeatureCollection со свойствами rank, distance и azimuth,
+// FormatCSV — с разделителем «;» и колонками neighbourHeader
+func WriteNeighbours(w io.Writer, format Format, neighbours []entity.Neighbour) error {
+	switch format {
+	case FormatGeoJSON:
+		features := make([]neighbourFeature, len(neighbours))
+		for i, n := range neighbours {
+			features[i] = neighbourFeature{
+				Type:     "Feature",
+				ID:       n.ID.String(),
+				Geometry: point{Type: "Point", Coordinates: []float64{n.Longitude, n.Latitude}},
+				Properties: neighbourProperties{
+					Rank: i + 1, Distance: n.Distance, Azimuth: n.Azimuth, properties: propertiesOf(n.Entity),
+				},
+			}
+		}
+		e := json.NewEncoder(w)
+		e.SetEscapeHTML(false)
+		return e.Encode(struct {
+			Type     string             `json:"type"`
+			Features []neighbourFeature `json:"features"`
+		}{Type: "FeatureCollection", Features: features})
+	case FormatCSV:
+		c := csv.NewWriter(w)
+		c.Comma = ';'
+		c.Write(neighbourHeader)
+		for i, n := range neighbours {
+			c.Write([]string{
+				strconv.Itoa(i + 1), strconv.FormatFloat(n.Distance, 'f', 3, 64), strconv.FormatFloat(n.Azimuth, 'f', 6, 64),
+				n.ID.String(), n.Filename, n.Name, formatFloat(n.Longitude), formatFloat(n.Latitude),
+			})
+		}
+		c.Flush()
+		return c.Error()
+	}
+	return fmt.Errorf("export: format %q is not supported for neighbours, expected geojson or csv", format)
+}
@@ -0,0 +1,50 @@
+package exporter
+
+import (
+	"fmt"
+	"github.com/audetv/datasets-parser/app/repos/entity"
+	"github.com/audetv/datasets-parser/dataset/shapefile"
+	"path/filepath"
+	"strings"
+)
+
+// shapefileFields поля dbf выгрузки сущностей в shapefile
+var shapefileFields = []shapefile.Field{
+	{Name: "ID", Type: shapefile.Character, Length: 36},
+	{Name: "FILENAME", Type: shapefile.Character, Length: 254},
+	{Name: "NAME", Type: shapefile.Character, Length: 254},
+	{Name: "DESCR", Type: shapefile.Character, Length: 254},
+	{Name: "LON", Type: shapefile.Numeric, Length: 19, Decimals: 11},
+	{Name: "LAT", Type: shapefile.Numeric, Length: 19, Decimals: 11},
+	{Name: "HEIGHT", Type: shapefile.Numeric, Length: 19, Decimals: 3},
+	{Name: "CELLID", Type: shapefile.Character, Length: 20},
+	{Name: "S2TOKEN", Type: shapefile.Character, Length: 16},
+	{Name: "GEOHASH", Type: shapefile.Character, Length: 12},
+}
+
+// shapefileWriter пишет сущности точками в shapefile. Строки длиннее 254 байт
+// обрезаются, DescriptionJson не выгружается.
+type shapefileWriter struct {
+	w *shapefile.Writer
+}
+
+func createShapefile(output string) (*shapefileWriter, error) {
+	base := strings.TrimSuffix(output, filepath.Ext(output))
+	w, err := shapefile.Create(base, shapefileFields)
+	if err != nil {
+		return nil, err
+	}
+	return &shapefileWriter{w: w}, nil
+}
+
+func (s *shapefileWriter) Write(e entity.Entity) error {
+	return s.w.Write(e.Longitude, e.Latitude, []interface{}{
+		e.ID.String(), e.Filename, e.Name, e.Description,
+		e.Longitude, e.Latitude, e.Height,
+		fmt.Sprint(e.CellID), e.S2Token, e.Geohash,
+	})
+}
+
+func (s *shapefileWriter) Close() error {
+	return s.w.Close()
+}
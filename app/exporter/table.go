@@ -0,0 +1,158 @@
+package exporter
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"github.com/audetv/datasets-parser/app/geoindex"
+	"github.com/audetv/datasets-parser/app/repos/entity"
+	"github.com/audetv/datasets-parser/dataset/geohash"
+	"io"
+	"strconv"
+)
+
+// csvWriter пишет сущности в csv с разделителем «;», description_json — строкой json
+type csvWriter struct {
+	f io.WriteCloser
+	w *csv.Writer
+	n int
+}
+
+var csvHeader = []string{
+	"id", "filename", "name", "description", "longitude", "latitude", "height", "cell_id", "s2_token", "geohash",
+	"description_json",
+}
+
+func newCSVWriter(f io.WriteCloser) *csvWriter {
+	w := csv.NewWriter(f)
+	w.Comma = ';'
+	return &csvWriter{f: f, w: w}
+}
+
+func (c *csvWriter) Write(e entity.Entity) error {
+	if c.n == 0 {
+		if err := c.w.Write(csvHeader); err != nil {
+			return err
+		}
+	}
+	dj := ""
+	if e.DescriptionJson != nil {
+		data, err := json.Marshal(e.DescriptionJson)
+		if err != nil {
+			return err
+		}
+		dj = string(data)
+	}
+	c.n++
+	return c.w.Write([]string{
+		e.ID.String(), e.Filename, e.Name, e.Description,
+		formatFloat(e.Longitude), formatFloat(e.Latitude), formatFloat(e.Height),
+		strconv.FormatUint(e.CellID, 10), e.S2Token, e.Geohash, dj,
+	})
+}
+
+func (c *csvWriter) Close() error {
+	if c.n == 0 {
+		c.w.Write(csvHeader)
+	}
+	return flush(c.w, c.f)
+}
+
+// ndjsonWriter пишет сущности json объектами, по одному на строку
+type ndjsonWriter struct {
+	f io.WriteCloser
+	w *bufio.Writer
+	e *json.Encoder
+}
+
+type record struct {
+	ID        string  `json:"id"`
+	Longitude float64 `json:"longitude"`
+	Latitude  float64 `json:"latitude"`
+	properties
+}
+
+func newNDJSONWriter(f io.WriteCloser) *ndjsonWriter {
+	w := bufio.NewWriter(f)
+	e := json.NewEncoder(w)
+	e.SetEscapeHTML(false)
+	return &ndjsonWriter{f: f, w: w, e: e}
+}
+
+func (n *ndjsonWriter) Write(e entity.Entity) error {
+	return n.e.Encode(record{ID: e.ID.String(), Longitude: e.Longitude, Latitude: e.Latitude, properties: propertiesOf(e)})
+}
+
+func (n *ndjsonWriter) Close() error {
+	err := n.w.Flush()
+	if cerr := n.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// geomatrixHeader колонки файла geomatrix_marks
+var geomatrixHeader = []string{
+	"id", "lat", "lon", "height", "name", "description", "geohash", "uuid",
+	"gh4", "gh_n", "gh_ne", "gh_e", "gh_se", "gh_s", "gh_sw", "gh_w", "gh_nw",
+}
+
+const (
+	// geomatrixPrecision длина geohash точки
+	geomatrixPrecision = geohash.MaxPrecision
+	// geomatrixCellPrecision длина geohash ячейки gh4, соседи gh_* — ячейки той же длины
+	geomatrixCellPrecision = 4
+)
+
+// geomatrixWriter пишет csv формата geomatrix_marks с разделителем «;»:
+// id — номер строки, uuid — ID сущности, geohash — geohash точки из 12 символов,
+// gh4 — его первые 4 символа, gh_n ... gh_nw — соседние с gh4 ячейки. Если geohash сущности
+// сохранён с другой длиной, он считается заново по координатам.
+type geomatrixWriter struct {
+	f io.WriteCloser
+	w *csv.Writer
+	n int
+}
+
+func newGeomatrixWriter(f io.WriteCloser) *geomatrixWriter {
+	w := csv.NewWriter(f)
+	w.Comma = ';'
+	return &geomatrixWriter{f: f, w: w}
+}
+
+func (g *geomatrixWriter) Write(e entity.Entity) error {
+	if g.n == 0 {
+		if err := g.w.Write(geomatrixHeader); err != nil {
+			return err
+		}
+	}
+	if len(e.Geohash) != geomatrixPrecision || len(e.GeohashCell) != geomatrixCellPrecision {
+		geoindex.Indexer{GeohashPrecision: geomatrixPrecision, CellPrecision: geomatrixCellPrecision}.Index(&e)
+	}
+	g.n++
+	record := []string{
+		strconv.Itoa(g.n), formatFloat(e.Latitude), formatFloat(e.Longitude), formatFloat(e.Height),
+		e.Name, e.Description, e.Geohash, e.ID.String(), e.GeohashCell,
+	}
+	return g.w.Write(append(record, e.GeohashNeighbours[:]...))
+}
+
+func (g *geomatrixWriter) Close() error {
+	if g.n == 0 {
+		g.w.Write(geomatrixHeader)
+	}
+	return flush(g.w, g.f)
+}
+
+func flush(w *csv.Writer, f io.Closer) error {
+	w.Flush()
+	err := w.Error()
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
@@ -0,0 +1,134 @@
+// Package geoindex вычисляет поля сущности, которые зависят только от координат:
+// ячейку S2 и её родителей, geohash с соседними ячейками и тайлы карты.
+package geoindex
+
+import (
+	"fmt"
+	"github.com/audetv/datasets-parser/app/repos/entity"
+	"github.com/audetv/datasets-parser/dataset/geohash"
+	"github.com/audetv/datasets-parser/dataset/tile"
+	"github.com/golang/geo/s2"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultGeohashPrecision длина geohash сущности по умолчанию, как в geomatrix_marks
+	DefaultGeohashPrecision = geohash.MaxPrecision
+	// DefaultGeohashCellPrecision длина ячейки geohash, соседи которой сохраняются, как gh4 в geomatrix_marks
+	DefaultGeohashCellPrecision = 4
+)
+
+var (
+	// DefaultS2Levels уровни родительских ячеек S2 по умолчанию: около 150, 10 и 0,6 км
+	DefaultS2Levels = []int{6, 10, 14}
+	// DefaultTileZooms масштабы тайлов карты по умолчанию
+	DefaultTileZooms = []int{8, 12}
+)
+
+var _ entity.Indexer = Indexer{}
+
+// Indexer вычисляет по координатам сущности ячейку S2 и её родителей, geohash
+// с соседними ячейками и тайлы карты
+type Indexer struct {
+	// GeohashPrecision длина Geohash, 0 — DefaultGeohashPrecision
+	GeohashPrecision int
+	// CellPrecision длина GeohashCell, не больше GeohashPrecision, 0 — DefaultGeohashCellPrecision
+	CellPrecision int
+	// S2Levels уровни родительских ячеек S2Cells от 0 до 30, пустой — ячейки не вычисляются
+	S2Levels []int
+	// TileZooms масштабы тайлов Tiles от 0 до tile.MaxZoom, пустой — тайлы не вычисляются
+	TileZooms []int
+}
+
+func (ix Indexer) withDefaults() Indexer {
+	if ix.GeohashPrecision == 0 {
+		ix.GeohashPrecision = DefaultGeohashPrecision
+	}
+	if ix.CellPrecision == 0 {
+		ix.CellPrecision = DefaultGeohashCellPrecision
+	}
+	return ix
+}
+
+// Validate проверяет длины geohash
+func (ix Indexer) Validate() error {
+	ix = ix.withDefaults()
+	if ix.GeohashPrecision < 1 || ix.GeohashPrecision > geohash.MaxPrecision {
+		return fmt.Errorf("geohash precision %d is out of range 1..%d", ix.GeohashPrecision, geohash.MaxPrecision)
+	}
+	if ix.CellPrecision < 1 || ix.CellPrecision > ix.GeohashPrecision {
+		return fmt.Errorf("geohash cell precision %d is out of range 1..%d", ix.CellPrecision, ix.GeohashPrecision)
+	}
+	if err := validateLevels("s2 level", ix.S2Levels, s2.MaxLevel); err != nil {
+		return err
+	}
+	return validateLevels("tile zoom", ix.TileZooms, tile.MaxZoom)
+}
+
+// ParseLevels разбирает уровни или масштабы через запятую, «none» и пустая строка — без уровней
+func ParseLevels(s string) ([]int, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "none" {
+		return nil, nil
+	}
+	var levels []int
+	for _, p := range strings.Split(s, ",") {
+		level, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil {
+			return nil, fmt.Errorf("levels %q: %w", s, err)
+		}
+		levels = append(levels, level)
+	}
+	return levels, nil
+}
+
+// FormatLevels записывает уровни через запятую, как их разбирает ParseLevels
+func FormatLevels(levels []int) string {
+	if len(levels) == 0 {
+		return "none"
+	}
+	parts := make([]string, len(levels))
+	for i, level := range levels {
+		parts[i] = strconv.Itoa(level)
+	}
+	return strings.Join(parts, ",")
+}
+
+func validateLevels(name string, levels []int, max int) error {
+	seen := make(map[int]bool, len(levels))
+	for _, level := range levels {
+		if level < 0 || level > max {
+			return fmt.Errorf("%v %d is out of range 0..%d", name, level, max)
+		}
+		if seen[level] {
+			return fmt.Errorf("%v %d is repeated", name, level)
+		}
+		seen[level] = true
+	}
+	return nil
+}
+
+// Index заполняет CellID, S2Token, Geohash, GeohashCell, GeohashNeighbours, S2Cells и Tiles
+// по координатам сущности. У ячеек за полюсом соседей нет, такие соседи остаются пустыми.
+func (ix Indexer) Index(e *entity.Entity) {
+	ix = ix.withDefaults()
+	cell := s2.CellIDFromLatLng(s2.LatLngFromDegrees(e.Latitude, e.Longitude))
+	e.CellID = uint64(cell)
+	e.S2Token = cell.ToToken()
+
+	e.Geohash = geohash.Encode(e.Latitude, e.Longitude, ix.GeohashPrecision)
+	e.GeohashCell = e.Geohash[:ix.CellPrecision]
+	// ячейка только что закодирована, ошибки разбора быть не может
+	e.GeohashNeighbours, _ = geohash.Neighbours(e.GeohashCell)
+
+	e.S2Cells = make([]entity.S2Cell, len(ix.S2Levels))
+	for i, level := range ix.S2Levels {
+		e.S2Cells[i] = entity.S2Cell{Level: level, ID: uint64(cell.Parent(level))}
+	}
+	e.Tiles = make([]entity.Tile, len(ix.TileZooms))
+	for i, zoom := range ix.TileZooms {
+		t := tile.At(e.Latitude, e.Longitude, zoom)
+		e.Tiles[i] = entity.Tile{Zoom: t.Zoom, X: t.X, Y: t.Y, Quadkey: t.Quadkey()}
+	}
+}
@@ -0,0 +1,78 @@
+package geoindex
+
+import (
+	"github.com/audetv/datasets-parser/app/repos/entity"
+	"github.com/golang/geo/s2"
+	"reflect"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	for _, ix := range []Indexer{{}, {GeohashPrecision: 1, CellPrecision: 1}, {GeohashPrecision: 6}} {
+		if err := ix.Validate(); err != nil {
+			t.Errorf("%+v: %v", ix, err)
+		}
+	}
+	for _, ix := range []Indexer{{GeohashPrecision: 13}, {GeohashPrecision: -1}, {GeohashPrecision: 3}, {CellPrecision: 13},
+		{S2Levels: []int{31}}, {S2Levels: []int{10, 10}}, {TileZooms: []int{-1}}, {TileZooms: []int{24}}} {
+		if err := ix.Validate(); err == nil {
+			t.Errorf("%+v accepted", ix)
+		}
+	}
+}
+
+func TestParseLevels(t *testing.T) {
+	tests := []struct {
+		s    string
+		want []int
+	}{
+		{"", nil},
+		{"none", nil},
+		{" 6, 10,14 ", []int{6, 10, 14}},
+		{"0", []int{0}},
+	}
+	for _, tt := range tests {
+		if got, err := ParseLevels(tt.s); err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: %v, %v, want %v", tt.s, got, err, tt.want)
+		}
+	}
+	if _, err := ParseLevels("6,,10"); err == nil {
+		t.Error("empty level accepted")
+	}
+
+	for _, levels := range [][]int{nil, {8, 12}} {
+		if got, err := ParseLevels(FormatLevels(levels)); err != nil || !reflect.DeepEqual(got, levels) {
+			t.Errorf("%v: %q parsed as %v, %v", levels, FormatLevels(levels), got, err)
+		}
+	}
+}
+
+func TestIndex(t *testing.T) {
+	e := entity.Entity{Latitude: 42.6, Longitude: -5.6}
+	Indexer{GeohashPrecision: 7, CellPrecision: 5, S2Levels: []int{14, 6}, TileZooms: []int{0, 12}}.Index(&e)
+
+	cell := s2.CellIDFromLatLng(s2.LatLngFromDegrees(42.6, -5.6))
+	if e.CellID != uint64(cell) || e.S2Token != cell.ToToken() {
+		t.Errorf("cell %v %q, want %v", e.CellID, e.S2Token, cell)
+	}
+	if len(e.Geohash) != 7 || e.GeohashCell != "ezs42" ||
+		e.GeohashNeighbours != [8]string{"ezs48", "ezs49", "ezs43", "ezs41", "ezs40", "ezefp", "ezefr", "ezefx"} {
+		t.Errorf("geohash %q %q %q", e.Geohash, e.GeohashCell, e.GeohashNeighbours)
+	}
+
+	if want := []entity.S2Cell{{Level: 14, ID: uint64(cell.Parent(14))}, {Level: 6, ID: uint64(cell.Parent(6))}}; !reflect.DeepEqual(e.S2Cells, want) {
+		t.Errorf("s2 cells %v, want %v", e.S2Cells, want)
+	}
+	if len(e.Tiles) != 2 || e.Tiles[0] != (entity.Tile{}) || e.Tiles[1].Zoom != 12 || len(e.Tiles[1].Quadkey) != 12 ||
+		e.Tiles[1].String() != "12/1984/1511" {
+		t.Errorf("tiles %v", e.Tiles)
+	}
+
+	// длины по умолчанию, как в geomatrix_marks; у полюса нет северных соседей
+	pole := entity.Entity{Latitude: 90, Longitude: 0}
+	Indexer{}.Index(&pole)
+	if len(pole.Geohash) != DefaultGeohashPrecision || len(pole.GeohashCell) != DefaultGeohashCellPrecision ||
+		pole.GeohashNeighbours[0] != "" || pole.GeohashNeighbours[4] == "" {
+		t.Errorf("pole %q %q %q", pole.Geohash, pole.GeohashCell, pole.GeohashNeighbours)
+	}
+}
@@ -0,0 +1,254 @@
+// Package geoquery выбирает сущности в геодезических областях: в коридоре вдоль большого круга,
+// в кольце вокруг точки, у антипода точки и в секторе азимутов. Кандидаты читаются из хранилища
+// по покрытию области ячейками S2, затем проверяются точно.
+package geoquery
+
+import (
+	"context"
+	"fmt"
+	"github.com/audetv/datasets-parser/app/repos/entity"
+	"github.com/audetv/datasets-parser/dataset/geodesy"
+	"github.com/golang/geo/s1"
+	"github.com/golang/geo/s2"
+	"math"
+	"sort"
+)
+
+// Query геодезический запрос
+type Query interface {
+	// Validate проверяет параметры запроса
+	Validate() error
+	// Region возвращает область на сфере, заведомо содержащую все подходящие точки
+	Region() s2.Region
+	// Match проверяет точку и возвращает её расстояние в метрах и азимут в градусах от 0 до 360,
+	// смысл которых зависит от запроса
+	Match(lat, lon float64) (distance float64, azimuth float64, ok bool)
+}
+
+const (
+	// maxCells сколько ячеек S2 покрывают область, столько диапазонов cell_id в запросе к хранилищу
+	maxCells = 256
+	// distanceMargin запас областей на сфере: расстояние по эллипсоиду отличается от сферического до 0,5 %
+	distanceMargin = 1.01
+	// azimuthMargin запас секторов на сфере в градусах: азимуты на эллипсоиде отличаются до 0,2°
+	azimuthMargin = 0.5
+	// maxDistance половина длины меридиана WGS 84, дальше любой точки Земли
+	maxDistance = 20003931.4586
+)
+
+// Search возвращает сущности store, подходящие под q, в порядке расстояния.
+// filter дополнительно ограничивает выборку, например по Filenames, его Cells заменяется покрытием области.
+func Search(ctx context.Context, store entity.ReadStore, q Query, filter entity.Filter) ([]entity.Neighbour, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	coverer := &s2.RegionCoverer{MaxLevel: s2.MaxLevel, MaxCells: maxCells}
+	filter.Cells = coverer.Covering(q.Region())
+
+	var found []entity.Neighbour
+	err := store.All(ctx, filter, func(e entity.Entity) error {
+		if distance, azimuth, ok := q.Match(e.Latitude, e.Longitude); ok {
+			found = append(found, entity.Neighbour{Entity: e, Distance: distance, Azimuth: azimuth})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].Distance != found[j].Distance {
+			return found[i].Distance < found[j].Distance
+		}
+		return found[i].ID.String() < found[j].ID.String()
+	})
+	return found, nil
+}
+
+// Corridor сущности не дальше Width метров от большого круга, проходящего через две точки.
+// Расстояние до круга считается на сфере среднего радиуса geodesy.Earth,
+// Match возвращает его и азимут из первой точки на сущность по эллипсоиду WGS 84.
+type Corridor struct {
+	Lat1, Lon1 float64
+	Lat2, Lon2 float64
+	Width      float64
+}
+
+func (c Corridor) Validate() error {
+	if err := validatePoint(c.Lat1, c.Lon1); err != nil {
+		return err
+	}
+	if err := validatePoint(c.Lat2, c.Lon2); err != nil {
+		return err
+	}
+	if !(c.Width > 0) {
+		return fmt.Errorf("corridor: width %v must be positive", c.Width)
+	}
+	if c.pole().Norm() == 0 {
+		return fmt.Errorf("corridor: points %v,%v and %v,%v do not define a great circle", c.Lat1, c.Lon1, c.Lat2, c.Lon2)
+	}
+	return nil
+}
+
+// pole полюс большого круга или нулевой вектор, если точки совпадают или противоположны
+func (c Corridor) pole() s2.Point {
+	a := point(c.Lat1, c.Lon1)
+	b := point(c.Lat2, c.Lon2)
+	n := a.Cross(b.Vector)
+	if n.Norm() < 1e-12 {
+		return s2.Point{}
+	}
+	return s2.Point{Vector: n.Normalize()}
+}
+
+func (c Corridor) Region() s2.Region {
+	return band(c.pole(), angle(c.Width)+1e-9)
+}
+
+func (c Corridor) Match(lat, lon float64) (float64, float64, bool) {
+	d := crossTrack(c.pole(), point(lat, lon)).Radians() * geodesy.Earth.Radius
+	if d > c.Width {
+		return 0, 0, false
+	}
+	return d, geodesy.Azimuth360(geodesy.WGS84.Inverse(c.Lat1, c.Lon1, lat, lon).Azimuth1), true
+}
+
+// Ring сущности на расстоянии Distance ± Tolerance метров от точки по эллипсоиду WGS 84,
+// Match возвращает расстояние и азимут из точки
+type Ring struct {
+	Lat, Lon  float64
+	Distance  float64
+	Tolerance float64
+}
+
+func (r Ring) Validate() error {
+	if err := validatePoint(r.Lat, r.Lon); err != nil {
+		return err
+	}
+	if !(r.Distance > 0) {
+		return fmt.Errorf("ring: distance %v must be positive", r.Distance)
+	}
+	if !(r.Tolerance >= 0) {
+		return fmt.Errorf("ring: tolerance %v must not be negative", r.Tolerance)
+	}
+	return nil
+}
+
+func (r Ring) Region() s2.Region {
+	return annulus(point(r.Lat, r.Lon), angle((r.Distance-r.Tolerance)/distanceMargin), angle((r.Distance+r.Tolerance)*distanceMargin))
+}
+
+func (r Ring) Match(lat, lon float64) (float64, float64, bool) {
+	l := geodesy.WGS84.Inverse(r.Lat, r.Lon, lat, lon)
+	if math.Abs(l.Distance-r.Distance) > r.Tolerance {
+		return 0, 0, false
+	}
+	return l.Distance, geodesy.Azimuth360(l.Azimuth1), true
+}
+
+// Antipode сущности не дальше Radius метров от точки, противоположной данной,
+// Match возвращает расстояние и азимут из антипода
+type Antipode struct {
+	Lat, Lon float64
+	Radius   float64
+}
+
+func (a Antipode) Validate() error {
+	if err := validatePoint(a.Lat, a.Lon); err != nil {
+		return err
+	}
+	if !(a.Radius > 0) {
+		return fmt.Errorf("antipode: radius %v must be positive", a.Radius)
+	}
+	return nil
+}
+
+// Point возвращает антипод: широта с обратным знаком, долгота на 180° дальше
+func (a Antipode) Point() (lat float64, lon float64) {
+	lon = a.Lon + 180
+	if lon > 180 {
+		lon -= 360
+	}
+	return -a.Lat, lon
+}
+
+func (a Antipode) Region() s2.Region {
+	lat, lon := a.Point()
+	return s2.CapFromCenterAngle(point(lat, lon), angle(a.Radius*distanceMargin))
+}
+
+func (a Antipode) Match(lat, lon float64) (float64, float64, bool) {
+	alat, alon := a.Point()
+	l := geodesy.WGS84.Inverse(alat, alon, lat, lon)
+	if l.Distance > a.Radius {
+		return 0, 0, false
+	}
+	return l.Distance, geodesy.Azimuth360(l.Azimuth1), true
+}
+
+// Sector сущности, азимут на которые из точки по эллипсоиду WGS 84 лежит между From и To
+// по часовой стрелке, не дальше Radius метров, 0 — без ограничения.
+// Если From больше To, сектор проходит через север. Match возвращает расстояние и азимут из точки.
+type Sector struct {
+	Lat, Lon float64
+	From, To float64
+	Radius   float64
+}
+
+func (s Sector) Validate() error {
+	if err := validatePoint(s.Lat, s.Lon); err != nil {
+		return err
+	}
+	if s.width() == 0 {
+		return fmt.Errorf("sector: azimuths %v and %v must differ", s.From, s.To)
+	}
+	if !(s.Radius >= 0) {
+		return fmt.Errorf("sector: radius %v must not be negative", s.Radius)
+	}
+	return nil
+}
+
+func (s Sector) from() float64 {
+	return geodesy.Azimuth360(math.Mod(s.From, 360))
+}
+
+// width ширина сектора в градусах
+func (s Sector) width() float64 {
+	return geodesy.Azimuth360(math.Mod(s.To, 360) - s.from())
+}
+
+func (s Sector) radius() float64 {
+	if s.Radius == 0 {
+		return maxDistance
+	}
+	return s.Radius
+}
+
+func (s Sector) Region() s2.Region {
+	return sector(point(s.Lat, s.Lon), geodesy.Azimuth360(s.from()-azimuthMargin), s.width()+2*azimuthMargin,
+		angle(s.radius()*distanceMargin))
+}
+
+func (s Sector) Match(lat, lon float64) (float64, float64, bool) {
+	l := geodesy.WGS84.Inverse(s.Lat, s.Lon, lat, lon)
+	azi := geodesy.Azimuth360(l.Azimuth1)
+	if l.Distance == 0 || l.Distance > s.radius() || geodesy.Azimuth360(azi-s.from()) > s.width() {
+		return 0, 0, false
+	}
+	return l.Distance, azi, true
+}
+
+func validatePoint(lat, lon float64) error {
+	if math.Abs(lat) > 90 || math.Abs(lon) > 180 {
+		return fmt.Errorf("point %v,%v is out of range", lat, lon)
+	}
+	return nil
+}
+
+func point(lat, lon float64) s2.Point {
+	return s2.PointFromLatLng(s2.LatLngFromDegrees(lat, lon))
+}
+
+// angle переводит расстояние в метрах в угол на сфере geodesy.Earth, не больше 180°
+func angle(distance float64) s1.Angle {
+	return s1.Angle(math.Max(0, math.Min(math.Pi, distance/geodesy.Earth.Radius)))
+}
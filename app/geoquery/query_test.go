@@ -0,0 +1,215 @@
+package geoquery
+
+import (
+	"context"
+	"github.com/audetv/datasets-parser/app/repos/entity"
+	"github.com/audetv/datasets-parser/dataset/geodesy"
+	"github.com/golang/geo/s2"
+	"github.com/google/uuid"
+	"testing"
+)
+
+// sliceStore хранилище сущностей в памяти, отбор по ячейкам как в базе данных
+type sliceStore []entity.Entity
+
+func (s sliceStore) All(ctx context.Context, filter entity.Filter, fn func(e entity.Entity) error) error {
+	for _, e := range s {
+		if filter.Match(e) {
+			if err := fn(e); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (s sliceStore) Get(ctx context.Context, id uuid.UUID) (entity.Entity, error) {
+	return entity.Entity{}, entity.ErrNotFound
+}
+
+func (s sliceStore) Near(ctx context.Context, q entity.NearQuery) ([]entity.Neighbour, error) {
+	return nil, nil
+}
+
+// at сущность на расстоянии distance метров от точки по азимуту azimuth
+func at(name string, lat, lon, azimuth, distance float64) entity.Entity {
+	p := geodesy.WGS84.Direct(lat, lon, azimuth, distance)
+	return entity.Entity{
+		ID:        uuid.NewSHA1(uuid.NameSpaceOID, []byte(name)),
+		Name:      name,
+		Latitude:  p.Lat,
+		Longitude: p.Lon,
+		CellID:    uint64(s2.CellIDFromLatLng(s2.LatLngFromDegrees(p.Lat, p.Lon))),
+	}
+}
+
+// search возвращает имена найденных сущностей в порядке расстояния
+func search(t *testing.T, store sliceStore, q Query) []string {
+	t.Helper()
+	found, err := Search(context.Background(), store, q, entity.Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, len(found))
+	for i, n := range found {
+		names[i] = n.Name
+	}
+	return names
+}
+
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestAntipodePoint(t *testing.T) {
+	tests := []struct {
+		lat, lon         float64
+		wantLat, wantLon float64
+	}{
+		{55.75, 37.62, -55.75, -142.38},
+		{-33.9, 151.2, 33.9, -28.8},
+		// полюса: долгота полюса не важна, она переносится как у любой точки
+		{90, 0, -90, 180},
+		{-90, 45, 90, -135},
+		// 180-й меридиан и нулевой меридиан переходят друг в друга
+		{10, 180, -10, 0},
+		{10, -180, -10, 0},
+		{-10, 0, 10, 180},
+	}
+	for _, tt := range tests {
+		lat, lon := Antipode{Lat: tt.lat, Lon: tt.lon}.Point()
+		if lat != tt.wantLat || !near(lon, tt.wantLon, 1e-9) {
+			t.Errorf("antipode of %v,%v = %v,%v, want %v,%v", tt.lat, tt.lon, lat, lon, tt.wantLat, tt.wantLon)
+		}
+	}
+}
+
+func TestAntipodeSearch(t *testing.T) {
+	// антипод северного полюса — южный полюс, точки вокруг него на любых долготах
+	store := sliceStore{
+		at("у полюса", -90, 0, 0, 30000),
+		at("за 180-м меридианом", -90, 0, 179.9, 60000),
+		at("далеко", -90, 0, 90, 200000),
+		at("на полюсе", 90, 0, 0, 0),
+	}
+	if got, want := search(t, store, Antipode{Lat: 90, Radius: 100000}), []string{"у полюса", "за 180-м меридианом"}; !equal(got, want) {
+		t.Errorf("south pole: %q, want %q", got, want)
+	}
+
+	// антипод точки на 180-м меридиане лежит на нулевом
+	store = sliceStore{
+		at("к западу", 0, 0, 270, 10000),
+		at("к востоку", 0, 0, 90, 20000),
+		at("у исходной точки", 0, 180, 0, 10000),
+	}
+	if got, want := search(t, store, Antipode{Lat: 0, Lon: 180, Radius: 50000}), []string{"к западу", "к востоку"}; !equal(got, want) {
+		t.Errorf("antimeridian: %q, want %q", got, want)
+	}
+
+	if err := (Antipode{Lat: 91, Radius: 1}).Validate(); err == nil {
+		t.Error("latitude 91 accepted")
+	}
+	if err := (Antipode{Radius: 0}).Validate(); err == nil {
+		t.Error("radius 0 accepted")
+	}
+}
+
+func TestSectorThroughNorth(t *testing.T) {
+	lat, lon := 55.75, 37.62
+	store := sliceStore{
+		at("350", lat, lon, 350.5, 5000),
+		at("355", lat, lon, 355, 10000),
+		at("0", lat, lon, 0, 15000),
+		at("5", lat, lon, 5, 20000),
+		at("10", lat, lon, 9.5, 25000),
+		at("20", lat, lon, 20, 30000),
+		at("340", lat, lon, 340, 35000),
+		at("180", lat, lon, 180, 40000),
+		at("далеко", lat, lon, 0, 200000),
+	}
+	// сектор от 350° до 10° проходит через 0°/360°
+	got := search(t, store, Sector{Lat: lat, Lon: lon, From: 350, To: 10, Radius: 100000})
+	if want := []string{"350", "355", "0", "5", "10"}; !equal(got, want) {
+		t.Errorf("350..10: %q, want %q", got, want)
+	}
+	// те же границы в другом порядке — дополнение до полного круга
+	got = search(t, store, Sector{Lat: lat, Lon: lon, From: 10, To: 350, Radius: 100000})
+	if want := []string{"20", "340", "180"}; !equal(got, want) {
+		t.Errorf("10..350: %q, want %q", got, want)
+	}
+	// отрицательные азимуты и больше 360° приводятся к 0..360
+	got = search(t, store, Sector{Lat: lat, Lon: lon, From: -10, To: 370, Radius: 100000})
+	if want := []string{"350", "355", "0", "5", "10"}; !equal(got, want) {
+		t.Errorf("-10..370: %q, want %q", got, want)
+	}
+
+	if err := (Sector{Lat: lat, Lon: lon, From: 10, To: 370}).Validate(); err == nil {
+		t.Error("empty sector accepted")
+	}
+}
+
+func TestRingWithoutHole(t *testing.T) {
+	lat, lon := 55.75, 37.62
+	store := sliceStore{
+		at("центр", lat, lon, 0, 0),
+		at("500 м", lat, lon, 45, 500),
+		at("999 м", lat, lon, 200, 999),
+		at("1,5 км", lat, lon, 90, 1500),
+	}
+	// допуск равен радиусу: внутренний радиус кольца 0, оно становится кругом вместе с центром
+	got := search(t, store, Ring{Lat: lat, Lon: lon, Distance: 500, Tolerance: 500})
+	if want := []string{"центр", "500 м", "999 м"}; !equal(got, want) {
+		t.Errorf("ring 500±500: %q, want %q", got, want)
+	}
+	got = search(t, store, Ring{Lat: lat, Lon: lon, Distance: 1000, Tolerance: 100})
+	if want := []string{"999 м"}; !equal(got, want) {
+		t.Errorf("ring 1000±100: %q, want %q", got, want)
+	}
+	// допуск больше радиуса тоже допустим, внутренний радиус не уходит ниже 0
+	got = search(t, store, Ring{Lat: lat, Lon: lon, Distance: 500, Tolerance: 1200})
+	if want := []string{"центр", "500 м", "999 м", "1,5 км"}; !equal(got, want) {
+		t.Errorf("ring 500±1200: %q, want %q", got, want)
+	}
+
+	if err := (Ring{Lat: lat, Lon: lon, Distance: 0}).Validate(); err == nil {
+		t.Error("distance 0 accepted")
+	}
+	if err := (Ring{Lat: lat, Lon: lon, Distance: 1, Tolerance: -1}).Validate(); err == nil {
+		t.Error("negative tolerance accepted")
+	}
+}
+
+func TestCorridor(t *testing.T) {
+	store := sliceStore{
+		at("на экваторе", 0, 10, 0, 0),
+		at("севернее", 0, 20, 0, 5000),
+		at("южнее", 0, -100, 180, 9000),
+		at("вне коридора", 0, 30, 0, 20000),
+	}
+	got := search(t, store, Corridor{Lat1: 0, Lon1: 0, Lat2: 0, Lon2: 90, Width: 10000})
+	if want := []string{"на экваторе", "севернее", "южнее"}; !equal(got, want) {
+		t.Errorf("equator: %q, want %q", got, want)
+	}
+
+	for _, c := range []Corridor{
+		{Lat1: 10, Lon1: 10, Lat2: 10, Lon2: 10, Width: 1},
+		{Lat1: 0, Lon1: 0, Lat2: 0, Lon2: 180, Width: 1},
+		{Lat1: 0, Lon1: 0, Lat2: 0, Lon2: 90, Width: 0},
+	} {
+		if err := c.Validate(); err == nil {
+			t.Errorf("%+v accepted", c)
+		}
+	}
+}
+
+func near(a, b, eps float64) bool {
+	return a-b <= eps && b-a <= eps
+}
@@ -0,0 +1,103 @@
+package geoquery
+
+import (
+	"github.com/audetv/datasets-parser/dataset/geodesy"
+	"github.com/golang/geo/s1"
+	"github.com/golang/geo/s2"
+	"math"
+)
+
+// capTest сообщает, пересекает ли круг с центром p и радиусом r область и лежит ли он в ней целиком.
+// Ошибаться можно только в безопасную сторону: лишнее пересечение и пропущенное вхождение.
+type capTest func(p s2.Point, r s1.Angle) (intersects bool, contains bool)
+
+// region область на сфере для s2.RegionCoverer: ячейки проверяются по описанным вокруг них кругам
+type region struct {
+	bound s2.Cap
+	test  capTest
+}
+
+var _ s2.Region = region{}
+
+func (r region) CapBound() s2.Cap {
+	return r.bound
+}
+
+func (r region) RectBound() s2.Rect {
+	return r.bound.RectBound()
+}
+
+func (r region) ContainsCell(c s2.Cell) bool {
+	cb := c.CapBound()
+	_, contains := r.test(cb.Center(), cb.Radius())
+	return contains
+}
+
+func (r region) IntersectsCell(c s2.Cell) bool {
+	if !r.bound.IntersectsCell(c) {
+		return false
+	}
+	cb := c.CapBound()
+	intersects, _ := r.test(cb.Center(), cb.Radius())
+	return intersects
+}
+
+func (r region) ContainsPoint(p s2.Point) bool {
+	_, contains := r.test(p, 0)
+	return contains
+}
+
+func (r region) CellUnionBound() []s2.CellID {
+	return r.bound.CellUnionBound()
+}
+
+// band полоса шириной width по обе стороны большого круга с полюсом pole
+func band(pole s2.Point, width s1.Angle) region {
+	return region{
+		bound: s2.FullCap(),
+		test: func(p s2.Point, r s1.Angle) (bool, bool) {
+			x := crossTrack(pole, p)
+			return x-r <= width, x+r <= width
+		},
+	}
+}
+
+// crossTrack угловое расстояние от точки p до большого круга с полюсом pole
+func crossTrack(pole s2.Point, p s2.Point) s1.Angle {
+	return s1.Angle(math.Abs(math.Asin(math.Max(-1, math.Min(1, pole.Dot(p.Vector))))))
+}
+
+// annulus кольцо вокруг center между радиусами inner и outer
+func annulus(center s2.Point, inner s1.Angle, outer s1.Angle) region {
+	return region{
+		bound: s2.CapFromCenterAngle(center, outer),
+		test: func(p s2.Point, r s1.Angle) (bool, bool) {
+			d := center.Distance(p)
+			return d+r >= inner && d-r <= outer, d-r >= inner && d+r <= outer
+		},
+	}
+}
+
+// sector часть круга радиуса radius вокруг center между азимутами from и from + width по часовой стрелке
+func sector(center s2.Point, from float64, width float64, radius s1.Angle) region {
+	c := s2.LatLngFromPoint(center)
+	return region{
+		bound: s2.CapFromCenterAngle(center, radius),
+		test: func(p s2.Point, r s1.Angle) (bool, bool) {
+			d := center.Distance(p)
+			inRadius, withinRadius := d-r <= radius, d+r <= radius
+			if d <= r || d >= math.Pi-r {
+				// круг накрывает центр или его антипод, где сходятся все азимуты
+				return inRadius, false
+			}
+			ll := s2.LatLngFromPoint(p)
+			azi := geodesy.Earth.Inverse(c.Lat.Degrees(), c.Lng.Degrees(), ll.Lat.Degrees(), ll.Lng.Degrees()).Azimuth1
+			// полуширина круга в азимутах, видимая из центра
+			half := math.Asin(math.Min(1, math.Sin(r.Radians())/math.Sin(d.Radians()))) / math.Pi * 180
+			off := geodesy.Azimuth360(geodesy.Azimuth360(azi) - from)
+			intersects := off <= width+half || off >= 360-half
+			contains := off >= half && off+half <= width
+			return inRadius && intersects, withinRadius && contains
+		},
+	}
+}
@@ -1,21 +1,94 @@
 package dataset
 
-import "context"
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
 
 // Entry преобразованная запись файла
 type Entry struct {
+	// ID идентификатор из файла, сохраняется как ID сущности; пустой генерируется заново
+	ID              uuid.UUID
 	Name            string
 	Description     string
 	Longitude       float64
 	Latitude        float64
 	Height          float64
 	DescriptionJson interface{}
+
+	// Line номер строки или записи в исходном файле, начиная с 1
+	Line int
+	// Raw исходные поля записи, попадают в карантин, если запись отклонена
+	Raw []string
+	// Errors ошибки разбора записи, запись с ошибками не сохраняется
+	Errors []RowError
+}
+
+// Rejected сообщает, что запись не разобрана и должна попасть в карантин
+func (e Entry) Rejected() bool {
+	return len(e.Errors) > 0
+}
+
+// CoordinateWarning ключ DescriptionJson с предупреждением о сомнительных координатах записи
+const CoordinateWarning = "coordinate_warning"
+
+// Warn записывает предупреждение о сомнительных координатах в DescriptionJson под ключом
+// CoordinateWarning, запись при этом сохраняется. nil ничего не меняет.
+func (e *Entry) Warn(warning error) {
+	if warning == nil {
+		return
+	}
+	dj, ok := e.DescriptionJson.(map[string]interface{})
+	if !ok {
+		if e.DescriptionJson != nil {
+			return
+		}
+		dj = make(map[string]interface{})
+		e.DescriptionJson = dj
+	}
+	dj[CoordinateWarning] = warning.Error()
+}
+
+// RowError ошибка разбора одного поля или всей записи файла
+type RowError struct {
+	File string `json:"file"`
+	Line int    `json:"line"`
+	// Column имя колонки, пустое, если не разобрана вся запись
+	Column string `json:"column,omitempty"`
+	Value  string `json:"value,omitempty"`
+	Reason string `json:"reason"`
+}
+
+func (e RowError) Error() string {
+	if e.Column == "" {
+		return fmt.Sprintf("%v:%d: %v", e.File, e.Line, e.Reason)
+	}
+	return fmt.Sprintf("%v:%d: %v %q: %v", e.File, e.Line, e.Column, e.Value, e.Reason)
 }
 
 type Store interface {
 	ReadAll(ctx context.Context) (chan Entry, error)
 }
 
+// Failer Store, который после закрытия канала ReadAll сообщает ошибку,
+// прервавшую чтение файла, nil если файл прочитан до конца
+type Failer interface {
+	Err() error
+}
+
+// Table табличный Store: отклонённые записи сохраняются в карантин
+// с заголовком и разделителем исходного файла
+type Table interface {
+	Columns() []string
+	Comma() rune
+}
+
 type Entries struct {
 	store Store
 }
@@ -48,3 +121,57 @@ func (es *Entries) ReadAll(ctx context.Context) (chan Entry, error) {
 	}()
 	return chout, nil
 }
+
+// Options параметры чтения файла, заданные в командной строке,
+// пустые значения читатель определяет по содержимому файла
+type Options struct {
+	Encoding  string
+	Delimiter rune
+	// Sheets glob шаблоны листов книги Excel, которые нужно прочитать
+	Sheets []string
+}
+
+// Source файл набора данных: обычный файл на диске или файл внутри архива
+type Source struct {
+	// Name имя файла, которое сохраняется в Filename сущности,
+	// для файла из архива — путь внутри архива: «archive.zip/dir/file.csv»
+	Name string
+	// Open открывает файл для чтения с начала, может вызываться несколько раз
+	Open func() (io.ReadCloser, error)
+	// Options кодировка, разделитель и листы, заданные для файла в командной строке
+	Options Options
+	// Sibling открывает файл name из той же папки или папки архива,
+	// nil если соседних файлов нет, например у сжатого gzip файла
+	Sibling func(name string) (io.ReadCloser, error)
+}
+
+// OpenSibling открывает соседний файл с тем же именем и расширением ext,
+// например .dbf рядом с .shp. Расширение ищется в нижнем и верхнем регистре.
+func (s Source) OpenSibling(ext string) (io.ReadCloser, error) {
+	base := path.Base(s.Name)
+	base = strings.TrimSuffix(base, path.Ext(base))
+	if s.Sibling == nil {
+		return nil, fmt.Errorf("%v: %w", base+ext, os.ErrNotExist)
+	}
+	var err error
+	for _, e := range []string{strings.ToLower(ext), strings.ToUpper(ext)} {
+		var rc io.ReadCloser
+		if rc, err = s.Sibling(base + e); err == nil {
+			return rc, nil
+		}
+	}
+	return nil, err
+}
+
+// FileSource возвращает Source для файла на диске
+func FileSource(name string, filePath string) Source {
+	return Source{
+		Name: name,
+		Open: func() (io.ReadCloser, error) {
+			return os.Open(filePath)
+		},
+		Sibling: func(name string) (io.ReadCloser, error) {
+			return os.Open(filepath.Join(filepath.Dir(filePath), name))
+		},
+	}
+}
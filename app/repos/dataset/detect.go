@@ -0,0 +1,135 @@
+package dataset
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	// MinConfidence минимальная доля совпадения заголовка с отпечатком читателя
+	MinConfidence = 0.6
+	// MinConfidenceMargin минимальный отрыв лучшего кандидата от следующего,
+	// при меньшем отрыве определение считается неоднозначным
+	MinConfidenceMargin = 0.1
+)
+
+// Header заголовок файла, определённый по его началу функцией Sniff читателя
+type Header struct {
+	// Delimiter разделитель полей, 0 у книги Excel
+	Delimiter rune
+	Columns   []string
+}
+
+// Candidate читатель, доля совпадения его отпечатка с заголовком файла
+// и заголовок, прочитанный функцией Sniff читателя
+type Candidate struct {
+	Reader     *Reader
+	Confidence float64
+	Header     Header
+}
+
+// Candidates читает заголовок файла функциями Sniff читателей с отпечатком и возвращает
+// читателей, отсортированных по убыванию доли совпадения отпечатка с заголовком,
+// и первый прочитанный заголовок. Ошибка возвращается, если заголовок не прочитан ни разу.
+func (rg *Registry) Candidates(src Source) ([]Candidate, Header, error) {
+	var candidates []Candidate
+	var first *Header
+	var firstErr error
+	for _, r := range rg.Readers() {
+		if len(r.Fingerprint) == 0 || r.Sniff == nil {
+			continue
+		}
+		header, err := r.Sniff(src)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		if first == nil {
+			first = &header
+		}
+		if c := similarity(r.Fingerprint, header.Columns); c > 0 {
+			candidates = append(candidates, Candidate{Reader: r, Confidence: c, Header: header})
+		}
+	}
+	if first == nil {
+		if firstErr == nil {
+			firstErr = errors.New("no dataset reader can read the header")
+		}
+		return nil, Header{}, firstErr
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Confidence > candidates[j].Confidence
+	})
+	return candidates, *first, nil
+}
+
+// Detect определяет читателя файла по заголовку. Возвращает ошибку, если
+// ни один отпечаток не совпадает достаточно или совпадение неоднозначно.
+func (rg *Registry) Detect(src Source) (*Candidate, error) {
+	candidates, header, err := rg.Candidates(src)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", src.Name, err)
+	}
+
+	if len(candidates) == 0 || candidates[0].Confidence < MinConfidence {
+		if len(candidates) > 0 {
+			header = candidates[0].Header
+		}
+		separator := string(header.Delimiter)
+		if header.Delimiter == 0 {
+			// у листа книги Excel разделителя нет
+			separator = "; "
+		}
+		return nil, fmt.Errorf("%v %w: header %q does not match any dataset", src.Name, ErrNotSupported, strings.Join(header.Columns, separator))
+	}
+
+	if len(candidates) > 1 && candidates[0].Confidence-candidates[1].Confidence < MinConfidenceMargin {
+		var names []string
+		for _, c := range candidates {
+			if candidates[0].Confidence-c.Confidence < MinConfidenceMargin {
+				names = append(names, fmt.Sprintf("%v (%.2f)", c.Reader.Name, c.Confidence))
+			}
+		}
+		return nil, fmt.Errorf("%v file is ambiguous, header matches several datasets: %v", src.Name, strings.Join(names, ", "))
+	}
+
+	return &candidates[0], nil
+}
+
+// similarity доля совпадения отпечатка и заголовка (коэффициент Жаккара)
+func similarity(fingerprint []string, columns []string) float64 {
+	set := make(map[string]bool, len(columns))
+	for _, c := range columns {
+		set[normalizeColumn(c)] = true
+	}
+
+	common := 0
+	union := len(set)
+	for _, f := range fingerprint {
+		// колонка отпечатка может перечислять синонимы через «|»
+		found := false
+		for _, name := range strings.Split(f, "|") {
+			if set[normalizeColumn(name)] {
+				found = true
+				break
+			}
+		}
+		if found {
+			common++
+		} else {
+			union++
+		}
+	}
+	if union == 0 {
+		return 0
+	}
+	return float64(common) / float64(union)
+}
+
+func normalizeColumn(name string) string {
+	return strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+}
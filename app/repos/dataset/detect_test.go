@@ -0,0 +1,190 @@
+package dataset
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// sniffLine читает заголовок из первой строки файла, разделитель — самый частый из «,;\t|»,
+// как Sniff читателей маппингов csv
+func sniffLine(src Source) (Header, error) {
+	rc, err := src.Open()
+	if err != nil {
+		return Header{}, err
+	}
+	defer rc.Close()
+	line, err := bufio.NewReader(rc).ReadString('\n')
+	if err != nil {
+		return Header{}, err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	h := Header{Delimiter: ','}
+	for _, d := range ";\t|" {
+		if strings.Count(line, string(d)) > strings.Count(line, string(h.Delimiter)) {
+			h.Delimiter = d
+		}
+	}
+	h.Columns = strings.Split(line, string(h.Delimiter))
+	return h, nil
+}
+
+// detectRegistry реестр с отпечатками землетрясений, вулканов и городов,
+// отпечатки вулканов и городов отличаются одной колонкой
+func detectRegistry(t *testing.T) *Registry {
+	rg := NewRegistry()
+	for name, fingerprint := range map[string][]string{
+		"quakes":    {"time", "latitude", "longitude", "mag|magnitude", "place"},
+		"volcanoes": {"name", "country", "latitude", "longitude", "elevation"},
+		"cities":    {"name", "country", "latitude", "longitude", "population"},
+	} {
+		r := testReader(name)
+		r.Fingerprint = fingerprint
+		r.Sniff = sniffLine
+		if err := rg.Register(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return rg
+}
+
+func TestDetect(t *testing.T) {
+	rg := detectRegistry(t)
+	tests := []struct {
+		name   string
+		header string
+		// want имя читателя, пустое — ошибка
+		want       string
+		confidence float64
+		delimiter  rune
+		notFound   bool
+	}{
+		{"exact", "time;latitude;longitude;mag;place", "quakes", 1, ';', false},
+		{"alias and extra column", "time,latitude,longitude,magnitude,place,depth", "quakes", 5. / 6, ',', false},
+		{"bom and case", "\ufeffName\tCountry\tLatitude\tLongitude\tElevation", "volcanoes", 1, '\t', false},
+		// доля совпадения ровно MinConfidence достаточна
+		{"min confidence", "time|latitude|longitude", "quakes", 0.6, '|', false},
+		{"below min confidence", "time,latitude,longitude,depth", "", 0, 0, true},
+		{"unknown header", "a,b,c", "", 0, 0, true},
+		// вулканы и города совпадают одинаково
+		{"ambiguous", "name,country,latitude,longitude", "", 0, 0, false},
+	}
+	dir := t.TempDir()
+	for _, tt := range tests {
+		path := filepath.Join(dir, tt.name+".csv")
+		data := tt.header + "\n" + strings.Repeat("1"+string(tt.delimiter), 2) + "1\n"
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		d, err := rg.Detect(FileSource(tt.name+".csv", path))
+		if tt.want == "" {
+			if err == nil {
+				t.Errorf("%v: detected %v, want error", tt.name, d.Reader.Name)
+			} else if errors.Is(err, ErrNotSupported) != tt.notFound {
+				t.Errorf("%v: error %v, not supported %v", tt.name, err, tt.notFound)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: %v", tt.name, err)
+			continue
+		}
+		if d.Reader.Name != tt.want || d.Confidence != tt.confidence || d.Header.Delimiter != tt.delimiter {
+			t.Errorf("%v: detected %v %.3f %q, want %v %.3f %q", tt.name,
+				d.Reader.Name, d.Confidence, d.Header.Delimiter, tt.want, tt.confidence, tt.delimiter)
+		}
+	}
+}
+
+func TestDetectMargin(t *testing.T) {
+	rg := detectRegistry(t)
+	// города совпадают на 4/6, землетрясения — на 2/8, отрыв вулканов больше MinConfidenceMargin
+	path := filepath.Join(t.TempDir(), "volcanoes.csv")
+	if err := os.WriteFile(path, []byte("name,country,latitude,longitude,elevation\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	candidates, header, err := rg.Candidates(FileSource("volcanoes.csv", path))
+	if err != nil || len(header.Columns) != 5 {
+		t.Fatalf("header %q, %v", header.Columns, err)
+	}
+	if len(candidates) != 3 || candidates[0].Reader.Name != "volcanoes" || candidates[1].Reader.Name != "cities" ||
+		candidates[1].Confidence != 4./6 || candidates[2].Confidence != 0.25 {
+		t.Fatalf("candidates %+v", candidates)
+	}
+	if candidates[0].Confidence-candidates[1].Confidence < MinConfidenceMargin {
+		t.Errorf("margin %.3f", candidates[0].Confidence-candidates[1].Confidence)
+	}
+}
+
+func TestDetectAmbiguous(t *testing.T) {
+	rg := detectRegistry(t)
+	path := filepath.Join(t.TempDir(), "places.csv")
+	if err := os.WriteFile(path, []byte("name,country,latitude,longitude\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// вулканы и города совпадают на 4/5, отрыв 0 меньше MinConfidenceMargin
+	_, err := rg.Detect(FileSource("places.csv", path))
+	if err == nil || errors.Is(err, ErrNotSupported) ||
+		!strings.Contains(err.Error(), "ambiguous") ||
+		!strings.Contains(err.Error(), "cities (0.80)") || !strings.Contains(err.Error(), "volcanoes (0.80)") ||
+		strings.Contains(err.Error(), "quakes") {
+		t.Errorf("ambiguous header: %v", err)
+	}
+}
+
+func TestCandidatesSniff(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "quakes.csv")
+	if err := os.WriteFile(path, []byte("time;latitude;longitude;mag;place\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	src := FileSource("quakes.csv", path)
+
+	calls := make(map[string]int)
+	reader := func(name string, fingerprint []string, sniff func(Source) (Header, error)) Reader {
+		r := testReader(name)
+		r.Fingerprint = fingerprint
+		if sniff != nil {
+			r.Sniff = func(src Source) (Header, error) {
+				calls[name]++
+				return sniff(src)
+			}
+		}
+		return r
+	}
+	broken := func(Source) (Header, error) { return Header{}, errors.New("broken header") }
+
+	rg := NewRegistry()
+	for _, r := range []Reader{
+		reader("broken", []string{"time"}, broken),
+		reader("quakes", []string{"time", "latitude", "longitude", "mag", "place"}, sniffLine),
+		reader("by name", nil, sniffLine),
+		reader("no sniff", []string{"time"}, nil),
+	} {
+		if err := rg.Register(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// ошибка одного читателя не мешает другим, читатели без отпечатка заголовок не читают
+	candidates, header, err := rg.Candidates(src)
+	if err != nil || len(candidates) != 1 || candidates[0].Reader.Name != "quakes" || header.Delimiter != ';' {
+		t.Errorf("candidates %+v, header %+v, %v", candidates, header, err)
+	}
+	if calls["broken"] != 1 || calls["quakes"] != 1 || calls["by name"] != 0 {
+		t.Errorf("sniff calls %v", calls)
+	}
+
+	// заголовок не прочитал ни один читатель — ошибка первого из них
+	rg = NewRegistry()
+	if err = rg.Register(reader("broken", []string{"time"}, broken)); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = rg.Candidates(src); err == nil || err.Error() != "broken header" {
+		t.Errorf("broken header: %v", err)
+	}
+	if _, err = rg.Detect(src); err == nil || !strings.Contains(err.Error(), "broken header") {
+		t.Errorf("detect broken header: %v", err)
+	}
+}
@@ -0,0 +1,228 @@
+package dataset
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrNotSupported для файла не нашлось читателя
+var ErrNotSupported = errors.New("file not supported")
+
+// Reader описание читателя набора данных, зарегистрированного в реестре
+type Reader struct {
+	// Name уникальное имя набора данных
+	Name string
+	// Patterns glob шаблоны имён файлов, например «globalterrorismdb_*.csv»
+	Patterns []string
+	// Regexps регулярные выражения для имён файлов
+	Regexps []*regexp.Regexp
+	// Fingerprint имена колонок заголовка, по которым узнаётся файл,
+	// синонимы колонки перечисляются через «|»: «height|heigh»
+	Fingerprint []string
+	// Companions расширения файлов, которые лежат рядом с основным файлом
+	// и читаются вместе с ним, например «.dbf» и «.prj» у «*.shp»
+	Companions []string
+	// Sniff читает заголовок файла для определения набора данных по Fingerprint,
+	// nil если читатель узнаёт файлы только по имени
+	Sniff func(src Source) (Header, error)
+	// New создаёт dataset.Store для файла
+	New func(src Source) (Store, error)
+}
+
+// Match сообщает, подходит ли имя файла под шаблоны читателя.
+// Регистр букв в glob шаблонах не учитывается: «*.kml» подходит и для «A.KML»,
+// регулярные выражения сравниваются как есть.
+func (r *Reader) Match(filename string) bool {
+	return r.specificity(filename) > 0
+}
+
+// specificity насколько точно имя файла подходит под шаблоны читателя:
+// 2 — шаблон совпадает с именем файла буквально, 1 — по glob шаблону
+// или регулярному выражению, 0 — не подходит
+func (r *Reader) specificity(filename string) int {
+	name := filepath.Base(filename)
+	best := 0
+	for _, p := range r.Patterns {
+		if ok, _ := filepath.Match(strings.ToLower(p), strings.ToLower(name)); !ok {
+			continue
+		}
+		if !strings.ContainsAny(p, `*?[\`) {
+			return 2
+		}
+		best = 1
+	}
+	for _, re := range r.Regexps {
+		if re.MatchString(name) {
+			best = 1
+		}
+	}
+	return best
+}
+
+// companionOf сообщает, что файл — сопутствующий файл основного файла читателя,
+// который есть рядом с ним. exists проверяет, есть ли файл с таким именем.
+func (r *Reader) companionOf(filename string, exists func(name string) bool) bool {
+	ext := path.Ext(filename)
+	companion := false
+	for _, c := range r.Companions {
+		if strings.EqualFold(c, ext) {
+			companion = true
+		}
+	}
+	if !companion {
+		return false
+	}
+
+	// основной файл ищется по шаблонам вида «*.shp», расширение — в нижнем и верхнем регистре
+	base := strings.TrimSuffix(filename, ext)
+	for _, p := range r.Patterns {
+		main := strings.ToLower(strings.TrimPrefix(p, "*"))
+		if !strings.HasPrefix(main, ".") || strings.ContainsAny(main, `*?[\`) {
+			continue
+		}
+		if exists(base+main) || exists(base+strings.ToUpper(main)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Registry реестр читателей наборов данных
+type Registry struct {
+	mu      sync.RWMutex
+	readers map[string]*Reader
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		readers: make(map[string]*Reader),
+	}
+}
+
+// DefaultRegistry реестр, в который регистрируются читатели из init функций пакетов
+var DefaultRegistry = NewRegistry()
+
+// Register регистрирует читателя в DefaultRegistry
+func Register(r Reader) error {
+	return DefaultRegistry.Register(r)
+}
+
+// MustRegister регистрирует читателя в DefaultRegistry и паникует при ошибке,
+// предназначен для вызова из init функций
+func MustRegister(r Reader) {
+	if err := Register(r); err != nil {
+		panic(err)
+	}
+}
+
+// Register регистрирует читателя, имя читателя должно быть уникальным
+func (rg *Registry) Register(r Reader) error {
+	if err := r.validate(); err != nil {
+		return err
+	}
+
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+
+	if _, ok := rg.readers[r.Name]; ok {
+		return fmt.Errorf("dataset reader %q is already registered", r.Name)
+	}
+	rg.readers[r.Name] = &r
+	return nil
+}
+
+// Replace регистрирует читателя, заменяя ранее зарегистрированного с тем же именем
+func (rg *Registry) Replace(r Reader) error {
+	if err := r.validate(); err != nil {
+		return err
+	}
+
+	rg.mu.Lock()
+	defer rg.mu.Unlock()
+
+	rg.readers[r.Name] = &r
+	return nil
+}
+
+func (r *Reader) validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("dataset reader name is empty")
+	}
+	if r.New == nil {
+		return fmt.Errorf("dataset reader %q has no constructor", r.Name)
+	}
+	return nil
+}
+
+// Readers возвращает зарегистрированных читателей, отсортированных по имени
+func (rg *Registry) Readers() []*Reader {
+	rg.mu.RLock()
+	defer rg.mu.RUnlock()
+
+	readers := make([]*Reader, 0, len(rg.readers))
+	for _, r := range rg.readers {
+		readers = append(readers, r)
+	}
+	sort.Slice(readers, func(i, j int) bool {
+		return readers[i].Name < readers[j].Name
+	})
+	return readers
+}
+
+// Lookup возвращает читателя по имени
+func (rg *Registry) Lookup(name string) (*Reader, bool) {
+	rg.mu.RLock()
+	defer rg.mu.RUnlock()
+
+	r, ok := rg.readers[name]
+	return r, ok
+}
+
+// Companion сообщает, что файл читается вместе с лежащим рядом основным файлом
+// и отдельно не обрабатывается: «.dbf» рядом с «.shp».
+// exists проверяет, есть ли рядом файл с таким именем.
+func (rg *Registry) Companion(filename string, exists func(name string) bool) bool {
+	for _, r := range rg.Readers() {
+		if r.companionOf(filename, exists) {
+			return true
+		}
+	}
+	return false
+}
+
+// Match возвращает читателя, под шаблоны которого подходит имя файла.
+// Шаблон, буквально совпадающий с именем файла, важнее glob шаблона,
+// поэтому маппинг отдельного файла уточняет общий маппинг формата.
+// Если одинаково подходят несколько читателей, возвращается ошибка.
+func (rg *Registry) Match(filename string) (*Reader, error) {
+	var matched []*Reader
+	best := 0
+	for _, r := range rg.Readers() {
+		switch s := r.specificity(filename); {
+		case s == 0 || s < best:
+		case s > best:
+			best, matched = s, []*Reader{r}
+		default:
+			matched = append(matched, r)
+		}
+	}
+
+	switch len(matched) {
+	case 0:
+		return nil, fmt.Errorf("%v %w", filename, ErrNotSupported)
+	case 1:
+		return matched[0], nil
+	default:
+		names := make([]string, len(matched))
+		for i, r := range matched {
+			names[i] = r.Name
+		}
+		return nil, fmt.Errorf("%v file matches several dataset readers: %v", filename, strings.Join(names, ", "))
+	}
+}
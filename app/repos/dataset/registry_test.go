@@ -0,0 +1,131 @@
+package dataset
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func testReader(name string, patterns ...string) Reader {
+	return Reader{Name: name, Patterns: patterns, New: func(Source) (Store, error) { return nil, nil }}
+}
+
+func TestRegistryMatch(t *testing.T) {
+	rg := NewRegistry()
+	quakes := testReader("quakes")
+	quakes.Regexps = []*regexp.Regexp{regexp.MustCompile(`^eq_\d+\.csv$`)}
+	for _, r := range []Reader{
+		testReader("kml", "*.kml"),
+		testReader("terror", "globalterrorismdb_*.csv"),
+		quakes,
+		testReader("notes", "*.txt"),
+		testReader("texts", "*.TXT"),
+	} {
+		if err := rg.Register(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := rg.Register(testReader("kml", "*.kmz")); err == nil {
+		t.Error("reader registered twice")
+	}
+
+	tests := []struct {
+		filename string
+		// want имя читателя, пустое — ошибка
+		want string
+	}{
+		{"data/doc.kml", "kml"},
+		// регистр букв в glob шаблонах не учитывается
+		{"DOC.KML", "kml"},
+		{"Doc.Kml", "kml"},
+		{"globalterrorismdb_0522dist.csv", "terror"},
+		{"eq_2023.csv", "quakes"},
+		// регулярное выражение сравнивается с учётом регистра
+		{"EQ_2023.csv", ""},
+		{"eq_2023.csv.bak", ""},
+		{"places.csv", ""},
+	}
+	for _, tt := range tests {
+		r, err := rg.Match(tt.filename)
+		switch {
+		case tt.want == "" && err == nil:
+			t.Errorf("%v: matched %v, want error", tt.filename, r.Name)
+		case tt.want != "" && err != nil:
+			t.Errorf("%v: %v", tt.filename, err)
+		case tt.want != "" && r.Name != tt.want:
+			t.Errorf("%v: matched %v, want %v", tt.filename, r.Name, tt.want)
+		}
+	}
+
+	_, err := rg.Match("readme.txt")
+	if err == nil || !strings.Contains(err.Error(), "notes, texts") {
+		t.Errorf("ambiguous match: %v", err)
+	}
+}
+
+func TestRegistryMatchSpecificity(t *testing.T) {
+	rg := NewRegistry()
+	ruins := testReader("ruins")
+	ruins.Regexps = []*regexp.Regexp{regexp.MustCompile(`^ruins_.*\.geojson$`)}
+	for _, r := range []Reader{
+		testReader("geojson", "*.geojson"),
+		testReader("temples", "Temples.geojson"),
+		ruins,
+	} {
+		if err := rg.Register(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		filename string
+		want     string
+	}{
+		{"places.geojson", "geojson"},
+		// маппинг отдельного файла важнее маппинга формата, регистр букв не учитывается
+		{"temples.geojson", "temples"},
+		{"data/TEMPLES.GEOJSON", "temples"},
+		// регулярное выражение подходит так же, как glob шаблон
+		{"ruins_rome.geojson", ""},
+	}
+	for _, tt := range tests {
+		r, err := rg.Match(tt.filename)
+		switch {
+		case tt.want == "" && err == nil:
+			t.Errorf("%v: matched %v, want error", tt.filename, r.Name)
+		case tt.want != "" && err != nil:
+			t.Errorf("%v: %v", tt.filename, err)
+		case tt.want != "" && r.Name != tt.want:
+			t.Errorf("%v: matched %v, want %v", tt.filename, r.Name, tt.want)
+		}
+	}
+}
+
+func TestRegistryCompanion(t *testing.T) {
+	rg := NewRegistry()
+	shp := testReader("shapefile", "*.shp")
+	shp.Companions = []string{".dbf", ".prj"}
+	if err := rg.Register(shp); err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]bool{"roads.shp": true, "roads.dbf": true, "ROADS.SHP": true, "ROADS.DBF": true, "lakes.dbf": true}
+	exists := func(name string) bool { return files[name] }
+	tests := []struct {
+		filename string
+		want     bool
+	}{
+		{"roads.dbf", true},
+		{"roads.PRJ", true},
+		{"ROADS.DBF", true},
+		{"roads.shp", false},
+		// dbf без shp рядом — отдельный файл
+		{"lakes.dbf", false},
+		{"roads.cpg", false},
+	}
+	for _, tt := range tests {
+		if got := rg.Companion(tt.filename, exists); got != tt.want {
+			t.Errorf("%v: companion %v, want %v", tt.filename, got, tt.want)
+		}
+	}
+}
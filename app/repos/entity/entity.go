@@ -2,8 +2,13 @@ package entity
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"github.com/golang/geo/s2"
 	"github.com/google/uuid"
+	"math"
+	"strconv"
+	"strings"
 )
 
 // Entity сущность
@@ -17,7 +22,37 @@ type Entity struct {
 	Height          float64
 	DescriptionJson interface{}
 	CellID          uint64
-	Geohash         string
+	// S2Token токен ячейки S2 CellID
+	S2Token string
+	// Geohash geohash точки
+	Geohash string
+	// GeohashCell начало Geohash — ячейка, соседи которой записаны в GeohashNeighbours
+	GeohashCell string
+	// GeohashNeighbours соседние с GeohashCell ячейки в порядке geohash.Directions: n, ne, e, se, s, sw, w, nw
+	GeohashNeighbours [8]string
+	// S2Cells родительские ячейки CellID на уровнях, которые задаёт Indexer
+	S2Cells []S2Cell
+	// Tiles тайлы карты, в которые попадает точка, на масштабах, которые задаёт Indexer
+	Tiles []Tile
+}
+
+// S2Cell ячейка S2 уровня Level
+type S2Cell struct {
+	Level int
+	ID    uint64
+}
+
+// Tile тайл карты z/x/y в проекции Web Mercator и его quadkey
+type Tile struct {
+	Zoom    int
+	X       int
+	Y       int
+	Quadkey string
+}
+
+// String возвращает тайл в виде «z/x/y»
+func (t Tile) String() string {
+	return fmt.Sprintf("%d/%d/%d", t.Zoom, t.X, t.Y)
 }
 
 type Store interface {
@@ -25,6 +60,138 @@ type Store interface {
 	BulkInsert(ctx context.Context, entities []Entity, batchSize int) error
 }
 
+// Transactor хранилище, которое может записать файл целиком в одной транзакции
+type Transactor interface {
+	Transaction(ctx context.Context, fn func(store Store) error) error
+}
+
+// ReadStore хранилище, из которого можно прочитать записанные сущности
+type ReadStore interface {
+	// All передаёт в fn сущности, подходящие под filter, в порядке файлов,
+	// ошибка fn прерывает чтение
+	All(ctx context.Context, filter Filter, fn func(e Entity) error) error
+	// Get возвращает сущность по идентификатору или ErrNotFound
+	Get(ctx context.Context, id uuid.UUID) (Entity, error)
+	// Near возвращает ближайшие к точке сущности в порядке расстояния
+	Near(ctx context.Context, q NearQuery) ([]Neighbour, error)
+}
+
+// ErrNotFound сущности с таким идентификатором нет в хранилище
+var ErrNotFound = errors.New("entity not found")
+
+// Indexer заполняет поля сущности, которые вычисляются по её координатам
+type Indexer interface {
+	Index(e *Entity)
+}
+
+// Filter условия отбора сущностей при чтении, пустое условие не ограничивает выборку
+type Filter struct {
+	// Filenames имена файлов наборов данных
+	Filenames []string
+	// BBox прямоугольник координат
+	BBox *BBox
+	// Cell ячейка S2 любого уровня, в которую попадает CellID сущности
+	Cell s2.CellID
+	// Cells покрытие области ячейками S2, в одну из которых попадает CellID сущности
+	Cells s2.CellUnion
+}
+
+// BBox прямоугольник координат в градусах. Если West больше East,
+// прямоугольник пересекает 180-й меридиан.
+type BBox struct {
+	West, South, East, North float64
+}
+
+// ParseBBox разбирает прямоугольник вида «запад,юг,восток,север», как bbox в GeoJSON
+func ParseBBox(s string) (BBox, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return BBox{}, fmt.Errorf("bbox %q must be west,south,east,north", s)
+	}
+	var v [4]float64
+	for i, p := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return BBox{}, fmt.Errorf("bbox %q: %w", s, err)
+		}
+		v[i] = f
+	}
+	b := BBox{West: v[0], South: v[1], East: v[2], North: v[3]}
+	if b.South > b.North || b.South < -90 || b.North > 90 || math.Abs(b.West) > 180 || math.Abs(b.East) > 180 {
+		return BBox{}, fmt.Errorf("bbox %q is out of range or south is above north", s)
+	}
+	return b, nil
+}
+
+// ParsePoint разбирает координаты точки «широта,долгота» в градусах
+func ParsePoint(s string) (lat float64, lon float64, err error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("point %q must be lat,lon", s)
+	}
+	if lat, err = strconv.ParseFloat(strings.TrimSpace(parts[0]), 64); err != nil {
+		return 0, 0, fmt.Errorf("point %q: %w", s, err)
+	}
+	if lon, err = strconv.ParseFloat(strings.TrimSpace(parts[1]), 64); err != nil {
+		return 0, 0, fmt.Errorf("point %q: %w", s, err)
+	}
+	if math.Abs(lat) > 90 || math.Abs(lon) > 180 {
+		return 0, 0, fmt.Errorf("point %q is out of range", s)
+	}
+	return lat, lon, nil
+}
+
+// Contains сообщает, что точка внутри прямоугольника или на его границе
+func (b BBox) Contains(lat float64, lon float64) bool {
+	if lat < b.South || lat > b.North {
+		return false
+	}
+	if b.West <= b.East {
+		return lon >= b.West && lon <= b.East
+	}
+	return lon >= b.West || lon <= b.East
+}
+
+// ParseCell разбирает ячейку S2, заданную токеном («47a1») или числом CellID
+func ParseCell(s string) (s2.CellID, error) {
+	s = strings.TrimSpace(s)
+	if id, err := strconv.ParseUint(s, 10, 64); err == nil && len(s) > 16 {
+		if c := s2.CellID(id); c.IsValid() {
+			return c, nil
+		}
+	}
+	if c := s2.CellIDFromToken(s); c.IsValid() {
+		return c, nil
+	}
+	return 0, fmt.Errorf("invalid S2 cell %q, expected token or cell id", s)
+}
+
+// Match сообщает, что сущность подходит под фильтр
+func (f Filter) Match(e Entity) bool {
+	if len(f.Filenames) > 0 {
+		found := false
+		for _, name := range f.Filenames {
+			if name == e.Filename {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.BBox != nil && !f.BBox.Contains(e.Latitude, e.Longitude) {
+		return false
+	}
+	if f.Cell != 0 && !f.Cell.Contains(s2.CellID(e.CellID)) {
+		return false
+	}
+	if len(f.Cells) > 0 && !f.Cells.ContainsCellID(s2.CellID(e.CellID)) {
+		return false
+	}
+	return true
+}
+
 type Entities struct {
 	store Store
 }
@@ -51,3 +218,21 @@ func (es *Entities) BulkInsert(ctx context.Context, entities []Entity, batchSize
 	}
 	return nil
 }
+
+// Transactional сообщает, откатываются ли записи при ошибке в Transaction
+func (es *Entities) Transactional() bool {
+	_, ok := es.store.(Transactor)
+	return ok
+}
+
+// Transaction выполняет fn в транзакции, если хранилище их поддерживает.
+// Иначе fn выполняется напрямую и записи, сохранённые до ошибки, остаются в хранилище.
+func (es *Entities) Transaction(ctx context.Context, fn func(es *Entities) error) error {
+	t, ok := es.store.(Transactor)
+	if !ok {
+		return fn(es)
+	}
+	return t.Transaction(ctx, func(store Store) error {
+		return fn(NewEntities(store))
+	})
+}
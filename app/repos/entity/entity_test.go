@@ -0,0 +1,110 @@
+package entity
+
+import (
+	"github.com/golang/geo/s2"
+	"testing"
+)
+
+func TestParseBBox(t *testing.T) {
+	b, err := ParseBBox(" 30, 50,40,60")
+	if err != nil || b != (BBox{West: 30, South: 50, East: 40, North: 60}) {
+		t.Errorf("bbox %+v, %v", b, err)
+	}
+	for _, s := range []string{"30,50,40", "30,60,40,50", "30,50,40,91", "181,50,40,60", "a,50,40,60"} {
+		if _, err = ParseBBox(s); err == nil {
+			t.Errorf("%q accepted", s)
+		}
+	}
+}
+
+func TestParsePoint(t *testing.T) {
+	lat, lon, err := ParsePoint(" 55.752, 37.6175 ")
+	if err != nil || lat != 55.752 || lon != 37.6175 {
+		t.Errorf("point %v, %v, %v", lat, lon, err)
+	}
+	for _, s := range []string{"55.752", "55.752,37.6,1", "north,37.6", "91,0", "0,-181"} {
+		if _, _, err = ParsePoint(s); err == nil {
+			t.Errorf("%q accepted", s)
+		}
+	}
+}
+
+func TestNearQueryValidate(t *testing.T) {
+	if err := (NearQuery{Lat: 55.75, Lon: 37.62, K: 1}).Validate(); err != nil {
+		t.Error(err)
+	}
+	for _, q := range []NearQuery{
+		{Lat: 91, K: 1},
+		{Lon: -181, K: 1},
+		{K: 0},
+		{K: 1, MaxDistance: -1},
+	} {
+		if err := q.Validate(); err == nil {
+			t.Errorf("%+v accepted", q)
+		}
+	}
+}
+
+func TestBBoxContains(t *testing.T) {
+	tests := []struct {
+		bbox     BBox
+		lat, lon float64
+		want     bool
+	}{
+		{BBox{30, 50, 40, 60}, 55, 37, true},
+		{BBox{30, 50, 40, 60}, 60, 40, true},
+		{BBox{30, 50, 40, 60}, 55, 41, false},
+		{BBox{30, 50, 40, 60}, 49, 37, false},
+		// запад больше востока — прямоугольник пересекает 180-й меридиан
+		{BBox{170, -20, -170, 20}, 0, 179, true},
+		{BBox{170, -20, -170, 20}, 0, -175, true},
+		{BBox{170, -20, -170, 20}, 0, 0, false},
+	}
+	for _, tt := range tests {
+		if got := tt.bbox.Contains(tt.lat, tt.lon); got != tt.want {
+			t.Errorf("%+v contains %v,%v = %v, want %v", tt.bbox, tt.lat, tt.lon, got, tt.want)
+		}
+	}
+}
+
+func TestParseCell(t *testing.T) {
+	c := s2.CellIDFromLatLng(s2.LatLngFromDegrees(55.75, 37.62)).Parent(10)
+	for _, s := range []string{c.ToToken(), " " + c.ToToken() + " "} {
+		if got, err := ParseCell(s); err != nil || got != c {
+			t.Errorf("%q: %v, %v", s, got, err)
+		}
+	}
+	if got, err := ParseCell("47"); err != nil || got.ToToken() != "47" {
+		t.Errorf("47: %v, %v", got, err)
+	}
+	for _, s := range []string{"", "zz", "0"} {
+		if _, err := ParseCell(s); err == nil {
+			t.Errorf("%q accepted", s)
+		}
+	}
+}
+
+func TestFilterMatch(t *testing.T) {
+	moscow := s2.CellIDFromLatLng(s2.LatLngFromDegrees(55.75, 37.62))
+	e := Entity{Filename: "cities.csv", Latitude: 55.75, Longitude: 37.62, CellID: uint64(moscow)}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   bool
+	}{
+		{"empty", Filter{}, true},
+		{"filename", Filter{Filenames: []string{"rivers.csv", "cities.csv"}}, true},
+		{"other filename", Filter{Filenames: []string{"rivers.csv"}}, false},
+		{"bbox", Filter{BBox: &BBox{30, 50, 40, 60}}, true},
+		{"other bbox", Filter{BBox: &BBox{0, 0, 10, 10}}, false},
+		{"cell", Filter{Cell: moscow.Parent(5)}, true},
+		{"other cell", Filter{Cell: moscow.Parent(5).Next()}, false},
+		{"all", Filter{Filenames: []string{"cities.csv"}, BBox: &BBox{0, 0, 10, 10}, Cell: moscow.Parent(5)}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.filter.Match(e); got != tt.want {
+			t.Errorf("%v: %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
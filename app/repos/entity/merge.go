@@ -0,0 +1,32 @@
+package entity
+
+import (
+	"context"
+	"errors"
+)
+
+// Merge объединение дубликатов: каноническая сущность и сущности, которые она заменяет
+type Merge struct {
+	Canonical Entity
+	Members   []MergeMember
+}
+
+// MergeMember сущность, объединённая в каноническую
+type MergeMember struct {
+	Entity
+	// Distance расстояние до канонической сущности по эллипсоиду WGS 84 в метрах
+	Distance float64
+	// Similarity сходство имени с именем канонической сущности от 0 до 1
+	Similarity float64
+}
+
+// Merger хранилище, которое может объединить сущности
+type Merger interface {
+	// Merge записывает каноническую сущность и состав объединения и скрывает объединённые сущности
+	// от чтения. Если какой-то сущности нет или она уже объединена, ничего не записывается
+	// и возвращается ErrMerged.
+	Merge(ctx context.Context, m Merge) error
+}
+
+// ErrMerged сущности нет в хранилище или она уже объединена
+var ErrMerged = errors.New("entity is missing or already merged")
@@ -0,0 +1,44 @@
+package entity
+
+import (
+	"fmt"
+	"github.com/google/uuid"
+	"math"
+)
+
+// NearQuery запрос ближайших к точке сущностей
+type NearQuery struct {
+	// Lat, Lon точка запроса в градусах
+	Lat, Lon float64
+	// K сколько ближайших сущностей вернуть
+	K int
+	// MaxDistance наибольшее расстояние до сущности в метрах, 0 — без ограничения
+	MaxDistance float64
+	// Filenames имена файлов наборов данных, пустой — все файлы
+	Filenames []string
+	// Exclude сущность, которая не попадает в результат, например та, соседи которой ищутся
+	Exclude uuid.UUID
+}
+
+// Validate проверяет точку и ограничения запроса
+func (q NearQuery) Validate() error {
+	if math.Abs(q.Lat) > 90 || math.Abs(q.Lon) > 180 {
+		return fmt.Errorf("near: point %v,%v is out of range", q.Lat, q.Lon)
+	}
+	if q.K < 1 {
+		return fmt.Errorf("near: k %d must be positive", q.K)
+	}
+	if !(q.MaxDistance >= 0) {
+		return fmt.Errorf("near: max distance %v must not be negative", q.MaxDistance)
+	}
+	return nil
+}
+
+// Neighbour сущность, найденная запросом NearQuery
+type Neighbour struct {
+	Entity
+	// Distance расстояние от точки запроса по эллипсоиду WGS 84 в метрах
+	Distance float64
+	// Azimuth азимут из точки запроса на сущность от 0 до 360
+	Azimuth float64
+}
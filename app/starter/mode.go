@@ -0,0 +1,69 @@
+package starter
+
+import (
+	"fmt"
+	"github.com/audetv/datasets-parser/app/repos/dataset"
+)
+
+// Mode режим обработки записей, которые не удалось разобрать
+type Mode string
+
+const (
+	// ModeLenient пропускает отклонённые записи, пока не превышен порог ошибок
+	ModeLenient Mode = "lenient"
+	// ModeStrict прерывает файл и откатывает его записи на первой отклонённой записи
+	ModeStrict Mode = "strict"
+)
+
+// ParseMode разбирает значение флага --mode
+func ParseMode(s string) (Mode, error) {
+	switch Mode(s) {
+	case ModeLenient, ModeStrict:
+		return Mode(s), nil
+	}
+	return "", fmt.Errorf("bad mode %q, expected %v or %v", s, ModeStrict, ModeLenient)
+}
+
+// Limits пороги ошибок файла в режиме lenient, нулевые значения не ограничивают
+type Limits struct {
+	// MaxErrors допустимое количество отклонённых записей
+	MaxErrors int
+	// MaxErrorRate допустимая доля отклонённых записей от всех записей файла, от 0 до 1
+	MaxErrorRate float64
+}
+
+// Validate проверяет значения порогов
+func (l Limits) Validate() error {
+	if l.MaxErrors < 0 {
+		return fmt.Errorf("bad max errors %d, expected 0 or more", l.MaxErrors)
+	}
+	if l.MaxErrorRate < 0 || l.MaxErrorRate > 1 {
+		return fmt.Errorf("bad max error rate %v, expected value from 0 to 1", l.MaxErrorRate)
+	}
+	return nil
+}
+
+// reject решает, продолжать ли файл после отклонённой записи entry,
+// rejected — количество отклонённых записей вместе с ней
+func (c Config) reject(entry dataset.Entry, rejected int) error {
+	if c.Mode == ModeStrict {
+		return fmt.Errorf("strict mode: %w", entry.Errors[0])
+	}
+	if c.Limits.MaxErrors > 0 && rejected > c.Limits.MaxErrors {
+		return fmt.Errorf("rejected %d rows, max errors %d, last: %w", rejected, c.Limits.MaxErrors, entry.Errors[0])
+	}
+	return nil
+}
+
+// finish проверяет долю отклонённых записей прочитанного файла
+func (c Config) finish(inserted int, rejected int) error {
+	if c.Mode == ModeStrict || c.Limits.MaxErrorRate == 0 || rejected == 0 {
+		return nil
+	}
+	rate := float64(rejected) / float64(inserted+rejected)
+	if rate > c.Limits.MaxErrorRate {
+		return fmt.Errorf("rejected %d of %d rows (%.1f%%), max error rate %.1f%%",
+			rejected, inserted+rejected, rate*100, c.Limits.MaxErrorRate*100)
+	}
+	return nil
+}
@@ -2,23 +2,14 @@ package starter
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"github.com/audetv/datasets-parser/app/geoindex"
 	"github.com/audetv/datasets-parser/app/repos/dataset"
 	"github.com/audetv/datasets-parser/app/repos/entity"
-	"github.com/audetv/datasets-parser/dataset/allcities"
-	"github.com/audetv/datasets-parser/dataset/ancienthuman"
-	"github.com/audetv/datasets-parser/dataset/bibleplaces"
-	"github.com/audetv/datasets-parser/dataset/earthquake"
-	"github.com/audetv/datasets-parser/dataset/globalpowerplant"
-	"github.com/audetv/datasets-parser/dataset/globalterrorismdb"
-	"github.com/audetv/datasets-parser/dataset/impactstructures"
-	"github.com/audetv/datasets-parser/dataset/monolith"
-	"github.com/audetv/datasets-parser/dataset/pleiades"
-	"github.com/audetv/datasets-parser/dataset/romantradestamps"
-	"github.com/audetv/datasets-parser/dataset/unesco"
-	"github.com/audetv/datasets-parser/dataset/volcanic"
-	"github.com/audetv/datasets-parser/dataset/worldpostalcode"
-	"github.com/golang/geo/s2"
+	"github.com/audetv/datasets-parser/dataset/archive"
+	"github.com/audetv/datasets-parser/dataset/input"
+	"github.com/audetv/datasets-parser/dataset/quarantine"
 	"github.com/google/uuid"
 	"log"
 	"os"
@@ -26,85 +17,132 @@ import (
 
 type App struct {
 	entities *entity.Entities
+	readers  *dataset.Registry
+	config   Config
 }
 
-func NewApp(store entity.Store) *App {
+// Config параметры обработки файлов
+type Config struct {
+	// Overrides кодировка и разделитель отдельных файлов, заданные в командной строке
+	Overrides input.Overrides
+	// Mode режим обработки отклонённых записей, по умолчанию ModeLenient
+	Mode Mode
+	// Limits пороги ошибок файла в режиме ModeLenient
+	Limits Limits
+	// Index вычисляет ячейки и geohash сущностей, nil — geoindex.Indexer с длинами по умолчанию
+	Index entity.Indexer
+}
+
+func NewApp(store entity.Store, readers *dataset.Registry, config Config) *App {
+	if config.Index == nil {
+		config.Index = geoindex.Indexer{}
+	}
 	app := &App{
 		entities: entity.NewEntities(store),
+		readers:  readers,
+		config:   config,
 	}
 	return app
 }
 
-func (a *App) parseDataset(ctx context.Context, entries dataset.Store, filename string) {
+// parseDataset сохраняет записи файла в одной транзакции, а отклонённые записи
+// передаёт в карантин q. Если файл прерван — в режиме strict, при превышении
+// порога ошибок или ошибке чтения и записи, — его записи откатываются.
+func (a *App) parseDataset(ctx context.Context, entries dataset.Store, filename string, q *quarantine.Writer) Result {
+	result := Result{Filename: filename, Status: StatusOK}
 
-	chin, err := entries.ReadAll(ctx)
-	if err != nil {
-		log.Panic(err)
-	}
+	err := a.entities.Transaction(ctx, func(es *entity.Entities) error {
+		// при прерывании файла останавливаем чтение
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		chin, err := entries.ReadAll(ctx)
+		if err != nil {
+			return err
+		}
+
+		var entities []entity.Entity
+		batchSize := 3500
+		batchSizeCount := 0
 
-	var entities []entity.Entity
-	batchSize := 3500
-	batchSizeCount := 0
+		for {
+			entry, ok := <-chin
+			if !ok {
+				break // exit break loop
+			} else if entry.Rejected() {
+				// запись с ошибками не сохраняем, а откладываем в карантин
+				result.Rejected++
+				if err = q.Write(entry); err != nil {
+					log.Println(err)
+				}
+				if err = a.config.reject(entry, result.Rejected); err != nil {
+					return err
+				}
+			} else {
+				en := entity.Entity{
+					ID:              uuid.New(),
+					Filename:        filename,
+					Name:            entry.Name,
+					Description:     entry.Description,
+					Longitude:       entry.Longitude,
+					Latitude:        entry.Latitude,
+					Height:          entry.Height,
+					DescriptionJson: entry.DescriptionJson,
+				}
+				if entry.ID != uuid.Nil {
+					en.ID = entry.ID
+				}
 
-	for {
-		entry, ok := <-chin
-		if !ok {
-			break // exit break loop
-		} else {
-			en := entity.Entity{
-				ID:              uuid.New(),
-				Filename:        filename,
-				Name:            entry.Name,
-				Description:     entry.Description,
-				Longitude:       entry.Longitude,
-				Latitude:        entry.Latitude,
-				Height:          entry.Height,
-				DescriptionJson: entry.DescriptionJson,
+				a.config.Index.Index(&en)
+
+				entities = append(entities, en)
+				batchSizeCount++
 			}
 
-			en.CellID = calculateCellID(en.Latitude, en.Longitude)
-			en.Geohash = calculateGeohash(en.Latitude, en.Longitude)
+			// Записываем пакетам по batchSize параграфов
+			if batchSizeCount == batchSize-1 {
+				if err = es.BulkInsert(ctx, entities, len(entities)); err != nil {
+					return err
+				}
+				result.Inserted += len(entities)
+				// очищаем slice
+				entities = nil
+				batchSizeCount = 0
+			}
+		}
 
-			entities = append(entities, en)
-			batchSizeCount++
+		if err = ctx.Err(); err != nil {
+			return err
+		}
+		if f, ok := entries.(dataset.Failer); ok && f.Err() != nil {
+			return f.Err()
 		}
 
-		// Записываем пакетам по batchSize параграфов
-		if batchSizeCount == batchSize-1 {
-			err = a.entities.BulkInsert(ctx, entities, len(entities))
-			if err != nil {
-				log.Printf("log bulk insert error query: %v \r\n", err)
+		// Если batchSizeCount меньше batchSize, то записываем оставшиеся параграфы
+		if len(entities) > 0 {
+			if err = es.BulkInsert(ctx, entities, len(entities)); err != nil {
+				return err
 			}
-			// очищаем slice
-			entities = nil
-			batchSizeCount = 0
+			result.Inserted += len(entities)
 		}
-	}
-	// Если batchSizeCount меньше batchSize, то записываем оставшиеся параграфы
-	if len(entities) > 0 {
-		err = a.entities.BulkInsert(ctx, entities, len(entities))
-	}
-}
 
-func calculateGeohash(lat float64, lon float64) string {
-	latlng := s2.LatLngFromDegrees(lat, lon)
-	cellID := s2.CellIDFromLatLng(latlng)
-	//b := make([]byte, 8)
-	//binary.LittleEndian.PutUint64(b, uint64(cellID))
-	//en.CellID = cellID.ToToken()[8:]
-	return cellID.ToToken()
-}
+		return a.config.finish(result.Inserted, result.Rejected)
+	})
 
-func calculateCellID(lat float64, lon float64) uint64 {
-	latlng := s2.LatLngFromDegrees(lat, lon)
-	cellID := s2.CellIDFromLatLng(latlng)
-	//b := make([]byte, 8)
-	//binary.LittleEndian.PutUint64(b, uint64(cellID))
-	//en.CellID = cellID.ToToken()[8:]
-	return uint64(cellID)
+	if err != nil {
+		result.Status = StatusAborted
+		result.Reason = err
+		result.RolledBack = a.entities.Transactional()
+		if result.RolledBack {
+			result.Inserted = 0
+		}
+	}
+	return result
 }
 
-func (a *App) Process(ctx context.Context, folder string) {
+// Process обрабатывает все файлы папки и возвращает итоги по каждому файлу
+func (a *App) Process(ctx context.Context, folder string) *Summary {
+	summary := &Summary{}
 
 	// читаем все файлы в директории
 	files, err := os.ReadDir(folder)
@@ -112,7 +150,10 @@ func (a *App) Process(ctx context.Context, folder string) {
 		log.Fatal(err)
 	}
 
-	var entries dataset.Store
+	names := make(map[string]bool, len(files))
+	for _, file := range files {
+		names[file.Name()] = true
+	}
 
 	// итерируемся по списку файлов
 	for _, file := range files {
@@ -121,201 +162,98 @@ func (a *App) Process(ctx context.Context, folder string) {
 			if file.Name() == ".gitignore" {
 				continue
 			}
+			// отчёты карантина прошлых запусков не загружаем
+			if quarantine.IsReport(file.Name()) {
+				continue
+			}
+			// сопутствующие файлы, например .dbf рядом с .shp, читаются вместе с основным
+			if a.readers.Companion(file.Name(), func(name string) bool { return names[name] }) {
+				continue
+			}
 
-			entries, err = getEntriesInstance(entries, folder, file.Name())
+			sources, err := getSources(folder, file.Name())
 			if err != nil {
 				log.Println(err)
+				summary.add(failed(file.Name(), err))
 				continue
 			}
-			a.parseDataset(ctx, entries, file.Name())
+
+			members := make(map[string]bool, len(sources))
+			for _, src := range sources {
+				members[src.Name] = true
+			}
+			for _, src := range sources {
+				if ctx.Err() != nil {
+					return summary
+				}
+				if a.readers.Companion(src.Name, func(name string) bool { return members[name] }) {
+					continue
+				}
+				summary.add(a.processSource(ctx, folder, src))
+			}
 		}
 	}
+	return summary
 }
 
-func getEntriesInstance(entries dataset.Store, folder string, filename string) (dataset.Store, error) {
+// processSource записывает один файл набора данных
+func (a *App) processSource(ctx context.Context, folder string, src dataset.Source) Result {
+	src.Options = a.config.Overrides.For(src.Name)
+	entries, err := a.getEntriesInstance(src)
+	if err != nil {
+		log.Println(err)
+		return failed(src.Name, err)
+	}
 
-	switch filename {
-	case "all-bible-places.csv":
-		ne, err := bibleplaces.NewCSVEntries(fmt.Sprintf("%v/%v", folder, filename))
-		if err != nil {
-			return nil, err
-		}
-		return ne, nil
-	case "all-cities-with-a-population.csv":
-		ne, err := allcities.NewCSVEntries(fmt.Sprintf("%v/%v", folder, filename))
-		if err != nil {
-			return nil, err
-		}
-		return ne, nil
-	case "All_ancient_human_dna.csv":
-		ne, err := ancienthuman.NewCSVEntries(fmt.Sprintf("%v/%v", folder, filename))
-		if err != nil {
-			return nil, err
-		}
-		return ne, nil
-	case "Ancient Locations al_sites.csv":
-		ne, err := ancienthuman.NewCSVEntries(fmt.Sprintf("%v/%v", folder, filename))
-		if err != nil {
-			return nil, err
-		}
-		return ne, nil
-	case "ANTARCTIC AGDC Dataset.csv":
-		ne, err := ancienthuman.NewCSVEntries(fmt.Sprintf("%v/%v", folder, filename))
-		if err != nil {
-			return nil, err
-		}
-		return ne, nil
-	case "archaeogeodesy.csv":
-		ne, err := ancienthuman.NewCSVEntries(fmt.Sprintf("%v/%v", folder, filename))
-		if err != nil {
-			return nil, err
-		}
-		return ne, nil
-	case "GPS System Objects.csv":
-		ne, err := ancienthuman.NewCSVEntries(fmt.Sprintf("%v/%v", folder, filename))
-		if err != nil {
-			return nil, err
-		}
-		return ne, nil
-	case "Historical Cities.csv":
-		ne, err := ancienthuman.NewCSVEntries(fmt.Sprintf("%v/%v", folder, filename))
-		if err != nil {
-			return nil, err
-		}
-		return ne, nil
-	case "Historical Objects.csv":
-		ne, err := ancienthuman.NewCSVEntries(fmt.Sprintf("%v/%v", folder, filename))
-		if err != nil {
-			return nil, err
-		}
-		return ne, nil
-	case "megalithic_earth_AJ.csv":
-		ne, err := ancienthuman.NewCSVEntries(fmt.Sprintf("%v/%v", folder, filename))
-		if err != nil {
-			return nil, err
-		}
-		return ne, nil
-	case "megalithic_earth_KZ.csv":
-		ne, err := ancienthuman.NewCSVEntries(fmt.Sprintf("%v/%v", folder, filename))
-		if err != nil {
-			return nil, err
-		}
-		return ne, nil
-	case "Rank 1 Archaeology Sites.csv":
-		ne, err := ancienthuman.NewCSVEntries(fmt.Sprintf("%v/%v", folder, filename))
-		if err != nil {
-			return nil, err
-		}
-		return ne, nil
-	case "World archaeology.csv":
-		ne, err := ancienthuman.NewCSVEntries(fmt.Sprintf("%v/%v", folder, filename))
-		if err != nil {
-			return nil, err
-		}
-		return ne, nil
-	case "Все вулканы мира.csv":
-		ne, err := ancienthuman.NewCSVEntries(fmt.Sprintf("%v/%v", folder, filename))
-		if err != nil {
-			return nil, err
-		}
-		return ne, nil
-	case "Древнееегипетские захоронения.csv":
-		ne, err := ancienthuman.NewCSVEntries(fmt.Sprintf("%v/%v", folder, filename))
-		if err != nil {
-			return nil, err
-		}
-		return ne, nil
-	case "Королевские резиденции.csv":
-		ne, err := ancienthuman.NewCSVEntries(fmt.Sprintf("%v/%v", folder, filename))
-		if err != nil {
-			return nil, err
-		}
-		return ne, nil
-	case "Полезные ископаемые мира.csv":
-		ne, err := ancienthuman.NewCSVEntries(fmt.Sprintf("%v/%v", folder, filename))
-		if err != nil {
-			return nil, err
-		}
-		return ne, nil
-	case "Полюса недоступности Земли.csv":
-		ne, err := ancienthuman.NewCSVEntries(fmt.Sprintf("%v/%v", folder, filename))
-		if err != nil {
-			return nil, err
-		}
-		return ne, nil
-	case "Православные Храмы.csv":
-		ne, err := ancienthuman.NewCSVEntries(fmt.Sprintf("%v/%v", folder, filename))
-		if err != nil {
-			return nil, err
-		}
-		return ne, nil
-	case "global_power_plant_database_github.csv":
-		ne, err := globalpowerplant.NewCSVEntries(fmt.Sprintf("%v/%v", folder, filename))
-		if err != nil {
-			return nil, err
-		}
-		return ne, nil
-	case "globalterrorismdb_full_may2023.csv":
-		ne, err := globalterrorismdb.NewCSVEntries(fmt.Sprintf("%v/%v", folder, filename))
-		if err != nil {
-			return nil, err
-		}
-		return ne, nil
-	case "monolith_tracker_parsed.csv":
-		ne, err := monolith.NewCSVEntries(fmt.Sprintf("%v/%v", folder, filename))
-		if err != nil {
-			return nil, err
-		}
-		return ne, nil
-	case "pleiades_data_places.csv":
-		ne, err := pleiades.NewCSVEntries(fmt.Sprintf("%v/%v", folder, filename))
-		if err != nil {
-			return nil, err
-		}
-		return ne, nil
-	case "Roman trade stamps ascii.csv":
-		ne, err := romantradestamps.NewCSVEntries(fmt.Sprintf("%v/%v", folder, filename))
-		if err != nil {
-			return nil, err
-		}
-		return ne, nil
-	case "significant-earthquake-database-parsed.csv":
-		ne, err := earthquake.NewCSVEntries(fmt.Sprintf("%v/%v", folder, filename))
-		if err != nil {
-			return nil, err
-		}
-		return ne, nil
-	case "significant-volcanic-eruption-database-parsed.csv":
-		ne, err := volcanic.NewCSVEntries(fmt.Sprintf("%v/%v", folder, filename))
-		if err != nil {
-			return nil, err
-		}
-		return ne, nil
-	case "UNESCO World Heritage.csv":
-		ne, err := unesco.NewCSVEntries(fmt.Sprintf("%v/%v", folder, filename))
-		if err != nil {
-			return nil, err
-		}
-		return ne, nil
-	case "Атомные станции.csv":
-		ne, err := ancienthuman.NewCSVEntries(fmt.Sprintf("%v/%v", folder, filename))
-		if err != nil {
-			return nil, err
-		}
-		return ne, nil
-	case "Импактные структуры Земли.csv":
-		ne, err := impactstructures.NewCSVEntries(fmt.Sprintf("%v/%v", folder, filename))
-		if err != nil {
-			return nil, err
-		}
-		return ne, nil
-	case "world-postal-code.csv":
-		ne, err := worldpostalcode.NewCSVEntries(fmt.Sprintf("%v/%v", folder, filename))
+	q := quarantine.New(folder, src.Name)
+	if table, ok := entries.(dataset.Table); ok {
+		q.SetHeader(table.Columns(), table.Comma())
+	}
+	result := a.parseDataset(ctx, entries, src.Name, q)
+	if err = q.Close(); err != nil {
+		log.Println(err)
+	}
+	if q.Count() > 0 {
+		log.Printf("%v: отклонено записей %d, см. %v и %v\n", src.Name, q.Count(), q.RejectedPath(), q.ErrorsPath())
+	}
+	if result.Status == StatusAborted {
+		log.Printf("%v: файл прерван: %v\n", src.Name, result.Reason)
+	}
+	return result
+}
+
+// failed возвращает итог файла, который не удалось начать читать:
+// неизвестные файлы пропускаются, остальные считаются прерванными
+func failed(filename string, err error) Result {
+	status := StatusAborted
+	if errors.Is(err, dataset.ErrNotSupported) || errors.Is(err, archive.ErrUnsupported) {
+		status = StatusSkipped
+	}
+	return Result{Filename: filename, Status: status, Reason: err, RolledBack: true}
+}
+
+// getSources возвращает файл или, если это архив, содержащиеся в нём файлы
+func getSources(folder string, filename string) ([]dataset.Source, error) {
+	path := fmt.Sprintf("%v/%v", folder, filename)
+	if archive.IsArchive(filename) {
+		return archive.Sources(filename, path)
+	}
+	return []dataset.Source{dataset.FileSource(filename, path)}, nil
+}
+
+func (a *App) getEntriesInstance(src dataset.Source) (dataset.Store, error) {
+	reader, err := a.readers.Match(src.Name)
+	if errors.Is(err, dataset.ErrNotSupported) {
+		// имя файла не известно, определяем набор данных по заголовку
+		detection, err := a.readers.Detect(src)
 		if err != nil {
 			return nil, err
 		}
-		return ne, nil
-	default:
-		return nil, fmt.Errorf("%v file not supported", filename)
+		log.Printf("%v: определён набор данных %v по заголовку, совпадение %.0f%%\n", src.Name, detection.Reader.Name, detection.Confidence*100)
+		reader = detection.Reader
+	} else if err != nil {
+		return nil, err
 	}
+
+	return reader.New(src)
 }
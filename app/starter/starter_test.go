@@ -0,0 +1,60 @@
+package starter
+
+import (
+	"errors"
+	"github.com/audetv/datasets-parser/app/repos/dataset"
+	"github.com/audetv/datasets-parser/dataset/mapping"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReaderFallsBackToDetect(t *testing.T) {
+	var opened []string
+	reader := func(name string, patterns []string, fingerprint []string) dataset.Reader {
+		return dataset.Reader{
+			Name: name, Patterns: patterns, Fingerprint: fingerprint, Sniff: mapping.Sniff,
+			New: func(src dataset.Source) (dataset.Store, error) {
+				opened = append(opened, name+" "+src.Name)
+				return nil, nil
+			},
+		}
+	}
+	rg := dataset.NewRegistry()
+	for _, r := range []dataset.Reader{
+		reader("quakes", []string{"quakes_*.csv"}, []string{"time", "latitude", "longitude", "mag", "place"}),
+		reader("volcanoes", []string{"volcanoes.csv"}, []string{"name", "country", "latitude", "longitude", "elevation"}),
+	} {
+		if err := rg.Register(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"quakes_2023.csv": "name;country;latitude;longitude;elevation\n",
+		"eq.csv":          "time,latitude,longitude,mag,place\n",
+		"unknown.csv":     "a,b,c\n",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	a := NewApp(nil, rg, Config{})
+	// подошедшее имя файла важнее заголовка
+	if _, err := a.getEntriesInstance(dataset.FileSource("quakes_2023.csv", filepath.Join(dir, "quakes_2023.csv"))); err != nil {
+		t.Error(err)
+	}
+	// имя не подошло, набор данных определяется по заголовку
+	if _, err := a.getEntriesInstance(dataset.FileSource("eq.csv", filepath.Join(dir, "eq.csv"))); err != nil {
+		t.Error(err)
+	}
+	if _, err := a.getEntriesInstance(dataset.FileSource("unknown.csv", filepath.Join(dir, "unknown.csv"))); !errors.Is(err, dataset.ErrNotSupported) {
+		t.Errorf("unknown header: %v", err)
+	}
+	if want := []string{"quakes quakes_2023.csv", "quakes eq.csv"}; len(opened) != 2 || opened[0] != want[0] || opened[1] != want[1] {
+		t.Errorf("opened %q, want %q", opened, want)
+	}
+}
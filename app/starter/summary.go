@@ -0,0 +1,46 @@
+package starter
+
+// Status итог обработки файла
+type Status string
+
+const (
+	// StatusOK файл записан, возможно без отклонённых записей
+	StatusOK Status = "ok"
+	// StatusAborted файл прерван, его записи откачены
+	StatusAborted Status = "aborted"
+	// StatusSkipped файл не является набором данных
+	StatusSkipped Status = "skipped"
+)
+
+// Result итог обработки одного файла
+type Result struct {
+	Filename string
+	Status   Status
+	Inserted int
+	Rejected int
+	// Reason причина, по которой файл прерван или пропущен
+	Reason error
+	// RolledBack записи прерванного файла откачены,
+	// false если хранилище не поддерживает транзакции
+	RolledBack bool
+}
+
+// Summary итоги обработки всех файлов папки
+type Summary struct {
+	Results []Result
+}
+
+// Aborted возвращает прерванные файлы
+func (s *Summary) Aborted() []Result {
+	var aborted []Result
+	for _, r := range s.Results {
+		if r.Status == StatusAborted {
+			aborted = append(aborted, r)
+		}
+	}
+	return aborted
+}
+
+func (s *Summary) add(r Result) {
+	s.Results = append(s.Results, r)
+}
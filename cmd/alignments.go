@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/audetv/datasets-parser/app/alignment"
+	"github.com/audetv/datasets-parser/app/exporter"
+	"github.com/audetv/datasets-parser/app/repos/entity"
+	flag "github.com/spf13/pflag"
+	"io"
+	"log"
+	"path/filepath"
+	"strings"
+)
+
+// alignmentOptions флаги команды alignments
+type alignmentOptions struct {
+	config  alignment.Config
+	output  string
+	members string
+	filter  filterOptions
+}
+
+// alignmentsCommand разобранные параметры команды alignments
+type alignmentsCommand struct {
+	config  alignment.Config
+	filter  entity.Filter
+	output  string
+	members string
+}
+
+// defaultAlignmentsOutput файл линий команды alignments по умолчанию
+const defaultAlignmentsOutput = "alignments.geojson"
+
+// flags регистрирует флаги команды alignments
+func (o *alignmentOptions) flags(fs *flag.FlagSet) {
+	fs.Float64Var(
+		&o.config.Tolerance,
+		"angle-tolerance",
+		alignment.DefaultTolerance,
+		"наибольшее отклонение сущности от большого круга в градусах дуги, 0.01° — около 1,1 км",
+	)
+	fs.Float64Var(
+		&o.config.MaxLength,
+		"max-length",
+		alignment.DefaultMaxLength,
+		"наибольшее расстояние между крайними сущностями линии в метрах",
+	)
+	fs.IntVar(
+		&o.config.MinMembers,
+		"min-members",
+		3,
+		"наименьшее число сущностей линии",
+	)
+	fs.Float64Var(
+		&o.config.MinSpacing,
+		"min-spacing",
+		alignment.DefaultMinSpacing,
+		"сущности ближе этого расстояния в метрах считаются одним местом",
+	)
+	fs.IntVar(
+		&o.config.Limit,
+		"limit",
+		alignment.DefaultLimit,
+		"сколько лучших линий записать",
+	)
+	fs.StringVarP(
+		&o.output,
+		"output",
+		"o",
+		defaultAlignmentsOutput,
+		"GeoJSON линий, «-» — стандартный вывод",
+	)
+	fs.StringVar(
+		&o.members,
+		"members",
+		"",
+		"csv сущностей линий, по умолчанию рядом с файлом -o с окончанием _members.csv",
+	)
+	o.filter.flags(fs)
+}
+
+// parse проверяет флаги команды alignments до подключения к базе данных
+func (o *alignmentOptions) parse(args []string) (readCommand, error) {
+	if err := noArgs("alignments", args); err != nil {
+		return nil, err
+	}
+	if err := o.config.Validate(); err != nil {
+		return nil, err
+	}
+	filter, err := o.filter.parse()
+	if err != nil {
+		return nil, err
+	}
+	cmd := &alignmentsCommand{config: o.config, filter: filter, output: o.output, members: o.members}
+	if cmd.output == "" {
+		cmd.output = defaultAlignmentsOutput
+	}
+	if cmd.members == "" {
+		base := defaultAlignmentsOutput
+		if cmd.output != exporter.Stdout {
+			base = cmd.output
+		}
+		cmd.members = strings.TrimSuffix(base, filepath.Ext(base)) + "_members.csv"
+	}
+	if cmd.members == exporter.Stdout && cmd.output == exporter.Stdout {
+		return nil, fmt.Errorf("alignments: geojson and members csv cannot both be written to stdout")
+	}
+	return cmd, nil
+}
+
+// run ищет линии и записывает их в GeoJSON и csv сущностей
+func (c *alignmentsCommand) run(ctx context.Context, store entity.ReadStore) error {
+	log.Printf("поиск линий: допуск %v°, длина до %v м, сущностей от %d\n",
+		c.config.Tolerance, c.config.MaxLength, c.config.MinMembers)
+	alignments, err := alignment.Find(ctx, store, c.filter, c.config)
+	if err != nil {
+		return err
+	}
+	err = writeFile(c.output, func(w io.Writer) error {
+		return exporter.WriteAlignments(w, alignments)
+	})
+	if err != nil {
+		return err
+	}
+	err = writeFile(c.members, func(w io.Writer) error {
+		return exporter.WriteAlignmentMembers(w, alignments)
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("найдено линий %d, записаны в %v и %v\n", len(alignments), c.output, c.members)
+	return nil
+}
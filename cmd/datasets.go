@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"github.com/audetv/datasets-parser/app/repos/dataset"
+	"github.com/audetv/datasets-parser/dataset/mapping"
+	flag "github.com/spf13/pflag"
+	"io"
+	"strings"
+	"text/tabwriter"
+)
+
+// listDatasets выводит список зарегистрированных читателей наборов данных
+func listDatasets(w io.Writer, registry *dataset.Registry) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tFILES\tHEADER")
+	for _, r := range registry.Readers() {
+		files := append([]string{}, r.Patterns...)
+		for _, re := range r.Regexps {
+			files = append(files, "/"+re.String()+"/")
+		}
+		fmt.Fprintf(tw, "%v\t%v\t%v\n", r.Name, strings.Join(files, ", "), strings.Join(r.Fingerprint, ";"))
+	}
+	tw.Flush()
+}
+
+// mappingsFlag регистрирует флаг папки с дополнительными маппингами наборов данных
+func mappingsFlag(fs *flag.FlagSet, mappingsPath *string) {
+	fs.StringVarP(
+		mappingsPath,
+		"mappings",
+		"m",
+		"",
+		"путь до папки с дополнительными json файлами маппинга наборов данных",
+	)
+}
+
+// registerMappings загружает маппинги папки mappingsPath и регистрирует их читателей
+func registerMappings(mappingsPath string) error {
+	mappings, err := mapping.Load(mappingsPath)
+	if err != nil {
+		return err
+	}
+	return mappings.Register(dataset.DefaultRegistry)
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/audetv/datasets-parser/app/dedup"
+	"github.com/audetv/datasets-parser/app/geoindex"
+	"github.com/audetv/datasets-parser/app/repos/entity"
+	"github.com/audetv/datasets-parser/db/entitystore"
+	"github.com/google/uuid"
+	flag "github.com/spf13/pflag"
+	"io"
+	"log"
+	"os"
+)
+
+// dedupOptions флаги команды dedup
+type dedupOptions struct {
+	config dedup.Config
+	apply  string
+	output string
+	filter filterOptions
+	// index флаги индексации, канонические сущности индексируются как при импорте
+	index indexOptions
+}
+
+// dedupCommand разобранные параметры команды dedup: поиск дубликатов с отчётом
+// или объединение кластеров проверенного отчёта
+type dedupCommand struct {
+	config  dedup.Config
+	filter  entity.Filter
+	output  string
+	apply   string
+	indexer geoindex.Indexer
+	// clusters кластеры отчёта apply
+	clusters [][]uuid.UUID
+}
+
+// defaultDedupOutput отчёт команды dedup по умолчанию
+const defaultDedupOutput = "duplicates.csv"
+
+// flags регистрирует флаги команды dedup
+func (o *dedupOptions) flags(fs *flag.FlagSet) {
+	fs.Float64Var(
+		&o.config.Distance,
+		"merge-distance",
+		dedup.DefaultDistance,
+		"наибольшее расстояние между дубликатами в метрах",
+	)
+	fs.Float64Var(
+		&o.config.Similarity,
+		"similarity",
+		dedup.DefaultSimilarity,
+		"наименьшее сходство имён дубликатов от 0 до 1, кириллица сравнивается в латинской записи",
+	)
+	fs.BoolVar(
+		&o.config.SameFile,
+		"same-file",
+		false,
+		"искать дубликаты и внутри одного файла набора данных",
+	)
+	fs.StringVar(
+		&o.apply,
+		"apply",
+		"",
+		"объединить в канонические сущности кластеры проверенного отчёта, записанного командой dedup",
+	)
+	fs.StringVarP(
+		&o.output,
+		"output",
+		"o",
+		defaultDedupOutput,
+		"отчёт о дубликатах, «-» — стандартный вывод",
+	)
+	o.filter.flags(fs)
+	o.index.flags(fs)
+}
+
+// parse проверяет флаги команды dedup и читает отчёт --apply до подключения к базе данных
+func (o *dedupOptions) parse(args []string) (readCommand, error) {
+	if err := noArgs("dedup", args); err != nil {
+		return nil, err
+	}
+	if err := o.index.parse(); err != nil {
+		return nil, err
+	}
+	cmd := &dedupCommand{config: o.config, output: o.output, apply: o.apply, indexer: o.index.indexer}
+	if o.apply != "" {
+		if len(o.filter.filenames) > 0 || o.filter.bbox != "" || o.filter.cell != "" {
+			return nil, fmt.Errorf("dedup: --apply merges the clusters of the report and takes no --filename, --bbox or --cell")
+		}
+		f, err := os.Open(o.apply)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		if cmd.clusters, err = dedup.ReadReport(f); err != nil {
+			return nil, fmt.Errorf("%v: %w", o.apply, err)
+		}
+		return cmd, nil
+	}
+	if err := o.config.Validate(); err != nil {
+		return nil, err
+	}
+	filter, err := o.filter.parse()
+	if err != nil {
+		return nil, err
+	}
+	cmd.filter = filter
+	if cmd.output == "" {
+		cmd.output = defaultDedupOutput
+	}
+	return cmd, nil
+}
+
+func (c *dedupCommand) run(ctx context.Context, store entity.ReadStore) error {
+	if c.apply != "" {
+		return c.merge(ctx, store)
+	}
+	log.Printf("поиск дубликатов: расстояние до %v м, сходство имён от %v\n", c.config.Distance, c.config.Similarity)
+	merges, err := dedup.Find(ctx, store, c.filter, c.config)
+	if err != nil {
+		return err
+	}
+	err = writeFile(c.output, func(w io.Writer) error {
+		return dedup.WriteReport(w, merges)
+	})
+	if err != nil {
+		return err
+	}
+	n := 0
+	for _, m := range merges {
+		n += len(m.Members)
+	}
+	log.Printf("найдено кластеров %d из сущностей %d, отчёт записан в %v\n", len(merges), n, c.output)
+	log.Printf("после проверки отчёта объедините кластеры: dedup --apply %v\n", c.output)
+	return nil
+}
+
+// merge объединяет кластеры отчёта в канонические сущности. Кластеры, сущности которых
+// уже объединены или удалены, пропускаются, так что отчёт можно применить повторно.
+func (c *dedupCommand) merge(ctx context.Context, store entity.ReadStore) error {
+	merger, ok := store.(entity.Merger)
+	if !ok {
+		return errors.New("dedup: store cannot merge entities")
+	}
+	// объединение пишет в базу данных, поэтому схема обновляется, как при импорте
+	if db, ok := store.(*entitystore.Entities); ok {
+		log.Println("обновление схемы базы данных")
+		if err := db.Migrate(ctx, c.indexer); err != nil {
+			return err
+		}
+	}
+	merged, skipped := 0, 0
+	for k, ids := range c.clusters {
+		if len(ids) < 2 {
+			log.Printf("кластер %d пропущен: в нём одна сущность\n", k+1)
+			skipped++
+			continue
+		}
+		es := make([]entity.Entity, 0, len(ids))
+		for _, id := range ids {
+			e, err := store.Get(ctx, id)
+			if errors.Is(err, entity.ErrNotFound) {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			es = append(es, e)
+		}
+		if len(es) < len(ids) {
+			log.Printf("кластер %d пропущен: сущности уже объединены или удалены\n", k+1)
+			skipped++
+			continue
+		}
+		m := dedup.Canonical(es)
+		c.indexer.Index(&m.Canonical)
+		err := merger.Merge(ctx, m)
+		if errors.Is(err, entity.ErrMerged) {
+			log.Printf("кластер %d пропущен: %v\n", k+1, err)
+			skipped++
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		merged++
+	}
+	log.Printf("объединено кластеров %d, пропущено %d\n", merged, skipped)
+	return nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/audetv/datasets-parser/app/repos/entity"
+	"github.com/audetv/datasets-parser/dataset/geodesy"
+	"github.com/google/uuid"
+	flag "github.com/spf13/pflag"
+	"io"
+	"text/tabwriter"
+)
+
+// distanceOptions флаги команды distance
+type distanceOptions struct {
+	sphere bool
+	out    io.Writer
+}
+
+// distanceCommand разобранные параметры команды distance
+type distanceCommand struct {
+	sites  [2]site
+	solver geodesy.Solver
+	method string
+	out    io.Writer
+}
+
+// site точка команды: координаты или сущность, координаты которой читаются из хранилища
+type site struct {
+	id     uuid.UUID
+	entity *entity.Entity
+	lat    float64
+	lon    float64
+}
+
+// parseSite разбирает идентификатор сущности или координаты «широта,долгота»
+func parseSite(s string) (site, error) {
+	if id, err := uuid.Parse(s); err == nil {
+		return site{id: id}, nil
+	}
+	lat, lon, err := entity.ParsePoint(s)
+	if err != nil {
+		return site{}, fmt.Errorf("%q is neither entity id nor lat,lon", s)
+	}
+	return site{lat: lat, lon: lon}, nil
+}
+
+// resolve читает из store координаты сущности
+func (s *site) resolve(ctx context.Context, store entity.ReadStore) error {
+	if s.id == uuid.Nil {
+		return nil
+	}
+	e, err := store.Get(ctx, s.id)
+	if err != nil {
+		return err
+	}
+	s.entity = &e
+	s.lat, s.lon = e.Latitude, e.Longitude
+	return nil
+}
+
+func (s site) String() string {
+	if s.entity != nil {
+		return fmt.Sprintf("%v (%v, %v) %.7f, %.7f", s.entity.Name, s.entity.Filename, s.entity.ID, s.lat, s.lon)
+	}
+	return fmt.Sprintf("%.7f, %.7f", s.lat, s.lon)
+}
+
+// flags регистрирует флаги команды distance
+func (o *distanceOptions) flags(fs *flag.FlagSet) {
+	fs.BoolVar(
+		&o.sphere,
+		"sphere",
+		false,
+		"считать на сфере среднего радиуса, быстрее, но с ошибкой до 0,5 %, по умолчанию — на эллипсоиде WGS 84",
+	)
+}
+
+// parse проверяет аргументы команды distance до подключения к базе данных
+func (o *distanceOptions) parse(args []string) (readCommand, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("distance expects 2 arguments: entity id or lat,lon, got %d", len(args))
+	}
+	cmd := &distanceCommand{solver: geodesy.WGS84, method: "эллипсоид WGS 84", out: o.out}
+	if o.sphere {
+		cmd.solver, cmd.method = geodesy.Earth, "сфера"
+	}
+	for i, arg := range args {
+		s, err := parseSite(arg)
+		if err != nil {
+			return nil, err
+		}
+		cmd.sites[i] = s
+	}
+	return cmd, nil
+}
+
+// needsStore сообщает, что среди точек есть сущности и нужна база данных
+func (c *distanceCommand) needsStore() bool {
+	return c.sites[0].id != uuid.Nil || c.sites[1].id != uuid.Nil
+}
+
+// run выводит расстояние, азимуты и середину линии между точками
+func (c *distanceCommand) run(ctx context.Context, store entity.ReadStore) error {
+	for i := range c.sites {
+		if err := c.sites[i].resolve(ctx, store); err != nil {
+			return err
+		}
+	}
+	a, b := c.sites[0], c.sites[1]
+	l := c.solver.Inverse(a.lat, a.lon, b.lat, b.lon)
+	mid := geodesy.Midpoint(c.solver, a.lat, a.lon, b.lat, b.lon)
+
+	tw := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "точка 1:\t%v\n", a)
+	fmt.Fprintf(tw, "точка 2:\t%v\n", b)
+	fmt.Fprintf(tw, "расстояние:\t%.3f м (%.3f км)\n", l.Distance, l.Distance/1000)
+	fmt.Fprintf(tw, "прямой азимут:\t%.6f°\n", geodesy.Azimuth360(l.Azimuth1))
+	fmt.Fprintf(tw, "азимут в точке 2:\t%.6f°\n", geodesy.Azimuth360(l.Azimuth2))
+	fmt.Fprintf(tw, "обратный азимут:\t%.6f°\n", geodesy.Azimuth360(l.BackAzimuth()))
+	fmt.Fprintf(tw, "середина:\t%.7f, %.7f\n", mid.Lat, mid.Lon)
+	fmt.Fprintf(tw, "модель:\t%v\n", c.method)
+	return tw.Flush()
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"github.com/audetv/datasets-parser/app/exporter"
+	"github.com/audetv/datasets-parser/app/repos/entity"
+	flag "github.com/spf13/pflag"
+	"log"
+)
+
+// exportOptions флаги команды export
+type exportOptions struct {
+	format string
+	output string
+	filter filterOptions
+}
+
+// filterOptions флаги отбора сущностей --filename, --bbox и --cell
+type filterOptions struct {
+	filenames []string
+	bbox      string
+	cell      string
+}
+
+// exportCommand разобранные параметры команды export
+type exportCommand struct {
+	format exporter.Format
+	output string
+	filter entity.Filter
+}
+
+// flags регистрирует флаги команды export
+func (o *exportOptions) flags(fs *flag.FlagSet) {
+	fs.StringVar(
+		&o.format,
+		"format",
+		string(exporter.FormatShapefile),
+		"формат выгрузки: geojson, geojsonseq, kml, csv, ndjson, shp или geomatrix — csv в формате geomatrix_marks",
+	)
+	fs.StringVarP(
+		&o.output,
+		"output",
+		"o",
+		"",
+		"файл выгрузки, «-» — стандартный вывод, для shp рядом создаются .shx, .dbf, .prj и .cpg",
+	)
+	o.filter.flags(fs)
+}
+
+// parse проверяет флаги команды export до подключения к базе данных
+func (o *exportOptions) parse(args []string) (readCommand, error) {
+	if err := noArgs("export", args); err != nil {
+		return nil, err
+	}
+	format, err := exporter.ParseFormat(o.format)
+	if err != nil {
+		return nil, err
+	}
+	filter, err := o.filter.parse()
+	if err != nil {
+		return nil, err
+	}
+	return &exportCommand{format: format, output: o.output, filter: filter}, nil
+}
+
+// flags регистрирует флаги отбора сущностей команд export, alignments и dedup
+func (o *filterOptions) flags(fs *flag.FlagSet) {
+	filenameFlag(fs, &o.filenames)
+	fs.StringVar(
+		&o.bbox,
+		"bbox",
+		"",
+		"только сущности в прямоугольнике «запад,юг,восток,север» в градусах, например «30,50,40,60»",
+	)
+	fs.StringVar(
+		&o.cell,
+		"cell",
+		"",
+		"только сущности в ячейке S2 любого уровня, заданной токеном («47a1») или числом",
+	)
+}
+
+// filenameFlag регистрирует флаг отбора сущностей по файлу набора данных
+func filenameFlag(fs *flag.FlagSet, filenames *[]string) {
+	fs.StringArrayVar(
+		filenames,
+		"filename",
+		nil,
+		"только сущности из файла набора данных с этим именем, флаг можно повторять",
+	)
+}
+
+// parse разбирает флаги отбора сущностей --filename, --bbox и --cell
+func (o *filterOptions) parse() (entity.Filter, error) {
+	filter := entity.Filter{Filenames: o.filenames}
+	if o.bbox != "" {
+		b, err := entity.ParseBBox(o.bbox)
+		if err != nil {
+			return filter, err
+		}
+		filter.BBox = &b
+	}
+	if o.cell != "" {
+		var err error
+		if filter.Cell, err = entity.ParseCell(o.cell); err != nil {
+			return filter, err
+		}
+	}
+	return filter, nil
+}
+
+// run выгружает сущности хранилища, подходящие под фильтр, в файл выгрузки
+func (c *exportCommand) run(ctx context.Context, store entity.ReadStore) error {
+	w, err := exporter.Create(c.format, c.output)
+	if err != nil {
+		return err
+	}
+	n, err := exporter.Export(ctx, store, c.filter, w)
+	if err != nil {
+		return err
+	}
+	log.Printf("выгружено записей %d в %v\n", n, c.output)
+	return nil
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"context"
+	"github.com/audetv/datasets-parser/app/geoindex"
+	"github.com/audetv/datasets-parser/app/repos/dataset"
+	"github.com/audetv/datasets-parser/app/repos/entity"
+	"github.com/audetv/datasets-parser/app/starter"
+	"github.com/audetv/datasets-parser/dataset/gpx"
+	"github.com/audetv/datasets-parser/dataset/input"
+	"github.com/audetv/datasets-parser/dataset/osm"
+	"github.com/audetv/datasets-parser/db/searchstore"
+	flag "github.com/spf13/pflag"
+	"log"
+	"os"
+)
+
+// importOptions флаги команды import
+type importOptions struct {
+	dataPath     string
+	mappingsPath string
+	encodings    []string
+	delimiters   []string
+	sheets       []string
+	mode         string
+	limits       starter.Limits
+	osmFilter    string
+	gpxTracks    string
+	storeName    string
+	search       searchstore.Config
+	index        indexOptions
+}
+
+// importCommand разобранные параметры команды import
+type importCommand struct {
+	dataPath  string
+	storeName string
+	search    searchstore.Config
+	config    starter.Config
+}
+
+// storePostgres хранилище сущностей по умолчанию
+const storePostgres = "postgres"
+
+// flags регистрирует флаги команды import
+func (o *importOptions) flags(fs *flag.FlagSet) {
+	fs.StringVarP(
+		&o.dataPath,
+		"data",
+		"d",
+		"./data/",
+		"путь до папки с файлами для обработки",
+	)
+	mappingsFlag(fs, &o.mappingsPath)
+	fs.StringArrayVar(
+		&o.encodings,
+		"encoding",
+		nil,
+		"кодировка файлов: шаблон=кодировка, например «*.csv=windows-1251» (utf-8, utf-16le, utf-16be, windows-1251, koi8-r, ibm866), по умолчанию определяется автоматически",
+	)
+	fs.StringArrayVar(
+		&o.delimiters,
+		"delimiter",
+		nil,
+		"разделитель полей файлов: шаблон=разделитель, например «plants.csv=comma» (comma, semicolon, tab, pipe или символ), по умолчанию определяется автоматически",
+	)
+	fs.StringArrayVar(
+		&o.sheets,
+		"sheet",
+		nil,
+		"листы книг Excel: шаблон=лист, например «храмы.xlsx=Лист1» или «*.xlsx=Храмы*», флаг можно повторять, по умолчанию читаются все видимые листы",
+	)
+	fs.StringVar(
+		&o.mode,
+		"mode",
+		string(starter.ModeLenient),
+		"режим обработки записей с ошибками: strict — прервать файл и откатить его записи на первой ошибке, lenient — пропускать записи с ошибками",
+	)
+	fs.IntVar(
+		&o.limits.MaxErrors,
+		"max-errors",
+		0,
+		"в режиме lenient: сколько записей файла можно отклонить, прежде чем файл будет прерван, 0 — без ограничения",
+	)
+	fs.Float64Var(
+		&o.limits.MaxErrorRate,
+		"max-error-rate",
+		0,
+		"в режиме lenient: допустимая доля отклонённых записей файла от 0 до 1, например 0.05, 0 — без ограничения",
+	)
+	fs.StringVar(
+		&o.osmFilter,
+		"osm-filter",
+		osm.DefaultFilter,
+		"отбор объектов OpenStreetMap по тегам: условия через запятую — «или», теги через & — «и», например «historic,amenity=place_of_worship&religion=christian,man_made=tower|lighthouse»",
+	)
+	fs.StringVar(
+		&o.gpxTracks,
+		"gpx-tracks",
+		string(gpx.ReduceNone),
+		"треки и маршруты GPX: none — не загружать, start, end — первая или последняя точка, centroid — центр точек",
+	)
+	fs.StringVar(
+		&o.storeName,
+		"store",
+		storePostgres,
+		"куда записывать сущности: postgres, manticore или elasticsearch — в поисковый индекс через HTTP API _bulk",
+	)
+	fs.StringVar(
+		&o.search.URL,
+		"search-url",
+		"",
+		"адрес HTTP API поискового движка, по умолчанию http://localhost:9308 для manticore и http://localhost:9200 для elasticsearch",
+	)
+	fs.StringVar(
+		&o.search.Index,
+		"search-index",
+		"entities",
+		"имя индекса поискового движка, создаётся, если его нет",
+	)
+	fs.IntVar(
+		&o.search.BatchSize,
+		"search-batch",
+		500,
+		"сколько документов отправлять в поисковый движок одним запросом _bulk",
+	)
+	o.index.flags(fs)
+}
+
+// parse проверяет флаги команды import и регистрирует читателей наборов данных
+func (o *importOptions) parse(args []string) (*importCommand, error) {
+	if err := noArgs("import", args); err != nil {
+		return nil, err
+	}
+	mode, err := starter.ParseMode(o.mode)
+	if err != nil {
+		return nil, err
+	}
+	if err = o.limits.Validate(); err != nil {
+		return nil, err
+	}
+	if err = o.index.parse(); err != nil {
+		return nil, err
+	}
+	overrides, err := input.ParseOverrides(o.encodings, o.delimiters, o.sheets)
+	if err != nil {
+		return nil, err
+	}
+	cmd := &importCommand{
+		dataPath:  o.dataPath,
+		storeName: o.storeName,
+		search:    o.search,
+		config:    starter.Config{Overrides: overrides, Mode: mode, Limits: o.limits, Index: o.index.indexer},
+	}
+	if cmd.storeName != storePostgres {
+		if cmd.search.Engine, err = searchstore.ParseEngine(cmd.storeName); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = registerMappings(o.mappingsPath); err != nil {
+		return nil, err
+	}
+	filter, err := osm.ParseFilter(o.osmFilter)
+	if err != nil {
+		return nil, err
+	}
+	if err = dataset.DefaultRegistry.Replace(osm.NewReader(filter)); err != nil {
+		return nil, err
+	}
+	reduce, err := gpx.ParseReduce(o.gpxTracks)
+	if err != nil {
+		return nil, err
+	}
+	if err = dataset.DefaultRegistry.Replace(gpx.NewReader(reduce)); err != nil {
+		return nil, err
+	}
+	return cmd, nil
+}
+
+// run записывает сущности файлов в базу данных или поисковый индекс
+func (c *importCommand) run(ctx context.Context) {
+	if c.storeName != storePostgres {
+		log.Printf("подготовка поискового индекса %v\n", c.search.Index)
+		searchStore, err := searchstore.NewEntities(ctx, c.search)
+		if err != nil {
+			log.Fatal(err)
+		}
+		process(ctx, searchStore, c.dataPath, c.config)
+		return
+	}
+	store := openEntities()
+	log.Println("обновление схемы базы данных")
+	if err := store.Migrate(ctx, c.config.Index); err != nil {
+		log.Fatal(err)
+	}
+	process(ctx, store, c.dataPath, c.config)
+}
+
+// indexOptions флаги вычисляемых полей сущностей
+type indexOptions struct {
+	indexer   geoindex.Indexer
+	s2Levels  string
+	tileZooms string
+}
+
+// flags регистрирует флаги вычисляемых полей сущностей
+func (o *indexOptions) flags(fs *flag.FlagSet) {
+	fs.IntVar(
+		&o.indexer.GeohashPrecision,
+		"geohash-precision",
+		geoindex.DefaultGeohashPrecision,
+		"длина geohash сущностей, от 1 до 12 символов",
+	)
+	fs.IntVar(
+		&o.indexer.CellPrecision,
+		"geohash-cell",
+		geoindex.DefaultGeohashCellPrecision,
+		"длина ячейки geohash, для которой сохраняются 8 соседних ячеек, как gh4 в geomatrix_marks",
+	)
+	fs.StringVar(
+		&o.s2Levels,
+		"s2-levels",
+		geoindex.FormatLevels(geoindex.DefaultS2Levels),
+		"уровни родительских ячеек S2 сущностей через запятую, от 0 до 30, none — не вычислять",
+	)
+	fs.StringVar(
+		&o.tileZooms,
+		"tile-zooms",
+		geoindex.FormatLevels(geoindex.DefaultTileZooms),
+		"масштабы тайлов карты z/x/y и quadkey сущностей через запятую, от 0 до 23, none — не вычислять",
+	)
+}
+
+// parse разбирает уровни S2 и масштабы тайлов и проверяет индексацию
+func (o *indexOptions) parse() error {
+	var err error
+	if o.indexer.S2Levels, err = geoindex.ParseLevels(o.s2Levels); err != nil {
+		return err
+	}
+	if o.indexer.TileZooms, err = geoindex.ParseLevels(o.tileZooms); err != nil {
+		return err
+	}
+	return o.indexer.Validate()
+}
+
+// process записывает в store сущности файлов папки dataPath и печатает итоги,
+// если файлы прерваны, программа завершается с кодом 1
+func process(ctx context.Context, store entity.Store, dataPath string, config starter.Config) {
+	app := starter.NewApp(store, dataset.DefaultRegistry, config)
+	summary := app.Process(ctx, dataPath)
+	printSummary(os.Stdout, summary)
+
+	log.Println("Done!")
+	if len(summary.Aborted()) > 0 {
+		os.Exit(1)
+	}
+}
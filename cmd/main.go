@@ -2,43 +2,123 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"github.com/audetv/datasets-parser/app/repos/dataset"
 	"github.com/audetv/datasets-parser/app/repos/entity"
-	"github.com/audetv/datasets-parser/app/starter"
+	_ "github.com/audetv/datasets-parser/dataset/kml"
 	"github.com/audetv/datasets-parser/db/entitystore"
 	flag "github.com/spf13/pflag"
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 )
 
+// commands команды программы, без команды выполняется import
+const commands = "import, export, distance, near, corridor, ring, antipode, sector, alignments, dedup, list-datasets"
+
 func main() {
 	ctx, _ := signal.NotifyContext(context.Background(), os.Interrupt)
 
-	var dataPath string
+	// у каждой команды свои флаги, флаги других команд — ошибка
+	name, args := "import", os.Args[1:]
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+
+	switch name {
+	case "import":
+		var o importOptions
+		o.flags(fs)
+		fs.Parse(args)
+		cmd, err := o.parse(fs.Args())
+		if err != nil {
+			log.Fatal(err)
+		}
+		cmd.run(ctx)
+		return
+	case "list-datasets":
+		var mappingsPath string
+		mappingsFlag(fs, &mappingsPath)
+		fs.Parse(args)
+		if err := noArgs(name, fs.Args()); err != nil {
+			log.Fatal(err)
+		}
+		if err := registerMappings(mappingsPath); err != nil {
+			log.Fatal(err)
+		}
+		listDatasets(os.Stdout, dataset.DefaultRegistry)
+		return
+	}
+
+	// команды чтения проверяют флаги до подключения к базе данных
+	var opts commandOptions
+	switch name {
+	case "export":
+		opts = &exportOptions{}
+	case "distance":
+		opts = &distanceOptions{out: os.Stdout}
+	case "near":
+		opts = &nearOptions{}
+	case "corridor", "ring", "antipode", "sector":
+		opts = &queryOptions{name: name}
+	case "alignments":
+		opts = &alignmentOptions{}
+	case "dedup":
+		opts = &dedupOptions{}
+	default:
+		log.Fatalf("неизвестная команда %q, команды: %v", name, commands)
+	}
+	opts.flags(fs)
+	fs.Parse(args)
+
+	readCmd, err := opts.parse(fs.Args())
+	if err != nil {
+		log.Fatal(err)
+	}
+	if distanceCmd, ok := readCmd.(*distanceCommand); ok && !distanceCmd.needsStore() {
+		if err = distanceCmd.run(ctx, nil); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if err = readCmd.run(ctx, openEntities()); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// commandOptions флаги команды, которая читает сущности из базы данных
+type commandOptions interface {
+	// flags регистрирует флаги команды
+	flags(fs *flag.FlagSet)
+	// parse проверяет аргументы и разобранные флаги до подключения к базе данных
+	parse(args []string) (readCommand, error)
+}
 
-	flag.StringVarP(
-		&dataPath,
-		"data",
-		"d",
-		"./data/",
-		"путь до папки с файлами для обработки",
-	)
-	flag.Parse()
+// readCommand команда, которая читает сущности из базы данных
+type readCommand interface {
+	run(ctx context.Context, store entity.ReadStore) error
+}
 
+// noArgs проверяет, что у команды нет аргументов кроме флагов
+func noArgs(command string, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("%v takes no arguments, got %q", command, args)
+	}
+	return nil
+}
+
+// openEntities подключается к базе данных сущностей
+func openEntities() *entitystore.Entities {
 	dsn := "host=localhost user=app password=secret dbname=geomatrix port=54325 sslmode=disable TimeZone=Europe/Moscow"
 	log.Println("подготовка соединения с базой данных")
 
-	var entityStore entity.Store
 	dbEntityStore, err := entitystore.NewEntities(dsn)
 	if err != nil {
 		log.Fatal(err)
 	}
 
 	log.Println("успешно завершено")
-	entityStore = dbEntityStore
-
-	app := starter.NewApp(entityStore)
-	app.Process(ctx, dataPath)
-
-	log.Println("Done!")
+	return dbEntityStore
 }
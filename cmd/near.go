@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/audetv/datasets-parser/app/exporter"
+	"github.com/audetv/datasets-parser/app/repos/entity"
+	flag "github.com/spf13/pflag"
+	"io"
+	"os"
+	"text/tabwriter"
+)
+
+// nearOptions флаги команды near
+type nearOptions struct {
+	k      int
+	radius float64
+	result resultOptions
+}
+
+// resultOptions флаги вывода найденных сущностей команд near, corridor, ring, antipode и sector
+type resultOptions struct {
+	format    string
+	output    string
+	filenames []string
+}
+
+// formatTable формат вывода команды near по умолчанию — таблица
+const formatTable = "table"
+
+// nearCommand разобранные параметры команды near
+type nearCommand struct {
+	site   site
+	query  entity.NearQuery
+	format exporter.Format
+	output string
+}
+
+// flags регистрирует флаги команды near
+func (o *nearOptions) flags(fs *flag.FlagSet) {
+	fs.IntVarP(
+		&o.k,
+		"k",
+		"k",
+		10,
+		"сколько ближайших сущностей вывести",
+	)
+	fs.Float64Var(
+		&o.radius,
+		"radius",
+		0,
+		"искать сущности не дальше этого расстояния в метрах, 0 — без ограничения",
+	)
+	o.result.flags(fs)
+}
+
+// flags регистрирует флаги вывода найденных сущностей
+func (o *resultOptions) flags(fs *flag.FlagSet) {
+	fs.StringVar(
+		&o.format,
+		"format",
+		formatTable,
+		"формат вывода: table, csv или geojson",
+	)
+	fs.StringVarP(
+		&o.output,
+		"output",
+		"o",
+		"",
+		"файл вывода, по умолчанию стандартный вывод",
+	)
+	filenameFlag(fs, &o.filenames)
+}
+
+// parse проверяет аргументы и флаги команды near до подключения к базе данных
+func (o *nearOptions) parse(args []string) (readCommand, error) {
+	if len(args) != 1 {
+		return nil, fmt.Errorf("near expects 1 argument: entity id or lat,lon, got %d", len(args))
+	}
+	s, err := parseSite(args[0])
+	if err != nil {
+		return nil, err
+	}
+	cmd := &nearCommand{
+		site:   s,
+		query:  entity.NearQuery{Lat: s.lat, Lon: s.lon, K: o.k, MaxDistance: o.radius, Filenames: o.result.filenames, Exclude: s.id},
+		output: o.result.output,
+	}
+	if err = cmd.query.Validate(); err != nil {
+		return nil, err
+	}
+	if cmd.format, err = parseResultFormat("near", o.result.format); err != nil {
+		return nil, err
+	}
+	return cmd, nil
+}
+
+// parseResultFormat разбирает формат вывода найденных сущностей: table, csv или geojson
+func parseResultFormat(command string, format string) (exporter.Format, error) {
+	if format == "" || format == formatTable {
+		return formatTable, nil
+	}
+	f, err := exporter.ParseFormat(format)
+	if err != nil {
+		return "", err
+	}
+	if f != exporter.FormatGeoJSON && f != exporter.FormatCSV {
+		return "", fmt.Errorf("%v: unsupported format %q, expected table, csv or geojson", command, format)
+	}
+	return f, nil
+}
+
+// run находит ближайшие сущности и выводит их в файл или стандартный вывод
+func (c *nearCommand) run(ctx context.Context, store entity.ReadStore) error {
+	if err := c.site.resolve(ctx, store); err != nil {
+		return err
+	}
+	c.query.Lat, c.query.Lon = c.site.lat, c.site.lon
+	neighbours, err := store.Near(ctx, c.query)
+	if err != nil {
+		return err
+	}
+	return writeResults(c.output, c.format, fmt.Sprintf("ближайшие к %v", c.site), neighbours)
+}
+
+// writeResults выводит найденные сущности в файл output или стандартный вывод,
+// таблица начинается строкой title с количеством сущностей
+func writeResults(output string, format exporter.Format, title string, neighbours []entity.Neighbour) error {
+	if output == "" {
+		output = exporter.Stdout
+	}
+	return writeFile(output, func(w io.Writer) error {
+		if format == formatTable {
+			printNeighbours(w, title, neighbours)
+			return nil
+		}
+		return exporter.WriteNeighbours(w, format, neighbours)
+	})
+}
+
+// printNeighbours выводит найденные сущности таблицей
+func printNeighbours(w io.Writer, title string, neighbours []entity.Neighbour) {
+	fmt.Fprintf(w, "%v: %d\n", title, len(neighbours))
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "#\tDISTANCE, KM\tAZIMUTH\tNAME\tFILE\tID")
+	for i, n := range neighbours {
+		fmt.Fprintf(tw, "%d\t%.3f\t%.2f°\t%v\t%v\t%v\n", i+1, n.Distance/1000, n.Azimuth, n.Name, n.Filename, n.ID)
+	}
+	tw.Flush()
+}
+
+// writeFile вызывает write для файла output или стандартного вывода
+func writeFile(output string, write func(w io.Writer) error) error {
+	if output == exporter.Stdout {
+		return write(os.Stdout)
+	}
+	f, err := os.Create(output)
+	if err != nil {
+		return err
+	}
+	err = write(f)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
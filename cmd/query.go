@@ -0,0 +1,199 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/audetv/datasets-parser/app/exporter"
+	"github.com/audetv/datasets-parser/app/geoquery"
+	"github.com/audetv/datasets-parser/app/repos/entity"
+	"github.com/google/uuid"
+	flag "github.com/spf13/pflag"
+	"strconv"
+	"strings"
+)
+
+// queryOptions флаги команд corridor, ring, antipode и sector
+type queryOptions struct {
+	name      string
+	width     float64
+	distance  float64
+	tolerance float64
+	azimuth   string
+	radius    float64
+	result    resultOptions
+}
+
+// queryCommand разобранные параметры команд corridor, ring, antipode и sector
+type queryCommand struct {
+	name   string
+	sites  []site
+	build  func(sites []site) geoquery.Query
+	filter entity.Filter
+	format exporter.Format
+	output string
+}
+
+// flags регистрирует флаги запроса o.name, у каждого запроса только свои флаги области
+func (o *queryOptions) flags(fs *flag.FlagSet) {
+	switch o.name {
+	case "corridor":
+		fs.Float64Var(
+			&o.width,
+			"width",
+			0,
+			"наибольшее расстояние от большого круга в метрах",
+		)
+	case "ring":
+		fs.Float64Var(
+			&o.distance,
+			"distance",
+			0,
+			"радиус кольца в метрах",
+		)
+		fs.Float64Var(
+			&o.tolerance,
+			"tolerance",
+			0,
+			"допуск радиуса кольца в метрах, кольцо от distance - tolerance до distance + tolerance",
+		)
+	case "antipode":
+		fs.Float64Var(
+			&o.radius,
+			"radius",
+			0,
+			"радиус круга вокруг антипода в метрах",
+		)
+	case "sector":
+		fs.StringVar(
+			&o.azimuth,
+			"azimuth",
+			"",
+			"азимуты границ сектора «от,до» в градусах по часовой стрелке, например «350,10»",
+		)
+		fs.Float64Var(
+			&o.radius,
+			"radius",
+			0,
+			"дальность сектора в метрах, 0 — до антипода",
+		)
+	}
+	o.result.flags(fs)
+}
+
+// parse проверяет аргументы и флаги запроса до подключения к базе данных
+func (o *queryOptions) parse(args []string) (readCommand, error) {
+	name := o.name
+	cmd := &queryCommand{name: name, output: o.result.output, filter: entity.Filter{Filenames: o.result.filenames}}
+	sites := 1
+	switch name {
+	case "corridor":
+		sites = 2
+		cmd.build = func(s []site) geoquery.Query {
+			return geoquery.Corridor{Lat1: s[0].lat, Lon1: s[0].lon, Lat2: s[1].lat, Lon2: s[1].lon, Width: o.width}
+		}
+	case "ring":
+		cmd.build = func(s []site) geoquery.Query {
+			return geoquery.Ring{Lat: s[0].lat, Lon: s[0].lon, Distance: o.distance, Tolerance: o.tolerance}
+		}
+	case "antipode":
+		cmd.build = func(s []site) geoquery.Query {
+			return geoquery.Antipode{Lat: s[0].lat, Lon: s[0].lon, Radius: o.radius}
+		}
+	case "sector":
+		from, to, err := parseAzimuths(o.azimuth)
+		if err != nil {
+			return nil, err
+		}
+		cmd.build = func(s []site) geoquery.Query {
+			return geoquery.Sector{Lat: s[0].lat, Lon: s[0].lon, From: from, To: to, Radius: o.radius}
+		}
+	default:
+		return nil, fmt.Errorf("unknown query %q", name)
+	}
+
+	if len(args) != sites {
+		return nil, fmt.Errorf("%v expects %d argument(s): entity id or lat,lon, got %d", name, sites, len(args))
+	}
+	for _, arg := range args {
+		s, err := parseSite(arg)
+		if err != nil {
+			return nil, err
+		}
+		cmd.sites = append(cmd.sites, s)
+	}
+	// координаты сущностей известны только после чтения базы, тогда запрос проверяется в geoquery.Search
+	if cmd.resolved() {
+		if err := cmd.build(cmd.sites).Validate(); err != nil {
+			return nil, err
+		}
+	}
+	var err error
+	if cmd.format, err = parseResultFormat(name, o.result.format); err != nil {
+		return nil, err
+	}
+	return cmd, nil
+}
+
+// parseAzimuths разбирает азимуты сектора «от,до» в градусах
+func parseAzimuths(s string) (float64, float64, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("azimuth %q must be from,to", s)
+	}
+	var v [2]float64
+	for i, p := range parts {
+		f, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			return 0, 0, fmt.Errorf("azimuth %q: %w", s, err)
+		}
+		v[i] = f
+	}
+	return v[0], v[1], nil
+}
+
+// resolved сообщает, что координаты всех точек известны без базы данных
+func (c *queryCommand) resolved() bool {
+	for _, s := range c.sites {
+		if s.id != uuid.Nil {
+			return false
+		}
+	}
+	return true
+}
+
+// run выбирает сущности в области запроса, кроме сущностей, заданных точками
+func (c *queryCommand) run(ctx context.Context, store entity.ReadStore) error {
+	for i := range c.sites {
+		if err := c.sites[i].resolve(ctx, store); err != nil {
+			return err
+		}
+	}
+	found, err := geoquery.Search(ctx, store, c.build(c.sites), c.filter)
+	if err != nil {
+		return err
+	}
+	result := found[:0]
+	for _, n := range found {
+		if !c.isSite(n.ID) {
+			result = append(result, n)
+		}
+	}
+	return writeResults(c.output, c.format, fmt.Sprintf("%v %v", c.name, c.sitesString()), result)
+}
+
+func (c *queryCommand) isSite(id uuid.UUID) bool {
+	for _, s := range c.sites {
+		if s.id == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *queryCommand) sitesString() string {
+	names := make([]string, len(c.sites))
+	for i, s := range c.sites {
+		names[i] = s.String()
+	}
+	return strings.Join(names, " — ")
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"fmt"
+	"github.com/audetv/datasets-parser/app/starter"
+	"io"
+	"text/tabwriter"
+)
+
+// printSummary выводит итоги обработки файлов и причины, по которым файлы прерваны
+func printSummary(w io.Writer, summary *starter.Summary) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "FILE\tSTATUS\tINSERTED\tREJECTED")
+	for _, r := range summary.Results {
+		fmt.Fprintf(tw, "%v\t%v\t%d\t%d\n", r.Filename, r.Status, r.Inserted, r.Rejected)
+	}
+	tw.Flush()
+
+	aborted := summary.Aborted()
+	if len(aborted) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\nпрервано файлов: %d\n", len(aborted))
+	for _, r := range aborted {
+		fmt.Fprintf(w, "- %v: %v\n", r.Filename, r.Reason)
+		if !r.RolledBack {
+			fmt.Fprintf(w, "  записано %d записей, хранилище не поддерживает откат\n", r.Inserted)
+		}
+	}
+}
@@ -0,0 +1,221 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/bzip2"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"github.com/audetv/datasets-parser/app/repos/dataset"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ErrUnsupported архив формата, который не умеем читать
+var ErrUnsupported = errors.New("archive format not supported")
+
+type kind int
+
+const (
+	none kind = iota
+	zipKind
+	tarKind
+	tarGzKind
+	tarBz2Kind
+	gzKind
+	bz2Kind
+	sevenZipKind
+)
+
+func kindOf(filename string) kind {
+	name := strings.ToLower(filename)
+	switch {
+	case strings.HasSuffix(name, ".zip"):
+		return zipKind
+	case strings.HasSuffix(name, ".tar"):
+		return tarKind
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return tarGzKind
+	case strings.HasSuffix(name, ".tar.bz2"), strings.HasSuffix(name, ".tbz2"):
+		return tarBz2Kind
+	case strings.HasSuffix(name, ".gz"):
+		return gzKind
+	case strings.HasSuffix(name, ".bz2"):
+		return bz2Kind
+	case strings.HasSuffix(name, ".7z"):
+		return sevenZipKind
+	}
+	return none
+}
+
+// IsArchive сообщает, является ли файл архивом по его расширению
+func IsArchive(filename string) bool {
+	return kindOf(filename) != none
+}
+
+// Sources возвращает файлы, содержащиеся в архиве. Имя каждого файла —
+// имя архива и путь внутри него: «archive.zip/dir/file.csv».
+// Файлы не распаковываются на диск, а читаются из архива потоком.
+func Sources(name string, filePath string) ([]dataset.Source, error) {
+	switch kindOf(name) {
+	case zipKind:
+		return zipSources(name, filePath)
+	case tarKind, tarGzKind, tarBz2Kind:
+		return tarSources(name, filePath)
+	case gzKind, bz2Kind:
+		member := name[:len(name)-len(filepath.Ext(name))]
+		return []dataset.Source{{
+			Name: path.Join(name, path.Base(filepath.ToSlash(member))),
+			Open: func() (io.ReadCloser, error) {
+				return openCompressed(filePath)
+			},
+		}}, nil
+	case sevenZipKind:
+		return nil, fmt.Errorf("%v: 7z %w, extract the archive before processing", name, ErrUnsupported)
+	}
+	return nil, fmt.Errorf("%v: %w", name, ErrUnsupported)
+}
+
+func zipSources(name string, filePath string) ([]dataset.Source, error) {
+	zr, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", name, err)
+	}
+	defer zr.Close()
+
+	var sources []dataset.Source
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		member := f.Name
+		sources = append(sources, dataset.Source{
+			Name: path.Join(name, member),
+			Open: func() (io.ReadCloser, error) {
+				return openZipMember(filePath, member)
+			},
+			Sibling: func(name string) (io.ReadCloser, error) {
+				return openZipMember(filePath, path.Join(path.Dir(member), name))
+			},
+		})
+	}
+	return sources, nil
+}
+
+func openZipMember(filePath string, member string) (io.ReadCloser, error) {
+	zr, err := zip.OpenReader(filePath)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range zr.File {
+		if f.Name != member {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			zr.Close()
+			return nil, err
+		}
+		return &readCloser{Reader: rc, closers: []io.Closer{rc, zr}}, nil
+	}
+	zr.Close()
+	return nil, fmt.Errorf("%v: %w", member, os.ErrNotExist)
+}
+
+func tarSources(name string, filePath string) ([]dataset.Source, error) {
+	rc, err := openCompressed(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", name, err)
+	}
+	defer rc.Close()
+
+	var sources []dataset.Source
+	tr := tar.NewReader(rc)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", name, err)
+		}
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+		member := h.Name
+		sources = append(sources, dataset.Source{
+			Name: path.Join(name, member),
+			Open: func() (io.ReadCloser, error) {
+				return openTarMember(filePath, member)
+			},
+			Sibling: func(name string) (io.ReadCloser, error) {
+				return openTarMember(filePath, path.Join(path.Dir(member), name))
+			},
+		})
+	}
+	return sources, nil
+}
+
+func openTarMember(filePath string, member string) (io.ReadCloser, error) {
+	rc, err := openCompressed(filePath)
+	if err != nil {
+		return nil, err
+	}
+	tr := tar.NewReader(rc)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			rc.Close()
+			return nil, err
+		}
+		if h.Name == member {
+			return &readCloser{Reader: tr, closers: []io.Closer{rc}}, nil
+		}
+	}
+	rc.Close()
+	return nil, fmt.Errorf("%v: %w", member, os.ErrNotExist)
+}
+
+// openCompressed открывает файл и, если он сжат gzip или bzip2, распаковывает его на лету
+func openCompressed(filePath string) (io.ReadCloser, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	switch kindOf(filePath) {
+	case tarGzKind, gzKind:
+		gr, err := gzip.NewReader(f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		return &readCloser{Reader: gr, closers: []io.Closer{gr, f}}, nil
+	case tarBz2Kind, bz2Kind:
+		return &readCloser{Reader: bzip2.NewReader(f), closers: []io.Closer{f}}, nil
+	}
+	return f, nil
+}
+
+// readCloser читает из Reader и при закрытии закрывает все closers по порядку
+type readCloser struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (rc *readCloser) Close() error {
+	var err error
+	for _, c := range rc.closers {
+		if cerr := c.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
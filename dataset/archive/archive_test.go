@@ -0,0 +1,157 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// readSources читает все файлы архива, каждый дважды: файл открывается заново при каждом чтении
+func readSources(t *testing.T, name string, filePath string) map[string]string {
+	sources, err := Sources(name, filePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[string]string)
+	for _, src := range sources {
+		for i := 0; i < 2; i++ {
+			rc, err := src.Open()
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := io.ReadAll(rc)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err = rc.Close(); err != nil {
+				t.Fatal(err)
+			}
+			files[src.Name] = string(b)
+		}
+	}
+	return files
+}
+
+func writeZip(t *testing.T, path string) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	if _, err := zw.Create("csv/"); err != nil {
+		t.Fatal(err)
+	}
+	for name, data := range map[string]string{"csv/places.csv": "name;lat;lon\n", "readme.txt": "fixture\n"} {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		io.WriteString(w, data)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeTar(t *testing.T, path string, compress bool) {
+	var buf bytes.Buffer
+	var w io.Writer = &buf
+	var gw *gzip.Writer
+	if compress {
+		gw = gzip.NewWriter(&buf)
+		w = gw
+	}
+	tw := tar.NewWriter(w)
+	tw.WriteHeader(&tar.Header{Name: "csv/", Typeflag: tar.TypeDir, Mode: 0o755})
+	for _, f := range []struct{ name, data string }{{"csv/places.csv", "name;lat;lon\n"}, {"readme.txt", "fixture\n"}} {
+		tw.WriteHeader(&tar.Header{Name: f.name, Typeflag: tar.TypeReg, Mode: 0o644, Size: int64(len(f.data))})
+		io.WriteString(tw, f.data)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if gw != nil {
+		if err := gw.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func writeGzip(t *testing.T, path string, data string) {
+	var buf bytes.Buffer
+	gw := gzip.NewWriter(&buf)
+	io.WriteString(gw, data)
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSources(t *testing.T) {
+	dir := t.TempDir()
+	writeZip(t, filepath.Join(dir, "data.zip"))
+	writeTar(t, filepath.Join(dir, "data.tar"), false)
+	writeTar(t, filepath.Join(dir, "data.TGZ"), true)
+	writeGzip(t, filepath.Join(dir, "places.csv.gz"), "name;lat;lon\n")
+
+	members := map[string]string{"csv/places.csv": "name;lat;lon\n", "readme.txt": "fixture\n"}
+	prefixed := func(archive string, files map[string]string) map[string]string {
+		m := make(map[string]string)
+		for name, data := range files {
+			m[archive+"/"+name] = data
+		}
+		return m
+	}
+	tests := []struct {
+		name string
+		path string
+		want map[string]string
+	}{
+		// каталоги архива не становятся файлами
+		{"data.zip", filepath.Join(dir, "data.zip"), prefixed("data.zip", members)},
+		{"data.tar", filepath.Join(dir, "data.tar"), prefixed("data.tar", members)},
+		// расширение определяется без учёта регистра
+		{"data.TGZ", filepath.Join(dir, "data.TGZ"), prefixed("data.TGZ", members)},
+		{"places.csv.gz", filepath.Join(dir, "places.csv.gz"), map[string]string{"places.csv.gz/places.csv": "name;lat;lon\n"}},
+		{"places.csv.bz2", "testdata/places.csv.bz2", map[string]string{"places.csv.bz2/places.csv": "name;lat;lon\nМосква;55.75;37.62\n"}},
+		{"places.tar.bz2", "testdata/places.tar.bz2", map[string]string{
+			"places.tar.bz2/csv/places.csv": "name;lat;lon\nТверь;56.86;35.9\n", "places.tar.bz2/readme.txt": "fixture\n"}},
+	}
+	for _, tt := range tests {
+		if !IsArchive(tt.name) {
+			t.Errorf("%v is not an archive", tt.name)
+		}
+		if got := readSources(t, tt.name, tt.path); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%v: files %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSourcesUnsupported(t *testing.T) {
+	for _, name := range []string{"data.7z", "data.rar"} {
+		if _, err := Sources(name, name); !errors.Is(err, ErrUnsupported) {
+			t.Errorf("%v: error %v, want %v", name, err, ErrUnsupported)
+		}
+	}
+	if IsArchive("data.csv") || IsArchive("data.rar") {
+		t.Error("csv or rar is an archive")
+	}
+	// повреждённый архив — ошибка, а не пустой список файлов
+	path := filepath.Join(t.TempDir(), "broken.zip")
+	os.WriteFile(path, []byte("not a zip"), 0o644)
+	if _, err := Sources("broken.zip", path); err == nil {
+		t.Error("broken zip is read")
+	}
+}
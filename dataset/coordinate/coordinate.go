@@ -0,0 +1,285 @@
+package coordinate
+
+import (
+	"errors"
+	"fmt"
+	"github.com/golang/geo/r3"
+	"github.com/golang/geo/s2"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Ошибки разбора и проверки координат
+var (
+	ErrEmpty      = errors.New("empty value")
+	ErrSyntax     = errors.New("invalid coordinate")
+	ErrHemisphere = errors.New("invalid hemisphere")
+	ErrRange      = errors.New("coordinate out of range")
+	ErrSwapped    = errors.New("latitude and longitude are likely swapped")
+	ErrNullIsland = errors.New("null island: point at 0, 0")
+)
+
+// Axis ось координаты: широта или долгота
+type Axis int
+
+const (
+	Latitude Axis = iota
+	Longitude
+)
+
+func (a Axis) String() string {
+	if a == Latitude {
+		return "latitude"
+	}
+	return "longitude"
+}
+
+// Limit максимальное по модулю значение координаты в градусах
+func (a Axis) Limit() float64 {
+	if a == Latitude {
+		return 90
+	}
+	return 180
+}
+
+// hemispheres буквы полушарий на латинице и кириллице и знак координаты
+var hemispheres = map[rune]struct {
+	axis Axis
+	sign float64
+}{
+	'N': {Latitude, 1}, 'S': {Latitude, -1}, 'E': {Longitude, 1}, 'W': {Longitude, -1},
+	'С': {Latitude, 1}, 'Ю': {Latitude, -1}, 'В': {Longitude, 1}, 'З': {Longitude, -1},
+}
+
+// Знаки градусов, минут и секунд, которые заменяются пробелами
+var dmsReplacer = strings.NewReplacer(
+	"°", " ", "º", " ", "˚", " ",
+	"′", " ", "'", " ", "’", " ", "‘", " ",
+	"″", " ", "\"", " ", "”", " ", "“", " ",
+)
+
+// Parse разбирает координату оси axis в градусах. Принимает:
+//   - десятичные градусы со знаком: «-33.8688», «+151,2093» (запятая как десятичный разделитель);
+//   - градусы, минуты и секунды: «55°45′21″N», «55 45 21.5», «55°45.35'»;
+//   - букву полушария до или после значения: N, S, E, W или С, Ю, В, З.
+//
+// Диапазон не проверяется, для этого есть Validate, ParseLatitude и ParseLongitude.
+func Parse(s string, axis Axis) (float64, error) {
+	text := strings.TrimSpace(s)
+	if text == "" {
+		return 0, ErrEmpty
+	}
+
+	sign := 1.0
+	text, hemisphere, err := cutHemisphere(text, axis)
+	if err != nil {
+		return 0, err
+	}
+	if hemisphere != 0 {
+		sign = hemisphere
+	}
+
+	switch {
+	case strings.HasPrefix(text, "-"), strings.HasPrefix(text, "−"):
+		if hemisphere != 0 {
+			return 0, fmt.Errorf("%w: %q has both sign and hemisphere", ErrSyntax, s)
+		}
+		sign = -1
+		_, n := utf8.DecodeRuneInString(text)
+		text = text[n:]
+	case strings.HasPrefix(text, "+"):
+		text = text[1:]
+	}
+
+	parts := strings.Fields(dmsReplacer.Replace(text))
+	if len(parts) == 0 || len(parts) > 3 {
+		return 0, fmt.Errorf("%w: %q", ErrSyntax, s)
+	}
+
+	var value float64
+	for i, part := range parts {
+		v, err := parseDecimal(part)
+		if err != nil {
+			return 0, fmt.Errorf("%w: %q", ErrSyntax, s)
+		}
+		// минуты и секунды меньше 60, дробными могут быть только последние
+		if i > 0 && v >= 60 {
+			return 0, fmt.Errorf("%w: %q has minutes or seconds not less than 60", ErrSyntax, s)
+		}
+		if i < len(parts)-1 && strings.ContainsAny(part, ".,") {
+			return 0, fmt.Errorf("%w: %q", ErrSyntax, s)
+		}
+		switch i {
+		case 0:
+			value = v
+		case 1:
+			value += v / 60
+		case 2:
+			value += v / 3600
+		}
+	}
+
+	return sign * value, nil
+}
+
+// ParseLatitude разбирает широту и проверяет, что она в пределах ±90°
+func ParseLatitude(s string) (float64, error) {
+	return parseAxis(s, Latitude)
+}
+
+// ParseLongitude разбирает долготу и проверяет, что она в пределах ±180°
+func ParseLongitude(s string) (float64, error) {
+	return parseAxis(s, Longitude)
+}
+
+func parseAxis(s string, axis Axis) (float64, error) {
+	v, err := Parse(s, axis)
+	if err != nil {
+		return 0, err
+	}
+	if axis == Longitude {
+		v = NormalizeLongitude(v)
+	}
+	if err = checkRange(v, axis); err != nil {
+		return 0, err
+	}
+	return v, nil
+}
+
+// ParseDecimal разбирает десятичное число, допуская запятую как десятичный разделитель
+func ParseDecimal(s string) (float64, error) {
+	text := strings.TrimSpace(s)
+	if text == "" {
+		return 0, ErrEmpty
+	}
+	sign := 1.0
+	switch {
+	case strings.HasPrefix(text, "-"), strings.HasPrefix(text, "−"):
+		sign = -1
+		_, n := utf8.DecodeRuneInString(text)
+		text = text[n:]
+	case strings.HasPrefix(text, "+"):
+		text = text[1:]
+	}
+	v, err := parseDecimal(text)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q is not a number", ErrSyntax, s)
+	}
+	return sign * v, nil
+}
+
+// NormalizeLongitude приводит долготу от 180° до 360° к западной долготе от -180° до 0°,
+// так записывают долготу некоторые выгрузки из kml. Остальные значения не меняются.
+func NormalizeLongitude(lon float64) float64 {
+	if lon > 180 && lon <= 360 {
+		return lon - 360
+	}
+	return lon
+}
+
+// Validate проверяет, что координаты в допустимых пределах
+func Validate(lat float64, lon float64) error {
+	if err := checkRange(lat, Latitude); err != nil {
+		return err
+	}
+	return checkRange(lon, Longitude)
+}
+
+// Check проверяет точку перед сохранением и возвращает её, возможно исправленной.
+// Точка вне допустимых пределов — ошибка err. Сомнительная точка сохраняется с предупреждением
+// warning: если широта вне пределов, а с переставленными широтой и долготой точка допустима,
+// координаты переставляются и warning — ErrSwapped; точка 0, 0 — ErrNullIsland, туда обычно
+// попадают записи с незаполненными координатами.
+func Check(lat float64, lon float64) (p Point, warning error, err error) {
+	if checkRange(lat, Latitude) != nil && checkRange(lon, Longitude) == nil &&
+		checkRange(lat, Longitude) == nil && checkRange(lon, Latitude) == nil {
+		warning = fmt.Errorf("%w: latitude %v, longitude %v", ErrSwapped, lat, lon)
+		lat, lon = lon, lat
+	}
+	if err = Validate(lat, lon); err != nil {
+		return Point{}, nil, err
+	}
+	if lat == 0 && lon == 0 {
+		warning = ErrNullIsland
+	}
+	return Point{Latitude: lat, Longitude: lon}, warning, nil
+}
+
+// Point точка в градусах
+type Point struct {
+	Latitude  float64
+	Longitude float64
+}
+
+// Centroid возвращает сферический центр точек — нормализованное на сферу
+// среднее их единичных векторов. false, если точек нет или они
+// уравновешивают друг друга и центр не определён.
+func Centroid(points []Point) (Point, bool) {
+	var sum r3.Vector
+	for _, p := range points {
+		sum = sum.Add(s2.PointFromLatLng(s2.LatLngFromDegrees(p.Latitude, p.Longitude)).Vector)
+	}
+	if len(points) == 0 || sum.Norm() < 1e-12 {
+		return Point{}, false
+	}
+	ll := s2.LatLngFromPoint(s2.Point{Vector: sum.Normalize()})
+	return Point{Latitude: ll.Lat.Degrees(), Longitude: ll.Lng.Degrees()}, true
+}
+
+func checkRange(v float64, axis Axis) error {
+	if v < -axis.Limit() || v > axis.Limit() {
+		return fmt.Errorf("%w: %v %v not in [%v, %v]", ErrRange, axis, v, -axis.Limit(), axis.Limit())
+	}
+	return nil
+}
+
+// cutHemisphere отрезает букву полушария в начале или в конце строки
+// и возвращает знак координаты, 0 если буквы нет
+func cutHemisphere(text string, axis Axis) (string, float64, error) {
+	first, n := utf8.DecodeRuneInString(text)
+	last, m := utf8.DecodeLastRuneInString(text)
+
+	// буква полушария не может быть частью слова
+	next, _ := utf8.DecodeRuneInString(text[n:])
+	prev, _ := utf8.DecodeLastRuneInString(text[:len(text)-m])
+	atStart := isHemisphere(first) && !unicode.IsLetter(next)
+	atEnd := len(text) > n && isHemisphere(last) && !unicode.IsLetter(prev)
+
+	var letter rune
+	switch {
+	case atStart && atEnd:
+		return "", 0, fmt.Errorf("%w: %q has two hemisphere letters", ErrHemisphere, text)
+	case atStart:
+		letter, text = first, text[n:]
+	case atEnd:
+		letter, text = last, text[:len(text)-m]
+	default:
+		return text, 0, nil
+	}
+
+	h := hemispheres[unicode.ToUpper(letter)]
+	if h.axis != axis {
+		return "", 0, fmt.Errorf("%w: %c is not a %v hemisphere", ErrHemisphere, letter, axis)
+	}
+	return strings.TrimSpace(text), h.sign, nil
+}
+
+func isHemisphere(r rune) bool {
+	_, ok := hemispheres[unicode.ToUpper(r)]
+	return ok
+}
+
+// decimal неотрицательное десятичное число с точкой или запятой и необязательной экспонентой
+var decimal = regexp.MustCompile(`^(\d+([.,]\d*)?|[.,]\d+)([eE][-+]?\d+)?$`)
+
+// parseDecimal разбирает неотрицательное десятичное число без знака,
+// запятая считается десятичным разделителем
+func parseDecimal(s string) (float64, error) {
+	if !decimal.MatchString(s) {
+		return 0, ErrSyntax
+	}
+	return strconv.ParseFloat(strings.Replace(s, ",", ".", 1), 64)
+}
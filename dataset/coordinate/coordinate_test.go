@@ -0,0 +1,151 @@
+package coordinate
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		s    string
+		axis Axis
+		want float64
+		err  error
+	}{
+		// десятичные градусы
+		{s: "-33.8688", axis: Latitude, want: -33.8688},
+		{s: "+151,2093", axis: Longitude, want: 151.2093},
+		{s: "−33,5", axis: Latitude, want: -33.5},
+		{s: " 55.75 ", axis: Latitude, want: 55.75},
+		// градусы, минуты и секунды
+		{s: "55°45′21″N", axis: Latitude, want: 55 + 45./60 + 21./3600},
+		{s: "55 45 21.5", axis: Latitude, want: 55 + 45./60 + 21.5/3600},
+		{s: "55°45.35'", axis: Latitude, want: 55 + 45.35/60},
+		{s: `48°52'31.8"S`, axis: Latitude, want: -(48 + 52./60 + 31.8/3600)},
+		{s: "37°37′E", axis: Longitude, want: 37 + 37./60},
+		{s: "W 122.4194", axis: Longitude, want: -122.4194},
+		{s: "s 12.5", axis: Latitude, want: -12.5},
+		// полушария на кириллице
+		{s: "55°45′С", axis: Latitude, want: 55.75},
+		{s: "Ю 33,9", axis: Latitude, want: -33.9},
+		{s: "37,62 В", axis: Longitude, want: 37.62},
+		{s: "З 70.5", axis: Longitude, want: -70.5},
+		// ошибки
+		{s: "", axis: Latitude, err: ErrEmpty},
+		{s: "  ", axis: Latitude, err: ErrEmpty},
+		{s: "-55.75N", axis: Latitude, err: ErrSyntax},
+		{s: "−55.75 С", axis: Latitude, err: ErrSyntax},
+		{s: "55 60 00", axis: Latitude, err: ErrSyntax},
+		{s: "55 45 60", axis: Latitude, err: ErrSyntax},
+		{s: "55.5 30", axis: Latitude, err: ErrSyntax},
+		{s: "55 30,5 10", axis: Latitude, err: ErrSyntax},
+		{s: "55 45 21 10", axis: Latitude, err: ErrSyntax},
+		{s: "55.75E", axis: Latitude, err: ErrHemisphere},
+		{s: "37.62 Ю", axis: Longitude, err: ErrHemisphere},
+		{s: "N 55.75 S", axis: Latitude, err: ErrHemisphere},
+		{s: "North", axis: Latitude, err: ErrSyntax},
+		{s: "1,234.5", axis: Latitude, err: ErrSyntax},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.s, tt.axis)
+		if !errors.Is(err, tt.err) || (tt.err == nil) != (err == nil) {
+			t.Errorf("Parse(%q, %v) error = %v, want %v", tt.s, tt.axis, err, tt.err)
+			continue
+		}
+		if err == nil && math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("Parse(%q, %v) = %v, want %v", tt.s, tt.axis, got, tt.want)
+		}
+	}
+}
+
+func TestParseAxis(t *testing.T) {
+	if _, err := ParseLatitude("91"); !errors.Is(err, ErrRange) {
+		t.Errorf("latitude 91: %v", err)
+	}
+	if lon, err := ParseLongitude("270"); err != nil || lon != -90 {
+		t.Errorf("longitude 270 = %v, %v, want -90", lon, err)
+	}
+	if _, err := ParseLongitude("-181"); !errors.Is(err, ErrRange) {
+		t.Errorf("longitude -181: %v", err)
+	}
+}
+
+func TestNormalizeLongitude(t *testing.T) {
+	tests := []struct {
+		lon, want float64
+	}{
+		{37.62, 37.62},
+		{-122.4, -122.4},
+		{180, 180},
+		{180.5, -179.5},
+		{270, -90},
+		{360, 0},
+		// значения вне 180°–360° остаются как есть и отклоняются проверкой пределов
+		{360.5, 360.5},
+		{-190, -190},
+	}
+	for _, tt := range tests {
+		if got := NormalizeLongitude(tt.lon); got != tt.want {
+			t.Errorf("NormalizeLongitude(%v) = %v, want %v", tt.lon, got, tt.want)
+		}
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		lat, lon float64
+		want     Point
+		warning  error
+		err      error
+	}{
+		{lat: 55.75, lon: 37.62, want: Point{Latitude: 55.75, Longitude: 37.62}},
+		{lat: 137.62, lon: 55.75, want: Point{Latitude: 55.75, Longitude: 137.62}, warning: ErrSwapped},
+		{lat: 0, lon: 0, want: Point{}, warning: ErrNullIsland},
+		{lat: 91, lon: 181, err: ErrRange},
+		{lat: 45, lon: 190, err: ErrRange},
+	}
+	for _, tt := range tests {
+		p, warning, err := Check(tt.lat, tt.lon)
+		if !errors.Is(err, tt.err) || (tt.err == nil) != (err == nil) {
+			t.Errorf("Check(%v, %v) error = %v, want %v", tt.lat, tt.lon, err, tt.err)
+			continue
+		}
+		if !errors.Is(warning, tt.warning) || (tt.warning == nil) != (warning == nil) {
+			t.Errorf("Check(%v, %v) warning = %v, want %v", tt.lat, tt.lon, warning, tt.warning)
+		}
+		if err == nil && p != tt.want {
+			t.Errorf("Check(%v, %v) = %v, want %v", tt.lat, tt.lon, p, tt.want)
+		}
+	}
+}
+
+func TestCentroid(t *testing.T) {
+	tests := []struct {
+		name   string
+		points []Point
+		want   Point
+		ok     bool
+	}{
+		{"one point", []Point{{Latitude: 55.75, Longitude: 37.62}}, Point{Latitude: 55.75, Longitude: 37.62}, true},
+		{"equator", []Point{{Longitude: 10}, {Longitude: 20}}, Point{Longitude: 15}, true},
+		// центр через антимеридиан, а не через нулевой меридиан
+		{"antimeridian", []Point{{Longitude: 179}, {Longitude: -179}}, Point{Longitude: 180}, true},
+		{"pole", []Point{{Latitude: 80}, {Latitude: 80, Longitude: 180}}, Point{Latitude: 90}, true},
+		{"empty", nil, Point{}, false},
+		// противоположные точки уравновешивают друг друга
+		{"antipodal", []Point{{Latitude: 10, Longitude: 20}, {Latitude: -10, Longitude: -160}}, Point{}, false},
+		{"antipodal poles", []Point{{Latitude: 90}, {Latitude: -90}}, Point{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := Centroid(tt.points)
+		if ok != tt.ok {
+			t.Errorf("%v: ok %v, want %v", tt.name, ok, tt.ok)
+			continue
+		}
+		lonDiff := math.Abs(got.Longitude - tt.want.Longitude)
+		if math.Abs(got.Latitude-tt.want.Latitude) > 1e-9 || (lonDiff > 1e-9 && math.Abs(lonDiff-360) > 1e-9 && tt.want.Latitude != 90) {
+			t.Errorf("%v: %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
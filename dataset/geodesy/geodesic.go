@@ -0,0 +1,607 @@
+package geodesy
+
+import (
+	"fmt"
+	"math"
+)
+
+// Geodesic эллипсоид, на котором задачи решаются по алгоритму Карни:
+// перенос GeographicLib geodesic.c с рядами шестого порядка
+type Geodesic struct {
+	a, f, f1, e2, ep2, n, b float64
+	etol2                   float64
+	a3x                     [nA3x]float64
+	c3x                     [nC3x]float64
+}
+
+// WGS84 эллипсоид WGS 84
+var WGS84 = mustGeodesic(6378137, 1/298.257223563)
+
+var _ Solver = (*Geodesic)(nil)
+
+const (
+	maxit1 = 20
+	maxit2 = maxit1 + 53 + 10
+)
+
+var (
+	tiny    = math.Sqrt(math.SmallestNonzeroFloat64 * (1 << 52))
+	tol0    = math.Nextafter(1, 2) - 1
+	tol1    = 200 * tol0
+	tol2    = math.Sqrt(tol0)
+	tolb    = tol0 * tol2
+	xthresh = 1000 * tol2
+)
+
+// NewGeodesic возвращает эллипсоид с большой полуосью a метров и сплющиванием f.
+// Ряды алгоритма точны для сплющивания от 0 до 0,01, то есть для любого земного эллипсоида.
+func NewGeodesic(a float64, f float64) (*Geodesic, error) {
+	if !(a > 0) || math.IsInf(a, 0) {
+		return nil, fmt.Errorf("geodesy: equatorial radius %v must be positive", a)
+	}
+	if !(f >= 0 && f <= 0.01) {
+		return nil, fmt.Errorf("geodesy: flattening %v is out of range 0..0.01", f)
+	}
+	g := &Geodesic{a: a, f: f}
+	g.f1 = 1 - f
+	g.e2 = f * (2 - f)
+	g.ep2 = g.e2 / (g.f1 * g.f1)
+	g.n = f / (2 - f)
+	g.b = a * g.f1
+	g.etol2 = 0.1 * tol2 / math.Sqrt(math.Max(0.001, f)*math.Min(1, 1-f/2)/2)
+	g.a3x = a3coeff(g.n)
+	g.c3x = c3coeff(g.n)
+	return g, nil
+}
+
+func mustGeodesic(a float64, f float64) *Geodesic {
+	g, err := NewGeodesic(a, f)
+	if err != nil {
+		panic(err)
+	}
+	return g
+}
+
+func (g *Geodesic) a3f(eps float64) float64 {
+	return polyval(nA3-1, g.a3x[:], eps)
+}
+
+func (g *Geodesic) c3f(eps float64, c []float64) {
+	mult := 1.0
+	o := 0
+	for l := 1; l < nC3; l++ {
+		m := nC3 - l - 1
+		mult *= eps
+		c[l] = mult * polyval(m, g.c3x[o:], eps)
+		o += m + 1
+	}
+}
+
+// Inverse решает обратную задачу: Distance, Azimuth1 и Azimuth2 кратчайшей геодезической линии
+func (g *Geodesic) Inverse(lat1, lon1, lat2, lon2 float64) Line {
+	s12, salp1, calp1, salp2, calp2 := g.inverse(lat1, lon1, lat2, lon2)
+	return Line{Distance: s12, Azimuth1: atan2d(salp1, calp1), Azimuth2: atan2d(salp2, calp2)}
+}
+
+func (g *Geodesic) inverse(lat1, lon1, lat2, lon2 float64) (s12, salp1, calp1, salp2, calp2 float64) {
+	var ca [nC]float64
+
+	// разность долгот с учётом ошибки округления
+	lon12, lon12s := angDiff(lon1, lon2)
+	lonsign := 1.0
+	if math.Signbit(lon12) {
+		lonsign = -1
+	}
+	// если точки почти на одном полумеридиане, считаем, что на одном
+	lon12 = lonsign * angRound(lon12)
+	lon12s = angRound((180 - lon12) - lonsign*lon12s)
+	lam12 := lon12 * degree
+	var slam12, clam12 float64
+	if lon12 > 90 {
+		slam12, clam12 = sincosd(lon12s)
+		clam12 = -clam12
+	} else {
+		slam12, clam12 = sincosd(lon12)
+	}
+
+	// точки очень близко к экватору считаем лежащими на нём
+	lat1 = angRound(latFix(lat1))
+	lat2 = angRound(latFix(lat2))
+	// первой становится точка с большей по модулю широтой
+	swapp := 1.0
+	if math.Abs(lat1) < math.Abs(lat2) || math.IsNaN(lat2) {
+		swapp = -1
+		lonsign *= -1
+		lat1, lat2 = lat2, lat1
+	}
+	// lat1 <= -0
+	latsign := -1.0
+	if math.Signbit(lat1) {
+		latsign = 1
+	}
+	lat1 *= latsign
+	lat2 *= latsign
+	// теперь 0 <= lon12 <= 180, -90 <= lat1 <= -0, lat1 <= lat2 <= -lat1
+
+	sbet1, cbet1 := sincosd(lat1)
+	sbet1 *= g.f1
+	sbet1, cbet1 = norm2(sbet1, cbet1)
+	cbet1 = math.Max(tiny, cbet1)
+
+	sbet2, cbet2 := sincosd(lat2)
+	sbet2 *= g.f1
+	sbet2, cbet2 = norm2(sbet2, cbet2)
+	cbet2 = math.Max(tiny, cbet2)
+
+	// при |bet2| = |bet1| делаем их равными точно
+	if cbet1 < -sbet1 {
+		if cbet2 == cbet1 {
+			sbet2 = math.Copysign(sbet1, sbet2)
+		}
+	} else if math.Abs(sbet2) == -sbet1 {
+		cbet2 = cbet1
+	}
+
+	dn1 := math.Sqrt(1 + g.ep2*sbet1*sbet1)
+	dn2 := math.Sqrt(1 + g.ep2*sbet2*sbet2)
+
+	var sig12, s12x, m12x float64
+	meridian := lat1 == -90 || slam12 == 0
+
+	if meridian {
+		// точки на одном меридиане, линия может идти по нему
+		calp1, salp1 = clam12, slam12
+		calp2, salp2 = 1, 0
+
+		ssig1, csig1 := sbet1, calp1*cbet1
+		ssig2, csig2 := sbet2, calp2*cbet2
+
+		sig12 = math.Atan2(math.Max(0, csig1*ssig2-ssig1*csig2)+0, csig1*csig2+ssig1*ssig2)
+		s12x, m12x = g.lengths(g.n, sig12, ssig1, csig1, dn1, ssig2, csig2, dn2, ca[:])
+		// при sig12 > pi/2 меридиан — не кратчайший путь
+		if sig12 < 1 || m12x >= 0 {
+			if sig12 < 3*tiny || (sig12 < tol0 && (s12x < 0 || m12x < 0)) {
+				sig12, m12x, s12x = 0, 0, 0
+			}
+			m12x *= g.b
+			s12x *= g.b
+		} else {
+			meridian = false
+		}
+	}
+
+	switch {
+	case meridian:
+	case sbet1 == 0 && (g.f <= 0 || lon12s >= g.f*180):
+		// линия идёт по экватору
+		calp1, calp2 = 0, 0
+		salp1, salp2 = 1, 1
+		s12x = g.a * lam12
+	default:
+		// точки в полусфере, ограниченной меридианом, линия не меридиан и не экватор
+		var dnm float64
+		sig12, salp1, calp1, salp2, calp2, dnm = g.inverseStart(sbet1, cbet1, dn1, sbet2, cbet2, dn2,
+			lam12, slam12, clam12, salp2, calp2)
+
+		if sig12 >= 0 {
+			// короткая линия
+			s12x = sig12 * g.b * dnm
+			break
+		}
+
+		// метод Ньютона для lambda12(alp1) - lam12 = 0 с удержанием интервала (alp1a, alp1b), содержащего корень
+		var ssig1, csig1, ssig2, csig2, eps float64
+		salp1a, calp1a, salp1b, calp1b := tiny, 1.0, tiny, -1.0
+		tripn, tripb := false, false
+		for numit := 0; ; numit++ {
+			var v, dv float64
+			v, dv, salp2, calp2, sig12, ssig1, csig1, ssig2, csig2, eps = g.lambda12(sbet1, cbet1, dn1, sbet2, cbet2, dn2,
+				salp1, calp1, slam12, clam12, numit < maxit1, ca[:])
+			tol := 1.0
+			if tripn {
+				tol = 8
+			}
+			if tripb || !(math.Abs(v) >= tol*tol0) || numit == maxit2 {
+				break
+			}
+			if v > 0 && (numit > maxit1 || calp1/salp1 > calp1b/salp1b) {
+				salp1b, calp1b = salp1, calp1
+			} else if v < 0 && (numit > maxit1 || calp1/salp1 < calp1a/salp1a) {
+				salp1a, calp1a = salp1, calp1
+			}
+			if numit < maxit1 && dv > 0 {
+				dalp1 := -v / dv
+				if math.Abs(dalp1) < math.Pi {
+					sdalp1, cdalp1 := math.Sincos(dalp1)
+					nsalp1 := salp1*cdalp1 + calp1*sdalp1
+					if nsalp1 > 0 {
+						calp1 = calp1*cdalp1 - salp1*sdalp1
+						salp1 = nsalp1
+						salp1, calp1 = norm2(salp1, calp1)
+						// вблизи корня производная мала, сходимость перестаёт быть квадратичной
+						tripn = math.Abs(v) <= 16*tol0
+						continue
+					}
+				}
+			}
+			// производная не положительна или шаг вышел из интервала — делим интервал пополам
+			salp1 = (salp1a + salp1b) / 2
+			calp1 = (calp1a + calp1b) / 2
+			salp1, calp1 = norm2(salp1, calp1)
+			tripn = false
+			tripb = math.Abs(salp1a-salp1)+(calp1a-calp1) < tolb || math.Abs(salp1-salp1b)+(calp1-calp1b) < tolb
+		}
+		s12x, _ = g.lengths(eps, sig12, ssig1, csig1, dn1, ssig2, csig2, dn2, ca[:])
+		s12x *= g.b
+	}
+
+	s12 = 0 + s12x
+
+	// возвращаем точки и знаки на место
+	if swapp < 0 {
+		salp1, salp2 = salp2, salp1
+		calp1, calp2 = calp2, calp1
+	}
+	salp1 *= swapp * lonsign
+	calp1 *= swapp * latsign
+	salp2 *= swapp * lonsign
+	calp2 *= swapp * latsign
+	return s12, salp1, calp1, salp2, calp2
+}
+
+// lengths возвращает длину s12/b и приведённую длину m12/b линии
+func (g *Geodesic) lengths(eps, sig12, ssig1, csig1, dn1, ssig2, csig2, dn2 float64, ca []float64) (s12b, m12b float64) {
+	var cb [nC]float64
+	a1 := a1m1f(eps)
+	c1f(eps, ca)
+	a2 := a2m1f(eps)
+	c2f(eps, cb[:])
+	m0 := a1 - a2
+	a1++
+	a2++
+
+	b1 := sinCosSeries(true, ssig2, csig2, ca, nC1) - sinCosSeries(true, ssig1, csig1, ca, nC1)
+	s12b = a1 * (sig12 + b1)
+	b2 := sinCosSeries(true, ssig2, csig2, cb[:], nC2) - sinCosSeries(true, ssig1, csig1, cb[:], nC2)
+	j12 := m0*sig12 + (a1*b1 - a2*b2)
+	m12b = dn2*(csig1*ssig2) - dn1*(ssig1*csig2) - csig1*csig2*j12
+	return s12b, m12b
+}
+
+// astroid решает k^4 + 2k^3 - (x^2 + y^2 - 1)k^2 - 2y^2k - y^2 = 0 относительно положительного k
+func astroid(x, y float64) float64 {
+	p := x * x
+	q := y * y
+	r := (p + q - 1) / 6
+	if q == 0 && r <= 0 {
+		return 0
+	}
+	s := p * q / 4
+	r2 := r * r
+	r3 := r * r2
+	disc := s * (s + 2*r3)
+	u := r
+	if disc >= 0 {
+		t3 := s + r3
+		if t3 < 0 {
+			t3 -= math.Sqrt(disc)
+		} else {
+			t3 += math.Sqrt(disc)
+		}
+		t := math.Cbrt(t3)
+		if t != 0 {
+			u += t + r2/t
+		}
+	} else {
+		ang := math.Atan2(math.Sqrt(-disc), -(s + r3))
+		u += 2 * r * math.Cos(ang/3)
+	}
+	v := math.Sqrt(u*u + q)
+	var uv float64
+	if u < 0 {
+		uv = q / (v - u)
+	} else {
+		uv = u + v
+	}
+	w := (uv - q) / (2 * v)
+	return uv / (math.Sqrt(uv+w*w) + w)
+}
+
+// inverseStart возвращает начальное приближение alp1 для метода Ньютона и sig12 = -1.
+// Для коротких линий решение находится сразу: sig12 >= 0, заданы alp2 и dnm.
+func (g *Geodesic) inverseStart(sbet1, cbet1, dn1, sbet2, cbet2, dn2, lam12, slam12, clam12, salp2in, calp2in float64) (
+	sig12, salp1, calp1, salp2, calp2, dnm float64) {
+	sig12 = -1
+	salp2, calp2 = salp2in, calp2in
+	sbet12 := sbet2*cbet1 - cbet2*sbet1
+	cbet12 := cbet2*cbet1 + sbet2*sbet1
+	sbet12a := sbet2*cbet1 + cbet2*sbet1
+	shortline := cbet12 >= 0 && sbet12 < 0.5 && cbet2*lam12 < 0.5
+
+	var somg12, comg12 float64
+	if shortline {
+		sbetm2 := (sbet1 + sbet2) * (sbet1 + sbet2)
+		sbetm2 /= sbetm2 + (cbet1+cbet2)*(cbet1+cbet2)
+		dnm = math.Sqrt(1 + g.ep2*sbetm2)
+		omg12 := lam12 / (g.f1 * dnm)
+		somg12, comg12 = math.Sincos(omg12)
+	} else {
+		somg12, comg12 = slam12, clam12
+	}
+
+	salp1 = cbet2 * somg12
+	if comg12 >= 0 {
+		calp1 = sbet12 + cbet2*sbet1*somg12*somg12/(1+comg12)
+	} else {
+		calp1 = sbet12a - cbet2*sbet1*somg12*somg12/(1-comg12)
+	}
+
+	ssig12 := math.Hypot(salp1, calp1)
+	csig12 := sbet1*sbet2 + cbet1*cbet2*comg12
+
+	switch {
+	case shortline && ssig12 < g.etol2:
+		// совсем короткие линии
+		salp2 = cbet1 * somg12
+		t := 1 - comg12
+		if comg12 >= 0 {
+			t = somg12 * somg12 / (1 + comg12)
+		}
+		calp2 = sbet12 - cbet1*sbet2*t
+		salp2, calp2 = norm2(salp2, calp2)
+		sig12 = math.Atan2(ssig12, csig12)
+	case math.Abs(g.n) > 0.1 || csig12 >= 0 || ssig12 >= 6*math.Abs(g.n)*math.Pi*cbet1*cbet1:
+		// сферического приближения достаточно
+	default:
+		// почти антиподные точки: в координатах x, y антипод в начале, особая точка в (-1, 0)
+		lam12x := math.Atan2(-slam12, -clam12)
+		k2 := sbet1 * sbet1 * g.ep2
+		eps := k2 / (2*(1+math.Sqrt(1+k2)) + k2)
+		lamscale := g.f * cbet1 * g.a3f(eps) * math.Pi
+		betscale := lamscale * cbet1
+		x := lam12x / lamscale
+		y := sbet12a / betscale
+
+		if y > -tol1 && x > -1-xthresh {
+			salp1 = math.Min(1, -x)
+			calp1 = -math.Sqrt(1 - salp1*salp1)
+		} else {
+			k := astroid(x, y)
+			omg12a := lamscale * (-x * k / (1 + k))
+			somg12, comg12 = math.Sincos(omg12a)
+			comg12 = -comg12
+			salp1 = cbet2 * somg12
+			calp1 = sbet12a - cbet2*sbet1*somg12*somg12/(1-comg12)
+		}
+	}
+	// проверка приближения, NaN проходит
+	if !(salp1 <= 0) {
+		salp1, calp1 = norm2(salp1, calp1)
+	} else {
+		salp1, calp1 = 1, 0
+	}
+	return sig12, salp1, calp1, salp2, calp2, dnm
+}
+
+// lambda12 возвращает разность lambda12(alp1) - lam12 и её производную по alp1
+func (g *Geodesic) lambda12(sbet1, cbet1, dn1, sbet2, cbet2, dn2, salp1, calp1, slam120, clam120 float64,
+	diffp bool, ca []float64) (v, dlam12, salp2, calp2, sig12, ssig1, csig1, ssig2, csig2, eps float64) {
+	if sbet1 == 0 && calp1 == 0 {
+		// вырожденный случай экваториальной линии уже обработан
+		calp1 = -tiny
+	}
+
+	salp0 := salp1 * cbet1
+	calp0 := math.Hypot(calp1, salp1*sbet1)
+
+	ssig1 = sbet1
+	somg1 := salp0 * sbet1
+	csig1 = calp1 * cbet1
+	comg1 := csig1
+	ssig1, csig1 = norm2(ssig1, csig1)
+
+	if cbet2 != cbet1 {
+		salp2 = salp0 / cbet2
+	} else {
+		salp2 = salp1
+	}
+	if cbet2 != cbet1 || math.Abs(sbet2) != -sbet1 {
+		var d float64
+		if cbet1 < -sbet1 {
+			d = (cbet2 - cbet1) * (cbet1 + cbet2)
+		} else {
+			d = (sbet1 - sbet2) * (sbet1 + sbet2)
+		}
+		calp2 = math.Sqrt((calp1*cbet1)*(calp1*cbet1)+d) / cbet2
+	} else {
+		calp2 = math.Abs(calp1)
+	}
+
+	ssig2 = sbet2
+	somg2 := salp0 * sbet2
+	csig2 = calp2 * cbet2
+	comg2 := csig2
+	ssig2, csig2 = norm2(ssig2, csig2)
+
+	sig12 = math.Atan2(math.Max(0, csig1*ssig2-ssig1*csig2)+0, csig1*csig2+ssig1*ssig2)
+
+	somg12 := math.Max(0, comg1*somg2-somg1*comg2) + 0
+	comg12 := comg1*comg2 + somg1*somg2
+	eta := math.Atan2(somg12*clam120-comg12*slam120, comg12*clam120+somg12*slam120)
+
+	k2 := calp0 * calp0 * g.ep2
+	eps = k2 / (2*(1+math.Sqrt(1+k2)) + k2)
+	g.c3f(eps, ca)
+	b312 := sinCosSeries(true, ssig2, csig2, ca, nC3-1) - sinCosSeries(true, ssig1, csig1, ca, nC3-1)
+	domg12 := -g.f * g.a3f(eps) * salp0 * (sig12 + b312)
+	v = eta + domg12
+
+	if diffp {
+		if calp2 == 0 {
+			dlam12 = -2 * g.f1 * dn1 / sbet1
+		} else {
+			_, dlam12 = g.lengths(eps, sig12, ssig1, csig1, dn1, ssig2, csig2, dn2, ca)
+			dlam12 *= g.f1 / (calp2 * cbet2)
+		}
+	}
+	return v, dlam12, salp2, calp2, sig12, ssig1, csig1, ssig2, csig2, eps
+}
+
+// Direct решает прямую задачу: точку на расстоянии s12 метров по геодезической линии с азимутом azi1
+func (g *Geodesic) Direct(lat1, lon1, azi1, s12 float64) Point {
+	azi1 = angNormalize(azi1)
+	salp1, calp1 := sincosd(angRound(azi1))
+	lat1 = latFix(lat1)
+
+	sbet1, cbet1 := sincosd(angRound(lat1))
+	sbet1 *= g.f1
+	sbet1, cbet1 = norm2(sbet1, cbet1)
+	cbet1 = math.Max(tiny, cbet1)
+
+	// sin(alp0) = sin(alp1) * cos(bet1)
+	salp0 := salp1 * cbet1
+	calp0 := math.Hypot(calp1, salp1*sbet1)
+	// sig1 и omg1 отсчитываются от ближайшего пересечения экватора к северу
+	ssig1 := sbet1
+	somg1 := salp0 * sbet1
+	csig1 := 1.0
+	if sbet1 != 0 || calp1 != 0 {
+		csig1 = cbet1 * calp1
+	}
+	comg1 := csig1
+	ssig1, csig1 = norm2(ssig1, csig1)
+
+	k2 := calp0 * calp0 * g.ep2
+	eps := k2 / (2*(1+math.Sqrt(1+k2)) + k2)
+
+	var c1a, c1pa, c3a [nC]float64
+	a1m1 := a1m1f(eps)
+	c1f(eps, c1a[:])
+	b11 := sinCosSeries(true, ssig1, csig1, c1a[:], nC1)
+	s, c := math.Sincos(b11)
+	// tau1 = sig1 + B11
+	stau1 := ssig1*c + csig1*s
+	ctau1 := csig1*c - ssig1*s
+	c1pf(eps, c1pa[:])
+	g.c3f(eps, c3a[:])
+	a3c := -g.f * salp0 * g.a3f(eps)
+	b31 := sinCosSeries(true, ssig1, csig1, c3a[:], nC3-1)
+
+	tau12 := s12 / (g.b * (1 + a1m1))
+	s, c = math.Sincos(tau12)
+	b12 := -sinCosSeries(true, stau1*c+ctau1*s, ctau1*c-stau1*s, c1pa[:], nC1p)
+	sig12 := tau12 - (b12 - b11)
+	ssig12, csig12 := math.Sincos(sig12)
+
+	// sig2 = sig1 + sig12
+	ssig2 := ssig1*csig12 + csig1*ssig12
+	csig2 := csig1*csig12 - ssig1*ssig12
+	sbet2 := calp0 * ssig2
+	cbet2 := math.Hypot(salp0, calp0*csig2)
+	if cbet2 == 0 {
+		cbet2, csig2 = tiny, tiny
+	}
+	salp2 := salp0
+	calp2 := calp0 * csig2
+
+	somg2 := salp0 * ssig2
+	comg2 := csig2
+	omg12 := math.Atan2(somg2*comg1-comg2*somg1, comg2*comg1+somg2*somg1)
+	lam12 := omg12 + a3c*(sig12+(sinCosSeries(true, ssig2, csig2, c3a[:], nC3-1)-b31))
+	lon12 := lam12 / degree
+
+	return Point{
+		Lat:     atan2d(sbet2, g.f1*cbet2),
+		Lon:     angNormalize(angNormalize(lon1) + angNormalize(lon12)),
+		Azimuth: atan2d(salp2, calp2),
+	}
+}
+
+// angDiff возвращает lon2 - lon1, приведённую к [-180, 180], и ошибку её округления
+func angDiff(x, y float64) (float64, float64) {
+	d, t := sumx(angNormalize(-x), angNormalize(y))
+	d = angNormalize(d)
+	if d == 180 && t > 0 {
+		d = -180
+	}
+	return sumx(d, t)
+}
+
+// sumx возвращает u + v и ошибку округления суммы
+func sumx(u, v float64) (float64, float64) {
+	s := u + v
+	up := s - v
+	vpp := s - up
+	up -= u
+	vpp -= v
+	return s, -(up + vpp)
+}
+
+// angRound округляет малые углы, чтобы точки у экватора и меридиана ложились на них точно
+func angRound(x float64) float64 {
+	const z = 1.0 / 16
+	y := math.Abs(x)
+	if y < z {
+		y = z - (z - y)
+	}
+	return math.Copysign(y, x)
+}
+
+func latFix(x float64) float64 {
+	if math.Abs(x) > 90 {
+		return math.NaN()
+	}
+	return x
+}
+
+// sincosd возвращает синус и косинус угла в градусах, точные для кратных 90
+func sincosd(x float64) (float64, float64) {
+	r := math.Remainder(x, 360)
+	q := int(math.Round(r / 90))
+	r -= 90 * float64(q)
+	r *= degree
+	s, c := math.Sincos(r)
+	var sinx, cosx float64
+	switch q & 3 {
+	case 0:
+		sinx, cosx = s, c
+	case 1:
+		sinx, cosx = c, -s
+	case 2:
+		sinx, cosx = -s, -c
+	default:
+		sinx, cosx = -c, s
+	}
+	if sinx == 0 {
+		sinx = math.Copysign(sinx, x)
+	}
+	return sinx, 0 + cosx
+}
+
+// atan2d возвращает atan2(y, x) в градусах, от -180 до 180
+func atan2d(y, x float64) float64 {
+	q := 0
+	if math.Abs(y) > math.Abs(x) {
+		x, y = y, x
+		q = 2
+	}
+	if math.Signbit(x) {
+		x = -x
+		q++
+	}
+	ang := math.Atan2(y, x) / degree
+	switch q {
+	case 1:
+		ang = math.Copysign(180, y) - ang
+	case 2:
+		ang = 90 - ang
+	case 3:
+		ang = -90 + ang
+	}
+	return ang
+}
+
+func norm2(s, c float64) (float64, float64) {
+	r := math.Hypot(s, c)
+	return s / r, c / r
+}
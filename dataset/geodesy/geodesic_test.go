@@ -0,0 +1,86 @@
+package geodesy
+
+import (
+	"math"
+	"testing"
+)
+
+// azimuthDiff разница азимутов в градусах с учётом того, что 180 и -180 совпадают
+func azimuthDiff(a, b float64) float64 {
+	return math.Abs(angNormalize(a - b))
+}
+
+// Эталонные значения GeographicLib (GeodSolve) для WGS 84
+func TestGeodesicInverse(t *testing.T) {
+	tests := []struct {
+		name                   string
+		lat1, lon1, lat2, lon2 float64
+		distance               float64
+		azi1, azi2             float64
+		// tolerance допуск расстояния: у эталонов GeodSolve, округлённых до метра, — полметра
+		tolerance float64
+	}{
+		{"JFK–LHR", 40.6, -73.8, 51.6, -0.5, 5551759.400319, 51.198882845579824, 107.821776735514248, 1e-6},
+		{"nearly antipodal", -30, 0, 29.9, 179.8, 19989832.827610, 161.890524736, 18.090737246, 1e-6},
+		{"equator, 179°", 0, 0, 0, 179, 19926189, 90, 90, 0.5},
+		{"equator, 179.5°", 0, 0, 0, 179.5, 19980862, 55.96650, 124.03350, 0.5},
+		{"antipodal on equator", 0, 0, 0, 180, 20003931, 0, 180, 0.5},
+		{"antipodal off equator", 0, 0, 1, 180, 19893357, 0, 180, 0.5},
+		// дуга экватора короче полуокружности — сам экватор
+		{"equator, 1°", 0, 0, 0, 1, 6378137 * math.Pi / 180, 90, 90, 1e-6},
+		{"quarter meridian", 0, 0, 90, 0, 10001965.729313, 0, 0, 1e-6},
+		{"meridian, 45°", 0, 0, 45, 0, 4984944.378, 0, 0, 1e-3},
+		{"pole to pole", -90, 0, 90, 0, 2 * 10001965.729313, 0, 0, 1e-6},
+	}
+	for _, tt := range tests {
+		l := WGS84.Inverse(tt.lat1, tt.lon1, tt.lat2, tt.lon2)
+		if math.Abs(l.Distance-tt.distance) > tt.tolerance {
+			t.Errorf("%v: distance %.6f, want %.6f", tt.name, l.Distance, tt.distance)
+		}
+		if azimuthDiff(l.Azimuth1, tt.azi1) > 1e-5 || azimuthDiff(l.Azimuth2, tt.azi2) > 1e-5 {
+			t.Errorf("%v: azimuths %.9f %.9f, want %.9f %.9f", tt.name, l.Azimuth1, l.Azimuth2, tt.azi1, tt.azi2)
+		}
+	}
+}
+
+func TestGeodesicDirect(t *testing.T) {
+	p := WGS84.Direct(40.6, -73.8, 51.198882845579824, 5551759.400319)
+	if math.Abs(p.Lat-51.6) > 1e-9 || math.Abs(p.Lon+0.5) > 1e-9 || math.Abs(p.Azimuth-107.821776735514248) > 1e-9 {
+		t.Errorf("JFK–LHR direct: %.12f %.12f %.12f", p.Lat, p.Lon, p.Azimuth)
+	}
+}
+
+func TestGeodesicRoundTrip(t *testing.T) {
+	points := [][4]float64{
+		{55.7558, 37.6173, 59.9343, 30.3351},
+		{-33.8688, 151.2093, 51.5074, -0.1278},
+		{0, 0, 0.5, 179.7},
+		{-30, 0, 29.9, 179.8},
+		{89.9, 0, -89.9, 45},
+		{0, -179.9, 0, 179.9},
+		{12, 34, 12.000001, 34.000001},
+	}
+	for _, c := range points {
+		l := WGS84.Inverse(c[0], c[1], c[2], c[3])
+		p := WGS84.Direct(c[0], c[1], l.Azimuth1, l.Distance)
+		// 1e-9 градуса — около 0,1 мм
+		if math.Abs(p.Lat-c[2]) > 1e-9 || math.Abs(angNormalize(p.Lon-c[3])) > 1e-9 || azimuthDiff(p.Azimuth, l.Azimuth2) > 1e-8 {
+			t.Errorf("%v: direct %.12f %.12f %.9f, inverse azimuth %.9f", c, p.Lat, p.Lon, p.Azimuth, l.Azimuth2)
+		}
+		back := WGS84.Inverse(c[2], c[3], c[0], c[1])
+		if math.Abs(back.Distance-l.Distance) > 1e-6 || azimuthDiff(back.Azimuth1, l.BackAzimuth()) > 1e-8 {
+			t.Errorf("%v: reverse line %.6f %.9f, want %.6f %.9f", c, back.Distance, back.Azimuth1, l.Distance, l.BackAzimuth())
+		}
+	}
+}
+
+func TestSphereAgreesWithGeodesic(t *testing.T) {
+	l := Earth.Inverse(40.6, -73.8, 51.6, -0.5)
+	if d := math.Abs(l.Distance-5551759.400319) / 5551759.400319; d > 0.005 {
+		t.Errorf("sphere distance %.3f differs from ellipsoid by %.2f%%", l.Distance, d*100)
+	}
+	p := Earth.Direct(40.6, -73.8, l.Azimuth1, l.Distance)
+	if math.Abs(p.Lat-51.6) > 1e-9 || math.Abs(p.Lon+0.5) > 1e-9 {
+		t.Errorf("sphere round trip: %.12f %.12f", p.Lat, p.Lon)
+	}
+}
@@ -0,0 +1,68 @@
+// Package geodesy решает прямую и обратную геодезические задачи: на эллипсоиде WGS 84
+// по алгоритму Карни (C. F. F. Karney, Algorithms for geodesics, 2013) с точностью
+// до нанометров для любых точек, в том числе почти антиподных, и на сфере — быстро,
+// с ошибкой до 0,5 %.
+package geodesy
+
+import "math"
+
+// Solver решает геодезические задачи на поверхности Земли. Координаты и азимуты — в градусах,
+// азимуты отсчитываются от севера по часовой стрелке, расстояния — в метрах.
+type Solver interface {
+	// Inverse находит кратчайшую линию между двумя точками
+	Inverse(lat1, lon1, lat2, lon2 float64) Line
+	// Direct находит точку на расстоянии s12 от первой точки по линии с начальным азимутом azi1
+	Direct(lat1, lon1, azi1, s12 float64) Point
+}
+
+// Line решение обратной задачи
+type Line struct {
+	// Distance длина линии в метрах
+	Distance float64
+	// Azimuth1 азимут линии в первой точке, от -180 до 180
+	Azimuth1 float64
+	// Azimuth2 азимут продолжения линии во второй точке, от -180 до 180.
+	// Азимут из второй точки на первую — BackAzimuth.
+	Azimuth2 float64
+}
+
+// BackAzimuth азимут линии из второй точки на первую
+func (l Line) BackAzimuth() float64 {
+	return angNormalize(l.Azimuth2 + 180)
+}
+
+// Point решение прямой задачи
+type Point struct {
+	Lat float64
+	Lon float64
+	// Azimuth азимут линии в точке, от -180 до 180
+	Azimuth float64
+}
+
+// Midpoint возвращает середину линии между двумя точками. Азимут середины — азимут линии в ней.
+func Midpoint(s Solver, lat1, lon1, lat2, lon2 float64) Point {
+	l := s.Inverse(lat1, lon1, lat2, lon2)
+	return s.Direct(lat1, lon1, l.Azimuth1, l.Distance/2)
+}
+
+// Azimuth360 переводит азимут в диапазон от 0 до 360
+func Azimuth360(azi float64) float64 {
+	if azi < 0 {
+		azi += 360
+	}
+	if azi >= 360 {
+		azi -= 360
+	}
+	return azi
+}
+
+const degree = math.Pi / 180
+
+// angNormalize приводит угол к диапазону от -180 до 180
+func angNormalize(x float64) float64 {
+	y := math.Remainder(x, 360)
+	if math.Abs(y) == 180 {
+		return math.Copysign(180, x)
+	}
+	return y
+}
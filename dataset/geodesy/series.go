@@ -0,0 +1,190 @@
+package geodesy
+
+// Коэффициенты разложений в ряды шестого порядка по третьему сплющиванию n и
+// параметру eps из GeographicLib (Karney 2013, формулы 15–18, 24–25).
+
+const (
+	order = 6
+	nA1   = order
+	nC1   = order
+	nC1p  = order
+	nA2   = order
+	nC2   = order
+	nA3   = order
+	nA3x  = nA3
+	nC3   = order
+	nC3x  = (nC3 * (nC3 - 1)) / 2
+	nC    = order + 1
+)
+
+// polyval вычисляет многочлен степени n с коэффициентами p по схеме Горнера,
+// старший коэффициент первый
+func polyval(n int, p []float64, x float64) float64 {
+	if n < 0 {
+		return 0
+	}
+	y := p[0]
+	for i := 1; i <= n; i++ {
+		y = y*x + p[i]
+	}
+	return y
+}
+
+// a1m1f возвращает A1 - 1 (формула 17)
+func a1m1f(eps float64) float64 {
+	coeff := [...]float64{
+		// (1-eps)*A1-1, многочлен от eps2 степени 3
+		1, 4, 64, 0, 256,
+	}
+	m := nA1 / 2
+	t := polyval(m, coeff[:], eps*eps) / coeff[m+1]
+	return (t + eps) / (1 - eps)
+}
+
+// c1f вычисляет коэффициенты C1[l] (формула 18), c[1]..c[nC1]
+func c1f(eps float64, c []float64) {
+	coeff := [...]float64{
+		-1, 6, -16, 32,
+		-9, 64, -128, 2048,
+		9, -16, 768,
+		3, -5, 512,
+		-7, 1280,
+		-7, 2048,
+	}
+	seriesCoeffs(nC1, coeff[:], eps, c)
+}
+
+// c1pf вычисляет коэффициенты обратного ряда C1'[l] (формула 21), c[1]..c[nC1p]
+func c1pf(eps float64, c []float64) {
+	coeff := [...]float64{
+		205, -432, 768, 1536,
+		4005, -4736, 3840, 12288,
+		-225, 116, 384,
+		-7173, 2695, 7680,
+		3467, 7680,
+		38081, 61440,
+	}
+	seriesCoeffs(nC1p, coeff[:], eps, c)
+}
+
+// a2m1f возвращает A2 - 1 (формула 42)
+func a2m1f(eps float64) float64 {
+	coeff := [...]float64{
+		// (eps+1)*A2-1, многочлен от eps2 степени 3
+		-11, -28, -192, 0, 256,
+	}
+	m := nA2 / 2
+	t := polyval(m, coeff[:], eps*eps) / coeff[m+1]
+	return (t - eps) / (1 + eps)
+}
+
+// c2f вычисляет коэффициенты C2[l] (формула 43), c[1]..c[nC2]
+func c2f(eps float64, c []float64) {
+	coeff := [...]float64{
+		1, 2, 16, 32,
+		35, 64, 384, 2048,
+		15, 80, 768,
+		7, 35, 512,
+		63, 1280,
+		77, 2048,
+	}
+	seriesCoeffs(nC2, coeff[:], eps, c)
+}
+
+// seriesCoeffs вычисляет c[l] = eps^l * P_l(eps^2) для l от 1 до n, многочлены P_l
+// записаны в coeff подряд, каждый со знаменателем в конце
+func seriesCoeffs(n int, coeff []float64, eps float64, c []float64) {
+	eps2 := eps * eps
+	d := eps
+	o := 0
+	for l := 1; l <= n; l++ {
+		m := (n - l) / 2
+		c[l] = d * polyval(m, coeff[o:], eps2) / coeff[o+m+1]
+		o += m + 2
+		d *= eps
+	}
+}
+
+// a3coeff вычисляет коэффициенты A3 как многочлена от eps (формула 24)
+func a3coeff(n float64) [nA3x]float64 {
+	coeff := [...]float64{
+		-3, 128,
+		-2, -3, 64,
+		-1, -3, -1, 16,
+		3, -1, -2, 8,
+		1, -1, 2,
+		1, 1,
+	}
+	var a3x [nA3x]float64
+	o, k := 0, 0
+	for j := nA3 - 1; j >= 0; j-- {
+		m := j
+		if nA3-j-1 < j {
+			m = nA3 - j - 1
+		}
+		a3x[k] = polyval(m, coeff[o:], n) / coeff[o+m+1]
+		k++
+		o += m + 2
+	}
+	return a3x
+}
+
+// c3coeff вычисляет коэффициенты C3[l] как многочленов от eps (формула 25)
+func c3coeff(n float64) [nC3x]float64 {
+	coeff := [...]float64{
+		3, 128,
+		2, 5, 128,
+		-1, 3, 3, 64,
+		-1, 0, 1, 8,
+		-1, 1, 4,
+		5, 256,
+		1, 3, 128,
+		-3, -2, 3, 64,
+		1, -3, 2, 32,
+		7, 512,
+		-10, 9, 384,
+		5, -9, 5, 192,
+		7, 512,
+		-14, 7, 512,
+		21, 2560,
+	}
+	var c3x [nC3x]float64
+	o, k := 0, 0
+	for l := 1; l < nC3; l++ {
+		for j := nC3 - 1; j >= l; j-- {
+			m := j
+			if nC3-j-1 < j {
+				m = nC3 - j - 1
+			}
+			c3x[k] = polyval(m, coeff[o:], n) / coeff[o+m+1]
+			k++
+			o += m + 2
+		}
+	}
+	return c3x
+}
+
+// sinCosSeries суммирует ряд Кленшоу: sum(c[l] * sin(2*l*x)) при sinp,
+// иначе sum(c[l] * cos((2*l+1)*x))
+func sinCosSeries(sinp bool, sinx, cosx float64, c []float64, n int) float64 {
+	k := n
+	if sinp {
+		k++
+	}
+	ar := 2 * (cosx - sinx) * (cosx + sinx)
+	var y0, y1 float64
+	if n&1 != 0 {
+		k--
+		y0 = c[k]
+	}
+	for i := n / 2; i > 0; i-- {
+		k--
+		y1 = ar*y0 - y1 + c[k]
+		k--
+		y0 = ar*y1 - y0 + c[k]
+	}
+	if sinp {
+		return 2 * sinx * cosx * y0
+	}
+	return cosx * (y0 - y1)
+}
@@ -0,0 +1,54 @@
+package geodesy
+
+import "math"
+
+// Sphere сфера радиуса Radius метров
+type Sphere struct {
+	Radius float64
+}
+
+// Earth сфера среднего радиуса Земли R1 = (2a + b) / 3 эллипсоида WGS 84
+var Earth = Sphere{Radius: 6371008.8}
+
+var _ Solver = Sphere{}
+
+// Inverse находит дугу большого круга между точками по формуле гаверсинусов
+func (s Sphere) Inverse(lat1, lon1, lat2, lon2 float64) Line {
+	phi1, phi2 := lat1*degree, lat2*degree
+	dlam := angNormalize(lon2-lon1) * degree
+	sinPhi1, cosPhi1 := math.Sincos(phi1)
+	sinPhi2, cosPhi2 := math.Sincos(phi2)
+	sinLam, cosLam := math.Sincos(dlam)
+
+	h := hav(phi2-phi1) + cosPhi1*cosPhi2*hav(dlam)
+	sigma := 2 * math.Asin(math.Sqrt(math.Min(1, h)))
+
+	azi1 := math.Atan2(sinLam*cosPhi2, cosPhi1*sinPhi2-sinPhi1*cosPhi2*cosLam) / degree
+	azi2 := math.Atan2(sinLam*cosPhi1, sinPhi2*cosPhi1*cosLam-cosPhi2*sinPhi1) / degree
+	return Line{Distance: s.Radius * sigma, Azimuth1: azi1, Azimuth2: azi2}
+}
+
+// Direct находит точку на дуге большого круга
+func (s Sphere) Direct(lat1, lon1, azi1, s12 float64) Point {
+	phi1, theta := lat1*degree, azi1*degree
+	delta := s12 / s.Radius
+	sinPhi1, cosPhi1 := math.Sincos(phi1)
+	sinDelta, cosDelta := math.Sincos(delta)
+	sinTheta, cosTheta := math.Sincos(theta)
+
+	sinPhi2 := sinPhi1*cosDelta + cosPhi1*sinDelta*cosTheta
+	phi2 := math.Asin(math.Max(-1, math.Min(1, sinPhi2)))
+	dlam := math.Atan2(sinTheta*sinDelta*cosPhi1, cosDelta-sinPhi1*sinPhi2)
+
+	azi2 := math.Atan2(sinTheta*cosPhi1, cosDelta*cosPhi1*cosTheta-sinPhi1*sinDelta)
+	return Point{
+		Lat:     phi2 / degree,
+		Lon:     angNormalize(lon1 + dlam/degree),
+		Azimuth: azi2 / degree,
+	}
+}
+
+func hav(x float64) float64 {
+	s := math.Sin(x / 2)
+	return s * s
+}
@@ -0,0 +1,202 @@
+// Package geohash кодирует координаты в geohash — строку base32, каждый символ
+// которой делит ячейку на 32 части, — и находит соседние ячейки.
+package geohash
+
+import (
+	"fmt"
+	"math"
+)
+
+// MaxPrecision наибольшая длина geohash: 60 бит, ячейка меньше 4 см
+const MaxPrecision = 12
+
+// alphabet base32 алфавит geohash без букв a, i, l, o
+const alphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+var decodeMap [256]byte
+
+func init() {
+	for i := range decodeMap {
+		decodeMap[i] = 0xff
+	}
+	for i := 0; i < len(alphabet); i++ {
+		decodeMap[alphabet[i]] = byte(i)
+	}
+}
+
+// Encode возвращает geohash точки длиной precision символов, от 1 до MaxPrecision
+func Encode(lat float64, lon float64, precision int) string {
+	if precision < 1 {
+		precision = 1
+	}
+	if precision > MaxPrecision {
+		precision = MaxPrecision
+	}
+	latMin, latMax := -90.0, 90.0
+	lonMin, lonMax := -180.0, 180.0
+
+	b := make([]byte, precision)
+	// биты чередуются: чётные делят долготу, нечётные — широту
+	even := true
+	for i := range b {
+		var ch byte
+		for bit := 4; bit >= 0; bit-- {
+			if even {
+				mid := (lonMin + lonMax) / 2
+				if lon >= mid {
+					ch |= 1 << bit
+					lonMin = mid
+				} else {
+					lonMax = mid
+				}
+			} else {
+				mid := (latMin + latMax) / 2
+				if lat >= mid {
+					ch |= 1 << bit
+					latMin = mid
+				} else {
+					latMax = mid
+				}
+			}
+			even = !even
+		}
+		b[i] = alphabet[ch]
+	}
+	return string(b)
+}
+
+// Box границы ячейки geohash в градусах
+type Box struct {
+	MinLat, MaxLat float64
+	MinLon, MaxLon float64
+}
+
+// Center возвращает центр ячейки
+func (b Box) Center() (float64, float64) {
+	return (b.MinLat + b.MaxLat) / 2, (b.MinLon + b.MaxLon) / 2
+}
+
+// Bounds возвращает границы ячейки geohash, регистр букв не важен
+func Bounds(hash string) (Box, error) {
+	if hash == "" || len(hash) > MaxPrecision {
+		return Box{}, fmt.Errorf("geohash %q must have 1 to %d characters", hash, MaxPrecision)
+	}
+	b := Box{MinLat: -90, MaxLat: 90, MinLon: -180, MaxLon: 180}
+	even := true
+	for i := 0; i < len(hash); i++ {
+		c := hash[i]
+		if c >= 'A' && c <= 'Z' {
+			c += 'a' - 'A'
+		}
+		v := decodeMap[c]
+		if v == 0xff {
+			return Box{}, fmt.Errorf("geohash %q has invalid character %q", hash, hash[i])
+		}
+		for bit := 4; bit >= 0; bit-- {
+			set := v&(1<<bit) != 0
+			if even {
+				mid := (b.MinLon + b.MaxLon) / 2
+				if set {
+					b.MinLon = mid
+				} else {
+					b.MaxLon = mid
+				}
+			} else {
+				mid := (b.MinLat + b.MaxLat) / 2
+				if set {
+					b.MinLat = mid
+				} else {
+					b.MaxLat = mid
+				}
+			}
+			even = !even
+		}
+	}
+	return b, nil
+}
+
+// Decode возвращает центр ячейки geohash
+func Decode(hash string) (float64, float64, error) {
+	b, err := Bounds(hash)
+	if err != nil {
+		return 0, 0, err
+	}
+	lat, lon := b.Center()
+	return lat, lon, nil
+}
+
+// Direction направление на соседнюю ячейку
+type Direction int
+
+const (
+	North Direction = iota
+	NorthEast
+	East
+	SouthEast
+	South
+	SouthWest
+	West
+	NorthWest
+)
+
+// Directions направления соседей по часовой стрелке, начиная с севера
+var Directions = [8]Direction{North, NorthEast, East, SouthEast, South, SouthWest, West, NorthWest}
+
+var directionNames = [8]string{"n", "ne", "e", "se", "s", "sw", "w", "nw"}
+
+func (d Direction) String() string {
+	return directionNames[d]
+}
+
+// offset сдвиг на соседнюю ячейку в ячейках по широте и долготе
+func (d Direction) offset() (int, int) {
+	switch d {
+	case North:
+		return 1, 0
+	case NorthEast:
+		return 1, 1
+	case East:
+		return 0, 1
+	case SouthEast:
+		return -1, 1
+	case South:
+		return -1, 0
+	case SouthWest:
+		return -1, -1
+	case West:
+		return 0, -1
+	}
+	return 1, -1
+}
+
+// Neighbour возвращает соседнюю ячейку той же длины в направлении d.
+// По долготе ячейки продолжаются через 180-й меридиан, за полюсом соседа нет —
+// тогда возвращается пустая строка.
+func Neighbour(hash string, d Direction) (string, error) {
+	b, err := Bounds(hash)
+	if err != nil {
+		return "", err
+	}
+	dLat, dLon := d.offset()
+	lat, lon := b.Center()
+	lat += float64(dLat) * (b.MaxLat - b.MinLat)
+	lon += float64(dLon) * (b.MaxLon - b.MinLon)
+	if lat > 90 || lat < -90 {
+		return "", nil
+	}
+	lon = math.Mod(lon+540, 360) - 180
+	return Encode(lat, lon, len(hash)), nil
+}
+
+// Neighbours возвращает восемь соседних ячеек в порядке Directions
+func Neighbours(hash string) ([8]string, error) {
+	var ns [8]string
+	for i, d := range Directions {
+		n, err := Neighbour(hash, d)
+		if err != nil {
+			return ns, err
+		}
+		ns[i] = n
+	}
+	return ns, nil
+}
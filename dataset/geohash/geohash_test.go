@@ -0,0 +1,134 @@
+package geohash
+
+import (
+	"math"
+	"testing"
+)
+
+func TestEncode(t *testing.T) {
+	tests := []struct {
+		lat, lon  float64
+		precision int
+		want      string
+	}{
+		{57.64911, 10.40744, 11, "u4pruydqqvj"},
+		{42.6, -5.6, 5, "ezs42"},
+		{55.7539, 37.6204, 12, "ucfv0jdq4b72"},
+		// длина ограничивается от 1 до MaxPrecision
+		{42.6, -5.6, 0, "e"},
+		{57.64911, 10.40744, 20, "u4pruydqqvj8"},
+		// границы ячеек относятся к северной и восточной ячейке
+		{0, 0, 2, "s0"},
+		{90, 180, 2, "zz"},
+		{-90, -180, 2, "00"},
+	}
+	for _, tt := range tests {
+		if got := Encode(tt.lat, tt.lon, tt.precision); got != tt.want {
+			t.Errorf("Encode(%v, %v, %d) = %q, want %q", tt.lat, tt.lon, tt.precision, got, tt.want)
+		}
+	}
+}
+
+func TestBounds(t *testing.T) {
+	b, err := Bounds("ezs42")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Box{MinLat: 42.583, MaxLat: 42.627, MinLon: -5.625, MaxLon: -5.581}
+	if math.Abs(b.MinLat-want.MinLat) > 1e-3 || math.Abs(b.MaxLat-want.MaxLat) > 1e-3 ||
+		math.Abs(b.MinLon-want.MinLon) > 1e-3 || math.Abs(b.MaxLon-want.MaxLon) > 1e-3 {
+		t.Errorf("bounds %+v, want %+v", b, want)
+	}
+
+	// регистр букв не важен
+	if upper, err := Bounds("EZS42"); err != nil || upper != b {
+		t.Errorf("upper case bounds %+v, %v", upper, err)
+	}
+	for _, hash := range []string{"", "ezs4a", "ezs42ezs42ezs", "ёж"} {
+		if _, err = Bounds(hash); err == nil {
+			t.Errorf("%q accepted", hash)
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	tests := []struct {
+		hash     string
+		lat, lon float64
+		// eps половина ячейки
+		eps float64
+	}{
+		{"u4pruydqqvj", 57.64911, 10.40744, 1e-5},
+		{"ezs42", 42.605, -5.603, 1e-3},
+		{"s", 22.5, 22.5, 1e-9},
+	}
+	for _, tt := range tests {
+		lat, lon, err := Decode(tt.hash)
+		if err != nil || math.Abs(lat-tt.lat) > tt.eps || math.Abs(lon-tt.lon) > tt.eps {
+			t.Errorf("Decode(%q) = %v, %v, %v, want %v, %v", tt.hash, lat, lon, err, tt.lat, tt.lon)
+		}
+		// центр ячейки кодируется в ту же ячейку
+		if got := Encode(lat, lon, len(tt.hash)); got != tt.hash {
+			t.Errorf("Encode(Decode(%q)) = %q", tt.hash, got)
+		}
+	}
+}
+
+func TestNeighbour(t *testing.T) {
+	tests := []struct {
+		hash string
+		d    Direction
+		want string
+	}{
+		{"ezs42", North, "ezs48"},
+		{"ezs42", West, "ezefr"},
+		{"u4pruydqqvj", SouthEast, "u4pruydqquy"},
+		// через 180-й меридиан
+		{"xbpbp", East, "80000"},
+		{"80000", West, "xbpbp"},
+		// за полюсом соседа нет
+		{"uzfrc", North, ""},
+		{"h0000", SouthWest, ""},
+	}
+	for _, tt := range tests {
+		if got, err := Neighbour(tt.hash, tt.d); err != nil || got != tt.want {
+			t.Errorf("Neighbour(%q, %v) = %q, %v, want %q", tt.hash, tt.d, got, err, tt.want)
+		}
+	}
+	if _, err := Neighbour("ezs4a", North); err == nil {
+		t.Error("invalid geohash accepted")
+	}
+}
+
+func TestNeighbours(t *testing.T) {
+	tests := []struct {
+		hash string
+		want [8]string
+	}{
+		{"ezs42", [8]string{"ezs48", "ezs49", "ezs43", "ezs41", "ezs40", "ezefp", "ezefr", "ezefx"}},
+		{"dqcjq", [8]string{"dqcjw", "dqcjx", "dqcjr", "dqcjp", "dqcjn", "dqcjj", "dqcjm", "dqcjt"}},
+		{"u4pruydqqvj", [8]string{"u4pruydqqvm", "u4pruydqqvq", "u4pruydqqvn", "u4pruydqquy",
+			"u4pruydqquv", "u4pruydqquu", "u4pruydqqvh", "u4pruydqqvk"}},
+		// восточные соседи — по другую сторону 180-го меридиана
+		{"xczbz", [8]string{"xczcp", "81b10", "81b0b", "81b08", "xczbx", "xczbw", "xczby", "xczcn"}},
+		{"2n0p0", [8]string{"2n0p2", "2n0p3", "2n0p1", "2n0nc", "2n0nb", "rypyz", "rypzp", "rypzr"}},
+		// у ячеек у полюсов нет соседей за полюсом
+		{"uzfrc", [8]string{"", "", "uzfrf", "uzfrd", "uzfr9", "uzfr8", "uzfrb", ""}},
+		{"h0000", [8]string{"h0002", "h0003", "h0001", "", "", "", "5bpbp", "5bpbr"}},
+	}
+	for _, tt := range tests {
+		if got, err := Neighbours(tt.hash); err != nil || got != tt.want {
+			t.Errorf("Neighbours(%q) = %q, %v, want %q", tt.hash, got, err, tt.want)
+		}
+	}
+}
+
+func TestDirectionString(t *testing.T) {
+	names := ""
+	for _, d := range Directions {
+		names += d.String() + " "
+	}
+	if names != "n ne e se s sw w nw " {
+		t.Errorf("directions %q", names)
+	}
+}
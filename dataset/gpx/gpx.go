@@ -0,0 +1,383 @@
+// Package gpx читает точки GPX файлов GPS навигаторов: путевые точки
+// и, по выбору, треки и маршруты, сведённые к одной точке.
+package gpx
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"github.com/audetv/datasets-parser/app/repos/dataset"
+	"github.com/audetv/datasets-parser/dataset/coordinate"
+	"github.com/audetv/datasets-parser/dataset/input"
+	"io"
+	"log"
+	"strings"
+)
+
+// Reduce способ свести трек или маршрут к одной точке
+type Reduce string
+
+const (
+	// ReduceNone треки и маршруты не загружаются
+	ReduceNone Reduce = "none"
+	// ReduceStart первая точка трека
+	ReduceStart Reduce = "start"
+	// ReduceEnd последняя точка трека
+	ReduceEnd Reduce = "end"
+	// ReduceCentroid сферический центр точек трека
+	ReduceCentroid Reduce = "centroid"
+)
+
+// ParseReduce разбирает способ сведения трека к точке
+func ParseReduce(s string) (Reduce, error) {
+	switch r := Reduce(strings.ToLower(strings.TrimSpace(s))); r {
+	case ReduceNone, ReduceStart, ReduceEnd, ReduceCentroid:
+		return r, nil
+	case "":
+		return ReduceNone, nil
+	}
+	return "", fmt.Errorf("unknown gpx track reduction %q, expected none, start, end or centroid", s)
+}
+
+func init() {
+	dataset.MustRegister(NewReader(ReduceNone))
+}
+
+// NewReader возвращает описание читателя GPX файлов, сводящего треки к точке способом reduce.
+// Чтобы сменить способ, читатель заменяется в реестре через Registry.Replace.
+func NewReader(reduce Reduce) dataset.Reader {
+	return dataset.Reader{
+		Name:     "gpx",
+		Patterns: []string{"*.gpx"},
+		New: func(src dataset.Source) (dataset.Store, error) {
+			return NewEntries(src, reduce), nil
+		},
+	}
+}
+
+var _ dataset.Store = &Entries{}
+var _ dataset.Failer = &Entries{}
+var _ dataset.Table = &Entries{}
+
+// Entries читает путевые точки wpt GPX файла, а треки trk и маршруты rte —
+// если задан способ сведения их к точке
+type Entries struct {
+	src    dataset.Source
+	reduce Reduce
+	// err ошибка, прервавшая чтение в ReadAll
+	err error
+	// Report сколько точек и треков прочитано, заполняется в ReadAll
+	Report Report
+}
+
+func NewEntries(src dataset.Source, reduce Reduce) *Entries {
+	return &Entries{src: src, reduce: reduce}
+}
+
+// Err возвращает ошибку, прервавшую чтение файла, после закрытия канала ReadAll
+func (e *Entries) Err() error {
+	return e.err
+}
+
+// Columns колонки отклонённых точек в карантине
+func (e *Entries) Columns() []string {
+	return []string{"element", "name", "lat", "lon"}
+}
+
+// Comma разделитель полей карантина
+func (e *Entries) Comma() rune {
+	return ';'
+}
+
+// Report сводка прочитанного GPX файла
+type Report struct {
+	Waypoints int
+	Tracks    int
+	Routes    int
+	Rejected  int
+	// Skipped треки и маршруты, пропущенные без способа сведения к точке
+	Skipped int
+}
+
+func (r Report) String() string {
+	return fmt.Sprintf("waypoints %d, tracks %d, routes %d, rejected %d, skipped tracks and routes %d",
+		r.Waypoints, r.Tracks, r.Routes, r.Rejected, r.Skipped)
+}
+
+// point точка wpt, rtept или trkpt
+type point struct {
+	Lat         string `xml:"lat,attr"`
+	Lon         string `xml:"lon,attr"`
+	Ele         string `xml:"ele"`
+	Time        string `xml:"time"`
+	Name        string `xml:"name"`
+	Comment     string `xml:"cmt"`
+	Description string `xml:"desc"`
+	Source      string `xml:"src"`
+	Links       []struct {
+		Href string `xml:"href,attr"`
+	} `xml:"link"`
+	Symbol string `xml:"sym"`
+	Type   string `xml:"type"`
+}
+
+// track трек или маршрут, точки трека собраны из всех его сегментов
+type track struct {
+	Name        string  `xml:"name"`
+	Comment     string  `xml:"cmt"`
+	Description string  `xml:"desc"`
+	Type        string  `xml:"type"`
+	RoutePoints []point `xml:"rtept"`
+	TrackPoints []point `xml:"trkseg>trkpt"`
+}
+
+func (e *Entries) ReadAll(ctx context.Context) (chan dataset.Entry, error) {
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	chout := make(chan dataset.Entry, 100)
+
+	go func() {
+		defer close(chout)
+
+		rc, err := e.src.Open()
+		if err != nil {
+			e.err = fmt.Errorf("%v: %w", e.src.Name, err)
+			return
+		}
+		defer rc.Close()
+
+		e.Report = Report{}
+		err = e.walk(rc, func(entry dataset.Entry) bool {
+			select {
+			case <-ctx.Done():
+				return false
+			case chout <- entry:
+				return true
+			}
+		})
+		if err != nil {
+			e.err = fmt.Errorf("%v: %w", e.src.Name, err)
+			return
+		}
+		if ctx.Err() == nil {
+			log.Printf("%v: %v\n", e.src.Name, e.Report)
+		}
+	}()
+
+	return chout, nil
+}
+
+// walk передаёт в emit записи путевых точек и сведённых к точке треков,
+// emit возвращает false, если чтение надо прекратить
+func (e *Entries) walk(r io.Reader, emit func(dataset.Entry) bool) error {
+	d := xml.NewDecoder(r)
+	d.Strict = false
+	d.CharsetReader = input.CharsetReader
+
+	for {
+		line, _ := d.InputPos()
+		tok, err := d.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		var entry dataset.Entry
+		switch start.Name.Local {
+		case "wpt":
+			var p point
+			if err = d.DecodeElement(&p, &start); err != nil {
+				return err
+			}
+			entry = e.waypoint(&p, line)
+		case "trk", "rte":
+			if e.reduce == ReduceNone {
+				e.Report.Skipped++
+				if err = d.Skip(); err != nil {
+					return err
+				}
+				continue
+			}
+			var t track
+			if err = d.DecodeElement(&t, &start); err != nil {
+				return err
+			}
+			entry = e.track(&t, start.Name.Local, line)
+		default:
+			continue
+		}
+		if !emit(entry) {
+			return nil
+		}
+	}
+}
+
+// waypoint собирает запись путевой точки
+func (e *Entries) waypoint(p *point, line int) dataset.Entry {
+	dj := pointJson(p)
+	dj["gpx_type"] = "wpt"
+	entry := dataset.Entry{
+		Name:            text(p.Name, "untitled"),
+		Description:     text(p.Description),
+		DescriptionJson: dj,
+		Line:            line,
+	}
+
+	lat, lon, ok := e.parse(&entry, p, "wpt")
+	if !ok {
+		return entry
+	}
+	entry.Latitude, entry.Longitude = lat, lon
+	entry.Height, _ = coordinate.ParseDecimal(p.Ele)
+	e.Report.Waypoints++
+	return entry
+}
+
+// track собирает запись трека или маршрута, сведённого к одной точке
+func (e *Entries) track(t *track, element string, line int) dataset.Entry {
+	points := t.TrackPoints
+	if element == "rte" {
+		points = t.RoutePoints
+	}
+	dj := map[string]interface{}{
+		"gpx_type": element,
+		"points":   len(points),
+		"reduced":  string(e.reduce),
+	}
+	if t.Comment != "" {
+		dj["cmt"] = strings.TrimSpace(t.Comment)
+	}
+	if t.Type != "" {
+		dj["type"] = strings.TrimSpace(t.Type)
+	}
+	entry := dataset.Entry{
+		Name:            text(t.Name, "untitled"),
+		Description:     text(t.Description),
+		DescriptionJson: dj,
+		Line:            line,
+	}
+
+	// точки с ошибками отклоняют весь трек, иначе его центр был бы смещён
+	var coordinates []coordinate.Point
+	for i := range points {
+		lat, lon, ok := e.parse(&entry, &points[i], element)
+		if !ok {
+			return entry
+		}
+		coordinates = append(coordinates, coordinate.Point{Latitude: lat, Longitude: lon})
+	}
+	if len(coordinates) == 0 {
+		e.reject(&entry, element, "", "", "", "track has no points")
+		return entry
+	}
+	first, last := points[0], points[len(points)-1]
+	if start := strings.TrimSpace(first.Time); start != "" {
+		dj["start_time"] = start
+	}
+	if end := strings.TrimSpace(last.Time); end != "" {
+		dj["end_time"] = end
+	}
+
+	var p coordinate.Point
+	switch e.reduce {
+	case ReduceStart:
+		p = coordinates[0]
+		entry.Height, _ = coordinate.ParseDecimal(first.Ele)
+	case ReduceEnd:
+		p = coordinates[len(coordinates)-1]
+		entry.Height, _ = coordinate.ParseDecimal(last.Ele)
+	default:
+		var ok bool
+		if p, ok = coordinate.Centroid(coordinates); !ok {
+			p = coordinates[0]
+		}
+	}
+	entry.Latitude, entry.Longitude = p.Latitude, p.Longitude
+
+	if element == "rte" {
+		e.Report.Routes++
+	} else {
+		e.Report.Tracks++
+	}
+	return entry
+}
+
+// parse разбирает и проверяет координаты точки, ошибки добавляются в запись
+func (e *Entries) parse(entry *dataset.Entry, p *point, element string) (float64, float64, bool) {
+	lat, err := coordinate.ParseDecimal(p.Lat)
+	if err != nil {
+		e.reject(entry, element, p.Lat, p.Lon, "lat", err.Error())
+		return 0, 0, false
+	}
+	lon, err := coordinate.ParseDecimal(p.Lon)
+	if err != nil {
+		e.reject(entry, element, p.Lat, p.Lon, "lon", err.Error())
+		return 0, 0, false
+	}
+	c, warning, err := coordinate.Check(lat, coordinate.NormalizeLongitude(lon))
+	if err != nil {
+		e.reject(entry, element, p.Lat, p.Lon, "lat, lon", err.Error())
+		return 0, 0, false
+	}
+	entry.Warn(warning)
+	return c.Latitude, c.Longitude, true
+}
+
+func (e *Entries) reject(entry *dataset.Entry, element string, lat string, lon string, column string, reason string) {
+	value := ""
+	if column != "" {
+		value = lat + ", " + lon
+	}
+	entry.Errors = append(entry.Errors, dataset.RowError{
+		File: e.src.Name, Line: entry.Line, Column: column, Value: value, Reason: reason,
+	})
+	entry.Raw = []string{element, entry.Name, lat, lon}
+	e.Report.Rejected++
+}
+
+// pointJson возвращает заполненные поля путевой точки для DescriptionJson
+func pointJson(p *point) map[string]interface{} {
+	dj := make(map[string]interface{})
+	for key, value := range map[string]string{
+		"time": p.Time,
+		"cmt":  p.Comment,
+		"src":  p.Source,
+		"sym":  p.Symbol,
+		"type": p.Type,
+	} {
+		if v := strings.TrimSpace(value); v != "" {
+			dj[key] = v
+		}
+	}
+	var links []string
+	for _, l := range p.Links {
+		if l.Href != "" {
+			links = append(links, l.Href)
+		}
+	}
+	if len(links) > 0 {
+		dj["links"] = links
+	}
+	return dj
+}
+
+// text возвращает первое непустое значение без пробелов по краям
+func text(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
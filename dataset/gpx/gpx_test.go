@@ -0,0 +1,134 @@
+package gpx
+
+import (
+	"context"
+	"github.com/audetv/datasets-parser/app/repos/dataset"
+	"math"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// readAll читает trip.gpx, сводя треки к точке способом reduce
+func readAll(t *testing.T, reduce Reduce) (*Entries, []dataset.Entry) {
+	t.Helper()
+	e := NewEntries(dataset.FileSource("trip.gpx", filepath.Join("testdata", "trip.gpx")), reduce)
+	ch, err := e.ReadAll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []dataset.Entry
+	for entry := range ch {
+		entries = append(entries, entry)
+	}
+	if e.Err() != nil {
+		t.Fatal(e.Err())
+	}
+	return e, entries
+}
+
+func TestWaypoints(t *testing.T) {
+	e, entries := readAll(t, ReduceNone)
+	if len(entries) != 2 {
+		t.Fatalf("entries %d, want 2", len(entries))
+	}
+
+	kremlin := entries[0]
+	want := map[string]interface{}{
+		"gpx_type": "wpt",
+		"time":     "2023-05-01T10:00:00Z",
+		"sym":      "Flag",
+		"links":    []string{"https://www.kreml.ru"},
+	}
+	if kremlin.Name != "Кремль" || kremlin.Description != "Московский Кремль" || kremlin.Line != 3 ||
+		kremlin.Latitude != 55.752 || kremlin.Longitude != 37.6175 || kremlin.Height != 145.5 ||
+		!reflect.DeepEqual(kremlin.DescriptionJson, want) {
+		t.Errorf("kremlin %+v", kremlin)
+	}
+
+	bad := entries[1]
+	if !bad.Rejected() || bad.Errors[0].Column != "lon" || !reflect.DeepEqual(bad.Raw, []string{"wpt", "Ошибка", "55,75", "north"}) {
+		t.Errorf("bad %+v", bad)
+	}
+
+	// без способа сведения треки и маршруты пропускаются
+	if want := (Report{Waypoints: 1, Rejected: 1, Skipped: 4}); e.Report != want {
+		t.Errorf("report %+v, want %+v", e.Report, want)
+	}
+}
+
+func TestTracks(t *testing.T) {
+	tests := []struct {
+		reduce Reduce
+		// track и route точки трека и маршрута, height высота трека
+		track, route latLon
+		height       float64
+	}{
+		{ReduceStart, latLon{0, 10}, latLon{10, 179}, 100},
+		{ReduceEnd, latLon{0, 30}, latLon{10, -179}, 120},
+		// сегменты трека объединяются, центр маршрута — на антимеридиане
+		{ReduceCentroid, latLon{0, 20}, latLon{10.0015, 180}, 0},
+	}
+	for _, tt := range tests {
+		e, entries := readAll(t, tt.reduce)
+		if len(entries) != 6 {
+			t.Fatalf("%v: entries %d, want 6", tt.reduce, len(entries))
+		}
+
+		track := entries[2]
+		dj := track.DescriptionJson.(map[string]interface{})
+		if !near(track, tt.track) || track.Height != tt.height || track.Name != "Прогулка" {
+			t.Errorf("%v: track %+v", tt.reduce, track)
+		}
+		want := map[string]interface{}{
+			"gpx_type":   "trk",
+			"points":     3,
+			"reduced":    string(tt.reduce),
+			"type":       "walking",
+			"start_time": "2023-05-01T11:00:00Z",
+			"end_time":   "2023-05-01T12:00:00Z",
+		}
+		if !reflect.DeepEqual(dj, want) {
+			t.Errorf("%v: track description_json %v", tt.reduce, dj)
+		}
+
+		route := entries[3]
+		if !near(route, tt.route) || route.DescriptionJson.(map[string]interface{})["gpx_type"] != "rte" {
+			t.Errorf("%v: route %+v", tt.reduce, route)
+		}
+
+		// точка с ошибкой отклоняет весь трек
+		if bad := entries[4]; !bad.Rejected() || len(bad.Errors) != 1 || bad.Errors[0].Column != "lat, lon" {
+			t.Errorf("%v: bad track %+v", tt.reduce, bad)
+		}
+		if empty := entries[5]; !empty.Rejected() || empty.Errors[0].Reason != "track has no points" {
+			t.Errorf("%v: empty track %+v", tt.reduce, empty)
+		}
+
+		if want := (Report{Waypoints: 1, Tracks: 1, Routes: 1, Rejected: 3}); e.Report != want {
+			t.Errorf("%v: report %+v, want %+v", tt.reduce, e.Report, want)
+		}
+	}
+}
+
+func TestParseReduce(t *testing.T) {
+	for s, want := range map[string]Reduce{"": ReduceNone, "none": ReduceNone, " Centroid ": ReduceCentroid, "END": ReduceEnd} {
+		if got, err := ParseReduce(s); err != nil || got != want {
+			t.Errorf("%q: %v, %v, want %v", s, got, err, want)
+		}
+	}
+	if _, err := ParseReduce("middle"); err == nil {
+		t.Error("middle accepted")
+	}
+}
+
+// latLon широта и долгота ожидаемой точки
+type latLon struct {
+	lat, lon float64
+}
+
+// near сообщает, что запись в точке c, долготы ±180° совпадают
+func near(e dataset.Entry, c latLon) bool {
+	lonDiff := math.Abs(e.Longitude - c.lon)
+	return math.Abs(e.Latitude-c.lat) < 1e-4 && (lonDiff < 1e-9 || math.Abs(lonDiff-360) < 1e-9)
+}
@@ -0,0 +1,97 @@
+package input
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// Delimiters разделители полей, которые распознаются в csv файлах
+var Delimiters = []rune{';', ',', '\t', '|'}
+
+// SniffDelimiter выбирает из Delimiters разделитель, который чаще встречается
+// в заголовке и столько же раз в следующих строках
+func SniffDelimiter(sample []byte) rune {
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(sample))
+	scanner.Buffer(make([]byte, 0, len(sample)+1), len(sample)+1)
+	for scanner.Scan() && len(lines) < 20 {
+		if strings.TrimSpace(scanner.Text()) != "" {
+			lines = append(lines, scanner.Text())
+		}
+	}
+	// последняя строка может быть обрезана
+	if len(lines) > 2 {
+		lines = lines[:len(lines)-1]
+	}
+
+	best, bestScore := Delimiters[0], 0.0
+	for _, d := range Delimiters {
+		if len(lines) == 0 {
+			break
+		}
+		header := countOutsideQuotes(lines[0], d)
+		if header == 0 {
+			continue
+		}
+		consistent := 0
+		for _, line := range lines[1:] {
+			if countOutsideQuotes(line, d) == header {
+				consistent++
+			}
+		}
+		// строки данных могут содержать переносы внутри кавычек,
+		// поэтому согласованность учитывается долей, а не строго
+		score := float64(header)
+		if len(lines) > 1 {
+			score *= 1 + float64(consistent)/float64(len(lines)-1)
+		}
+		if score > bestScore {
+			best, bestScore = d, score
+		}
+	}
+	return best
+}
+
+// CountFields возвращает количество полей первой строки при разделителе d
+func CountFields(sample []byte, d rune) int {
+	line := sample
+	if i := bytes.IndexByte(sample, '\n'); i >= 0 {
+		line = sample[:i]
+	}
+	return countOutsideQuotes(string(line), d) + 1
+}
+
+func countOutsideQuotes(line string, d rune) int {
+	n := 0
+	quoted := false
+	for _, r := range line {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case r == d && !quoted:
+			n++
+		}
+	}
+	return n
+}
+
+// ParseDelimiter разбирает разделитель, заданный символом или названием:
+// comma, semicolon, tab, pipe
+func ParseDelimiter(s string) (rune, error) {
+	switch strings.ToLower(s) {
+	case "comma":
+		return ',', nil
+	case "semicolon":
+		return ';', nil
+	case "tab", `\t`:
+		return '\t', nil
+	case "pipe":
+		return '|', nil
+	}
+	if r := []rune(s); len(r) == 1 {
+		return r[0], nil
+	}
+	return 0, fmt.Errorf("bad delimiter %q", s)
+}
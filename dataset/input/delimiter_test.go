@@ -0,0 +1,53 @@
+package input
+
+import "testing"
+
+func TestSniffDelimiter(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want rune
+	}{
+		{"semicolon", "a;b;c\n1;2;3\n4;5;6\n", ';'},
+		{"comma with decimal commas in quotes", "a,b,c\n\"1,5\",2,3\n\"4,5\",5,6\n", ','},
+		// запятые в десятичных числах встречаются чаще, но не в каждой строке поровну
+		{"semicolon with decimal commas", "name;lat;lon\nМосква;55,75;37,62\nТверь;56,85;35,9\n", ';'},
+		{"tab", "a\tb, c\td\n1\t2, 3\t4\n", '\t'},
+		{"pipe", "a|b|c\n1|2|3\n", '|'},
+		{"single column", "name\nМосква\n", ';'},
+	}
+	for _, tt := range tests {
+		if got := SniffDelimiter([]byte(tt.data)); got != tt.want {
+			t.Errorf("%v: delimiter %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseDelimiter(t *testing.T) {
+	tests := []struct {
+		value string
+		want  rune
+	}{
+		{"comma", ','},
+		{"Semicolon", ';'},
+		{"tab", '\t'},
+		{`\t`, '\t'},
+		{"pipe", '|'},
+		{"#", '#'},
+	}
+	for _, tt := range tests {
+		got, err := ParseDelimiter(tt.value)
+		if err != nil || got != tt.want {
+			t.Errorf("%q: %q, %v, want %q", tt.value, got, err, tt.want)
+		}
+	}
+	if _, err := ParseDelimiter("colon"); err == nil {
+		t.Error("colon accepted")
+	}
+}
+
+func TestCountFields(t *testing.T) {
+	if n := CountFields([]byte("name;\"a;b\";lat\n1;2;3;4\n"), ';'); n != 3 {
+		t.Errorf("fields %d, want 3", n)
+	}
+}
@@ -0,0 +1,133 @@
+package input
+
+import (
+	"bytes"
+	"fmt"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/unicode"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// Кодировки, которые распознаются во входных файлах
+const (
+	UTF8    = "utf-8"
+	UTF16LE = "utf-16le"
+	UTF16BE = "utf-16be"
+	CP1251  = "windows-1251"
+	KOI8R   = "koi8-r"
+	CP866   = "ibm866"
+)
+
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+)
+
+// Lookup возвращает каноническое имя и кодировку по имени, принимает распространённые синонимы,
+// для UTF-8 кодировка nil
+func Lookup(name string) (string, encoding.Encoding, error) {
+	switch strings.ToLower(strings.ReplaceAll(name, "_", "-")) {
+	case "utf-8", "utf8":
+		return UTF8, nil, nil
+	case "utf-16le", "utf16le", "utf-16", "utf16":
+		return UTF16LE, unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM), nil
+	case "utf-16be", "utf16be":
+		return UTF16BE, unicode.UTF16(unicode.BigEndian, unicode.IgnoreBOM), nil
+	case "windows-1251", "cp1251", "1251":
+		return CP1251, charmap.Windows1251, nil
+	case "koi8-r", "koi8r", "koi8":
+		return KOI8R, charmap.KOI8R, nil
+	case "ibm866", "cp866", "866":
+		return CP866, charmap.CodePage866, nil
+	}
+	return "", nil, fmt.Errorf("unsupported encoding %q", name)
+}
+
+// CharsetReader перекодирует в UTF-8 xml документ в кодировке label из его объявления,
+// подходит для xml.Decoder.CharsetReader
+func CharsetReader(label string, r io.Reader) (io.Reader, error) {
+	enc, err := htmlindex.Get(label)
+	if err != nil {
+		return nil, err
+	}
+	return enc.NewDecoder().Reader(r), nil
+}
+
+// stripBOM определяет кодировку по BOM и возвращает длину BOM
+func stripBOM(sample []byte) (string, int) {
+	switch {
+	case bytes.HasPrefix(sample, bomUTF8):
+		return UTF8, len(bomUTF8)
+	case bytes.HasPrefix(sample, bomUTF16LE):
+		return UTF16LE, len(bomUTF16LE)
+	case bytes.HasPrefix(sample, bomUTF16BE):
+		return UTF16BE, len(bomUTF16BE)
+	}
+	return "", 0
+}
+
+// DetectEncoding определяет кодировку по началу файла без BOM:
+// UTF-16 по нулевым байтам, UTF-8 по корректности последовательностей,
+// иначе выбирает между Windows-1251 и KOI8-R по частоте строчных букв
+func DetectEncoding(sample []byte) string {
+	if len(sample) == 0 {
+		return UTF8
+	}
+
+	var zeroEven, zeroOdd int
+	for i, b := range sample {
+		if b != 0 {
+			continue
+		}
+		if i%2 == 0 {
+			zeroEven++
+		} else {
+			zeroOdd++
+		}
+	}
+	// в UTF-16 латиница и цифры дают нулевой старший байт у каждого символа
+	if zeroOdd > len(sample)/4 && zeroOdd > zeroEven*4 {
+		return UTF16LE
+	}
+	if zeroEven > len(sample)/4 && zeroEven > zeroOdd*4 {
+		return UTF16BE
+	}
+
+	if validUTF8(sample) {
+		return UTF8
+	}
+
+	// В Windows-1251 строчные кириллические буквы занимают 0xE0–0xFF,
+	// а прописные 0xC0–0xDF, в KOI8-R наоборот. Обычный текст в основном
+	// состоит из строчных букв, поэтому кодировку выдаёт больший диапазон.
+	// high — байты 0xE0–0xFF (строчные в CP1251), low — 0xC0–0xDF (строчные в KOI8-R)
+	var high, low int
+	for _, b := range sample {
+		switch {
+		case b >= 0xE0:
+			high++
+		case b >= 0xC0:
+			low++
+		}
+	}
+	if low > high {
+		return KOI8R
+	}
+	return CP1251
+}
+
+// validUTF8 проверяет корректность UTF-8, допуская обрезанный последний символ
+func validUTF8(sample []byte) bool {
+	for i := 0; i < utf8.UTFMax && len(sample) > 0; i++ {
+		if utf8.Valid(sample) {
+			return true
+		}
+		sample = sample[:len(sample)-1]
+	}
+	return utf8.Valid(sample)
+}
@@ -0,0 +1,158 @@
+package input
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/audetv/datasets-parser/app/repos/dataset"
+	"golang.org/x/text/transform"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// SampleSize сколько байт с начала файла используется для определения кодировки и разделителя
+const SampleSize = 64 * 1024
+
+// Text входной файл, перекодированный в UTF-8 и без BOM
+type Text struct {
+	r        *bufio.Reader
+	closer   io.Closer
+	Encoding string
+}
+
+// Open определяет кодировку потока по BOM и содержимому, если encoding пустая,
+// убирает BOM и возвращает поток в UTF-8
+func Open(rc io.ReadCloser, encoding string) (*Text, error) {
+	br := bufio.NewReaderSize(rc, SampleSize)
+	raw, err := br.Peek(SampleSize)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		rc.Close()
+		return nil, err
+	}
+
+	name, n := stripBOM(raw)
+	if _, err = br.Discard(n); err != nil {
+		rc.Close()
+		return nil, err
+	}
+
+	if encoding != "" {
+		name = encoding
+	} else if name == "" {
+		name = DetectEncoding(raw[n:])
+	}
+
+	name, enc, err := Lookup(name)
+	if err != nil {
+		rc.Close()
+		return nil, err
+	}
+
+	var r io.Reader = br
+	if enc != nil {
+		r = transform.NewReader(br, enc.NewDecoder())
+	}
+
+	return &Text{
+		r:        bufio.NewReaderSize(r, SampleSize),
+		closer:   rc,
+		Encoding: name,
+	}, nil
+}
+
+func (t *Text) Read(p []byte) (int, error) {
+	return t.r.Read(p)
+}
+
+func (t *Text) Close() error {
+	return t.closer.Close()
+}
+
+// Sample возвращает начало файла в UTF-8, не сдвигая позицию чтения
+func (t *Text) Sample() []byte {
+	b, _ := t.r.Peek(SampleSize)
+	return b
+}
+
+// Override параметры чтения для файлов, подходящих под glob шаблон
+type Override struct {
+	Pattern string
+	Options dataset.Options
+}
+
+// Overrides параметры чтения отдельных файлов, заданные в командной строке
+type Overrides []Override
+
+// For возвращает параметры чтения файла, шаблон сравнивается с именем файла
+// и с полным путём внутри архива, более поздние переопределения важнее
+func (overrides Overrides) For(name string) dataset.Options {
+	var opts dataset.Options
+	for _, o := range overrides {
+		matched, _ := filepath.Match(o.Pattern, filepath.Base(name))
+		if !matched {
+			matched, _ = filepath.Match(o.Pattern, name)
+		}
+		if !matched {
+			continue
+		}
+		if o.Options.Encoding != "" {
+			opts.Encoding = o.Options.Encoding
+		}
+		if o.Options.Delimiter != 0 {
+			opts.Delimiter = o.Options.Delimiter
+		}
+		// листы, выбранные для файла несколькими флагами, читаются все
+		opts.Sheets = append(opts.Sheets, o.Options.Sheets...)
+	}
+	return opts
+}
+
+// ParseOverrides разбирает значения флагов вида «шаблон=кодировка», «шаблон=разделитель» и «шаблон=лист»
+func ParseOverrides(encodings []string, delimiters []string, sheets []string) (Overrides, error) {
+	var overrides Overrides
+	for _, v := range encodings {
+		pattern, value, err := splitOverride(v)
+		if err != nil {
+			return nil, err
+		}
+		name, _, err := Lookup(value)
+		if err != nil {
+			return nil, err
+		}
+		overrides = append(overrides, Override{Pattern: pattern, Options: dataset.Options{Encoding: name}})
+	}
+	for _, v := range delimiters {
+		pattern, value, err := splitOverride(v)
+		if err != nil {
+			return nil, err
+		}
+		d, err := ParseDelimiter(value)
+		if err != nil {
+			return nil, err
+		}
+		overrides = append(overrides, Override{Pattern: pattern, Options: dataset.Options{Delimiter: d}})
+	}
+	for _, v := range sheets {
+		pattern, value, err := splitOverride(v)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = path.Match(value, ""); err != nil {
+			return nil, fmt.Errorf("bad sheet pattern %q: %w", value, err)
+		}
+		overrides = append(overrides, Override{Pattern: pattern, Options: dataset.Options{Sheets: []string{value}}})
+	}
+	return overrides, nil
+}
+
+func splitOverride(v string) (string, string, error) {
+	i := strings.LastIndex(v, "=")
+	if i <= 0 || i == len(v)-1 {
+		return "", "", fmt.Errorf("bad override %q, expected pattern=value", v)
+	}
+	if _, err := filepath.Match(v[:i], ""); err != nil {
+		return "", "", fmt.Errorf("bad override pattern %q: %w", v[:i], err)
+	}
+	return v[:i], v[i+1:], nil
+}
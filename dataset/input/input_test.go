@@ -0,0 +1,111 @@
+package input
+
+import (
+	"github.com/audetv/datasets-parser/app/repos/dataset"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// cities содержимое файлов testdata/cities.*.csv в UTF-8
+const cities = "name;lat;lon\nМосква;55,75;37,62\nТверь;56,85;35,9\nПсков;57,82;28,33\n"
+
+func TestOpen(t *testing.T) {
+	tests := []struct {
+		file string
+		want string
+	}{
+		{"cities.utf8.csv", UTF8},
+		{"cities.utf8bom.csv", UTF8},
+		{"cities.utf16le.csv", UTF16LE},
+		{"cities.utf16be.csv", UTF16BE},
+		{"cities.utf16le-nobom.csv", UTF16LE},
+		{"cities.cp1251.csv", CP1251},
+		{"cities.koi8r.csv", KOI8R},
+	}
+	for _, tt := range tests {
+		text := openText(t, tt.file, "")
+		if text.Encoding != tt.want {
+			t.Errorf("%v: encoding %v, want %v", tt.file, text.Encoding, tt.want)
+		}
+		// образец и поток начинаются одинаково, BOM убран
+		if got := string(text.Sample()); got != cities {
+			t.Errorf("%v: sample %q", tt.file, got)
+		}
+		got, err := io.ReadAll(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != cities {
+			t.Errorf("%v: text %q", tt.file, got)
+		}
+	}
+}
+
+func TestOpenEncodingOverride(t *testing.T) {
+	// KOI8-R, прочитанный как Windows-1251, перекодируется без ошибки, но в другие буквы
+	text := openText(t, "cities.koi8r.csv", CP1251)
+	if text.Encoding != CP1251 {
+		t.Fatalf("encoding %v", text.Encoding)
+	}
+	if got := string(text.Sample()); got == cities {
+		t.Errorf("override ignored: %q", got)
+	}
+
+	if _, err := Open(io.NopCloser(strings.NewReader(cities)), "ibm437"); err == nil {
+		t.Error("unsupported encoding accepted")
+	}
+}
+
+func TestOverridesFor(t *testing.T) {
+	overrides, err := ParseOverrides(
+		[]string{"*.csv=cp1251", "cities.csv=UTF_8"},
+		[]string{"archive/*.csv=tab", "cities.csv=comma"},
+		[]string{"*.xlsx=Лист1", "cities.xlsx=Города*"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		want dataset.Options
+	}{
+		{"plants.csv", dataset.Options{Encoding: CP1251}},
+		{"cities.csv", dataset.Options{Encoding: UTF8, Delimiter: ','}},
+		{"archive/plants.csv", dataset.Options{Encoding: CP1251, Delimiter: '\t'}},
+		{"plants.txt", dataset.Options{}},
+		// листы, выбранные несколькими флагами, читаются все
+		{"cities.xlsx", dataset.Options{Sheets: []string{"Лист1", "Города*"}}},
+	}
+	for _, tt := range tests {
+		if got := overrides.For(tt.name); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%v: %+v, want %+v", tt.name, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"cities.csv", "=cp1251", "cities.csv=", "[.csv=cp1251", "*.csv=ibm437"} {
+		if _, err = ParseOverrides([]string{bad}, nil, nil); err == nil {
+			t.Errorf("%q accepted", bad)
+		}
+	}
+	if _, err = ParseOverrides(nil, nil, []string{"*.xlsx=[Лист"}); err == nil {
+		t.Error("bad sheet pattern accepted")
+	}
+}
+
+func openText(t *testing.T, file string, encoding string) *Text {
+	t.Helper()
+	f, err := os.Open(filepath.Join("testdata", file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	text, err := Open(f, encoding)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { text.Close() })
+	return text
+}
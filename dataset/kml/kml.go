@@ -0,0 +1,223 @@
+package kml
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"github.com/audetv/datasets-parser/app/repos/dataset"
+	"github.com/audetv/datasets-parser/dataset/input"
+	"io"
+	"log"
+	"path"
+	"strings"
+)
+
+func init() {
+	dataset.MustRegister(dataset.Reader{
+		Name:     "kml-native",
+		Patterns: []string{"*.kml", "*.kmz"},
+		New: func(src dataset.Source) (dataset.Store, error) {
+			return NewEntries(src), nil
+		},
+	})
+}
+
+var _ dataset.Store = &Entries{}
+var _ dataset.Failer = &Entries{}
+var _ dataset.Table = &Entries{}
+
+// Entries читает метки KML или KMZ файла. Каждая метка Placemark
+// становится одной точкой, вложенность папок сохраняется в DescriptionJson.
+type Entries struct {
+	src dataset.Source
+	// err ошибка, прервавшая чтение в ReadAll
+	err error
+	// Report что было упрощено при чтении файла, заполняется в ReadAll
+	Report Report
+}
+
+func NewEntries(src dataset.Source) *Entries {
+	return &Entries{src: src}
+}
+
+// Err возвращает ошибку, прервавшую чтение файла, после закрытия канала ReadAll
+func (e *Entries) Err() error {
+	return e.err
+}
+
+// Columns колонки отклонённых меток в карантине: имя метки и путь папок
+func (e *Entries) Columns() []string {
+	return []string{"name", "folders"}
+}
+
+// Comma разделитель полей карантина
+func (e *Entries) Comma() rune {
+	return ';'
+}
+
+func (e *Entries) ReadAll(ctx context.Context) (chan dataset.Entry, error) {
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	chout := make(chan dataset.Entry, 100)
+
+	go func() {
+		defer close(chout)
+
+		r, err := e.open()
+		if err != nil {
+			e.err = fmt.Errorf("%v: %w", e.src.Name, err)
+			return
+		}
+		defer r.Close()
+
+		e.Report = Report{}
+		err = e.walk(r, func(entry dataset.Entry) bool {
+			select {
+			case <-ctx.Done():
+				return false
+			case chout <- entry:
+				return true
+			}
+		})
+		if err != nil {
+			e.err = fmt.Errorf("%v: %w", e.src.Name, err)
+			return
+		}
+		if ctx.Err() == nil {
+			log.Printf("%v: %v\n", e.src.Name, e.Report)
+		}
+	}()
+
+	return chout, nil
+}
+
+// open открывает kml документ, для kmz — основной kml файл архива
+func (e *Entries) open() (io.ReadCloser, error) {
+	rc, err := e.src.Open()
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(path.Ext(e.src.Name), ".kmz") {
+		return rc, nil
+	}
+	defer rc.Close()
+
+	// zip читается с произвольного места, поэтому kmz загружается в память
+	b, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, err
+	}
+	zr, err := zip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		return nil, err
+	}
+
+	// основной документ — doc.kml, иначе первый kml файл в корне архива
+	var doc *zip.File
+	for _, f := range zr.File {
+		if !strings.EqualFold(path.Ext(f.Name), ".kml") {
+			continue
+		}
+		if strings.EqualFold(f.Name, "doc.kml") {
+			doc = f
+			break
+		}
+		if doc == nil || (strings.Contains(doc.Name, "/") && !strings.Contains(f.Name, "/")) {
+			doc = f
+		}
+	}
+	if doc == nil {
+		return nil, fmt.Errorf("kmz has no kml document")
+	}
+	return doc.Open()
+}
+
+// walk обходит документ и передаёт в emit запись для каждой метки,
+// emit возвращает false, если чтение надо прекратить
+func (e *Entries) walk(r io.Reader, emit func(dataset.Entry) bool) error {
+	d := xml.NewDecoder(r)
+	d.Strict = false
+	d.CharsetReader = input.CharsetReader
+
+	// folders стек открытых папок, имя папки становится известно
+	// из её дочернего элемента name
+	var folders []*folder
+	for {
+		line, _ := d.InputPos()
+		tok, err := d.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "Folder", "Document":
+				folders = append(folders, &folder{document: t.Name.Local == "Document"})
+			case "name":
+				if len(folders) == 0 || folders[len(folders)-1].named {
+					continue
+				}
+				var name string
+				if err = d.DecodeElement(&name, &t); err != nil {
+					return err
+				}
+				f := folders[len(folders)-1]
+				f.name, f.named = strings.TrimSpace(name), true
+			case "Placemark":
+				var p placemark
+				if err = d.DecodeElement(&p, &t); err != nil {
+					return err
+				}
+				if !emit(e.entry(&p, folderPath(folders), line)) {
+					return nil
+				}
+			case "GroundOverlay", "PhotoOverlay", "ScreenOverlay", "NetworkLink":
+				e.Report.skip(t.Name.Local)
+				if err = d.Skip(); err != nil {
+					return err
+				}
+			default:
+				// у вложенных элементов папки, например стилей, тоже есть name
+				if len(folders) > 0 && !folders[len(folders)-1].named {
+					if err = d.Skip(); err != nil {
+						return err
+					}
+				}
+			}
+		case xml.EndElement:
+			if (t.Name.Local == "Folder" || t.Name.Local == "Document") && len(folders) > 0 {
+				folders = folders[:len(folders)-1]
+			}
+		}
+	}
+}
+
+// folder открытая папка или документ KML
+type folder struct {
+	name     string
+	named    bool
+	document bool
+}
+
+// folderPath возвращает имена вложенных папок метки без имени документа
+func folderPath(folders []*folder) []string {
+	var names []string
+	for _, f := range folders {
+		if f.document || f.name == "" {
+			continue
+		}
+		names = append(names, f.name)
+	}
+	return names
+}
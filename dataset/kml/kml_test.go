@@ -0,0 +1,179 @@
+package kml
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"github.com/audetv/datasets-parser/app/repos/dataset"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// readAll читает все метки файла и проверяет, что чтение не прервано
+func readAll(t *testing.T, src dataset.Source) (*Entries, []dataset.Entry) {
+	t.Helper()
+	e := NewEntries(src)
+	ch, err := e.ReadAll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []dataset.Entry
+	for entry := range ch {
+		entries = append(entries, entry)
+	}
+	if e.Err() != nil {
+		t.Fatal(e.Err())
+	}
+	return e, entries
+}
+
+func fixture(name string) dataset.Source {
+	return dataset.FileSource(name, filepath.Join("testdata", name))
+}
+
+func TestEntries(t *testing.T) {
+	e, entries := readAll(t, fixture("places.kml"))
+	if len(entries) != 5 {
+		t.Fatalf("entries %d, want 5", len(entries))
+	}
+
+	// простая метка — точка без сведений об упрощении
+	khufu := entries[0]
+	if khufu.Name != "Пирамида Хеопса" || khufu.Description != "Великая пирамида" || khufu.Line != 10 ||
+		khufu.Longitude != 31.1342 || khufu.Latitude != 29.9792 || khufu.Height != 138 {
+		t.Errorf("khufu %+v", khufu)
+	}
+	want := map[string]interface{}{
+		"folders":       []string{"Egypt", "Giza"},
+		"kml_id":        "khufu",
+		"extended_data": map[string]string{"height": "146.6"},
+	}
+	if !reflect.DeepEqual(khufu.DescriptionJson, want) {
+		t.Errorf("khufu description_json %v", khufu.DescriptionJson)
+	}
+
+	// из вложенных MultiGeometry берётся точка, а не центр линии
+	road := entries[1]
+	dj := road.DescriptionJson.(map[string]interface{})
+	if road.Longitude != 31.2357 || road.Latitude != 30.0444 || !reflect.DeepEqual(dj["folders"], []string{"Egypt"}) {
+		t.Errorf("road %+v", road)
+	}
+	if !reflect.DeepEqual(dj["geometry"], []string{"MultiGeometry", "LineString", "MultiGeometry", "Point"}) ||
+		!reflect.DeepEqual(dj["collapsed"], map[string]interface{}{"method": "point", "points": 1, "vertices": 2}) {
+		t.Errorf("road collapsed %v", dj)
+	}
+
+	// полигон сводится к центру внешней границы, вырезы и повтор первой вершины не учитываются
+	square := entries[2]
+	dj = square.DescriptionJson.(map[string]interface{})
+	if math.Abs(square.Longitude-11) > 1e-9 || math.Abs(square.Latitude-21) > 0.01 || dj["folders"] != nil {
+		t.Errorf("square %+v", square)
+	}
+	if !reflect.DeepEqual(dj["collapsed"], map[string]interface{}{"method": "centroid", "points": 0, "vertices": 4}) {
+		t.Errorf("square collapsed %v", dj["collapsed"])
+	}
+
+	// камера не геометрия, метка откладывается в карантин
+	empty := entries[3]
+	if !empty.Rejected() || empty.Errors[0].Reason != "placemark has no geometry" ||
+		!reflect.DeepEqual(empty.Raw, []string{"Без геометрии", ""}) {
+		t.Errorf("empty %+v", empty)
+	}
+
+	if entries[4].Longitude != -90 || entries[4].Latitude != 10 {
+		t.Errorf("longitude 270 %+v", entries[4])
+	}
+
+	wantReport := Report{
+		Placemarks: 4,
+		Rejected:   1,
+		Collapsed: map[string]int{
+			"MultiGeometry, LineString, MultiGeometry, Point → point": 1,
+			"Polygon → centroid": 1,
+		},
+		Skipped: map[string]int{"GroundOverlay": 1},
+	}
+	if !reflect.DeepEqual(e.Report, wantReport) {
+		t.Errorf("report %+v", e.Report)
+	}
+}
+
+func TestEntriesCharset(t *testing.T) {
+	_, entries := readAll(t, fixture("temples-1251.kml"))
+	if len(entries) != 1 || entries[0].Name != "Храм Христа Спасителя" ||
+		!reflect.DeepEqual(entries[0].DescriptionJson.(map[string]interface{})["folders"], []string{"Храмы"}) {
+		t.Errorf("entries %+v", entries)
+	}
+}
+
+func TestEntriesKMZ(t *testing.T) {
+	places, err := os.ReadFile(filepath.Join("testdata", "places.kml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	temples, err := os.ReadFile(filepath.Join("testdata", "temples-1251.kml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		files map[string][]byte
+		want  int
+	}{
+		// doc.kml — основной документ, даже если он не первый
+		{"doc.kmz", map[string][]byte{"a.kml": temples, "doc.kml": places, "files/icon.png": {0x89}}, 5},
+		// без doc.kml берётся kml файл из корня архива, а не из папки
+		{"root.kmz", map[string][]byte{"files/a.kml": places, "temples.kml": temples}, 1},
+	}
+	for _, tt := range tests {
+		_, entries := readAll(t, kmzSource(t, tt.name, tt.files))
+		if len(entries) != tt.want {
+			t.Errorf("%v: entries %d, want %d", tt.name, len(entries), tt.want)
+		}
+	}
+
+	e := NewEntries(kmzSource(t, "images.KMZ", map[string][]byte{"files/icon.png": {0x89}}))
+	ch, err := e.ReadAll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range ch {
+	}
+	if e.Err() == nil {
+		t.Error("kmz without kml document read")
+	}
+}
+
+// kmzSource упаковывает файлы в kmz в памяти, порядок файлов в архиве — по имени
+func kmzSource(t *testing.T, name string, files map[string][]byte) dataset.Source {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, f := range []string{"a.kml", "doc.kml", "files/a.kml", "files/icon.png", "temples.kml"} {
+		b, ok := files[f]
+		if !ok {
+			continue
+		}
+		w, err := zw.Create(f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = w.Write(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return dataset.Source{
+		Name: name,
+		Open: func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(buf.Bytes())), nil
+		},
+	}
+}
@@ -0,0 +1,298 @@
+package kml
+
+import (
+	"encoding/xml"
+	"fmt"
+	"github.com/audetv/datasets-parser/app/repos/dataset"
+	"github.com/audetv/datasets-parser/dataset/coordinate"
+	"sort"
+	"strings"
+)
+
+// placemark метка KML. Геометрия разбирается как дерево элементов,
+// потому что MultiGeometry может быть вложена произвольно.
+type placemark struct {
+	ID          string `xml:"id,attr"`
+	Name        string `xml:"name"`
+	Description string `xml:"description"`
+	Data        []data `xml:"ExtendedData>Data"`
+	SimpleData  []data `xml:"ExtendedData>SchemaData>SimpleData"`
+	Elements    []node `xml:",any"`
+}
+
+type data struct {
+	Name  string `xml:"name,attr"`
+	Value string `xml:"value"`
+	Text  string `xml:",chardata"`
+}
+
+type node struct {
+	XMLName     xml.Name
+	Coordinates string   `xml:"coordinates"`
+	Longitude   string   `xml:"Location>longitude"`
+	Latitude    string   `xml:"Location>latitude"`
+	Altitude    string   `xml:"Location>altitude"`
+	Coords      []string `xml:"coord"`
+	Nodes       []node   `xml:",any"`
+}
+
+// shape геометрия метки, сведённая к вершинам
+type shape struct {
+	// kinds типы геометрий метки в порядке появления
+	kinds []string
+	// points явно заданные точки Point
+	points []vertex
+	// vertices вершины линий, полигонов и треков
+	vertices []vertex
+	// bad вершины, которые не удалось разобрать
+	bad []string
+}
+
+type vertex struct {
+	lon, lat, alt float64
+}
+
+// collect собирает вершины геометрий, данные камеры, стили и наложения пропускаются
+func (s *shape) collect(n node) {
+	switch n.XMLName.Local {
+	case "Point":
+		s.kinds = append(s.kinds, "Point")
+		s.points = append(s.points, s.parse(n.Coordinates, false)...)
+	case "LineString":
+		s.kinds = append(s.kinds, "LineString")
+		s.vertices = append(s.vertices, s.parse(n.Coordinates, false)...)
+	case "LinearRing":
+		s.kinds = append(s.kinds, "LinearRing")
+		s.vertices = append(s.vertices, s.parse(n.Coordinates, true)...)
+	case "Polygon":
+		s.kinds = append(s.kinds, "Polygon")
+		// центр полигона определяется внешней границей, вырезы не учитываются
+		for _, b := range n.Nodes {
+			if b.XMLName.Local != "outerBoundaryIs" {
+				continue
+			}
+			for _, ring := range b.Nodes {
+				if ring.XMLName.Local == "LinearRing" {
+					s.vertices = append(s.vertices, s.parse(ring.Coordinates, true)...)
+				}
+			}
+		}
+	case "Model":
+		s.kinds = append(s.kinds, "Model")
+		s.points = append(s.points, s.parse(n.Longitude+","+n.Latitude+","+n.Altitude, false)...)
+	case "Track":
+		s.kinds = append(s.kinds, "Track")
+		for _, c := range n.Coords {
+			s.vertices = append(s.vertices, s.parse(strings.Join(strings.Fields(c), ","), false)...)
+		}
+	case "MultiGeometry", "MultiTrack":
+		s.kinds = append(s.kinds, n.XMLName.Local)
+		for _, child := range n.Nodes {
+			s.collect(child)
+		}
+	}
+}
+
+// parse разбирает кортежи «долгота,широта[,высота]», разделённые пробелами.
+// У замкнутого кольца последняя вершина повторяет первую и отбрасывается.
+func (s *shape) parse(coordinates string, ring bool) []vertex {
+	// «37.6, 55.7» встречается в файлах, собранных вручную
+	coordinates = strings.ReplaceAll(coordinates, ", ", ",")
+
+	var vertices []vertex
+	for _, tuple := range strings.Fields(coordinates) {
+		parts := strings.Split(tuple, ",")
+		if len(parts) < 2 || len(parts) > 3 {
+			s.bad = append(s.bad, tuple)
+			continue
+		}
+		var v vertex
+		var err error
+		if v.lon, err = coordinate.ParseDecimal(parts[0]); err != nil {
+			s.bad = append(s.bad, tuple)
+			continue
+		}
+		if v.lat, err = coordinate.ParseDecimal(parts[1]); err != nil {
+			s.bad = append(s.bad, tuple)
+			continue
+		}
+		if len(parts) == 3 && parts[2] != "" {
+			v.alt, _ = coordinate.ParseDecimal(parts[2])
+		}
+		v.lon = coordinate.NormalizeLongitude(v.lon)
+		vertices = append(vertices, v)
+	}
+
+	if ring && len(vertices) > 1 && vertices[0] == vertices[len(vertices)-1] {
+		vertices = vertices[:len(vertices)-1]
+	}
+	return vertices
+}
+
+// point сводит геометрию к одной точке: явно заданная точка,
+// иначе сферический центр всех вершин. method — «point», «centroid»
+// или «first vertex», если центр вершин не определён.
+func (s *shape) point() (vertex, string, bool) {
+	if len(s.points) > 0 {
+		return s.points[0], "point", true
+	}
+	if len(s.vertices) == 0 {
+		return vertex{}, "", false
+	}
+
+	points := make([]coordinate.Point, len(s.vertices))
+	for i, v := range s.vertices {
+		points[i] = coordinate.Point{Latitude: v.lat, Longitude: v.lon}
+	}
+	c, ok := coordinate.Centroid(points)
+	if !ok {
+		// вершины уравновешивают друг друга, центр не определён
+		return s.vertices[0], "first vertex", true
+	}
+	return vertex{lon: c.Longitude, lat: c.Latitude}, "centroid", true
+}
+
+// simple сообщает, что метка — одна точка и упрощать нечего
+func (s *shape) simple() bool {
+	return len(s.kinds) == 1 && len(s.points) == 1
+}
+
+// entry собирает dataset.Entry из метки
+func (e *Entries) entry(p *placemark, folders []string, line int) dataset.Entry {
+	name := strings.TrimSpace(p.Name)
+	if name == "" {
+		name = "untitled"
+	}
+
+	dj := make(map[string]interface{})
+	if len(folders) > 0 {
+		dj["folders"] = folders
+	}
+	if p.ID != "" {
+		dj["kml_id"] = p.ID
+	}
+	if ext := extendedData(p); len(ext) > 0 {
+		dj["extended_data"] = ext
+	}
+
+	entry := dataset.Entry{
+		Name:            name,
+		Description:     strings.TrimSpace(p.Description),
+		DescriptionJson: dj,
+		Line:            line,
+	}
+
+	var s shape
+	for _, n := range p.Elements {
+		s.collect(n)
+	}
+	for _, tuple := range s.bad {
+		entry.Errors = append(entry.Errors, dataset.RowError{
+			File: e.src.Name, Line: line, Column: "coordinates", Value: tuple, Reason: "invalid coordinate tuple",
+		})
+	}
+
+	v, method, ok := s.point()
+	if !ok {
+		entry.Errors = append(entry.Errors, dataset.RowError{
+			File: e.src.Name, Line: line, Reason: "placemark has no geometry",
+		})
+	} else if c, warning, err := coordinate.Check(v.lat, v.lon); err != nil {
+		entry.Errors = append(entry.Errors, dataset.RowError{
+			File: e.src.Name, Line: line, Column: "coordinates", Value: fmt.Sprintf("%v,%v", v.lon, v.lat), Reason: err.Error(),
+		})
+	} else {
+		v.lat, v.lon = c.Latitude, c.Longitude
+		entry.Warn(warning)
+	}
+
+	if entry.Rejected() {
+		entry.Raw = []string{name, strings.Join(folders, "/")}
+		e.Report.Rejected++
+		return entry
+	}
+
+	entry.Longitude, entry.Latitude, entry.Height = v.lon, v.lat, v.alt
+	e.Report.Placemarks++
+	if !s.simple() {
+		// сложная метка: сохраняем, из чего и как получена точка
+		dj["geometry"] = s.kinds
+		dj["collapsed"] = map[string]interface{}{
+			"method":   method,
+			"points":   len(s.points),
+			"vertices": len(s.vertices),
+		}
+		e.Report.collapse(s.kinds, method)
+	}
+	return entry
+}
+
+// extendedData возвращает значения ExtendedData метки
+func extendedData(p *placemark) map[string]string {
+	ext := make(map[string]string, len(p.Data)+len(p.SimpleData))
+	for _, d := range p.Data {
+		if v := strings.TrimSpace(d.Value); d.Name != "" && v != "" {
+			ext[d.Name] = v
+		}
+	}
+	for _, d := range p.SimpleData {
+		if v := strings.TrimSpace(d.Text); d.Name != "" && v != "" {
+			ext[d.Name] = v
+		}
+	}
+	return ext
+}
+
+// Report сводка того, что было упрощено при чтении KML файла
+type Report struct {
+	// Placemarks количество меток, сведённых к точке
+	Placemarks int
+	// Rejected количество меток без геометрии или с неверными координатами
+	Rejected int
+	// Collapsed количество сложных меток по составу геометрии,
+	// например «MultiGeometry(Point, LineString) → point»
+	Collapsed map[string]int
+	// Skipped количество пропущенных наложений и сетевых ссылок по типу элемента
+	Skipped map[string]int
+}
+
+func (r *Report) collapse(kinds []string, method string) {
+	if r.Collapsed == nil {
+		r.Collapsed = make(map[string]int)
+	}
+	r.Collapsed[strings.Join(kinds, ", ")+" → "+method]++
+}
+
+func (r *Report) skip(element string) {
+	if r.Skipped == nil {
+		r.Skipped = make(map[string]int)
+	}
+	r.Skipped[element]++
+}
+
+func (r Report) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "placemarks %d, rejected %d", r.Placemarks, r.Rejected)
+	if len(r.Collapsed) > 0 {
+		b.WriteString("; collapsed to one point: ")
+		b.WriteString(counts(r.Collapsed))
+	}
+	if len(r.Skipped) > 0 {
+		b.WriteString("; skipped: ")
+		b.WriteString(counts(r.Skipped))
+	}
+	return b.String()
+}
+
+func counts(m map[string]int) string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = fmt.Sprintf("%v %d", k, m[k])
+	}
+	return strings.Join(parts, ", ")
+}
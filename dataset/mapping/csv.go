@@ -0,0 +1,390 @@
+package mapping
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/audetv/datasets-parser/app/repos/dataset"
+	"github.com/audetv/datasets-parser/dataset/coordinate"
+	"github.com/audetv/datasets-parser/dataset/input"
+	"github.com/google/uuid"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+)
+
+var _ dataset.Store = &Entries{}
+var _ dataset.Failer = &Entries{}
+
+// Entries читает csv файл по правилам маппинга
+type Entries struct {
+	src     dataset.Source
+	mapping *Mapping
+	layout  *layout
+	comma   rune
+	// err ошибка, прервавшая чтение в ReadAll
+	err error
+}
+
+// Sniff читает заголовок файла csv маппинга: определяет разделитель и читает первую
+// запись, у книги Excel — первую непустую строку первого выбранного листа
+func Sniff(src dataset.Source) (dataset.Header, error) {
+	if IsXLSX(src.Name) {
+		return sniffWorkbook(src)
+	}
+	t, err := openText(src)
+	if err != nil {
+		return dataset.Header{}, err
+	}
+	defer t.Close()
+
+	sample := t.Sample()
+	comma := delimiter(src, sample, 0)
+
+	reader := csv.NewReader(bytes.NewReader(sample))
+	reader.Comma = comma
+	reader.LazyQuotes = true
+	reader.FieldsPerRecord = -1
+	columns, err := reader.Read()
+	if err != nil {
+		return dataset.Header{}, fmt.Errorf("read header: %w", err)
+	}
+
+	return dataset.Header{Delimiter: comma, Columns: columns}, nil
+}
+
+// openText открывает файл в UTF-8: определяет кодировку, если она не задана
+// в Options, убирает BOM и перекодирует содержимое
+func openText(src dataset.Source) (*input.Text, error) {
+	rc, err := src.Open()
+	if err != nil {
+		return nil, err
+	}
+	return input.Open(rc, src.Options.Encoding)
+}
+
+// delimiter возвращает разделитель полей csv файла: заданный в Options,
+// иначе fallback, если первая строка делится им на несколько полей,
+// иначе определённый по началу файла
+func delimiter(src dataset.Source, sample []byte, fallback rune) rune {
+	if src.Options.Delimiter != 0 {
+		return src.Options.Delimiter
+	}
+	if fallback != 0 && input.CountFields(sample, fallback) > 1 {
+		return fallback
+	}
+	return input.SniffDelimiter(sample)
+}
+
+// NewCSVEntries определяет кодировку и разделитель файла, проверяет заголовок
+// по схеме маппинга и возвращает *SchemaError, если файл ей больше не соответствует
+func NewCSVEntries(src dataset.Source, mapping *Mapping) (*Entries, error) {
+	f, err := openText(src)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", src.Name, err)
+	}
+	defer f.Close()
+
+	if f.Encoding != input.UTF8 {
+		log.Printf("%v: кодировка %v, перекодируем в utf-8\n", src.Name, f.Encoding)
+	}
+
+	es := &Entries{
+		src:     src,
+		mapping: mapping,
+		comma:   delimiter(src, f.Sample(), mapping.Comma()),
+	}
+
+	record, err := es.newReader(f).Read()
+	if err != nil {
+		return nil, fmt.Errorf("%v: read header: %w", src.Name, err)
+	}
+
+	es.layout, err = mapping.resolve(record, src.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	return es, nil
+}
+
+func (e *Entries) newReader(r io.Reader) *csv.Reader {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = e.mapping.FieldsPerRecord
+	reader.Comma = e.comma
+	reader.LazyQuotes = e.mapping.LazyQuotes
+	return reader
+}
+
+// Err возвращает ошибку, прервавшую чтение файла, после закрытия канала ReadAll
+func (e *Entries) Err() error {
+	return e.err
+}
+
+// Columns возвращает имена колонок заголовка файла
+func (e *Entries) Columns() []string {
+	return e.layout.columns
+}
+
+// Comma возвращает разделитель полей файла
+func (e *Entries) Comma() rune {
+	return e.comma
+}
+
+func (e *Entries) ReadAll(ctx context.Context) (chan dataset.Entry, error) {
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	chout := make(chan dataset.Entry, 100)
+
+	go func() {
+		defer close(chout)
+
+		// Открываем dataset файл
+		f, err := openText(e.src)
+		if err != nil {
+			e.err = fmt.Errorf("%v: %w", e.src.Name, err)
+			return
+		}
+		defer f.Close()
+
+		// Создаём новый CSV reader, читающий записи из открытого файла.
+		// recorder сохраняет исходный текст записей, которые не удалось разобрать.
+		rec := &recorder{r: f}
+		reader := e.newReader(rec)
+
+		// Заголовок уже проверен в NewCSVEntries
+		if _, err = reader.Read(); err != nil {
+			e.err = fmt.Errorf("%v: read header: %w", e.src.Name, err)
+			return
+		}
+		rec.take(reader.InputOffset())
+
+		for {
+			// Read in a row. Check if we are at the end of the file.
+			record, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			raw := rec.take(reader.InputOffset())
+
+			var entry dataset.Entry
+			var parseErr *csv.ParseError
+			switch {
+			case errors.As(err, &parseErr):
+				// при неверном количестве полей запись всё равно возвращается
+				if record == nil {
+					record = []string{raw}
+				}
+				entry = dataset.Entry{
+					Line: parseErr.StartLine,
+					Raw:  record,
+					Errors: []dataset.RowError{{
+						File:   e.src.Name,
+						Line:   parseErr.StartLine,
+						Value:  raw,
+						Reason: parseErr.Err.Error(),
+					}},
+				}
+			case err != nil:
+				e.err = fmt.Errorf("%v: %w", e.src.Name, err)
+				return
+			default:
+				line, _ := reader.FieldPos(0)
+				entry = e.layout.entry(e.mapping, record, e.src.Name, line)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case chout <- entry:
+			}
+		}
+	}()
+
+	return chout, nil
+}
+
+// entry собирает dataset.Entry из строки csv файла. Если координаты
+// не разбираются, запись возвращается с ошибками и исходными полями.
+func (l *layout) entry(m *Mapping, record []string, file string, line int) dataset.Entry {
+	entry := dataset.Entry{
+		Name:            m.Entry.Name.value(record, l.name),
+		Description:     m.Entry.Description.value(record, l.description),
+		DescriptionJson: l.descriptionJson(m, record),
+		Line:            line,
+	}
+	lon, lonOk := l.parseCoordinate(&entry, record, l.longitude, coordinate.Longitude, file)
+	lat, latOk := l.parseCoordinate(&entry, record, l.latitude, coordinate.Latitude, file)
+	if lonOk && latOk {
+		p, warning, err := coordinate.Check(lat, coordinate.NormalizeLongitude(lon))
+		if err != nil {
+			entry.Errors = append(entry.Errors, dataset.RowError{
+				File:   file,
+				Line:   line,
+				Column: l.column(l.latitude) + ", " + l.column(l.longitude),
+				Value:  field(record, l.latitude) + ", " + field(record, l.longitude),
+				Reason: err.Error(),
+			})
+		}
+		entry.Warn(warning)
+		entry.Longitude, entry.Latitude = p.Longitude, p.Latitude
+	}
+	if l.height >= 0 {
+		entry.Height = l.parseHeight(&entry, record, file)
+	}
+	if l.id >= 0 {
+		entry.ID = l.parseID(&entry, record, file)
+	}
+	if entry.Rejected() {
+		entry.Raw = record
+	}
+
+	return entry
+}
+
+func (l *layout) descriptionJson(m *Mapping, record []string) map[string]interface{} {
+	dj := make(map[string]interface{}, len(m.DescriptionJson))
+	for i, f := range m.DescriptionJson {
+		value := field(record, l.json[i])
+		if value == "" {
+			continue
+		}
+		switch f.Type {
+		case "int":
+			n, _ := strconv.Atoi(value)
+			if n == 0 {
+				continue
+			}
+			dj[f.Key] = n
+		default:
+			dj[f.Key] = value
+		}
+	}
+	return dj
+}
+
+func field(record []string, idx int) string {
+	if idx < 0 || idx >= len(record) {
+		return ""
+	}
+	return record[idx]
+}
+
+func (t Text) value(record []string, idxs []int) string {
+	values := make([]interface{}, 0, len(idxs))
+	parts := make([]string, 0, len(idxs))
+	for _, idx := range idxs {
+		v := field(record, idx)
+		values = append(values, v)
+		if v != "" {
+			parts = append(parts, v)
+		}
+	}
+
+	var s string
+	if t.Format != "" {
+		s = fmt.Sprintf(t.Format, values...)
+	} else {
+		separator := t.Separator
+		if separator == "" {
+			separator = " "
+		}
+		s = strings.Join(parts, separator)
+	}
+
+	if s == "" {
+		return t.Default
+	}
+	return s
+}
+
+// parseCoordinate разбирает координату оси axis, ошибку добавляет в entry.Errors
+func (l *layout) parseCoordinate(entry *dataset.Entry, record []string, idx int, axis coordinate.Axis, file string) (float64, bool) {
+	value := field(record, idx)
+	v, err := coordinate.Parse(value, axis)
+	if err != nil {
+		entry.Errors = append(entry.Errors, l.error(entry, idx, value, file, err))
+		return 0, false
+	}
+	return v, true
+}
+
+// parseHeight разбирает высоту, пустая высота считается нулевой
+func (l *layout) parseHeight(entry *dataset.Entry, record []string, file string) float64 {
+	value := field(record, l.height)
+	v, err := coordinate.ParseDecimal(value)
+	if errors.Is(err, coordinate.ErrEmpty) {
+		return 0
+	}
+	if err != nil {
+		entry.Errors = append(entry.Errors, l.error(entry, l.height, value, file, err))
+		return 0
+	}
+	return v
+}
+
+// parseID разбирает uuid записи, пустой uuid будет сгенерирован при сохранении
+func (l *layout) parseID(entry *dataset.Entry, record []string, file string) uuid.UUID {
+	value := strings.TrimSpace(field(record, l.id))
+	if value == "" {
+		return uuid.Nil
+	}
+	id, err := uuid.Parse(value)
+	if err != nil {
+		entry.Errors = append(entry.Errors, l.error(entry, l.id, value, file, err))
+		return uuid.Nil
+	}
+	return id
+}
+
+func (l *layout) error(entry *dataset.Entry, idx int, value string, file string, err error) dataset.RowError {
+	return dataset.RowError{
+		File:   file,
+		Line:   entry.Line,
+		Column: l.column(idx),
+		Value:  value,
+		Reason: err.Error(),
+	}
+}
+
+// column возвращает имя колонки для сообщений об ошибках
+func (l *layout) column(idx int) string {
+	if idx >= 0 && idx < len(l.columns) && l.columns[idx] != "" {
+		return l.columns[idx]
+	}
+	return fmt.Sprintf("#%d", idx)
+}
+
+// recorder запоминает прочитанный текст, чтобы по смещениям csv.Reader
+// восстановить исходную строку записи
+type recorder struct {
+	r      io.Reader
+	buf    []byte
+	offset int64
+}
+
+func (r *recorder) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.buf = append(r.buf, p[:n]...)
+	return n, err
+}
+
+// take возвращает текст от предыдущего смещения до end и забывает его
+func (r *recorder) take(end int64) string {
+	n := int(end - r.offset)
+	if n > len(r.buf) {
+		n = len(r.buf)
+	}
+	s := strings.TrimRight(string(r.buf[:n]), "\r\n")
+	r.buf = append(r.buf[:0], r.buf[n:]...)
+	r.offset = end
+	return s
+}
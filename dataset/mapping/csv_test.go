@@ -0,0 +1,153 @@
+package mapping
+
+import (
+	"context"
+	"github.com/audetv/datasets-parser/app/repos/dataset"
+	"github.com/audetv/datasets-parser/dataset/coordinate"
+	"github.com/google/uuid"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const placesMapping = `{
+  "name": "places",
+  "files": ["places.csv"],
+  "delimiter": ";",
+  "header": ["name", "lat", "lon"],
+  "entry": {"name": "name", "longitude": "lon", "latitude": "lat"}
+}`
+
+// readEntries читает записи файла с содержимым data по маппингу placesMapping
+func readEntries(t *testing.T, data string) (*Entries, []dataset.Entry) {
+	t.Helper()
+	m, err := Parse([]byte(placesMapping))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return readCSV(t, m, "places.csv", data)
+}
+
+// readCSV читает данные data как файл name по маппингу m
+func readCSV(t *testing.T, m *Mapping, name string, data string) (*Entries, []dataset.Entry) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	es, err := NewCSVEntries(dataset.FileSource(name, path), m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch, err := es.ReadAll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []dataset.Entry
+	for e := range ch {
+		entries = append(entries, e)
+	}
+	return es, entries
+}
+
+func TestEntriesTable(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		comma   rune
+		columns []string
+	}{
+		{"mapping delimiter", "name;lat;lon\nМосква;55.75;37.62\n", ';', []string{"name", "lat", "lon"}},
+		// разделитель маппинга не делит заголовок, он определяется по файлу
+		{"sniffed delimiter", "Name,Lon,Lat,Extra\nМосква,37.62,55.75,1\n", ',', []string{"Name", "Lon", "Lat", "Extra"}},
+	}
+	for _, tt := range tests {
+		es, entries := readEntries(t, tt.data)
+		var table dataset.Table = es
+		if table.Comma() != tt.comma || !reflect.DeepEqual(table.Columns(), tt.columns) {
+			t.Errorf("%v: comma %q, columns %q", tt.name, table.Comma(), table.Columns())
+		}
+		if len(entries) != 1 || entries[0].Rejected() || entries[0].Latitude != 55.75 || entries[0].Longitude != 37.62 {
+			t.Errorf("%v: entries %+v", tt.name, entries)
+		}
+	}
+}
+
+func TestEntriesRejected(t *testing.T) {
+	_, entries := readEntries(t, "name;lat;lon\nМосква;55.75;37.62\nТверь;north;\nПсков;57.82\nОрёл;52.97;36.07\n")
+	if len(entries) != 4 {
+		t.Fatalf("entries %+v", entries)
+	}
+	if entries[0].Rejected() || entries[3].Rejected() || entries[3].Line != 5 {
+		t.Errorf("valid entries %+v, %+v", entries[0], entries[3])
+	}
+
+	// у записи с неразобранными полями ошибка на каждое поле и исходные поля
+	bad := entries[1]
+	want := []dataset.RowError{
+		{File: "places.csv", Line: 3, Column: "lon", Value: "", Reason: "empty value"},
+		{File: "places.csv", Line: 3, Column: "lat", Value: "north", Reason: `invalid coordinate: "north"`},
+	}
+	if bad.Line != 3 || !reflect.DeepEqual(bad.Errors, want) || !reflect.DeepEqual(bad.Raw, []string{"Тверь", "north", ""}) {
+		t.Errorf("bad fields %+v", bad)
+	}
+
+	// запись с неверным количеством полей отклоняется целиком
+	short := entries[2]
+	if short.Line != 4 || len(short.Errors) != 1 || short.Errors[0].Column != "" ||
+		short.Errors[0].Value != "Псков;57.82" || !reflect.DeepEqual(short.Raw, []string{"Псков", "57.82"}) {
+		t.Errorf("short record %+v", short)
+	}
+}
+
+func TestEntriesCoordinateWarning(t *testing.T) {
+	_, entries := readEntries(t, "name;lat;lon\nМосква;137.62;55.75\nНоль;0;0\nЮг;-91;-91\n")
+	if len(entries) != 3 {
+		t.Fatalf("entries %+v", entries)
+	}
+
+	// перепутанные местами координаты переставляются, запись сохраняется с предупреждением
+	swapped := entries[0]
+	if swapped.Rejected() || swapped.Latitude != 55.75 || swapped.Longitude != 137.62 {
+		t.Errorf("swapped %+v", swapped)
+	}
+	if w, _ := swapped.DescriptionJson.(map[string]interface{})[dataset.CoordinateWarning].(string); !strings.Contains(w, "swapped") {
+		t.Errorf("swapped warning %q", w)
+	}
+	if w := entries[1].DescriptionJson.(map[string]interface{})[dataset.CoordinateWarning]; entries[1].Rejected() || w != coordinate.ErrNullIsland.Error() {
+		t.Errorf("null island %+v", entries[1])
+	}
+	if !entries[2].Rejected() {
+		t.Errorf("out of range %+v", entries[2])
+	}
+}
+
+func TestEntriesID(t *testing.T) {
+	ms, err := Builtin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := "id;lat;lon;height;name;description;geohash;uuid;gh4;gh_n;gh_ne;gh_e;gh_se;gh_s;gh_sw;gh_w;gh_nw\n" +
+		"1;55.75;37.62;156;Москва;столица;ucfv0;0f8fad5b-d9cb-469f-a165-70867728950e;;;;;;;;;\n" +
+		"2;59.94;30.31;0;;;;;;;;;;;;;\n" +
+		"3;56.33;44;0;Нижний;;;not-a-uuid;;;;;;;;;\n"
+	_, entries := readCSV(t, ms["geomatrix_marks"], "geomatrix_marks.csv", data)
+	if len(entries) != 3 {
+		t.Fatalf("entries %d, want 3", len(entries))
+	}
+
+	moscow := entries[0]
+	if moscow.ID.String() != "0f8fad5b-d9cb-469f-a165-70867728950e" || moscow.Name != "Москва" || moscow.Height != 156 ||
+		!reflect.DeepEqual(moscow.DescriptionJson, map[string]interface{}{"geomatrix_id": 1}) {
+		t.Errorf("moscow %+v", moscow)
+	}
+	// пустой uuid генерируется при сохранении
+	if entries[1].Rejected() || entries[1].ID != uuid.Nil || entries[1].Name != "untitled" {
+		t.Errorf("without uuid %+v", entries[1])
+	}
+	if bad := entries[2]; !bad.Rejected() || bad.Errors[0].Column != "uuid" {
+		t.Errorf("bad uuid %+v", bad)
+	}
+}
@@ -0,0 +1,359 @@
+package mapping
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/audetv/datasets-parser/app/repos/dataset"
+	"github.com/audetv/datasets-parser/dataset/coordinate"
+	"io"
+)
+
+var _ dataset.Store = &GeoJSONEntries{}
+var _ dataset.Failer = &GeoJSONEntries{}
+var _ dataset.Table = &GeoJSONEntries{}
+
+// GeoJSONEntries читает объекты GeoJSON по правилам маппинга: колонки маппинга —
+// это свойства объекта, свойства, не ставшие названием и описанием,
+// попадают в DescriptionJson. Файл читается потоком, объект за объектом.
+type GeoJSONEntries struct {
+	src     dataset.Source
+	mapping *Mapping
+	// err ошибка, прервавшая чтение в ReadAll
+	err error
+}
+
+// NewGeoJSONEntries возвращает Store для FeatureCollection, отдельного Feature
+// или последовательности объектов Feature, по одному на строку
+func NewGeoJSONEntries(src dataset.Source, mapping *Mapping) *GeoJSONEntries {
+	return &GeoJSONEntries{
+		src:     src,
+		mapping: mapping,
+	}
+}
+
+// Err возвращает ошибку, прервавшую чтение файла, после закрытия канала ReadAll
+func (e *GeoJSONEntries) Err() error {
+	return e.err
+}
+
+// Columns колонки отклонённых объектов в карантине: объект целиком
+func (e *GeoJSONEntries) Columns() []string {
+	return []string{"feature"}
+}
+
+// Comma разделитель полей карантина
+func (e *GeoJSONEntries) Comma() rune {
+	return ';'
+}
+
+func (e *GeoJSONEntries) ReadAll(ctx context.Context) (chan dataset.Entry, error) {
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	chout := make(chan dataset.Entry, 100)
+
+	go func() {
+		defer close(chout)
+
+		f, err := openText(e.src)
+		if err != nil {
+			e.err = fmt.Errorf("%v: %w", e.src.Name, err)
+			return
+		}
+		defer f.Close()
+
+		// n номер объекта в файле, начиная с 1
+		n := 0
+		err = walkFeatures(json.NewDecoder(&recordSeparators{r: f}), func(raw json.RawMessage) bool {
+			n++
+			select {
+			case <-ctx.Done():
+				return false
+			case chout <- e.entry(raw, n):
+				return true
+			}
+		})
+		if err != nil {
+			e.err = fmt.Errorf("%v: %w", e.src.Name, err)
+		}
+	}()
+
+	return chout, nil
+}
+
+// walkFeatures передаёт в emit объекты Feature документа, не загружая
+// FeatureCollection целиком. emit возвращает false, если чтение надо прекратить.
+func walkFeatures(d *json.Decoder, emit func(json.RawMessage) bool) error {
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if tok != json.Delim('{') {
+			return fmt.Errorf("expected geojson object, got %v", tok)
+		}
+
+		// свойства верхнего объекта, кроме features
+		object := make(map[string]json.RawMessage)
+		streamed := false
+		for d.More() {
+			tok, err = d.Token()
+			if err != nil {
+				return err
+			}
+			key, _ := tok.(string)
+			if key != "features" {
+				var raw json.RawMessage
+				if err = d.Decode(&raw); err != nil {
+					return err
+				}
+				object[key] = raw
+				continue
+			}
+
+			if tok, err = d.Token(); err != nil {
+				return err
+			}
+			if tok != json.Delim('[') {
+				return fmt.Errorf("expected features array, got %v", tok)
+			}
+			for d.More() {
+				var raw json.RawMessage
+				if err = d.Decode(&raw); err != nil {
+					return err
+				}
+				if !emit(raw) {
+					return nil
+				}
+			}
+			if _, err = d.Token(); err != nil {
+				return err
+			}
+			streamed = true
+		}
+		if _, err = d.Token(); err != nil {
+			return err
+		}
+
+		if streamed {
+			continue
+		}
+		var kind string
+		_ = json.Unmarshal(object["type"], &kind)
+		if kind != "Feature" {
+			return fmt.Errorf("expected FeatureCollection or Feature, got %q", kind)
+		}
+		raw, err := json.Marshal(object)
+		if err != nil {
+			return err
+		}
+		if !emit(raw) {
+			return nil
+		}
+	}
+}
+
+// feature объект GeoJSON
+type feature struct {
+	ID         interface{}            `json:"id"`
+	Geometry   *geometry              `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type geometry struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+	Geometries  []geometry      `json:"geometries"`
+}
+
+// entry собирает dataset.Entry из объекта Feature. n — номер объекта в файле.
+func (e *GeoJSONEntries) entry(raw json.RawMessage, n int) dataset.Entry {
+	entry := dataset.Entry{Line: n}
+	reject := func(column string, value string, reason string) dataset.Entry {
+		entry.Errors = append(entry.Errors, dataset.RowError{
+			File: e.src.Name, Line: n, Column: column, Value: value, Reason: reason,
+		})
+		entry.Raw = []string{string(raw)}
+		return entry
+	}
+
+	var f feature
+	d := json.NewDecoder(bytes.NewReader(raw))
+	d.UseNumber()
+	if err := d.Decode(&f); err != nil {
+		return reject("", "", err.Error())
+	}
+
+	dj := e.mapping.fromProperties(&entry, f.Properties)
+	if f.ID != nil {
+		if _, ok := dj["id"]; !ok {
+			dj["id"] = f.ID
+		}
+	}
+
+	if f.Geometry == nil {
+		return reject("geometry", "", "feature has no geometry")
+	}
+	var s geoShape
+	if err := s.collect(*f.Geometry); err != nil {
+		return reject("geometry", f.Geometry.Type, err.Error())
+	}
+	p, ok := s.point()
+	if !ok {
+		return reject("geometry", f.Geometry.Type, "geometry has no coordinates")
+	}
+	p.Longitude = coordinate.NormalizeLongitude(p.Longitude)
+	c, warning, err := coordinate.Check(p.Latitude, p.Longitude)
+	if err != nil {
+		return reject("geometry", fmt.Sprintf("%v,%v", p.Longitude, p.Latitude), err.Error())
+	}
+	entry.Warn(warning)
+
+	entry.Longitude, entry.Latitude, entry.Height = c.Longitude, c.Latitude, s.height
+	if !s.simple() {
+		dj["geometry"] = f.Geometry.Type
+	}
+	return entry
+}
+
+// geoShape геометрия объекта, сведённая к точкам
+type geoShape struct {
+	kinds []string
+	// points явно заданные точки Point
+	points []coordinate.Point
+	// vertices точки MultiPoint, вершины линий и внешних границ полигонов
+	vertices []coordinate.Point
+	height   float64
+}
+
+func (s *geoShape) collect(g geometry) error {
+	s.kinds = append(s.kinds, g.Type)
+
+	var err error
+	switch g.Type {
+	case "Point":
+		var c []float64
+		if err = json.Unmarshal(g.Coordinates, &c); err == nil {
+			err = s.add(&s.points, c)
+			if len(s.points) == 1 && len(c) > 2 {
+				s.height = c[2]
+			}
+		}
+	case "MultiPoint", "LineString":
+		var cs [][]float64
+		if err = json.Unmarshal(g.Coordinates, &cs); err == nil {
+			err = s.addAll(cs, false)
+		}
+	case "MultiLineString":
+		var lines [][][]float64
+		if err = json.Unmarshal(g.Coordinates, &lines); err == nil {
+			for _, line := range lines {
+				if err = s.addAll(line, false); err != nil {
+					break
+				}
+			}
+		}
+	case "Polygon":
+		// центр полигона определяется внешней границей, вырезы не учитываются
+		var rings [][][]float64
+		if err = json.Unmarshal(g.Coordinates, &rings); err == nil && len(rings) > 0 {
+			err = s.addAll(rings[0], true)
+		}
+	case "MultiPolygon":
+		var polygons [][][][]float64
+		if err = json.Unmarshal(g.Coordinates, &polygons); err == nil {
+			for _, rings := range polygons {
+				if len(rings) == 0 {
+					continue
+				}
+				if err = s.addAll(rings[0], true); err != nil {
+					break
+				}
+			}
+		}
+	case "GeometryCollection":
+		for _, child := range g.Geometries {
+			if err = s.collect(child); err != nil {
+				break
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported geometry type %q", g.Type)
+	}
+	if err != nil {
+		return fmt.Errorf("%v: %w", g.Type, err)
+	}
+	return nil
+}
+
+// addAll добавляет вершины линии или кольца, у замкнутого кольца
+// последняя вершина повторяет первую и отбрасывается
+func (s *geoShape) addAll(line [][]float64, ring bool) error {
+	if ring && len(line) > 1 && equalPosition(line[0], line[len(line)-1]) {
+		line = line[:len(line)-1]
+	}
+	for _, c := range line {
+		if err := s.add(&s.vertices, c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *geoShape) add(target *[]coordinate.Point, c []float64) error {
+	if len(c) < 2 {
+		return fmt.Errorf("position %v has less than 2 coordinates", c)
+	}
+	*target = append(*target, coordinate.Point{Longitude: c[0], Latitude: c[1]})
+	return nil
+}
+
+func equalPosition(a []float64, b []float64) bool {
+	return len(a) >= 2 && len(b) >= 2 && a[0] == b[0] && a[1] == b[1]
+}
+
+// point сводит геометрию к одной точке: первая точка Point, в том числе
+// внутри GeometryCollection, иначе сферический центр точек MultiPoint,
+// вершин линий и полигонов
+func (s *geoShape) point() (coordinate.Point, bool) {
+	if len(s.points) > 0 {
+		return s.points[0], true
+	}
+	if c, ok := coordinate.Centroid(s.vertices); ok {
+		return c, true
+	}
+	if len(s.vertices) > 0 {
+		return s.vertices[0], true
+	}
+	return coordinate.Point{}, false
+}
+
+// simple сообщает, что геометрия — одна точка и упрощать нечего
+func (s *geoShape) simple() bool {
+	return len(s.kinds) == 1 && s.kinds[0] == "Point"
+}
+
+// recordSeparators убирает символы RS (0x1E), которыми разделяются объекты
+// в последовательностях GeoJSON Text Sequences (RFC 8142)
+type recordSeparators struct {
+	r io.Reader
+}
+
+func (rs *recordSeparators) Read(p []byte) (int, error) {
+	n, err := rs.r.Read(p)
+	for i := 0; i < n; i++ {
+		if p[i] == 0x1e {
+			p[i] = ' '
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,89 @@
+package mapping
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/audetv/datasets-parser/app/repos/dataset"
+	"math"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// readGeoJSON читает файл из testdata встроенным маппингом geojson
+func readGeoJSON(t *testing.T, name string) []dataset.Entry {
+	t.Helper()
+	ms, err := Builtin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := ms["geojson"].Reader().New(dataset.FileSource(name, filepath.Join("testdata", name)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ch, err := store.ReadAll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []dataset.Entry
+	for e := range ch {
+		entries = append(entries, e)
+	}
+	if err = store.(dataset.Failer).Err(); err != nil {
+		t.Fatal(err)
+	}
+	return entries
+}
+
+func TestGeoJSONEntries(t *testing.T) {
+	entries := readGeoJSON(t, "places.geojson")
+	if len(entries) != 6 {
+		t.Fatalf("entries %d, want 6", len(entries))
+	}
+
+	// свойства ищутся без учёта регистра, остальные свойства и id попадают в description_json
+	moscow := entries[0]
+	want := map[string]interface{}{"population": json.Number("13010112"), "id": json.Number("1")}
+	if moscow.Name != "Москва" || moscow.Description != "столица" || moscow.Line != 1 ||
+		moscow.Longitude != 37.62 || moscow.Latitude != 55.75 || moscow.Height != 156 ||
+		!reflect.DeepEqual(moscow.DescriptionJson, want) {
+		t.Errorf("moscow %+v", moscow)
+	}
+
+	// вырезы полигона не учитываются
+	square := entries[1]
+	dj := square.DescriptionJson.(map[string]interface{})
+	if square.Name != "Квадрат" || math.Abs(square.Longitude-11) > 1e-9 || math.Abs(square.Latitude-21) > 0.01 ||
+		dj["geometry"] != "Polygon" {
+		t.Errorf("square %+v", square)
+	}
+
+	// точка внутри GeometryCollection важнее центра линии
+	collection := entries[2]
+	if collection.Longitude != 30.5 || collection.Latitude != 50.45 ||
+		collection.DescriptionJson.(map[string]interface{})["geometry"] != "GeometryCollection" {
+		t.Errorf("collection %+v", collection)
+	}
+
+	empty := entries[3]
+	if !empty.Rejected() || empty.Line != 4 || empty.Errors[0].Reason != "feature has no geometry" || len(empty.Raw) != 1 {
+		t.Errorf("empty %+v", empty)
+	}
+
+	swapped := entries[4]
+	if swapped.Rejected() || swapped.Latitude != 55.75 || swapped.Longitude != 137.62 ||
+		swapped.DescriptionJson.(map[string]interface{})[dataset.CoordinateWarning] == nil {
+		t.Errorf("swapped %+v", swapped)
+	}
+
+	if entries[5].Name != "untitled" || entries[5].Longitude != -90 {
+		t.Errorf("untitled %+v", entries[5])
+	}
+}
+
+func TestGeoJSONTextSequence(t *testing.T) {
+	entries := readGeoJSON(t, "places.geojsons")
+	if len(entries) != 2 || entries[0].Name != "Тверь" || entries[1].Name != "Псков" || entries[1].Line != 2 {
+		t.Errorf("entries %+v", entries)
+	}
+}
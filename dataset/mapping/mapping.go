@@ -0,0 +1,368 @@
+package mapping
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"github.com/audetv/datasets-parser/app/repos/dataset"
+	"github.com/audetv/datasets-parser/dataset/shapefile"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+//go:embed mappings/*.json
+var builtin embed.FS
+
+// Форматы файлов, которые читаются по правилам маппинга
+const (
+	FormatCSV       = "csv"
+	FormatGeoJSON   = "geojson"
+	FormatShapefile = "shapefile"
+)
+
+// Mapping декларативное описание csv файла набора данных:
+// разделитель, количество полей, ожидаемый заголовок
+// и то, какие колонки становятся полями dataset.Entry.
+// Для GeoJSON колонками считаются свойства объектов, для shapefile — поля dbf.
+// Книги Excel (.xlsx), подходящие под шаблоны csv маппинга, читаются
+// по тем же правилам, колонками считаются ячейки строк листа.
+type Mapping struct {
+	Name    string   `json:"name"`
+	Format  string   `json:"format,omitempty"`
+	Files   []string `json:"files"`
+	Regexps []string `json:"regexps,omitempty"`
+	// Sheets glob шаблоны листов книги Excel, по умолчанию читаются все видимые листы
+	Sheets          []string    `json:"sheets,omitempty"`
+	Delimiter       string      `json:"delimiter"`
+	FieldsPerRecord int         `json:"fields_per_record"`
+	Header          []Column    `json:"header,omitempty"`
+	Entry           Entry       `json:"entry"`
+	DescriptionJson []JsonField `json:"description_json"`
+
+	// LazyQuotes разрешает кавычки внутри полей без экранирования,
+	// они встречаются в html описаниях, выгруженных из kml
+	LazyQuotes bool `json:"lazy_quotes,omitempty"`
+}
+
+// Entry колонки, из которых собираются поля dataset.Entry
+type Entry struct {
+	// ID колонка с uuid, который сохраняется как ID сущности
+	ID          *Column `json:"id,omitempty"`
+	Name        Text    `json:"name"`
+	Description Text    `json:"description"`
+	Longitude   Column  `json:"longitude"`
+	Latitude    Column  `json:"latitude"`
+	Height      *Column `json:"height,omitempty"`
+}
+
+// Column колонка csv файла. Колонка ищется по имени в заголовке файла
+// с учётом синонимов, а если имя не задано — по номеру, начиная с 0.
+// В файле маппинга задаётся номером, строкой с именем,
+// массивом строк (имя и синонимы) или объектом.
+type Column struct {
+	Header  string   `json:"header,omitempty"`
+	Aliases []string `json:"aliases,omitempty"`
+	Index   int      `json:"index,omitempty"`
+}
+
+// Text строковое поле, собираемое из одной или нескольких колонок.
+// В файле маппинга может быть задано числом — номером колонки,
+// или объектом с перечнем колонок, форматом и значением по умолчанию.
+type Text struct {
+	Columns   []Column `json:"columns"`
+	Format    string   `json:"format,omitempty"`
+	Separator string   `json:"separator,omitempty"`
+	Default   string   `json:"default,omitempty"`
+}
+
+// JsonField колонка, которая попадает в DescriptionJson под ключом Key
+type JsonField struct {
+	Key    string `json:"key"`
+	Column Column `json:"column"`
+	// Type тип значения: string (по умолчанию) или int
+	Type string `json:"type,omitempty"`
+}
+
+func (c *Column) UnmarshalJSON(data []byte) error {
+	var index int
+	if err := json.Unmarshal(data, &index); err == nil {
+		*c = Column{Index: index}
+		return nil
+	}
+
+	var header string
+	if err := json.Unmarshal(data, &header); err == nil {
+		*c = Column{Header: header}
+		return nil
+	}
+
+	var names []string
+	if err := json.Unmarshal(data, &names); err == nil {
+		if len(names) == 0 {
+			return fmt.Errorf("empty column names list")
+		}
+		*c = Column{Header: names[0], Aliases: names[1:]}
+		return nil
+	}
+
+	type column Column
+	var v column
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*c = Column(v)
+	return nil
+}
+
+// Names возвращает имя колонки и её синонимы
+func (c Column) Names() []string {
+	return append([]string{c.Header}, c.Aliases...)
+}
+
+func (c Column) String() string {
+	if c.Header == "" {
+		return fmt.Sprintf("#%d", c.Index)
+	}
+	return strings.Join(c.Names(), "|")
+}
+
+func (t *Text) UnmarshalJSON(data []byte) error {
+	var column Column
+	if data[0] != '{' {
+		if err := json.Unmarshal(data, &column); err != nil {
+			return err
+		}
+		t.Columns = []Column{column}
+		return nil
+	}
+
+	type text Text
+	var v text
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*t = Text(v)
+	return nil
+}
+
+// Mappings набор маппингов, индексированный по имени
+type Mappings map[string]*Mapping
+
+// Parse читает маппинг из json документа и проверяет его
+func Parse(data []byte) (*Mapping, error) {
+	var m Mapping
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	if err := m.validate(); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+func (m *Mapping) validate() error {
+	if m.Name == "" {
+		return fmt.Errorf("mapping name is empty")
+	}
+	for _, f := range m.Files {
+		if _, err := filepath.Match(f, ""); err != nil {
+			return fmt.Errorf("mapping %v: bad file pattern %q: %w", m.Name, f, err)
+		}
+	}
+	for _, re := range m.Regexps {
+		if _, err := regexp.Compile(re); err != nil {
+			return fmt.Errorf("mapping %v: bad file regexp %q: %w", m.Name, re, err)
+		}
+	}
+	for _, s := range m.Sheets {
+		if _, err := path.Match(s, ""); err != nil {
+			return fmt.Errorf("mapping %v: bad sheet pattern %q: %w", m.Name, s, err)
+		}
+	}
+	if len([]rune(m.Delimiter)) > 1 {
+		return fmt.Errorf("mapping %v: delimiter must be a single character, got %q", m.Name, m.Delimiter)
+	}
+	if len(m.Entry.Name.Columns) == 0 {
+		return fmt.Errorf("mapping %v: entry name column is not set", m.Name)
+	}
+	switch m.Format {
+	case "", FormatCSV:
+	case FormatGeoJSON, FormatShapefile:
+		// свойства GeoJSON объекта не упорядочены, поэтому и они,
+		// и поля dbf ищутся только по имени
+		for _, c := range m.columns() {
+			if c.Header == "" {
+				return fmt.Errorf("mapping %v: %v property must be set by name, got %v", m.Name, m.Format, c)
+			}
+		}
+		if m.Entry.ID != nil {
+			return fmt.Errorf("mapping %v: entry id is not supported for %v", m.Name, m.Format)
+		}
+	default:
+		return fmt.Errorf("mapping %v: unsupported format %q", m.Name, m.Format)
+	}
+	for _, f := range m.DescriptionJson {
+		if f.Key == "" {
+			return fmt.Errorf("mapping %v: description_json field for column %v has no key", m.Name, f.Column)
+		}
+		if f.Type != "" && f.Type != "string" && f.Type != "int" {
+			return fmt.Errorf("mapping %v: unsupported type %q of description_json field %v", m.Name, f.Type, f.Key)
+		}
+	}
+	return nil
+}
+
+// Comma возвращает разделитель полей, 0 если он не задан и определяется по файлу
+func (m *Mapping) Comma() rune {
+	if m.Delimiter == "" {
+		return 0
+	}
+	return []rune(m.Delimiter)[0]
+}
+
+// Fingerprint возвращает имена колонок, по которым узнаётся файл набора данных:
+// схему заголовка, а если она не задана — колонки, на которые ссылается маппинг.
+// У GeoJSON и shapefile нет текстового заголовка, такие файлы узнаются только по имени.
+func (m *Mapping) Fingerprint() []string {
+	if m.Format == FormatGeoJSON || m.Format == FormatShapefile {
+		return nil
+	}
+	columns := m.Header
+	if len(columns) == 0 {
+		if m.Entry.ID != nil {
+			columns = append(columns, *m.Entry.ID)
+		}
+		columns = append(columns, m.Entry.Name.Columns...)
+		columns = append(columns, m.Entry.Description.Columns...)
+		columns = append(columns, m.Entry.Longitude, m.Entry.Latitude)
+		if m.Entry.Height != nil {
+			columns = append(columns, *m.Entry.Height)
+		}
+		for _, f := range m.DescriptionJson {
+			columns = append(columns, f.Column)
+		}
+	}
+
+	var fingerprint []string
+	for _, c := range columns {
+		if c.Header != "" {
+			fingerprint = appendUnique(fingerprint, c.String())
+		}
+	}
+	return fingerprint
+}
+
+// columns возвращает текстовые колонки и колонки description_json маппинга
+func (m *Mapping) columns() []Column {
+	var columns []Column
+	columns = append(columns, m.Entry.Name.Columns...)
+	columns = append(columns, m.Entry.Description.Columns...)
+	for _, f := range m.DescriptionJson {
+		columns = append(columns, f.Column)
+	}
+	return columns
+}
+
+// Reader возвращает описание читателя набора данных для реестра
+func (m *Mapping) Reader() dataset.Reader {
+	r := dataset.Reader{
+		Name:        m.Name,
+		Patterns:    m.Files,
+		Fingerprint: m.Fingerprint(),
+		New: func(src dataset.Source) (dataset.Store, error) {
+			switch m.Format {
+			case FormatGeoJSON:
+				return NewGeoJSONEntries(src, m), nil
+			case FormatShapefile:
+				return NewShapefileEntries(src, m), nil
+			}
+			if IsXLSX(src.Name) {
+				return NewXLSXEntries(src, m)
+			}
+			return NewCSVEntries(src, m)
+		},
+	}
+	if m.Format == FormatShapefile {
+		r.Companions = shapefile.Companions
+	}
+	if len(r.Fingerprint) > 0 {
+		r.Sniff = Sniff
+	}
+	for _, re := range m.Regexps {
+		r.Regexps = append(r.Regexps, regexp.MustCompile(re))
+	}
+	return r
+}
+
+// Builtin возвращает маппинги, встроенные в приложение
+func Builtin() (Mappings, error) {
+	ms := Mappings{}
+	if err := ms.load(builtin, "mappings"); err != nil {
+		return nil, err
+	}
+	return ms, nil
+}
+
+// Load возвращает встроенные маппинги, дополненные маппингами из папки dir.
+// Маппинг из папки с тем же именем заменяет встроенный.
+func Load(dir string) (Mappings, error) {
+	ms, err := Builtin()
+	if err != nil {
+		return nil, err
+	}
+	if dir == "" {
+		return ms, nil
+	}
+	if err = ms.load(os.DirFS(dir), "."); err != nil {
+		return nil, err
+	}
+	return ms, nil
+}
+
+func (ms Mappings) load(fsys fs.FS, dir string) error {
+	files, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return err
+	}
+	for _, file := range files {
+		if file.IsDir() || !strings.EqualFold(filepath.Ext(file.Name()), ".json") {
+			continue
+		}
+		data, err := fs.ReadFile(fsys, filepath.ToSlash(filepath.Join(dir, file.Name())))
+		if err != nil {
+			return err
+		}
+		m, err := Parse(data)
+		if err != nil {
+			return fmt.Errorf("%v: %w", file.Name(), err)
+		}
+		ms[m.Name] = m
+	}
+	return nil
+}
+
+// Register регистрирует маппинги в реестре читателей наборов данных,
+// заменяя зарегистрированных ранее читателей с теми же именами
+func (ms Mappings) Register(registry *dataset.Registry) error {
+	for _, name := range ms.Names() {
+		if err := registry.Replace(ms[name].Reader()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Names возвращает отсортированный список имён маппингов
+func (ms Mappings) Names() []string {
+	names := make([]string, 0, len(ms))
+	for name := range ms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
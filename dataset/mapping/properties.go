@@ -0,0 +1,84 @@
+package mapping
+
+import (
+	"encoding/json"
+	"github.com/audetv/datasets-parser/app/repos/dataset"
+	"strings"
+)
+
+// fromProperties заполняет название, описание и DescriptionJson записи по свойствам
+// объекта GeoJSON или атрибутам shapefile: колонки маппинга ищутся среди свойств
+// по имени или синониму, остальные свойства попадают в DescriptionJson как есть
+func (m *Mapping) fromProperties(entry *dataset.Entry, values map[string]interface{}) map[string]interface{} {
+	props := newProperties(values)
+	columns := m.columns()
+	record := make([]string, len(columns))
+	for i, c := range columns {
+		record[i] = props.value(c)
+	}
+	idxs := func(from int, count int) []int {
+		idx := make([]int, count)
+		for i := range idx {
+			idx[i] = from + i
+		}
+		return idx
+	}
+	nameCount, descriptionCount := len(m.Entry.Name.Columns), len(m.Entry.Description.Columns)
+	entry.Name = m.Entry.Name.value(record, idxs(0, nameCount))
+	entry.Description = m.Entry.Description.value(record, idxs(nameCount, descriptionCount))
+
+	l := &layout{json: idxs(nameCount+descriptionCount, len(m.DescriptionJson))}
+	dj := l.descriptionJson(m, record)
+	for key, v := range values {
+		if !props.used[key] && v != nil {
+			dj[key] = v
+		}
+	}
+	entry.DescriptionJson = dj
+	return dj
+}
+
+// properties свойства объекта с поиском по имени без учёта регистра
+type properties struct {
+	values map[string]interface{}
+	lower  map[string]string
+	// used свойства, на которые ссылается маппинг
+	used map[string]bool
+}
+
+func newProperties(values map[string]interface{}) *properties {
+	p := &properties{
+		values: values,
+		lower:  make(map[string]string, len(values)),
+		used:   make(map[string]bool),
+	}
+	for key := range values {
+		if _, ok := p.lower[strings.ToLower(key)]; !ok {
+			p.lower[strings.ToLower(key)] = key
+		}
+	}
+	return p
+}
+
+// value возвращает значение свойства колонки c строкой, пустую строку если его нет
+func (p *properties) value(c Column) string {
+	for _, name := range c.Names() {
+		key, ok := p.lower[strings.ToLower(name)]
+		if !ok {
+			continue
+		}
+		p.used[key] = true
+		switch v := p.values[key].(type) {
+		case nil:
+			return ""
+		case string:
+			return v
+		case json.Number:
+			return v.String()
+		default:
+			b, _ := json.Marshal(v)
+			return string(b)
+		}
+	}
+	return ""
+}
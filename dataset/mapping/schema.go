@@ -0,0 +1,171 @@
+package mapping
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// SchemaError заголовок файла не соответствует схеме набора данных
+type SchemaError struct {
+	Mapping    string
+	Path       string
+	Expected   []string
+	Actual     []string
+	Missing    []string
+	Unexpected []string
+}
+
+func (e *SchemaError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%v: header does not match schema %q\n", e.Path, e.Mapping)
+	fmt.Fprintf(&b, "  expected:   %v\n", strings.Join(e.Expected, "; "))
+	fmt.Fprintf(&b, "  actual:     %v\n", strings.Join(e.Actual, "; "))
+	if len(e.Missing) > 0 {
+		fmt.Fprintf(&b, "  missing:    %v\n", strings.Join(e.Missing, "; "))
+	}
+	if len(e.Unexpected) > 0 {
+		fmt.Fprintf(&b, "  unexpected: %v\n", strings.Join(e.Unexpected, "; "))
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// layout номера колонок файла, из которых собираются поля dataset.Entry
+type layout struct {
+	id          int
+	name        []int
+	description []int
+	longitude   int
+	latitude    int
+	height      int
+	json        []int
+	// columns имена колонок заголовка файла для сообщений об ошибках
+	columns []string
+}
+
+// header хранит нормализованные имена колонок заголовка файла
+type header struct {
+	names []string
+	index map[string]int
+}
+
+func newHeader(record []string) *header {
+	h := &header{
+		names: make([]string, len(record)),
+		index: make(map[string]int, len(record)),
+	}
+	for i, name := range record {
+		name = normalizeHeader(name)
+		h.names[i] = name
+		if _, ok := h.index[strings.ToLower(name)]; !ok {
+			h.index[strings.ToLower(name)] = i
+		}
+	}
+	return h
+}
+
+// normalizeHeader убирает BOM и пробелы вокруг имени колонки
+func normalizeHeader(name string) string {
+	return strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+}
+
+// find возвращает номер колонки в заголовке, -1 если колонка не найдена
+func (h *header) find(c Column) int {
+	if c.Header == "" {
+		if c.Index < len(h.names) {
+			return c.Index
+		}
+		return -1
+	}
+	for _, name := range c.Names() {
+		if i, ok := h.index[strings.ToLower(normalizeHeader(name))]; ok {
+			return i
+		}
+	}
+	return -1
+}
+
+// resolve сопоставляет колонки маппинга с заголовком файла.
+// Если заголовок не содержит колонок, описанных в маппинге,
+// возвращается *SchemaError с разницей ожидаемого и фактического заголовков,
+// о лишних колонках только пишется предупреждение в лог.
+func (m *Mapping) resolve(record []string, path string) (*layout, error) {
+	h := newHeader(record)
+
+	var missing, referenced []string
+	find := func(c Column) int {
+		referenced = appendUnique(referenced, c.String())
+		i := h.find(c)
+		if i < 0 {
+			missing = appendUnique(missing, c.String())
+		}
+		return i
+	}
+
+	l := &layout{id: -1, height: -1, columns: h.names}
+	if m.Entry.ID != nil {
+		l.id = find(*m.Entry.ID)
+	}
+	for _, c := range m.Entry.Name.Columns {
+		l.name = append(l.name, find(c))
+	}
+	for _, c := range m.Entry.Description.Columns {
+		l.description = append(l.description, find(c))
+	}
+	l.longitude = find(m.Entry.Longitude)
+	l.latitude = find(m.Entry.Latitude)
+	if m.Entry.Height != nil {
+		l.height = find(*m.Entry.Height)
+	}
+	for _, f := range m.DescriptionJson {
+		l.json = append(l.json, find(f.Column))
+	}
+
+	// ожидаемый заголовок — схема из маппинга, а если она не задана,
+	// то перечень колонок, на которые ссылается маппинг
+	expected := referenced
+	known := make(map[int]bool, len(m.Header))
+	if len(m.Header) > 0 {
+		expected = make([]string, 0, len(m.Header))
+		for _, c := range m.Header {
+			expected = append(expected, c.String())
+			if i := find(c); i >= 0 {
+				known[i] = true
+			}
+		}
+	}
+
+	var unexpected []string
+	if len(m.Header) > 0 {
+		for i, name := range h.names {
+			if !known[i] {
+				unexpected = append(unexpected, name)
+			}
+		}
+	}
+
+	if len(missing) > 0 {
+		return nil, &SchemaError{
+			Mapping:    m.Name,
+			Path:       path,
+			Expected:   expected,
+			Actual:     h.names,
+			Missing:    missing,
+			Unexpected: unexpected,
+		}
+	}
+	if len(unexpected) > 0 {
+		log.Printf("%v: columns not described by schema %q: %v\n", path, m.Name, strings.Join(unexpected, "; "))
+	}
+
+	return l, nil
+}
+
+func appendUnique(list []string, s string) []string {
+	for _, v := range list {
+		if v == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
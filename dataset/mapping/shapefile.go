@@ -0,0 +1,133 @@
+package mapping
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/audetv/datasets-parser/app/repos/dataset"
+	"github.com/audetv/datasets-parser/dataset/coordinate"
+	"github.com/audetv/datasets-parser/dataset/shapefile"
+	"io"
+	"math"
+	"strconv"
+)
+
+var _ dataset.Store = &ShapefileEntries{}
+var _ dataset.Failer = &ShapefileEntries{}
+var _ dataset.Table = &ShapefileEntries{}
+
+// ShapefileEntries читает записи shapefile по правилам маппинга: колонки маппинга —
+// это поля dbf, поля, не ставшие названием и описанием, попадают в DescriptionJson.
+// Координаты пересчитываются в градусы по .prj, линии и полигоны сводятся к точке.
+type ShapefileEntries struct {
+	src     dataset.Source
+	mapping *Mapping
+	// err ошибка, прервавшая чтение в ReadAll
+	err error
+}
+
+func NewShapefileEntries(src dataset.Source, mapping *Mapping) *ShapefileEntries {
+	return &ShapefileEntries{
+		src:     src,
+		mapping: mapping,
+	}
+}
+
+// Err возвращает ошибку, прервавшую чтение файла, после закрытия канала ReadAll
+func (e *ShapefileEntries) Err() error {
+	return e.err
+}
+
+// Columns колонки отклонённых записей в карантине: тип геометрии и атрибуты в json
+func (e *ShapefileEntries) Columns() []string {
+	return []string{"shape", "attributes"}
+}
+
+// Comma разделитель полей карантина
+func (e *ShapefileEntries) Comma() rune {
+	return ';'
+}
+
+func (e *ShapefileEntries) ReadAll(ctx context.Context) (chan dataset.Entry, error) {
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	chout := make(chan dataset.Entry, 100)
+
+	go func() {
+		defer close(chout)
+
+		r, err := shapefile.Open(e.src)
+		if err != nil {
+			e.err = fmt.Errorf("%v: %w", e.src.Name, err)
+			return
+		}
+		defer r.Close()
+
+		for {
+			rec, err := r.Next()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				e.err = fmt.Errorf("%v: %w", e.src.Name, err)
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case chout <- e.entry(rec):
+			}
+		}
+	}()
+
+	return chout, nil
+}
+
+// entry собирает dataset.Entry из записи shapefile
+func (e *ShapefileEntries) entry(rec *shapefile.Record) dataset.Entry {
+	entry := dataset.Entry{Line: rec.Number}
+	dj := e.mapping.fromProperties(&entry, rec.Attributes)
+
+	kind := ""
+	if rec.Shape != nil {
+		kind = rec.Shape.Type.String()
+	}
+	reject := func(value string, reason string) dataset.Entry {
+		entry.Errors = append(entry.Errors, dataset.RowError{
+			File: e.src.Name, Line: rec.Number, Column: "shape", Value: value, Reason: reason,
+		})
+		attributes, _ := json.Marshal(rec.Attributes)
+		entry.Raw = []string{kind, string(attributes)}
+		return entry
+	}
+
+	if rec.Err != nil {
+		return reject("", rec.Err.Error())
+	}
+	p, ok := rec.Shape.Point()
+	if !ok {
+		return reject(kind, "shape has no coordinates")
+	}
+	if math.IsNaN(p.Latitude) || math.IsNaN(p.Longitude) {
+		return reject(kind, "shape coordinates cannot be converted to degrees")
+	}
+	lat, lon := p.Latitude, p.Longitude
+	p, warning, err := coordinate.Check(lat, coordinate.NormalizeLongitude(lon))
+	if err != nil {
+		return reject(strconv.FormatFloat(lon, 'f', -1, 64)+","+strconv.FormatFloat(lat, 'f', -1, 64), err.Error())
+	}
+	entry.Warn(warning)
+
+	entry.Longitude, entry.Latitude, entry.Height = p.Longitude, p.Latitude, rec.Shape.Height()
+	switch rec.Shape.Type {
+	case shapefile.Point, shapefile.PointM, shapefile.PointZ:
+	default:
+		dj["geometry"] = kind
+	}
+	return entry
+}
@@ -0,0 +1,196 @@
+package mapping
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/audetv/datasets-parser/app/repos/dataset"
+	"github.com/audetv/datasets-parser/dataset/xlsx"
+	"log"
+	"path"
+	"strings"
+)
+
+var _ dataset.Store = &XLSXEntries{}
+var _ dataset.Failer = &XLSXEntries{}
+var _ dataset.Table = &XLSXEntries{}
+
+// IsXLSX сообщает, что файл — книга Excel, которую csv маппинг читает по листам
+func IsXLSX(filename string) bool {
+	return strings.EqualFold(path.Ext(filename), ".xlsx")
+}
+
+// sniffWorkbook читает первую непустую строку первого листа книги, выбранного флагом --sheet
+func sniffWorkbook(src dataset.Source) (dataset.Header, error) {
+	rc, err := src.Open()
+	if err != nil {
+		return dataset.Header{}, err
+	}
+	book, err := xlsx.Read(rc)
+	rc.Close()
+	if err != nil {
+		return dataset.Header{}, err
+	}
+	sheets, err := book.Select(src.Options.Sheets)
+	if err != nil {
+		return dataset.Header{}, err
+	}
+	_, columns, err := book.Header(sheets[0])
+	if err != nil {
+		return dataset.Header{}, fmt.Errorf("read header: %w", err)
+	}
+	return dataset.Header{Columns: columns}, nil
+}
+
+// XLSXEntries читает листы книги Excel по правилам csv маппинга: первая непустая
+// строка листа — заголовок, колонки маппинга ищутся в нём так же, как в csv.
+// Разделитель и количество полей маппинга к книге не относятся.
+type XLSXEntries struct {
+	src     dataset.Source
+	mapping *Mapping
+	book    *xlsx.Workbook
+	sheets  []sheet
+	// err ошибка, прервавшая чтение в ReadAll
+	err error
+}
+
+// sheet лист книги, заголовок которого соответствует маппингу
+type sheet struct {
+	xlsx.Sheet
+	layout *layout
+	// header номер строки заголовка
+	header int
+}
+
+// NewXLSXEntries читает книгу и выбирает листы: заданные флагом --sheet,
+// иначе в маппинге, иначе все видимые. Заголовок каждого листа проверяется
+// по схеме маппинга. Явно выбранный лист, не соответствующий схеме, — ошибка,
+// а из всех листов такие пропускаются, если подошёл хотя бы один.
+func NewXLSXEntries(src dataset.Source, mapping *Mapping) (*XLSXEntries, error) {
+	rc, err := src.Open()
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", src.Name, err)
+	}
+	book, err := xlsx.Read(rc)
+	rc.Close()
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", src.Name, err)
+	}
+
+	patterns := src.Options.Sheets
+	if len(patterns) == 0 {
+		patterns = mapping.Sheets
+	}
+	selected, err := book.Select(patterns)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", src.Name, err)
+	}
+
+	es := &XLSXEntries{src: src, mapping: mapping, book: book}
+	var mismatch error
+	for _, s := range selected {
+		row, header, err := book.Header(s)
+		if err == nil {
+			var l *layout
+			if l, err = mapping.resolve(header, es.path(s.Name)); err == nil {
+				es.sheets = append(es.sheets, sheet{Sheet: s, layout: l, header: row})
+				continue
+			}
+		}
+		// SchemaError уже содержит имя книги и листа
+		var schemaErr *SchemaError
+		isSchemaErr := errors.As(err, &schemaErr)
+		if !isSchemaErr {
+			err = fmt.Errorf("%v: %w", src.Name, err)
+		}
+		if len(patterns) > 0 || !isSchemaErr && !errors.Is(err, xlsx.ErrEmptySheet) {
+			return nil, err
+		}
+		log.Printf("%v: лист %q пропущен: %v\n", src.Name, s.Name, err)
+		if mismatch == nil {
+			mismatch = err
+		}
+	}
+	if len(es.sheets) == 0 {
+		return nil, mismatch
+	}
+	return es, nil
+}
+
+// path имя листа для сообщений об ошибках: «книга.xlsx/Лист1»
+func (e *XLSXEntries) path(sheet string) string {
+	return e.src.Name + "/" + sheet
+}
+
+// Err возвращает ошибку, прервавшую чтение файла, после закрытия канала ReadAll
+func (e *XLSXEntries) Err() error {
+	return e.err
+}
+
+// Columns колонки отклонённых строк в карантине: имя листа и колонки заголовка первого листа
+func (e *XLSXEntries) Columns() []string {
+	return append([]string{"sheet"}, e.sheets[0].layout.columns...)
+}
+
+// Comma разделитель полей карантина
+func (e *XLSXEntries) Comma() rune {
+	return ';'
+}
+
+func (e *XLSXEntries) ReadAll(ctx context.Context) (chan dataset.Entry, error) {
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	chout := make(chan dataset.Entry, 100)
+
+	go func() {
+		defer close(chout)
+
+		for _, s := range e.sheets {
+			stopped := false
+			err := e.book.Rows(s.Sheet, func(row int, cells []string) bool {
+				if row <= s.header {
+					return true
+				}
+				select {
+				case <-ctx.Done():
+					stopped = true
+				case chout <- e.entry(&s, cells, row):
+				}
+				return !stopped
+			})
+			if err != nil {
+				e.err = fmt.Errorf("%v: %w", e.src.Name, err)
+				return
+			}
+			if stopped {
+				return
+			}
+		}
+	}()
+
+	return chout, nil
+}
+
+// entry собирает dataset.Entry из строки листа. Имя листа записывается
+// в DescriptionJson под ключом sheet, если маппинг не занял этот ключ.
+func (e *XLSXEntries) entry(s *sheet, cells []string, row int) dataset.Entry {
+	// пустые ячейки в конце строки в книге не хранятся
+	for len(cells) < len(s.layout.columns) {
+		cells = append(cells, "")
+	}
+	entry := s.layout.entry(e.mapping, cells, e.path(s.Name), row)
+	if dj, ok := entry.DescriptionJson.(map[string]interface{}); ok {
+		if _, taken := dj["sheet"]; !taken {
+			dj["sheet"] = s.Name
+		}
+	}
+	if entry.Rejected() {
+		entry.Raw = append([]string{s.Name}, cells...)
+	}
+	return entry
+}
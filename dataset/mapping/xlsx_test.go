@@ -0,0 +1,93 @@
+package mapping
+
+import (
+	"context"
+	"errors"
+	"github.com/audetv/datasets-parser/app/repos/dataset"
+	"reflect"
+	"testing"
+)
+
+// workbook книга с листами «Места», скрытым «Скрытый» и пустым «Пустой», см. dataset/xlsx
+const workbook = "../xlsx/testdata/places.xlsx"
+
+const workbookMapping = `{
+  "name": "places",
+  "files": ["places*.xlsx"],
+  "entry": {
+    "name": "name",
+    "description": {"columns": ["note"], "default": ""},
+    "latitude": "lat",
+    "longitude": "lon"
+  },
+  "description_json": [
+    {"key": "founded", "column": "founded"}
+  ]
+}`
+
+func TestXLSXEntries(t *testing.T) {
+	m, err := Parse([]byte(workbookMapping))
+	if err != nil {
+		t.Fatal(err)
+	}
+	src := dataset.FileSource("places.xlsx", workbook)
+	es, err := NewXLSXEntries(src, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"sheet", "name", "lat", "lon", "note", "founded"}; !reflect.DeepEqual(es.Columns(), want) {
+		t.Errorf("quarantine columns %v, want %v", es.Columns(), want)
+	}
+
+	ch, err := es.ReadAll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []dataset.Entry
+	for e := range ch {
+		entries = append(entries, e)
+	}
+	if err = es.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []dataset.Entry{
+		{Name: "Москва", Description: "столица", Latitude: 55.7539, Longitude: 37.6204, Line: 3,
+			DescriptionJson: map[string]interface{}{"founded": "2017-01-01", "sheet": "Места"}},
+		{Name: "Санкт-Петербург", Latitude: 59.9386, Longitude: 30.3141, Line: 4,
+			DescriptionJson: map[string]interface{}{"founded": "2003-06-28 12:00:00", "sheet": "Места"}},
+		{Name: "Казань", Latitude: 55.7963, Longitude: 49.1088, Line: 6,
+			DescriptionJson: map[string]interface{}{"founded": "18:00:00", "sheet": "Места"}},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("entries\n%+v\nwant\n%+v", entries, want)
+	}
+}
+
+func TestXLSXSheetSelection(t *testing.T) {
+	m, err := Parse([]byte(workbookMapping))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// явно выбранный лист не по схеме — ошибка
+	src := dataset.FileSource("places.xlsx", workbook)
+	src.Options.Sheets = []string{"Скрытый"}
+	var schemaErr *SchemaError
+	if _, err = NewXLSXEntries(src, m); !errors.As(err, &schemaErr) || schemaErr.Path != "places.xlsx/Скрытый" {
+		t.Errorf("schema error %v", err)
+	}
+
+	src.Options.Sheets = []string{"Нет такого"}
+	if _, err = NewXLSXEntries(src, m); err == nil {
+		t.Error("missing sheet is not reported")
+	}
+
+	header, err := Sniff(dataset.FileSource("places.xlsx", workbook))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"name", "lat", "lon", "note", "founded"}; header.Delimiter != 0 || !reflect.DeepEqual(header.Columns, want) {
+		t.Errorf("sniffed header %q %q", header.Delimiter, header.Columns)
+	}
+}
@@ -0,0 +1,139 @@
+package osm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultFilter отбор объектов по умолчанию: исторические объекты,
+// культовые сооружения и башни
+const DefaultFilter = "historic,amenity=place_of_worship,man_made=tower"
+
+// Filter выражение отбора объектов по тегам. Условия через запятую
+// объединяются по «или», теги условия через «&» — по «и».
+// Тег: «key» или «key=*» — тег есть, «key=v1|v2» — одно из значений,
+// «key!=v1|v2» — тега нет или значение другое.
+type Filter struct {
+	text    string
+	clauses [][]condition
+}
+
+type condition struct {
+	key    string
+	values []string
+	// not условие выполняется, если значение тега не из values
+	not bool
+}
+
+// ParseFilter разбирает выражение отбора, например «historic,amenity=place_of_worship&religion=christian»
+func ParseFilter(expr string) (Filter, error) {
+	f := Filter{text: expr}
+	for _, c := range strings.Split(expr, ",") {
+		if strings.TrimSpace(c) == "" {
+			continue
+		}
+		var clause []condition
+		for _, t := range strings.Split(c, "&") {
+			cond, err := parseCondition(strings.TrimSpace(t))
+			if err != nil {
+				return Filter{}, fmt.Errorf("osm filter %q: %w", expr, err)
+			}
+			clause = append(clause, cond)
+		}
+		f.clauses = append(f.clauses, clause)
+	}
+	if len(f.clauses) == 0 {
+		return Filter{}, fmt.Errorf("osm filter is empty")
+	}
+	return f, nil
+}
+
+// MustParseFilter разбирает выражение отбора и паникует при ошибке
+func MustParseFilter(expr string) Filter {
+	f, err := ParseFilter(expr)
+	if err != nil {
+		panic(err)
+	}
+	return f
+}
+
+func parseCondition(t string) (condition, error) {
+	var c condition
+	key, value, found := strings.Cut(t, "=")
+	if strings.HasSuffix(key, "!") {
+		c.not = true
+		key = strings.TrimSuffix(key, "!")
+	}
+	c.key = strings.TrimSpace(key)
+	if c.key == "" {
+		return condition{}, fmt.Errorf("tag key is empty in %q", t)
+	}
+	if !found {
+		return c, nil
+	}
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return condition{}, fmt.Errorf("tag value is empty in %q, use %v=* for any value", t, c.key)
+	}
+	if value == "*" {
+		if c.not {
+			return condition{}, fmt.Errorf("%q: %v!=* is not supported", t, c.key)
+		}
+		return c, nil
+	}
+	for _, v := range strings.Split(value, "|") {
+		c.values = append(c.values, strings.TrimSpace(v))
+	}
+	return c, nil
+}
+
+// Match сообщает, подходит ли объект с тегами tags под выражение
+func (f Filter) Match(tags map[string]string) bool {
+	for _, clause := range f.clauses {
+		ok := true
+		for _, c := range clause {
+			if !c.match(tags) {
+				ok = false
+				break
+			}
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (c condition) match(tags map[string]string) bool {
+	v, ok := tags[c.key]
+	if len(c.values) == 0 {
+		return ok
+	}
+	in := false
+	if ok {
+		for _, value := range c.values {
+			if v == value {
+				in = true
+				break
+			}
+		}
+	}
+	return in != c.not
+}
+
+// label возвращает «key=value» первого тега условий, найденного у объекта,
+// им называется объект без имени
+func (f Filter) label(tags map[string]string) string {
+	for _, clause := range f.clauses {
+		for _, c := range clause {
+			if v, ok := tags[c.key]; ok && !c.not {
+				return c.key + "=" + v
+			}
+		}
+	}
+	return ""
+}
+
+func (f Filter) String() string {
+	return f.text
+}
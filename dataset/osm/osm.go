@@ -0,0 +1,265 @@
+// Package osm читает выгрузки OpenStreetMap в форматах XML (.osm) и PBF (.osm.pbf):
+// узлы и центры линий, отобранные по тегам.
+package osm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/audetv/datasets-parser/app/repos/dataset"
+	"github.com/audetv/datasets-parser/dataset/coordinate"
+	"log"
+	"path"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	dataset.MustRegister(NewReader(MustParseFilter(DefaultFilter)))
+}
+
+// NewReader возвращает описание читателя OSM файлов, отбирающего объекты по filter.
+// Чтобы сменить отбор, читатель заменяется в реестре через Registry.Replace.
+func NewReader(filter Filter) dataset.Reader {
+	return dataset.Reader{
+		Name:     "osm",
+		Patterns: []string{"*.osm", "*.pbf"},
+		New: func(src dataset.Source) (dataset.Store, error) {
+			return NewEntries(src, filter), nil
+		},
+	}
+}
+
+var _ dataset.Store = &Entries{}
+var _ dataset.Failer = &Entries{}
+var _ dataset.Table = &Entries{}
+
+// Entries читает узлы и линии OSM файла, подходящие под фильтр тегов.
+// Линия сводится к сферическому центру своих узлов, отношения не читаются.
+type Entries struct {
+	src    dataset.Source
+	filter Filter
+	// err ошибка, прервавшая чтение в ReadAll
+	err error
+	// Report сколько объектов отобрано, заполняется в ReadAll
+	Report Report
+}
+
+func NewEntries(src dataset.Source, filter Filter) *Entries {
+	return &Entries{src: src, filter: filter}
+}
+
+// Err возвращает ошибку, прервавшую чтение файла, после закрытия канала ReadAll
+func (e *Entries) Err() error {
+	return e.err
+}
+
+// Columns колонки отклонённых объектов в карантине
+func (e *Entries) Columns() []string {
+	return []string{"osm_type", "osm_id", "tags"}
+}
+
+// Comma разделитель полей карантина
+func (e *Entries) Comma() rune {
+	return ';'
+}
+
+const (
+	nodeKind = "node"
+	wayKind  = "way"
+)
+
+// element узел или линия OSM
+type element struct {
+	kind     string
+	id       int64
+	lat, lon float64
+	tags     map[string]string
+	// refs узлы линии
+	refs []int64
+	// line номер строки в XML или порядковый номер объекта в PBF
+	line int
+}
+
+// Report сводка отобранных объектов OSM файла
+type Report struct {
+	Nodes    int
+	Ways     int
+	Rejected int
+}
+
+func (r Report) String() string {
+	return fmt.Sprintf("nodes %d, ways %d, rejected %d", r.Nodes, r.Ways, r.Rejected)
+}
+
+// ReadAll читает файл в два прохода: в первом отправляет отобранные узлы
+// и запоминает отобранные линии, во втором находит координаты их узлов.
+// В памяти держатся только отобранные линии и их узлы.
+func (e *Entries) ReadAll(ctx context.Context) (chan dataset.Entry, error) {
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+
+	chout := make(chan dataset.Entry, 100)
+
+	go func() {
+		defer close(chout)
+
+		send := func(entry dataset.Entry) bool {
+			select {
+			case <-ctx.Done():
+				return false
+			case chout <- entry:
+				return true
+			}
+		}
+
+		e.Report = Report{}
+		var ways []*element
+		nodes := make(map[int64]*coordinate.Point)
+		err := e.walk(true, func(el *element) bool {
+			if !e.filter.Match(el.tags) {
+				return true
+			}
+			if el.kind == nodeKind {
+				return send(e.entry(el, []coordinate.Point{{Latitude: el.lat, Longitude: el.lon}}, 0))
+			}
+			ways = append(ways, el)
+			for _, ref := range el.refs {
+				nodes[ref] = nil
+			}
+			return true
+		})
+		if err != nil {
+			e.err = fmt.Errorf("%v: %w", e.src.Name, err)
+			return
+		}
+
+		if len(ways) > 0 && ctx.Err() == nil {
+			err = e.walk(false, func(el *element) bool {
+				if _, ok := nodes[el.id]; ok {
+					nodes[el.id] = &coordinate.Point{Latitude: el.lat, Longitude: el.lon}
+				}
+				return ctx.Err() == nil
+			})
+			if err != nil {
+				e.err = fmt.Errorf("%v: %w", e.src.Name, err)
+				return
+			}
+			for _, w := range ways {
+				refs := w.refs
+				// у замкнутой линии последний узел повторяет первый
+				if len(refs) > 1 && refs[0] == refs[len(refs)-1] {
+					refs = refs[:len(refs)-1]
+				}
+				var points []coordinate.Point
+				for _, ref := range refs {
+					if p := nodes[ref]; p != nil {
+						points = append(points, *p)
+					}
+				}
+				if !send(e.entry(w, points, len(refs)-len(points))) {
+					return
+				}
+			}
+		}
+
+		if ctx.Err() == nil {
+			log.Printf("%v: %v (filter %v)\n", e.src.Name, e.Report, e.filter)
+		}
+	}()
+
+	return chout, nil
+}
+
+// walk открывает файл и передаёт в emit его узлы и, если ways, линии
+func (e *Entries) walk(ways bool, emit func(*element) bool) error {
+	rc, err := e.src.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	if strings.EqualFold(path.Ext(e.src.Name), ".pbf") {
+		return walkPBF(rc, ways, emit)
+	}
+	return walkXML(rc, ways, emit)
+}
+
+// entry собирает dataset.Entry из объекта: points — координаты узла
+// или найденных узлов линии, missing — сколько узлов линии нет в выгрузке
+func (e *Entries) entry(el *element, points []coordinate.Point, missing int) dataset.Entry {
+	name := firstTag(el.tags, "name:ru", "name")
+	if name == "" {
+		name = e.filter.label(el.tags)
+	}
+	if name == "" {
+		name = "untitled"
+	}
+
+	dj := map[string]interface{}{
+		"osm_type": el.kind,
+		"osm_id":   el.id,
+	}
+	if len(el.tags) > 0 {
+		dj["tags"] = el.tags
+	}
+	entry := dataset.Entry{
+		Name:            name,
+		Description:     firstTag(el.tags, "description:ru", "description"),
+		DescriptionJson: dj,
+		Line:            el.line,
+	}
+	if ele, ok := el.tags["ele"]; ok {
+		entry.Height, _ = coordinate.ParseDecimal(ele)
+	}
+
+	reject := func(column string, value string, reason string) dataset.Entry {
+		entry.Errors = append(entry.Errors, dataset.RowError{
+			File: e.src.Name, Line: el.line, Column: column, Value: value, Reason: reason,
+		})
+		tags, _ := json.Marshal(el.tags)
+		entry.Raw = []string{el.kind, strconv.FormatInt(el.id, 10), string(tags)}
+		e.Report.Rejected++
+		return entry
+	}
+
+	if len(points) == 0 {
+		return reject("nd", "", "way nodes are not in the extract")
+	}
+	p := points[0]
+	if c, ok := coordinate.Centroid(points); ok && len(points) > 1 {
+		p = c
+	}
+	lat, lon := p.Latitude, p.Longitude
+	p, warning, err := coordinate.Check(lat, lon)
+	if err != nil {
+		return reject("lat, lon", fmt.Sprintf("%v, %v", lat, lon), err.Error())
+	}
+	entry.Warn(warning)
+	entry.Latitude, entry.Longitude = p.Latitude, p.Longitude
+
+	if el.kind == wayKind {
+		dj["geometry"] = wayKind
+		dj["nodes"] = len(points)
+		if missing > 0 {
+			// линия обрезана границей выгрузки
+			dj["missing_nodes"] = missing
+		}
+		e.Report.Ways++
+	} else {
+		e.Report.Nodes++
+	}
+	return entry
+}
+
+func firstTag(tags map[string]string, keys ...string) string {
+	for _, k := range keys {
+		if v := strings.TrimSpace(tags[k]); v != "" {
+			return v
+		}
+	}
+	return ""
+}
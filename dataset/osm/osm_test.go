@@ -0,0 +1,152 @@
+package osm
+
+import (
+	"context"
+	"encoding/binary"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/audetv/datasets-parser/app/repos/dataset"
+)
+
+// readAll читает файл testdata фильтром по умолчанию
+func readAll(t *testing.T, name string) []dataset.Entry {
+	es := NewEntries(dataset.FileSource(name, "testdata/"+name), MustParseFilter(DefaultFilter))
+	ch, err := es.ReadAll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var entries []dataset.Entry
+	for e := range ch {
+		entries = append(entries, e)
+	}
+	if err = es.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return entries
+}
+
+// fixture.osm и fixture.osm.pbf — одна и та же выгрузка: узел Node и узлы DenseNodes,
+// замкнутая линия, линия с узлом за границей выгрузки, линия и узел без нужных тегов
+// и отношение, которое не читается
+func TestXMLAndPBFGiveSameEntries(t *testing.T) {
+	want := []struct {
+		name     string
+		lat, lon float64
+	}{
+		{"Памятник", 55.3101, 38.1302},
+		{"Храм", 55.752, 37.6175},
+		{"Кремль", 55.7505, 37.611},
+		{"man_made=tower", 55.75, 37.61},
+	}
+	xml := readAll(t, "fixture.osm")
+	pbf := readAll(t, "fixture.osm.pbf")
+	if len(xml) != len(want) || len(pbf) != len(want) {
+		t.Fatalf("entries: xml %d, pbf %d, want %d", len(xml), len(pbf), len(want))
+	}
+	for i, w := range want {
+		x, p := xml[i], pbf[i]
+		if x.Name != w.name || p.Name != w.name {
+			t.Errorf("entry %d: names %q and %q, want %q", i, x.Name, p.Name, w.name)
+		}
+		// центр линии вычисляется на сфере, поэтому допуск больше шага координат PBF
+		for _, e := range []dataset.Entry{x, p} {
+			if math.Abs(e.Latitude-w.lat) > 1e-4 || math.Abs(e.Longitude-w.lon) > 1e-4 {
+				t.Errorf("%v: point %v, %v, want %v, %v", w.name, e.Latitude, e.Longitude, w.lat, w.lon)
+			}
+		}
+		if math.Abs(x.Latitude-p.Latitude) > 1e-9 || math.Abs(x.Longitude-p.Longitude) > 1e-9 {
+			t.Errorf("%v: xml point %v, %v, pbf point %v, %v", w.name, x.Latitude, x.Longitude, p.Latitude, p.Longitude)
+		}
+		if !reflect.DeepEqual(x.DescriptionJson, p.DescriptionJson) {
+			t.Errorf("%v: xml description_json %v, pbf %v", w.name, x.DescriptionJson, p.DescriptionJson)
+		}
+		if x.Rejected() || p.Rejected() {
+			t.Errorf("%v: rejected: %v %v", w.name, x.Errors, p.Errors)
+		}
+	}
+
+	castle := pbf[2].DescriptionJson.(map[string]interface{})
+	if castle["osm_type"] != wayKind || castle["osm_id"] != int64(10) || castle["nodes"] != 4 {
+		t.Errorf("closed way: %v", castle)
+	}
+	if tags := castle["tags"].(map[string]string); tags["historic"] != "castle" {
+		t.Errorf("closed way tags: %v", tags)
+	}
+	tower := pbf[3].DescriptionJson.(map[string]interface{})
+	if tower["nodes"] != 1 || tower["missing_nodes"] != 1 {
+		t.Errorf("way cut by the extract: %v", tower)
+	}
+	if tags := pbf[1].DescriptionJson.(map[string]interface{})["tags"].(map[string]string); tags["religion"] != "christian" {
+		t.Errorf("dense node tags: %v", tags)
+	}
+}
+
+// Кодирование protocol buffers для ошибочных файлов
+
+func pbKey(b []byte, num int, wire int) []byte {
+	return binary.AppendUvarint(b, uint64(num<<3|wire))
+}
+
+func pbVarint(b []byte, num int, v uint64) []byte {
+	return binary.AppendUvarint(pbKey(b, num, 0), v)
+}
+
+func pbBytes(b []byte, num int, data []byte) []byte {
+	return append(binary.AppendUvarint(pbKey(b, num, 2), uint64(len(data))), data...)
+}
+
+// pbFileBlock блок файла: длина заголовка, BlobHeader и Blob
+func pbFileBlock(kind string, blob []byte) []byte {
+	header := pbVarint(pbBytes(nil, 1, []byte(kind)), 3, uint64(len(blob)))
+	b := binary.BigEndian.AppendUint32(nil, uint32(len(header)))
+	return append(append(b, header...), blob...)
+}
+
+func TestPBFErrors(t *testing.T) {
+	header := pbFileBlock("OSMHeader", pbBytes(nil, 1, pbBytes(nil, 4, []byte("OsmSchema-V0.6"))))
+	dense := pbBytes(nil, 1, []byte{2, 4})
+	dense = pbBytes(dense, 8, []byte{2})
+	dense = pbBytes(dense, 9, []byte{2, 2})
+	block := pbBytes(nil, 2, pbBytes(nil, 2, dense))
+
+	tests := []struct {
+		name string
+		file []byte
+		want string
+	}{
+		{
+			"required feature",
+			pbFileBlock("OSMHeader", pbBytes(nil, 1, pbBytes(pbBytes(nil, 4, []byte("OsmSchema-V0.6")), 4, []byte("LocationsOnWays")))),
+			`pbf required feature "LocationsOnWays" is not supported`,
+		},
+		{
+			"compression",
+			append(header, pbFileBlock("OSMData", pbBytes(pbVarint(nil, 2, 10), 7, []byte("zstd")))...),
+			"pbf blob compression zstd is not supported",
+		},
+		{
+			"dense nodes",
+			append(header, pbFileBlock("OSMData", pbBytes(nil, 1, block))...),
+			"pbf dense nodes have 2 ids, 1 lats and 2 lons",
+		},
+		{
+			"truncated",
+			header[:len(header)-1],
+			"unexpected EOF",
+		},
+		{
+			"bad field",
+			append(header, pbFileBlock("OSMData", []byte{0x0a, 0x05, 0x01})...),
+			"pbf: bad length in field 1",
+		},
+	}
+	for _, tt := range tests {
+		err := walkPBF(strings.NewReader(string(tt.file)), true, func(*element) bool { return true })
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%v: error %v, want %q", tt.name, err, tt.want)
+		}
+	}
+}
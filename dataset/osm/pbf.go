@@ -0,0 +1,472 @@
+package osm
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Формат OSM PBF: последовательность блоков, каждый — длина заголовка,
+// заголовок BlobHeader и блок Blob, сжатый zlib. Сообщения protocol buffers
+// разбираются вручную по номерам полей из osmformat.proto и fileformat.proto.
+
+const (
+	// maxHeaderSize и maxBlobSize пределы размеров из спецификации формата
+	maxHeaderSize = 64 * 1024
+	maxBlobSize   = 32 * 1024 * 1024
+)
+
+// supportedFeatures обязательные возможности файла, которые мы умеем читать
+var supportedFeatures = map[string]bool{
+	"OsmSchema-V0.6":        true,
+	"DenseNodes":            true,
+	"HistoricalInformation": true,
+}
+
+// walkPBF передаёт в emit узлы и, если ways, линии файла OSM PBF.
+// emit возвращает false, если чтение надо прекратить.
+func walkPBF(r io.Reader, ways bool, emit func(*element) bool) error {
+	br := bufio.NewReaderSize(r, 1<<20)
+	// n порядковый номер объекта в файле, заменяет номер строки
+	n := 0
+	var buf, data []byte
+	for {
+		var size [4]byte
+		if _, err := io.ReadFull(br, size[:]); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		headerSize := binary.BigEndian.Uint32(size[:])
+		if headerSize > maxHeaderSize {
+			return fmt.Errorf("pbf blob header size %d is too large", headerSize)
+		}
+		header := make([]byte, headerSize)
+		if _, err := io.ReadFull(br, header); err != nil {
+			return err
+		}
+		kind, blobSize, err := parseBlobHeader(header)
+		if err != nil {
+			return err
+		}
+		if blobSize > maxBlobSize {
+			return fmt.Errorf("pbf blob size %d is too large", blobSize)
+		}
+		if cap(buf) < blobSize {
+			buf = make([]byte, blobSize)
+		}
+		blob := buf[:blobSize]
+		if _, err = io.ReadFull(br, blob); err != nil {
+			return err
+		}
+		if data, err = unpackBlob(blob, data[:0]); err != nil {
+			return err
+		}
+
+		switch kind {
+		case "OSMHeader":
+			if err = checkHeader(data); err != nil {
+				return err
+			}
+		case "OSMData":
+			stop := false
+			err = parsePrimitiveBlock(data, ways, func(e *element) bool {
+				n++
+				e.line = n
+				if !emit(e) {
+					stop = true
+					return false
+				}
+				return true
+			})
+			if err != nil {
+				return err
+			}
+			if stop {
+				return nil
+			}
+		}
+	}
+}
+
+func parseBlobHeader(b []byte) (string, int, error) {
+	var kind string
+	size := -1
+	err := fields(b, func(f field) error {
+		switch f.num {
+		case 1:
+			kind = string(f.data)
+		case 3:
+			size = int(f.value)
+		}
+		return nil
+	})
+	if err == nil && (kind == "" || size < 0) {
+		err = fmt.Errorf("pbf blob header has no type or size")
+	}
+	return kind, size, err
+}
+
+// unpackBlob возвращает содержимое блока, несжатое или сжатое zlib
+func unpackBlob(b []byte, out []byte) ([]byte, error) {
+	var raw, compressed []byte
+	rawSize := 0
+	var unsupported string
+	err := fields(b, func(f field) error {
+		switch f.num {
+		case 1:
+			raw = f.data
+		case 2:
+			rawSize = int(f.value)
+		case 3:
+			compressed = f.data
+		case 4:
+			unsupported = "lzma"
+		case 6:
+			unsupported = "lz4"
+		case 7:
+			unsupported = "zstd"
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case raw != nil:
+		return append(out, raw...), nil
+	case compressed != nil:
+		zr, err := zlib.NewReader(bytes.NewReader(compressed))
+		if err != nil {
+			return nil, err
+		}
+		defer zr.Close()
+		w := bytes.NewBuffer(out)
+		w.Grow(rawSize)
+		if _, err = io.Copy(w, zr); err != nil {
+			return nil, err
+		}
+		return w.Bytes(), nil
+	case unsupported != "":
+		return nil, fmt.Errorf("pbf blob compression %v is not supported", unsupported)
+	}
+	return out, nil
+}
+
+func checkHeader(b []byte) error {
+	return fields(b, func(f field) error {
+		if f.num == 4 && !supportedFeatures[string(f.data)] {
+			return fmt.Errorf("pbf required feature %q is not supported", f.data)
+		}
+		return nil
+	})
+}
+
+// block параметры PrimitiveBlock для пересчёта координат и строк
+type block struct {
+	strings     [][]byte
+	granularity int64
+	latOffset   int64
+	lonOffset   int64
+}
+
+func (b *block) string(i uint64) (string, error) {
+	if i >= uint64(len(b.strings)) {
+		return "", fmt.Errorf("pbf string index %d out of range", i)
+	}
+	return string(b.strings[i]), nil
+}
+
+func (b *block) degrees(offset int64, v int64) float64 {
+	return 1e-9 * float64(offset+b.granularity*v)
+}
+
+func parsePrimitiveBlock(data []byte, ways bool, emit func(*element) bool) error {
+	blk := &block{granularity: 100}
+	var groups [][]byte
+	err := fields(data, func(f field) error {
+		switch f.num {
+		case 1:
+			return fields(f.data, func(s field) error {
+				if s.num == 1 {
+					blk.strings = append(blk.strings, s.data)
+				}
+				return nil
+			})
+		case 2:
+			groups = append(groups, f.data)
+		case 17:
+			blk.granularity = int64(f.value)
+		case 19:
+			blk.latOffset = int64(f.value)
+		case 20:
+			blk.lonOffset = int64(f.value)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, g := range groups {
+		err = fields(g, func(f field) error {
+			var e *element
+			var err error
+			switch f.num {
+			case 1:
+				if e, err = blk.node(f.data); err != nil {
+					return err
+				}
+				if !emit(e) {
+					return errStop
+				}
+			case 2:
+				return blk.denseNodes(f.data, emit)
+			case 3:
+				if !ways {
+					return nil
+				}
+				if e, err = blk.way(f.data); err != nil {
+					return err
+				}
+				if !emit(e) {
+					return errStop
+				}
+			}
+			return nil
+		})
+		if err == errStop {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// errStop прекращает разбор блока, когда emit вернул false
+var errStop = errors.New("stop")
+
+func (b *block) node(data []byte) (*element, error) {
+	e := &element{kind: nodeKind}
+	var keys, vals []uint64
+	var lat, lon int64
+	err := fields(data, func(f field) error {
+		switch f.num {
+		case 1:
+			e.id = zigzag(f.value)
+		case 2:
+			keys = f.uints(keys)
+		case 3:
+			vals = f.uints(vals)
+		case 8:
+			lat = zigzag(f.value)
+		case 9:
+			lon = zigzag(f.value)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	e.lat, e.lon = b.degrees(b.latOffset, lat), b.degrees(b.lonOffset, lon)
+	e.tags, err = b.tags(keys, vals)
+	return e, err
+}
+
+func (b *block) denseNodes(data []byte, emit func(*element) bool) error {
+	var ids, lats, lons []int64
+	var keysVals []uint64
+	err := fields(data, func(f field) error {
+		switch f.num {
+		case 1:
+			ids = f.sints(ids)
+		case 8:
+			lats = f.sints(lats)
+		case 9:
+			lons = f.sints(lons)
+		case 10:
+			keysVals = f.uints(keysVals)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if len(lats) != len(ids) || len(lons) != len(ids) {
+		return fmt.Errorf("pbf dense nodes have %d ids, %d lats and %d lons", len(ids), len(lats), len(lons))
+	}
+
+	var id, lat, lon int64
+	kv := 0
+	for i := range ids {
+		id, lat, lon = id+ids[i], lat+lats[i], lon+lons[i]
+		e := &element{kind: nodeKind, id: id, lat: b.degrees(b.latOffset, lat), lon: b.degrees(b.lonOffset, lon)}
+		// теги всех узлов подряд: ключ, значение, ..., 0 — конец тегов узла
+		for kv < len(keysVals) && keysVals[kv] != 0 {
+			if kv+1 >= len(keysVals) {
+				return fmt.Errorf("pbf dense node %d has a key without value", id)
+			}
+			k, err := b.string(keysVals[kv])
+			if err != nil {
+				return err
+			}
+			v, err := b.string(keysVals[kv+1])
+			if err != nil {
+				return err
+			}
+			if e.tags == nil {
+				e.tags = make(map[string]string)
+			}
+			e.tags[k] = v
+			kv += 2
+		}
+		kv++
+		if !emit(e) {
+			return errStop
+		}
+	}
+	return nil
+}
+
+func (b *block) way(data []byte) (*element, error) {
+	e := &element{kind: wayKind}
+	var keys, vals []uint64
+	var refs []int64
+	err := fields(data, func(f field) error {
+		switch f.num {
+		case 1:
+			e.id = int64(f.value)
+		case 2:
+			keys = f.uints(keys)
+		case 3:
+			vals = f.uints(vals)
+		case 8:
+			refs = f.sints(refs)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	var ref int64
+	e.refs = make([]int64, len(refs))
+	for i, d := range refs {
+		ref += d
+		e.refs[i] = ref
+	}
+	e.tags, err = b.tags(keys, vals)
+	return e, err
+}
+
+func (b *block) tags(keys []uint64, vals []uint64) (map[string]string, error) {
+	if len(keys) != len(vals) {
+		return nil, fmt.Errorf("pbf element has %d tag keys and %d values", len(keys), len(vals))
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	tags := make(map[string]string, len(keys))
+	for i := range keys {
+		k, err := b.string(keys[i])
+		if err != nil {
+			return nil, err
+		}
+		v, err := b.string(vals[i])
+		if err != nil {
+			return nil, err
+		}
+		tags[k] = v
+	}
+	return tags, nil
+}
+
+// field поле сообщения protocol buffers: число для varint и fixed,
+// байты для length-delimited
+type field struct {
+	num   int
+	wire  int
+	value uint64
+	data  []byte
+}
+
+// fields вызывает fn для каждого поля сообщения b по порядку
+func fields(b []byte, fn func(f field) error) error {
+	for len(b) > 0 {
+		key, n := binary.Uvarint(b)
+		if n <= 0 {
+			return fmt.Errorf("pbf: bad field key")
+		}
+		b = b[n:]
+		f := field{num: int(key >> 3), wire: int(key & 7)}
+		switch f.wire {
+		case 0:
+			if f.value, n = binary.Uvarint(b); n <= 0 {
+				return fmt.Errorf("pbf: bad varint in field %d", f.num)
+			}
+			b = b[n:]
+		case 1:
+			if len(b) < 8 {
+				return fmt.Errorf("pbf: short fixed64 in field %d", f.num)
+			}
+			f.value, b = binary.LittleEndian.Uint64(b), b[8:]
+		case 2:
+			size, n := binary.Uvarint(b)
+			if n <= 0 || size > uint64(len(b)-n) {
+				return fmt.Errorf("pbf: bad length in field %d", f.num)
+			}
+			f.data, b = b[n:n+int(size)], b[n+int(size):]
+		case 5:
+			if len(b) < 4 {
+				return fmt.Errorf("pbf: short fixed32 in field %d", f.num)
+			}
+			f.value, b = uint64(binary.LittleEndian.Uint32(b)), b[4:]
+		default:
+			return fmt.Errorf("pbf: unsupported wire type %d in field %d", f.wire, f.num)
+		}
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// uints добавляет к to значения упакованного или одиночного поля varint
+func (f field) uints(to []uint64) []uint64 {
+	if f.wire == 0 {
+		return append(to, f.value)
+	}
+	for b := f.data; len(b) > 0; {
+		v, n := binary.Uvarint(b)
+		if n <= 0 {
+			break
+		}
+		to, b = append(to, v), b[n:]
+	}
+	return to
+}
+
+// sints добавляет к to значения упакованного или одиночного поля sint64
+func (f field) sints(to []int64) []int64 {
+	if f.wire == 0 {
+		return append(to, zigzag(f.value))
+	}
+	for b := f.data; len(b) > 0; {
+		v, n := binary.Uvarint(b)
+		if n <= 0 {
+			break
+		}
+		to, b = append(to, zigzag(v)), b[n:]
+	}
+	return to
+}
+
+func zigzag(v uint64) int64 {
+	return int64(v>>1) ^ -int64(v&1)
+}
@@ -0,0 +1,96 @@
+package osm
+
+import (
+	"encoding/xml"
+	"github.com/audetv/datasets-parser/dataset/input"
+	"io"
+)
+
+type xmlTag struct {
+	Key   string `xml:"k,attr"`
+	Value string `xml:"v,attr"`
+}
+
+type xmlNode struct {
+	ID   int64    `xml:"id,attr"`
+	Lat  float64  `xml:"lat,attr"`
+	Lon  float64  `xml:"lon,attr"`
+	Tags []xmlTag `xml:"tag"`
+}
+
+type xmlWay struct {
+	ID   int64 `xml:"id,attr"`
+	Refs []struct {
+		Ref int64 `xml:"ref,attr"`
+	} `xml:"nd"`
+	Tags []xmlTag `xml:"tag"`
+}
+
+// walkXML передаёт в emit узлы и, если ways, линии файла OSM XML.
+// emit возвращает false, если чтение надо прекратить.
+func walkXML(r io.Reader, ways bool, emit func(*element) bool) error {
+	d := xml.NewDecoder(r)
+	d.CharsetReader = input.CharsetReader
+
+	for {
+		line, _ := d.InputPos()
+		tok, err := d.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		var e *element
+		switch start.Name.Local {
+		case "node":
+			var n xmlNode
+			if err = d.DecodeElement(&n, &start); err != nil {
+				return err
+			}
+			e = &element{kind: nodeKind, id: n.ID, lat: n.Lat, lon: n.Lon, tags: xmlTags(n.Tags)}
+		case "way":
+			if !ways {
+				if err = d.Skip(); err != nil {
+					return err
+				}
+				continue
+			}
+			var w xmlWay
+			if err = d.DecodeElement(&w, &start); err != nil {
+				return err
+			}
+			e = &element{kind: wayKind, id: w.ID, tags: xmlTags(w.Tags), refs: make([]int64, len(w.Refs))}
+			for i, nd := range w.Refs {
+				e.refs[i] = nd.Ref
+			}
+		case "relation":
+			if err = d.Skip(); err != nil {
+				return err
+			}
+			continue
+		default:
+			continue
+		}
+		e.line = line
+		if !emit(e) {
+			return nil
+		}
+	}
+}
+
+func xmlTags(tags []xmlTag) map[string]string {
+	if len(tags) == 0 {
+		return nil
+	}
+	m := make(map[string]string, len(tags))
+	for _, t := range tags {
+		m[t.Key] = t.Value
+	}
+	return m
+}
@@ -0,0 +1,156 @@
+package quarantine
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/audetv/datasets-parser/app/repos/dataset"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Суффиксы файлов карантина, которые создаются рядом с файлом набора данных
+const (
+	RejectedSuffix = ".rejected.csv"
+	ErrorsSuffix   = ".errors.json"
+)
+
+// IsReport сообщает, что файл создан карантином и не является набором данных
+func IsReport(filename string) bool {
+	return strings.HasSuffix(filename, RejectedSuffix) || strings.HasSuffix(filename, ErrorsSuffix)
+}
+
+// Writer сохраняет отклонённые записи одного файла набора данных
+// в «<файл>.rejected.csv», а их ошибки — массивом json в «<файл>.errors.json».
+// Файлы создаются при первой отклонённой записи, старые отчёты
+// файла без отклонённых записей удаляются при закрытии.
+type Writer struct {
+	path    string
+	columns []string
+	comma   rune
+
+	rejected *os.File
+	csv      *csv.Writer
+	report   *os.File
+	errors   int
+	count    int
+}
+
+// New возвращает Writer для файла name в папке folder. Файл из архива
+// «archive.zip/dir/file.csv» получает отчёты «archive.zip_dir_file.csv.*» рядом с архивом.
+func New(folder string, name string) *Writer {
+	return &Writer{
+		path:  filepath.Join(folder, strings.ReplaceAll(name, "/", "_")),
+		comma: ',',
+	}
+}
+
+// SetHeader задаёт заголовок и разделитель исходного файла,
+// чтобы отклонённые записи можно было исправить и загрузить повторно
+func (w *Writer) SetHeader(columns []string, comma rune) {
+	w.columns = columns
+	if comma != 0 {
+		w.comma = comma
+	}
+}
+
+// RejectedPath путь файла с отклонёнными записями
+func (w *Writer) RejectedPath() string {
+	return w.path + RejectedSuffix
+}
+
+// ErrorsPath путь отчёта об ошибках
+func (w *Writer) ErrorsPath() string {
+	return w.path + ErrorsSuffix
+}
+
+// Count количество отклонённых записей
+func (w *Writer) Count() int {
+	return w.count
+}
+
+// Write сохраняет отклонённую запись и её ошибки
+func (w *Writer) Write(entry dataset.Entry) error {
+	if w.rejected == nil {
+		if err := w.open(); err != nil {
+			return err
+		}
+	}
+
+	record := append([]string{strconv.Itoa(entry.Line)}, entry.Raw...)
+	if err := w.csv.Write(record); err != nil {
+		return fmt.Errorf("%v: %w", w.RejectedPath(), err)
+	}
+
+	for _, e := range entry.Errors {
+		b, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		sep := ",\n  "
+		if w.errors == 0 {
+			sep = "\n  "
+		}
+		if _, err = fmt.Fprintf(w.report, "%v%s", sep, b); err != nil {
+			return fmt.Errorf("%v: %w", w.ErrorsPath(), err)
+		}
+		w.errors++
+	}
+	w.count++
+	return nil
+}
+
+func (w *Writer) open() error {
+	rejected, err := os.Create(w.RejectedPath())
+	if err != nil {
+		return err
+	}
+	report, err := os.Create(w.ErrorsPath())
+	if err != nil {
+		rejected.Close()
+		return err
+	}
+
+	w.rejected, w.report = rejected, report
+	w.csv = csv.NewWriter(rejected)
+	w.csv.Comma = w.comma
+
+	if len(w.columns) > 0 {
+		if err = w.csv.Write(append([]string{"line"}, w.columns...)); err != nil {
+			return fmt.Errorf("%v: %w", w.RejectedPath(), err)
+		}
+	}
+	if _, err = fmt.Fprint(w.report, "["); err != nil {
+		return fmt.Errorf("%v: %w", w.ErrorsPath(), err)
+	}
+	return nil
+}
+
+// Close дописывает и закрывает файлы карантина
+func (w *Writer) Close() error {
+	if w.rejected == nil {
+		// отчёты прошлого запуска больше не актуальны
+		for _, p := range []string{w.RejectedPath(), w.ErrorsPath()} {
+			if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+		}
+		return nil
+	}
+
+	w.csv.Flush()
+	err := w.csv.Error()
+	if _, rerr := fmt.Fprint(w.report, "\n]\n"); rerr != nil && err == nil {
+		err = rerr
+	}
+	if cerr := w.rejected.Close(); cerr != nil && err == nil {
+		err = cerr
+	}
+	if cerr := w.report.Close(); cerr != nil && err == nil {
+		err = cerr
+	}
+	return err
+}
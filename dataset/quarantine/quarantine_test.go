@@ -0,0 +1,122 @@
+package quarantine
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/audetv/datasets-parser/app/repos/dataset"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWriter(t *testing.T) {
+	dir := t.TempDir()
+	w := New(dir, "archive.zip/dir/places.csv")
+	w.SetHeader([]string{"name", "lat", "lon"}, ';')
+
+	rejected := []dataset.Entry{
+		{
+			Line: 3,
+			Raw:  []string{"Москва", "x", "37,62"},
+			Errors: []dataset.RowError{
+				{File: "archive.zip/dir/places.csv", Line: 3, Column: "lat", Value: "x", Reason: "not a number"},
+				{File: "archive.zip/dir/places.csv", Line: 3, Column: "lon", Value: "37,62", Reason: "not a number"},
+			},
+		},
+		{
+			Line:   7,
+			Raw:    []string{"Тверь;56.85"},
+			Errors: []dataset.RowError{{File: "archive.zip/dir/places.csv", Line: 7, Reason: "wrong number of fields"}},
+		},
+	}
+	for _, e := range rejected {
+		if err := w.Write(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if w.Count() != 2 {
+		t.Errorf("count %d", w.Count())
+	}
+	if want := filepath.Join(dir, "archive.zip_dir_places.csv.rejected.csv"); w.RejectedPath() != want {
+		t.Errorf("rejected path %v, want %v", w.RejectedPath(), want)
+	}
+
+	// первая колонка — номер строки исходного файла, дальше поля записи с разделителем файла
+	b, err := os.ReadFile(w.RejectedPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "line;name;lat;lon\n3;Москва;x;37,62\n7;\"Тверь;56.85\"\n"
+	if string(b) != want {
+		t.Errorf("rejected.csv\n%s\nwant\n%s", b, want)
+	}
+
+	b, err = os.ReadFile(w.ErrorsPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var errs []dataset.RowError
+	if err = json.Unmarshal(b, &errs); err != nil {
+		t.Fatalf("errors.json: %v\n%s", err, b)
+	}
+	if len(errs) != 3 || errs[1] != rejected[0].Errors[1] || errs[2] != rejected[1].Errors[0] {
+		t.Errorf("errors.json %+v", errs)
+	}
+}
+
+func TestWriterWithoutHeader(t *testing.T) {
+	w := New(t.TempDir(), "places.kml")
+	err := w.Write(dataset.Entry{
+		Line:   2,
+		Raw:    []string{"Москва", "55.75,37.62"},
+		Errors: []dataset.RowError{{File: "places.kml", Line: 2, Reason: "bad coordinates"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// без заголовка нет строки с именами колонок, разделитель — запятая
+	b, err := os.ReadFile(w.RejectedPath())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "2,Москва,\"55.75,37.62\"\n"; string(b) != want {
+		t.Errorf("rejected.csv %q, want %q", b, want)
+	}
+}
+
+func TestWriterRemovesStaleReports(t *testing.T) {
+	dir := t.TempDir()
+	w := New(dir, "places.csv")
+	for _, p := range []string{w.RejectedPath(), w.ErrorsPath()} {
+		if err := os.WriteFile(p, []byte("stale"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if !IsReport(filepath.Base(p)) {
+			t.Errorf("%v is not a report", p)
+		}
+	}
+
+	// файл прочитан без отклонённых записей
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{w.RejectedPath(), w.ErrorsPath()} {
+		if _, err := os.Stat(p); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("%v: %v, want removed", p, err)
+		}
+	}
+	if err := New(dir, "other.csv").Close(); err != nil {
+		t.Errorf("close without reports: %v", err)
+	}
+	if IsReport("places.csv") {
+		t.Error("places.csv is a report")
+	}
+}
@@ -0,0 +1,237 @@
+package shapefile
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"github.com/audetv/datasets-parser/dataset/input"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Field поле dbf таблицы атрибутов
+type Field struct {
+	Name string
+	// Type тип поля dBase: C — строка, N и F — число, L — логическое, D — дата
+	Type     byte
+	Length   int
+	Decimals int
+}
+
+// Типы полей dbf
+const (
+	Character byte = 'C'
+	Numeric   byte = 'N'
+	Float     byte = 'F'
+	Logical   byte = 'L'
+	Date      byte = 'D'
+)
+
+const (
+	dbfHeaderLength = 32
+	dbfFieldLength  = 32
+	dbfTerminator   = 0x0D
+	dbfEOF          = 0x1A
+	dbfDeleted      = '*'
+)
+
+// languageDrivers кодировки по байту language driver заголовка dbf,
+// если рядом нет .cpg файла
+var languageDrivers = map[byte]encoding.Encoding{
+	0x26: charmap.CodePage866,
+	0x65: charmap.CodePage866,
+	0xC9: charmap.Windows1251,
+}
+
+// dbfReader читает записи dbf файла по порядку
+type dbfReader struct {
+	r       *bufio.Reader
+	fields  []Field
+	records int
+	length  int
+	// decoder перекодирует строки в UTF-8, nil для UTF-8
+	decoder *encoding.Decoder
+	buf     []byte
+}
+
+// newDbfReader читает заголовок dbf. Кодировка строк — из .cpg файла cpg,
+// иначе по байту language driver, иначе определяется по первым записям.
+func newDbfReader(r io.Reader, cpg string) (*dbfReader, error) {
+	dr := &dbfReader{r: bufio.NewReaderSize(r, 64*1024)}
+
+	var h [dbfHeaderLength]byte
+	if _, err := io.ReadFull(dr.r, h[:]); err != nil {
+		return nil, fmt.Errorf("dbf header: %w", err)
+	}
+	dr.records = int(binary.LittleEndian.Uint32(h[4:]))
+	headerLen := int(binary.LittleEndian.Uint16(h[8:]))
+	dr.length = int(binary.LittleEndian.Uint16(h[10:]))
+	ldid := h[29]
+
+	read := dbfHeaderLength
+	for {
+		b, err := dr.r.Peek(1)
+		if err != nil {
+			return nil, fmt.Errorf("dbf fields: %w", err)
+		}
+		if b[0] == dbfTerminator {
+			break
+		}
+		var fd [dbfFieldLength]byte
+		if _, err = io.ReadFull(dr.r, fd[:]); err != nil {
+			return nil, fmt.Errorf("dbf fields: %w", err)
+		}
+		read += dbfFieldLength
+		name := fd[:11]
+		if i := bytes.IndexByte(name, 0); i >= 0 {
+			name = name[:i]
+		}
+		dr.fields = append(dr.fields, Field{
+			Name:     strings.TrimSpace(string(name)),
+			Type:     fd[11],
+			Length:   int(fd[16]),
+			Decimals: int(fd[17]),
+		})
+	}
+	// остаток заголовка: терминатор и, у Visual FoxPro, ссылка на базу
+	if headerLen > read {
+		if _, err := dr.r.Discard(headerLen - read); err != nil {
+			return nil, fmt.Errorf("dbf header: %w", err)
+		}
+	}
+
+	width := 1
+	for _, f := range dr.fields {
+		width += f.Length
+	}
+	if dr.length < width {
+		return nil, fmt.Errorf("dbf record length %d is less than fields length %d", dr.length, width)
+	}
+
+	enc, err := dr.encoding(cpg, ldid)
+	if err != nil {
+		return nil, err
+	}
+	if enc != nil {
+		dr.decoder = enc.NewDecoder()
+	}
+	return dr, nil
+}
+
+// encoding определяет кодировку строк, nil для UTF-8
+func (dr *dbfReader) encoding(cpg string, ldid byte) (encoding.Encoding, error) {
+	if cpg = strings.TrimSpace(cpg); cpg != "" {
+		// ArcGIS пишет в .cpg «ANSI 1251» или просто номер кодовой страницы
+		name := strings.TrimSpace(strings.TrimPrefix(strings.ToUpper(cpg), "ANSI"))
+		_, enc, err := input.Lookup(name)
+		if err != nil {
+			return nil, fmt.Errorf("cpg: %w", err)
+		}
+		return enc, nil
+	}
+	if enc, ok := languageDrivers[ldid]; ok {
+		return enc, nil
+	}
+
+	sample, _ := dr.r.Peek(dr.r.Size())
+	switch input.DetectEncoding(sample) {
+	case input.CP1251:
+		return charmap.Windows1251, nil
+	case input.KOI8R:
+		return charmap.KOI8R, nil
+	}
+	return nil, nil
+}
+
+// next возвращает значения полей следующей записи и признак удалённой записи,
+// io.EOF в конце файла
+func (dr *dbfReader) next() (map[string]interface{}, bool, error) {
+	if dr.records == 0 {
+		return nil, false, io.EOF
+	}
+	if cap(dr.buf) < dr.length {
+		dr.buf = make([]byte, dr.length)
+	}
+	b := dr.buf[:dr.length]
+	if _, err := io.ReadFull(dr.r, b); err != nil {
+		if err == io.EOF || (err == io.ErrUnexpectedEOF && b[0] == dbfEOF) {
+			return nil, false, io.EOF
+		}
+		return nil, false, fmt.Errorf("dbf record: %w", err)
+	}
+	dr.records--
+	if b[0] == dbfEOF {
+		return nil, false, io.EOF
+	}
+
+	values := make(map[string]interface{}, len(dr.fields))
+	offset := 1
+	for _, f := range dr.fields {
+		if v := dr.value(f, b[offset:offset+f.Length]); v != nil {
+			values[f.Name] = v
+		}
+		offset += f.Length
+	}
+	return values, b[0] == dbfDeleted, nil
+}
+
+// value разбирает значение поля, nil для пустого значения.
+// Числа возвращаются как json.Number, чтобы не терять точность.
+func (dr *dbfReader) value(f Field, raw []byte) interface{} {
+	text := strings.TrimSpace(string(bytes.TrimRight(raw, "\x00")))
+	switch f.Type {
+	case Numeric, Float:
+		// поле, не поместившееся в ширину, заполняется звёздочками
+		if text == "" || strings.Trim(text, "*") == "" {
+			return nil
+		}
+		text = strings.Replace(text, ",", ".", 1)
+		var n json.Number
+		if err := json.Unmarshal([]byte(text), &n); err == nil {
+			return n
+		}
+		// «007», «.5» и «+5» не являются json числами
+		if v, err := strconv.ParseFloat(text, 64); err == nil && !math.IsInf(v, 0) && !math.IsNaN(v) {
+			return json.Number(strconv.FormatFloat(v, 'f', -1, 64))
+		}
+		return text
+	case Logical:
+		switch text {
+		case "T", "t", "Y", "y":
+			return true
+		case "F", "f", "N", "n":
+			return false
+		}
+		return nil
+	case Date:
+		if len(text) != 8 || strings.Trim(text, "0") == "" {
+			return nil
+		}
+		return text[:4] + "-" + text[4:6] + "-" + text[6:]
+	}
+	if text == "" {
+		return nil
+	}
+	return dr.decode(raw)
+}
+
+// decode перекодирует строковое поле в UTF-8
+func (dr *dbfReader) decode(raw []byte) string {
+	raw = bytes.TrimRight(raw, " \x00")
+	if dr.decoder != nil {
+		if b, err := dr.decoder.Bytes(raw); err == nil {
+			return strings.TrimSpace(string(b))
+		}
+	}
+	if !utf8.Valid(raw) {
+		return strings.TrimSpace(strings.ToValidUTF8(string(raw), "�"))
+	}
+	return strings.TrimSpace(string(raw))
+}
@@ -0,0 +1,319 @@
+package shapefile
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ErrProjection проекция .prj файла, которую не умеем пересчитывать
+var ErrProjection = errors.New("unsupported projection")
+
+// WGS84 описание географической системы координат WGS 84 для .prj файла
+const WGS84 = `GEOGCS["GCS_WGS_1984",DATUM["D_WGS_1984",SPHEROID["WGS_1984",6378137.0,298.257223563]],PRIMEM["Greenwich",0.0],UNIT["Degree",0.0174532925199433]]`
+
+// Transform пересчитывает координаты файла в долготу и широту в градусах
+type Transform func(x float64, y float64) (lon float64, lat float64)
+
+// wkt элемент описания системы координат в формате WKT: PROJCS[...], UNIT[...]
+type wkt struct {
+	name string
+	// args строки, числа и вложенные элементы
+	args []interface{}
+}
+
+// ParseProjection разбирает содержимое .prj файла и возвращает пересчёт координат
+// в градусы. Поддерживаются географические координаты и проекции Меркатора,
+// поперечная Меркатора (UTM, Гаусс — Крюгер) и коническая Ламберта.
+// Датум не учитывается: широта и долгота остаются в датуме файла,
+// для Пулково 1942 это расхождение с WGS 84 до сотни метров.
+func ParseProjection(prj string) (Transform, error) {
+	root, err := parseWKT(prj)
+	if err != nil {
+		return nil, fmt.Errorf("prj: %w", err)
+	}
+	switch root.name {
+	case "GEOGCS", "GEOGCRS", "GEODCRS":
+		return geographic(root)
+	case "PROJCS", "PROJCRS":
+		return projected(root)
+	}
+	return nil, fmt.Errorf("prj: %w: %v", ErrProjection, root.name)
+}
+
+// geographic пересчёт из угловых единиц географической системы координат
+func geographic(g *wkt) (Transform, error) {
+	unit := g.unit(math.Pi / 180)
+	meridian := 0.0
+	if pm := g.child("PRIMEM"); pm != nil {
+		meridian = pm.number(1) * unit * 180 / math.Pi
+	}
+	return func(x float64, y float64) (float64, float64) {
+		return x*unit*180/math.Pi + meridian, y * unit * 180 / math.Pi
+	}, nil
+}
+
+// projected обратный пересчёт проекции в географические координаты
+func projected(p *wkt) (Transform, error) {
+	g := p.child("GEOGCS")
+	if g == nil {
+		return nil, fmt.Errorf("prj: projected coordinate system has no GEOGCS")
+	}
+	e := ellipsoid{a: 6378137, f: 1 / 298.257223563}
+	if s := g.find("SPHEROID"); s != nil {
+		e.a = s.number(1)
+		if invf := s.number(2); invf != 0 {
+			e.f = 1 / invf
+		} else {
+			e.f = 0
+		}
+	}
+	if e.a <= 0 {
+		return nil, fmt.Errorf("prj: bad semi-major axis %v", e.a)
+	}
+
+	// угловые параметры проекции заданы в единицах GEOGCS, линейные — в единицах PROJCS
+	angular := g.unit(math.Pi / 180)
+	linear := p.unit(1)
+	meridian := 0.0
+	if pm := g.child("PRIMEM"); pm != nil {
+		meridian = pm.number(1) * angular
+	}
+	params := p.parameters()
+	angle := func(names ...string) (float64, bool) {
+		v, ok := params.get(names...)
+		return v * angular, ok
+	}
+
+	var method string
+	if pr := p.child("PROJECTION"); pr != nil {
+		method = normalize(pr.text(0))
+	}
+
+	var inverse func(x, y float64) (float64, float64)
+	lon0, _ := angle("central_meridian", "longitude_of_origin", "longitude_of_center", "longitude_of_natural_origin")
+	lat0, _ := angle("latitude_of_origin", "latitude_of_center", "latitude_of_natural_origin")
+	k0, ok := params.get("scale_factor", "scale_factor_at_natural_origin")
+	if !ok {
+		k0 = 1
+	}
+
+	switch method {
+	case "transverse_mercator", "gauss_kruger", "transverse_mercator_south_orientated":
+		inverse = e.transverseMercator(lat0, k0)
+	case "mercator", "mercator_1sp", "mercator_2sp":
+		if sp1, ok := angle("standard_parallel_1", "latitude_of_1st_standard_parallel"); ok {
+			k0 = math.Cos(sp1) / math.Sqrt(1-e.e2()*math.Pow(math.Sin(sp1), 2))
+		}
+		inverse = e.mercator(k0)
+	case "mercator_auxiliary_sphere", "popular_visualisation_pseudo_mercator", "pseudo_mercator":
+		inverse = ellipsoid{a: e.a}.mercator(k0)
+	case "lambert_conformal_conic", "lambert_conformal_conic_1sp", "lambert_conformal_conic_2sp":
+		sp1, ok := angle("standard_parallel_1", "latitude_of_1st_standard_parallel")
+		if !ok {
+			sp1 = lat0
+		}
+		sp2, ok := angle("standard_parallel_2", "latitude_of_2nd_standard_parallel")
+		if !ok {
+			sp2 = sp1
+		}
+		inverse = e.lambertConformalConic(lat0, sp1, sp2, k0)
+	default:
+		return nil, fmt.Errorf("prj: %w %q", ErrProjection, method)
+	}
+
+	fe, _ := params.get("false_easting", "easting_at_false_origin")
+	fn, _ := params.get("false_northing", "northing_at_false_origin")
+	south := method == "transverse_mercator_south_orientated"
+	return func(x float64, y float64) (float64, float64) {
+		x, y = x*linear-fe*linear, y*linear-fn*linear
+		if south {
+			x, y = -x, -y
+		}
+		lon, lat := inverse(x, y)
+		return (lon + lon0 + meridian) * 180 / math.Pi, lat * 180 / math.Pi
+	}, nil
+}
+
+// parameters параметры проекции по нормализованному имени
+type parameters map[string]float64
+
+func (p parameters) get(names ...string) (float64, bool) {
+	for _, name := range names {
+		if v, ok := p[name]; ok {
+			return v, true
+		}
+	}
+	return 0, false
+}
+
+func (w *wkt) parameters() parameters {
+	params := parameters{}
+	for _, a := range w.args {
+		if c, ok := a.(*wkt); ok && c.name == "PARAMETER" {
+			params[normalize(c.text(0))] = c.number(1)
+		}
+	}
+	return params
+}
+
+// unit множитель единиц элемента: радиан в угловой единице или метров в линейной
+func (w *wkt) unit(fallback float64) float64 {
+	for _, name := range []string{"UNIT", "ANGLEUNIT", "LENGTHUNIT"} {
+		if u := w.child(name); u != nil && u.number(1) > 0 {
+			return u.number(1)
+		}
+	}
+	return fallback
+}
+
+// child возвращает непосредственный вложенный элемент name
+func (w *wkt) child(name string) *wkt {
+	for _, a := range w.args {
+		if c, ok := a.(*wkt); ok && c.name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+// find ищет вложенный элемент name на любой глубине
+func (w *wkt) find(name string) *wkt {
+	for _, a := range w.args {
+		if c, ok := a.(*wkt); ok {
+			if c.name == name {
+				return c
+			}
+			if f := c.find(name); f != nil {
+				return f
+			}
+		}
+	}
+	return nil
+}
+
+func (w *wkt) text(i int) string {
+	if i < len(w.args) {
+		if s, ok := w.args[i].(string); ok {
+			return s
+		}
+	}
+	return ""
+}
+
+func (w *wkt) number(i int) float64 {
+	if i < len(w.args) {
+		if v, ok := w.args[i].(float64); ok {
+			return v
+		}
+	}
+	return 0
+}
+
+// normalize приводит имена проекций и параметров ESRI и EPSG к одному виду:
+// «Transverse Mercator» и «Transverse_Mercator» — «transverse_mercator»
+func normalize(name string) string {
+	return strings.Join(strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), "_")
+}
+
+// parseWKT разбирает текст WKT в дерево элементов
+func parseWKT(s string) (*wkt, error) {
+	p := &wktParser{s: strings.TrimSpace(s)}
+	w, err := p.element()
+	if err != nil {
+		return nil, err
+	}
+	if p.skipSpace(); p.pos < len(p.s) {
+		return nil, fmt.Errorf("unexpected %q at %d", p.s[p.pos:], p.pos)
+	}
+	return w, nil
+}
+
+type wktParser struct {
+	s   string
+	pos int
+}
+
+func (p *wktParser) skipSpace() {
+	for p.pos < len(p.s) && strings.IndexByte(" \t\r\n", p.s[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+// element разбирает «NAME[arg, ...]» или «NAME(arg, ...)», скобок может не быть: AXIS["Lat",NORTH]
+func (p *wktParser) element() (*wkt, error) {
+	p.skipSpace()
+	start := p.pos
+	for p.pos < len(p.s) && (p.s[p.pos] == '_' || isAlnum(p.s[p.pos])) {
+		p.pos++
+	}
+	if start == p.pos {
+		return nil, fmt.Errorf("expected keyword at %d", p.pos)
+	}
+	w := &wkt{name: strings.ToUpper(p.s[start:p.pos])}
+
+	p.skipSpace()
+	if p.pos >= len(p.s) || (p.s[p.pos] != '[' && p.s[p.pos] != '(') {
+		return w, nil
+	}
+	closing := byte(']')
+	if p.s[p.pos] == '(' {
+		closing = ')'
+	}
+	p.pos++
+
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.s) {
+			return nil, fmt.Errorf("%v is not closed", w.name)
+		}
+		switch c := p.s[p.pos]; {
+		case c == '"':
+			end := strings.IndexByte(p.s[p.pos+1:], '"')
+			if end < 0 {
+				return nil, fmt.Errorf("string at %d is not closed", p.pos)
+			}
+			w.args = append(w.args, p.s[p.pos+1:p.pos+1+end])
+			p.pos += end + 2
+		case c == '-' || c == '+' || c == '.' || (c >= '0' && c <= '9'):
+			start := p.pos
+			for p.pos < len(p.s) && strings.IndexByte("+-.eE0123456789", p.s[p.pos]) >= 0 {
+				p.pos++
+			}
+			v, err := strconv.ParseFloat(p.s[start:p.pos], 64)
+			if err != nil {
+				return nil, fmt.Errorf("bad number %q", p.s[start:p.pos])
+			}
+			w.args = append(w.args, v)
+		default:
+			child, err := p.element()
+			if err != nil {
+				return nil, err
+			}
+			w.args = append(w.args, child)
+		}
+
+		p.skipSpace()
+		if p.pos >= len(p.s) {
+			return nil, fmt.Errorf("%v is not closed", w.name)
+		}
+		switch p.s[p.pos] {
+		case ',':
+			p.pos++
+		case closing:
+			p.pos++
+			return w, nil
+		default:
+			return nil, fmt.Errorf("unexpected %q in %v", p.s[p.pos], w.name)
+		}
+	}
+}
+
+func isAlnum(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
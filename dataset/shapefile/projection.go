@@ -0,0 +1,113 @@
+package shapefile
+
+import "math"
+
+// ellipsoid эллипсоид проекции: большая полуось в метрах и сжатие, 0 для сферы
+type ellipsoid struct {
+	a, f float64
+}
+
+// e2 квадрат эксцентриситета
+func (e ellipsoid) e2() float64 {
+	return e.f * (2 - e.f)
+}
+
+// Обратные преобразования проекций принимают смещения от начала координат
+// в метрах и возвращают долготу от центрального меридиана и широту в радианах.
+
+// transverseMercator поперечная проекция Меркатора по рядам Крюгера
+// до третьего порядка третьего сжатия, точность — миллиметры в пределах зоны
+func (e ellipsoid) transverseMercator(lat0 float64, k0 float64) func(x, y float64) (float64, float64) {
+	n := e.f / (2 - e.f)
+	n2, n3 := n*n, n*n*n
+	A := e.a / (1 + n) * (1 + n2/4 + n2*n2/64)
+	alpha := [3]float64{n/2 - 2*n2/3 + 5*n3/16, 13*n2/48 - 3*n3/5, 61 * n3 / 240}
+	beta := [3]float64{n/2 - 2*n2/3 + 37*n3/96, n2/48 + n3/15, 17 * n3 / 480}
+	delta := [3]float64{2*n - 2*n2/3 - 2*n3, 7*n2/3 - 8*n3/5, 56 * n3 / 15}
+	c := 2 * math.Sqrt(n) / (1 + n)
+
+	// длина дуги меридиана до широты начала координат
+	t0 := math.Sinh(math.Atanh(math.Sin(lat0)) - c*math.Atanh(c*math.Sin(lat0)))
+	xi0 := math.Atan(t0)
+	m0 := xi0
+	for j := 1; j <= 3; j++ {
+		m0 += alpha[j-1] * math.Sin(2*float64(j)*xi0)
+	}
+	m0 *= A
+
+	return func(x, y float64) (float64, float64) {
+		xi := (y/k0 + m0) / A
+		eta := x / k0 / A
+		xi1, eta1 := xi, eta
+		for j := 1; j <= 3; j++ {
+			jj := 2 * float64(j)
+			xi1 -= beta[j-1] * math.Sin(jj*xi) * math.Cosh(jj*eta)
+			eta1 -= beta[j-1] * math.Cos(jj*xi) * math.Sinh(jj*eta)
+		}
+		chi := math.Asin(math.Sin(xi1) / math.Cosh(eta1))
+		lat := chi
+		for j := 1; j <= 3; j++ {
+			lat += delta[j-1] * math.Sin(2*float64(j)*chi)
+		}
+		return math.Atan2(math.Sinh(eta1), math.Cos(xi1)), lat
+	}
+}
+
+// mercator нормальная проекция Меркатора с масштабом k0 на экваторе
+func (e ellipsoid) mercator(k0 float64) func(x, y float64) (float64, float64) {
+	return func(x, y float64) (float64, float64) {
+		t := math.Exp(-y / (e.a * k0))
+		return x / (e.a * k0), e.latitude(t)
+	}
+}
+
+// lambertConformalConic равноугольная коническая проекция Ламберта
+// с одной (sp1 == sp2) или двумя стандартными параллелями
+func (e ellipsoid) lambertConformalConic(lat0, sp1, sp2, k0 float64) func(x, y float64) (float64, float64) {
+	m1, m2 := e.m(sp1), e.m(sp2)
+	t0, t1, t2 := e.t(lat0), e.t(sp1), e.t(sp2)
+	n := math.Sin(sp1)
+	if sp1 != sp2 {
+		n = (math.Log(m1) - math.Log(m2)) / (math.Log(t1) - math.Log(t2))
+	}
+	F := m1 / (n * math.Pow(t1, n))
+	rho0 := e.a * k0 * F * math.Pow(t0, n)
+
+	return func(x, y float64) (float64, float64) {
+		dy := rho0 - y
+		rho := math.Copysign(math.Hypot(x, dy), n)
+		theta := math.Atan2(math.Copysign(1, n)*x, math.Copysign(1, n)*dy)
+		if rho == 0 {
+			return 0, math.Copysign(math.Pi/2, n)
+		}
+		t := math.Pow(rho/(e.a*k0*F), 1/n)
+		return theta / n, e.latitude(t)
+	}
+}
+
+// m и t вспомогательные функции конформных проекций по Снайдеру
+func (e ellipsoid) m(lat float64) float64 {
+	s := math.Sin(lat)
+	return math.Cos(lat) / math.Sqrt(1-e.e2()*s*s)
+}
+
+func (e ellipsoid) t(lat float64) float64 {
+	ecc := math.Sqrt(e.e2())
+	s := math.Sin(lat)
+	return math.Tan(math.Pi/4-lat/2) / math.Pow((1-ecc*s)/(1+ecc*s), ecc/2)
+}
+
+// latitude находит широту по значению t итерациями
+func (e ellipsoid) latitude(t float64) float64 {
+	ecc := math.Sqrt(e.e2())
+	lat := math.Pi/2 - 2*math.Atan(t)
+	for i := 0; i < 15; i++ {
+		s := ecc * math.Sin(lat)
+		next := math.Pi/2 - 2*math.Atan(t*math.Pow((1-s)/(1+s), ecc/2))
+		if math.Abs(next-lat) < 1e-12 {
+			return next
+		}
+		lat = next
+	}
+	return lat
+}
@@ -0,0 +1,217 @@
+// Package shapefile читает и пишет ESRI Shapefile: геометрию .shp,
+// атрибуты .dbf, систему координат .prj и кодировку атрибутов .cpg.
+package shapefile
+
+import (
+	"errors"
+	"fmt"
+	"github.com/audetv/datasets-parser/app/repos/dataset"
+	"github.com/audetv/datasets-parser/dataset/coordinate"
+	"io"
+	"os"
+)
+
+// Companions расширения файлов, которые лежат рядом с .shp и читаются вместе с ним
+var Companions = []string{".dbf", ".shx", ".prj", ".cpg", ".sbn", ".sbx", ".qix", ".fix"}
+
+// Record запись shapefile: геометрия в градусах и атрибуты из dbf
+type Record struct {
+	// Number номер записи, начиная с 1
+	Number int
+	// Shape геометрия записи, nil если её не удалось разобрать
+	Shape *Shape
+	// Err ошибка разбора геометрии записи
+	Err error
+	// Attributes значения полей dbf, пустые значения пропускаются
+	Attributes map[string]interface{}
+}
+
+// Reader читает записи shapefile по порядку. Записи, помеченные в dbf
+// удалёнными, пропускаются.
+type Reader struct {
+	shp       *shpReader
+	dbf       *dbfReader
+	transform Transform
+	closers   []io.Closer
+}
+
+// Open открывает .shp файл src и лежащие рядом .dbf, .prj и .cpg.
+// Без .dbf у записей нет атрибутов, без .prj координаты считаются градусами.
+func Open(src dataset.Source) (*Reader, error) {
+	rc, err := src.Open()
+	if err != nil {
+		return nil, err
+	}
+	r := &Reader{closers: []io.Closer{rc}}
+	if r.shp, err = newShpReader(rc); err != nil {
+		r.Close()
+		return nil, err
+	}
+
+	prj, err := readSibling(src, ".prj")
+	if err != nil {
+		r.Close()
+		return nil, err
+	}
+	if prj != "" {
+		if r.transform, err = ParseProjection(prj); err != nil {
+			r.Close()
+			return nil, err
+		}
+	} else if h := r.shp.header; h.xmin < -360 || h.xmax > 360 || h.ymin < -90 || h.ymax > 90 {
+		r.Close()
+		return nil, fmt.Errorf("shapefile has no .prj file and its extent %v, %v – %v, %v is not in degrees", h.xmin, h.ymin, h.xmax, h.ymax)
+	}
+
+	cpg, err := readSibling(src, ".cpg")
+	if err != nil {
+		r.Close()
+		return nil, err
+	}
+	dbf, err := src.OpenSibling(".dbf")
+	if errors.Is(err, os.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		r.Close()
+		return nil, err
+	}
+	r.closers = append(r.closers, dbf)
+	if r.dbf, err = newDbfReader(dbf, cpg); err != nil {
+		r.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// readSibling возвращает содержимое соседнего файла, пустую строку если его нет
+func readSibling(src dataset.Source, ext string) (string, error) {
+	rc, err := src.OpenSibling(ext)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	b, err := io.ReadAll(rc)
+	if err != nil {
+		return "", fmt.Errorf("%v: %w", ext, err)
+	}
+	return string(b), nil
+}
+
+// Fields поля атрибутов dbf
+func (r *Reader) Fields() []Field {
+	if r.dbf == nil {
+		return nil
+	}
+	return r.dbf.fields
+}
+
+// Next возвращает следующую запись, io.EOF в конце файла
+func (r *Reader) Next() (*Record, error) {
+	for {
+		number, shape, err := r.shp.next()
+		if err != nil && number == 0 {
+			return nil, err
+		}
+		rec := &Record{Number: number, Shape: shape, Err: err}
+
+		if r.dbf != nil {
+			attributes, deleted, err := r.dbf.next()
+			if err != nil && err != io.EOF {
+				return nil, err
+			}
+			if deleted {
+				continue
+			}
+			rec.Attributes = attributes
+		}
+
+		if shape != nil && r.transform != nil {
+			for i := range shape.X {
+				shape.X[i], shape.Y[i] = r.transform(shape.X[i], shape.Y[i])
+			}
+		}
+		return rec, nil
+	}
+}
+
+// Close закрывает файлы shapefile
+func (r *Reader) Close() error {
+	var err error
+	for _, c := range r.closers {
+		if cerr := c.Close(); cerr != nil && err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// Point сводит геометрию к одной точке: точка Point, иначе сферический центр
+// точек MultiPoint, вершин линий и внешних колец полигонов.
+// Координаты Shape должны быть уже пересчитаны в градусы.
+func (s *Shape) Point() (coordinate.Point, bool) {
+	if len(s.X) == 0 {
+		return coordinate.Point{}, false
+	}
+	switch s.Type {
+	case Point, PointM, PointZ:
+		return coordinate.Point{Longitude: s.X[0], Latitude: s.Y[0]}, true
+	}
+
+	var points []coordinate.Point
+	switch s.Type {
+	case Polygon, PolygonM, PolygonZ:
+		points = s.outerRings()
+	default:
+		points = make([]coordinate.Point, len(s.X))
+		for i := range s.X {
+			points[i] = coordinate.Point{Longitude: s.X[i], Latitude: s.Y[i]}
+		}
+	}
+	if c, ok := coordinate.Centroid(points); ok {
+		return c, true
+	}
+	return coordinate.Point{Longitude: s.X[0], Latitude: s.Y[0]}, true
+}
+
+// Height высота точки PointZ, у остальных геометрий 0
+func (s *Shape) Height() float64 {
+	if s.Type == PointZ && len(s.Z) > 0 {
+		return s.Z[0]
+	}
+	return 0
+}
+
+// outerRings возвращает вершины внешних колец полигона без повторяющейся
+// последней вершины. Внешние кольца shapefile идут по часовой стрелке,
+// вырезы — против. Если колец по часовой стрелке нет, берутся все кольца.
+func (s *Shape) outerRings() []coordinate.Point {
+	var outer, all []coordinate.Point
+	for i := range s.Parts {
+		start, end := s.part(i)
+		if end-start > 1 && s.X[start] == s.X[end-1] && s.Y[start] == s.Y[end-1] {
+			end--
+		}
+		var area float64
+		ring := make([]coordinate.Point, 0, end-start)
+		for j := start; j < end; j++ {
+			next := j + 1
+			if next == end {
+				next = start
+			}
+			area += s.X[j]*s.Y[next] - s.X[next]*s.Y[j]
+			ring = append(ring, coordinate.Point{Longitude: s.X[j], Latitude: s.Y[j]})
+		}
+		if area < 0 {
+			outer = append(outer, ring...)
+		}
+		all = append(all, ring...)
+	}
+	if len(outer) == 0 {
+		return all
+	}
+	return outer
+}
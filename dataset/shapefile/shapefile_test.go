@@ -0,0 +1,178 @@
+package shapefile
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/audetv/datasets-parser/app/repos/dataset"
+)
+
+// readAll читает все записи shapefile
+func readAll(t *testing.T, src dataset.Source) ([]Field, []*Record) {
+	r, err := Open(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	var records []*Record
+	for {
+		rec, err := r.Next()
+		if err == io.EOF {
+			return r.Fields(), records
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		records = append(records, rec)
+	}
+}
+
+func TestWriteRead(t *testing.T) {
+	fields := []Field{
+		{Name: "name", Type: Character, Length: 17},
+		{Name: "population", Type: Numeric, Length: 6},
+		{Name: "height", Type: Float, Length: 8, Decimals: 2},
+		{Name: "capital", Type: Logical, Length: 1},
+		{Name: "founded", Type: Date, Length: 8},
+	}
+	base := filepath.Join(t.TempDir(), "places")
+	w, err := Create(base, fields)
+	if err != nil {
+		t.Fatal(err)
+	}
+	points := []struct {
+		lon, lat float64
+		values   []interface{}
+	}{
+		{37.6173, 55.7558, []interface{}{"Москва", 13010112, 156.5, true, time.Date(1147, 4, 4, 0, 0, 0, 0, time.UTC)}},
+		// строка обрезается по границе символа, перевод строки заменяется пробелом
+		{30.3351, 59.9343, []interface{}{"Санкт-\nПетербург", 560191, 3.0, false, nil}},
+		{-0.1278, 51.5074, []interface{}{"London", nil, nil, nil, nil}},
+	}
+	for _, p := range points {
+		if err = w.Write(p.lon, p.lat, p.values); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = w.Write(0, 0, []interface{}{"short"}); err == nil {
+		t.Error("record with missing values is written")
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	read, records := readAll(t, dataset.FileSource("places.shp", base+".shp"))
+	for i := range read {
+		read[i].Name = strings.ToLower(read[i].Name)
+	}
+	if !reflect.DeepEqual(read, fields) {
+		t.Errorf("fields %v, want %v", read, fields)
+	}
+	// числа шире поля записываются звёздочками и читаются как пустые
+	want := []map[string]interface{}{
+		{"NAME": "Москва", "HEIGHT": json.Number("156.50"), "CAPITAL": true, "FOUNDED": "1147-04-04"},
+		{"NAME": "Санкт- Пе", "POPULATION": json.Number("560191"), "HEIGHT": json.Number("3.00"), "CAPITAL": false},
+		{"NAME": "London"},
+	}
+	if len(records) != len(points) {
+		t.Fatalf("records %d, want %d", len(records), len(points))
+	}
+	for i, rec := range records {
+		if rec.Number != i+1 || rec.Err != nil {
+			t.Errorf("record %d: number %d, error %v", i, rec.Number, rec.Err)
+		}
+		p, ok := rec.Shape.Point()
+		// .prj с WGS 84 пересчитывает градусы через радианы
+		if !ok || math.Abs(p.Longitude-points[i].lon) > 1e-12 || math.Abs(p.Latitude-points[i].lat) > 1e-12 {
+			t.Errorf("record %d: point %v, want %v, %v", i, p, points[i].lon, points[i].lat)
+		}
+		if !reflect.DeepEqual(rec.Attributes, want[i]) {
+			t.Errorf("record %d: attributes %v, want %v", i, rec.Attributes, want[i])
+		}
+	}
+}
+
+// testdata/bng — точки в британской национальной сетке (поперечная Меркатора на эллипсоиде Эйри)
+func TestReadProjected(t *testing.T) {
+	_, records := readAll(t, dataset.FileSource("bng.shp", "testdata/bng.shp"))
+	want := []struct {
+		name     string
+		lon, lat float64
+	}{
+		{"EPSG example", 0.5, 50.5},
+		{"true origin", -2, 49},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("records %d, want %d", len(records), len(want))
+	}
+	for i, rec := range records {
+		p, _ := rec.Shape.Point()
+		if rec.Attributes["NAME"] != want[i].name || math.Abs(p.Longitude-want[i].lon) > 2e-7 || math.Abs(p.Latitude-want[i].lat) > 2e-7 {
+			t.Errorf("%v at %.9f, %.9f, want %v at %v, %v", rec.Attributes["NAME"], p.Longitude, p.Latitude, want[i].name, want[i].lon, want[i].lat)
+		}
+	}
+}
+
+// Примеры EPSG Guidance Note 7-2, координаты проекций даны с точностью до сантиметра
+func TestParseProjection(t *testing.T) {
+	tests := []struct {
+		name     string
+		prj      string
+		x, y     float64
+		lon, lat float64
+	}{
+		{
+			"Mercator 1SP, Makassar / NEIEZ",
+			`PROJCS["Makassar / NEIEZ",GEOGCS["Makassar",DATUM["Makassar",SPHEROID["Bessel 1841",6377397.155,299.1528128]],PRIMEM["Greenwich",0],UNIT["degree",0.0174532925199433]],PROJECTION["Mercator_1SP"],PARAMETER["central_meridian",110],PARAMETER["scale_factor",0.997],PARAMETER["false_easting",3900000],PARAMETER["false_northing",900000],UNIT["metre",1]]`,
+			5009726.58, 569150.82, 120, -3,
+		},
+		{
+			"Mercator 2SP, Pulkovo 1942 / Caspian Sea Mercator",
+			`PROJCS["Pulkovo 1942 / Caspian Sea Mercator",GEOGCS["Pulkovo 1942",DATUM["Pulkovo_1942",SPHEROID["Krassowsky 1940",6378245,298.3]],PRIMEM["Greenwich",0],UNIT["degree",0.0174532925199433]],PROJECTION["Mercator_2SP"],PARAMETER["standard_parallel_1",42],PARAMETER["central_meridian",51],PARAMETER["false_easting",0],PARAMETER["false_northing",0],UNIT["metre",1]]`,
+			165704.29, 5171848.07, 53, 53,
+		},
+		{
+			"Pseudo-Mercator",
+			`PROJCS["WGS 84 / Pseudo-Mercator",GEOGCS["WGS 84",DATUM["WGS_1984",SPHEROID["WGS 84",6378137,298.257223563]],PRIMEM["Greenwich",0],UNIT["degree",0.0174532925199433]],PROJECTION["Mercator_Auxiliary_Sphere"],PARAMETER["central_meridian",0],PARAMETER["false_easting",0],PARAMETER["false_northing",0],UNIT["metre",1]]`,
+			-11169055.58, 2800000.00, -100.333333333, 24.381786944,
+		},
+		{
+			"Lambert Conic Conformal 1SP, JAD69 / Jamaica National Grid",
+			`PROJCS["JAD69 / Jamaica National Grid",GEOGCS["JAD69",DATUM["Jamaica_1969",SPHEROID["Clarke 1866",6378206.4,294.9786982]],PRIMEM["Greenwich",0],UNIT["degree",0.0174532925199433]],PROJECTION["Lambert_Conformal_Conic_1SP"],PARAMETER["latitude_of_origin",18],PARAMETER["central_meridian",-77],PARAMETER["scale_factor",1],PARAMETER["false_easting",250000],PARAMETER["false_northing",150000],UNIT["metre",1]]`,
+			255966.58, 142493.51, -(76 + 56./60 + 37.26/3600), 17 + 55./60 + 55.80/3600,
+		},
+		{
+			"Lambert Conic Conformal 2SP, NAD27 / Texas South Central, US survey feet",
+			`PROJCS["NAD27 / Texas South Central",GEOGCS["NAD27",DATUM["North_American_Datum_1927",SPHEROID["Clarke 1866",6378206.4,294.9786982]],PRIMEM["Greenwich",0],UNIT["degree",0.0174532925199433]],PROJECTION["Lambert_Conformal_Conic_2SP"],PARAMETER["standard_parallel_1",28.38333333333333],PARAMETER["standard_parallel_2",30.28333333333333],PARAMETER["latitude_of_origin",27.83333333333333],PARAMETER["central_meridian",-99],PARAMETER["false_easting",2000000],PARAMETER["false_northing",0],UNIT["US survey foot",0.3048006096012192]]`,
+			2963503.91, 254759.80, -96, 28.5,
+		},
+		{
+			"geographic, grads from Paris",
+			`GEOGCS["NTF (Paris)",DATUM["Nouvelle_Triangulation_Francaise_Paris",SPHEROID["Clarke 1880 (IGN)",6378249.2,293.4660212936269]],PRIMEM["Paris",2.5969213],UNIT["grad",0.01570796326794897]]`,
+			0, 50, 2.33722917, 45,
+		},
+	}
+	for _, tt := range tests {
+		transform, err := ParseProjection(tt.prj)
+		if err != nil {
+			t.Errorf("%v: %v", tt.name, err)
+			continue
+		}
+		lon, lat := transform(tt.x, tt.y)
+		// сантиметр — около 1e-7 градуса
+		if math.Abs(lon-tt.lon) > 2e-7 || math.Abs(lat-tt.lat) > 2e-7 {
+			t.Errorf("%v: %.9f, %.9f, want %.9f, %.9f", tt.name, lon, lat, tt.lon, tt.lat)
+		}
+	}
+
+	_, err := ParseProjection(`PROJCS["x",GEOGCS["WGS 84",DATUM["WGS_1984",SPHEROID["WGS 84",6378137,298.257223563]]],PROJECTION["Polyconic"],UNIT["metre",1]]`)
+	if err == nil || !strings.Contains(err.Error(), ErrProjection.Error()) {
+		t.Errorf("unsupported projection: %v", err)
+	}
+}
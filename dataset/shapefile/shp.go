@@ -0,0 +1,243 @@
+package shapefile
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+)
+
+// ShapeType тип геометрии записи shp файла
+type ShapeType int32
+
+const (
+	NullShape   ShapeType = 0
+	Point       ShapeType = 1
+	PolyLine    ShapeType = 3
+	Polygon     ShapeType = 5
+	MultiPoint  ShapeType = 8
+	PointZ      ShapeType = 11
+	PolyLineZ   ShapeType = 13
+	PolygonZ    ShapeType = 15
+	MultiPointZ ShapeType = 18
+	PointM      ShapeType = 21
+	PolyLineM   ShapeType = 23
+	PolygonM    ShapeType = 25
+	MultiPointM ShapeType = 28
+	MultiPatch  ShapeType = 31
+)
+
+var shapeTypeNames = map[ShapeType]string{
+	NullShape: "NullShape", Point: "Point", PolyLine: "PolyLine", Polygon: "Polygon", MultiPoint: "MultiPoint",
+	PointZ: "PointZ", PolyLineZ: "PolyLineZ", PolygonZ: "PolygonZ", MultiPointZ: "MultiPointZ",
+	PointM: "PointM", PolyLineM: "PolyLineM", PolygonM: "PolygonM", MultiPointM: "MultiPointM",
+	MultiPatch: "MultiPatch",
+}
+
+func (t ShapeType) String() string {
+	if name, ok := shapeTypeNames[t]; ok {
+		return name
+	}
+	return fmt.Sprintf("ShapeType(%d)", int32(t))
+}
+
+// hasZ сообщает, что у вершин геометрии есть высота
+func (t ShapeType) hasZ() bool {
+	switch t {
+	case PointZ, PolyLineZ, PolygonZ, MultiPointZ, MultiPatch:
+		return true
+	}
+	return false
+}
+
+const (
+	fileCode     = 9994
+	headerLength = 100
+)
+
+// header заголовок shp и shx файла
+type header struct {
+	// length длина файла в 16-битных словах
+	length int32
+	kind   ShapeType
+	// xmin, ymin, xmax, ymax охват всех геометрий файла
+	xmin, ymin, xmax, ymax float64
+}
+
+func readHeader(r io.Reader) (header, error) {
+	var b [headerLength]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return header{}, fmt.Errorf("shp header: %w", err)
+	}
+	if code := binary.BigEndian.Uint32(b[0:]); code != fileCode {
+		return header{}, fmt.Errorf("not a shapefile: file code %d", code)
+	}
+	return header{
+		length: int32(binary.BigEndian.Uint32(b[24:])),
+		kind:   ShapeType(binary.LittleEndian.Uint32(b[32:])),
+		xmin:   float64At(b[:], 36),
+		ymin:   float64At(b[:], 44),
+		xmax:   float64At(b[:], 52),
+		ymax:   float64At(b[:], 60),
+	}, nil
+}
+
+// Shape геометрия записи в координатах файла
+type Shape struct {
+	Type ShapeType
+	// X, Y координаты вершин
+	X, Y []float64
+	// Z высоты вершин, пусто у геометрий без высоты
+	Z []float64
+	// Parts индексы первых вершин частей линии или колец полигона
+	Parts []int
+}
+
+// part возвращает индексы вершин части i
+func (s *Shape) part(i int) (int, int) {
+	end := len(s.X)
+	if i+1 < len(s.Parts) {
+		end = s.Parts[i+1]
+	}
+	return s.Parts[i], end
+}
+
+// shpReader читает записи shp файла по порядку
+type shpReader struct {
+	r      *bufio.Reader
+	header header
+	// offset прочитано байт от начала файла
+	offset int64
+	buf    []byte
+}
+
+func newShpReader(r io.Reader) (*shpReader, error) {
+	br := bufio.NewReader(r)
+	h, err := readHeader(br)
+	if err != nil {
+		return nil, err
+	}
+	return &shpReader{r: br, header: h, offset: headerLength}, nil
+}
+
+// next возвращает номер и геометрию следующей записи, io.EOF в конце файла
+func (sr *shpReader) next() (int, *Shape, error) {
+	if sr.offset >= int64(sr.header.length)*2 {
+		return 0, nil, io.EOF
+	}
+	var rh [8]byte
+	if _, err := io.ReadFull(sr.r, rh[:]); err != nil {
+		if err == io.EOF {
+			// длина в заголовке больше, чем записано в файл
+			return 0, nil, io.EOF
+		}
+		return 0, nil, fmt.Errorf("shp record header at %d: %w", sr.offset, err)
+	}
+	number := int(binary.BigEndian.Uint32(rh[0:]))
+	length := int(binary.BigEndian.Uint32(rh[4:])) * 2
+	if length < 4 || length > 1<<30 {
+		return 0, nil, fmt.Errorf("shp record %d: bad content length %d", number, length)
+	}
+	if cap(sr.buf) < length {
+		sr.buf = make([]byte, length)
+	}
+	content := sr.buf[:length]
+	if _, err := io.ReadFull(sr.r, content); err != nil {
+		return 0, nil, fmt.Errorf("shp record %d: %w", number, err)
+	}
+	sr.offset += int64(8 + length)
+
+	s, err := parseShape(content)
+	if err != nil {
+		return number, nil, fmt.Errorf("shp record %d: %w", number, err)
+	}
+	return number, s, nil
+}
+
+var errShort = errors.New("record content is shorter than its geometry")
+
+// parseShape разбирает содержимое записи. Значения M не нужны и пропускаются.
+func parseShape(b []byte) (*Shape, error) {
+	s := &Shape{Type: ShapeType(binary.LittleEndian.Uint32(b))}
+	b = b[4:]
+
+	switch s.Type {
+	case NullShape:
+		return s, nil
+	case Point, PointM, PointZ:
+		need := 16
+		if s.Type == PointZ {
+			need = 24
+		}
+		if len(b) < need {
+			return nil, errShort
+		}
+		s.X, s.Y = []float64{float64At(b, 0)}, []float64{float64At(b, 8)}
+		if s.Type == PointZ {
+			s.Z = []float64{float64At(b, 16)}
+		}
+		return s, nil
+	case MultiPoint, MultiPointM, MultiPointZ:
+		if len(b) < 36 {
+			return nil, errShort
+		}
+		n := int(binary.LittleEndian.Uint32(b[32:]))
+		return s, s.readPoints(b, 36, n)
+	case PolyLine, PolyLineM, PolyLineZ, Polygon, PolygonM, PolygonZ, MultiPatch:
+		if len(b) < 40 {
+			return nil, errShort
+		}
+		parts := int(binary.LittleEndian.Uint32(b[32:]))
+		n := int(binary.LittleEndian.Uint32(b[36:]))
+		offset := 40
+		if parts < 0 || len(b) < offset+parts*4 {
+			return nil, errShort
+		}
+		s.Parts = make([]int, parts)
+		for i := range s.Parts {
+			s.Parts[i] = int(binary.LittleEndian.Uint32(b[offset+i*4:]))
+			if s.Parts[i] < 0 || s.Parts[i] > n || (i > 0 && s.Parts[i] < s.Parts[i-1]) {
+				return nil, fmt.Errorf("bad part index %d", s.Parts[i])
+			}
+		}
+		offset += parts * 4
+		if s.Type == MultiPatch {
+			// типы частей: полосы и веера треугольников, кольца
+			offset += parts * 4
+		}
+		return s, s.readPoints(b, offset, n)
+	}
+	return nil, fmt.Errorf("unsupported shape type %v", s.Type)
+}
+
+// readPoints читает n вершин с offset и, у геометрий с высотой,
+// массив высот после диапазона высот
+func (s *Shape) readPoints(b []byte, offset int, n int) error {
+	if n < 0 || len(b) < offset+n*16 {
+		return errShort
+	}
+	s.X, s.Y = make([]float64, n), make([]float64, n)
+	for i := 0; i < n; i++ {
+		s.X[i] = float64At(b, offset+i*16)
+		s.Y[i] = float64At(b, offset+i*16+8)
+	}
+	if !s.Type.hasZ() {
+		return nil
+	}
+	offset += n*16 + 16
+	if len(b) < offset+n*8 {
+		// высоты необязательны для чтения, без них точка всё равно определена
+		return nil
+	}
+	s.Z = make([]float64, n)
+	for i := range s.Z {
+		s.Z[i] = float64At(b, offset+i*8)
+	}
+	return nil
+}
+
+func float64At(b []byte, offset int) float64 {
+	return math.Float64frombits(binary.LittleEndian.Uint64(b[offset:]))
+}
@@ -0,0 +1,259 @@
+package shapefile
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Writer записывает точки с атрибутами в shapefile: .shp, .shx, .dbf,
+// а также .prj с WGS 84 и .cpg с кодировкой UTF-8
+type Writer struct {
+	fields []Field
+	shp    *output
+	shx    *output
+	dbf    *output
+	count  int
+	// xmin, ymin, xmax, ymax охват записанных точек
+	xmin, ymin, xmax, ymax float64
+}
+
+// output файл, который пишется потоком, а заголовок дописывается при закрытии
+type output struct {
+	f *os.File
+	w *bufio.Writer
+	// size записано байт
+	size int64
+}
+
+func (o *output) write(b []byte) error {
+	n, err := o.w.Write(b)
+	o.size += int64(n)
+	return err
+}
+
+// Create создаёт shapefile с именем base без расширения и полями атрибутов fields.
+// Имена полей dbf не длиннее 10 символов, строковые поля не длиннее 254 байт.
+func Create(base string, fields []Field) (*Writer, error) {
+	for _, f := range fields {
+		if f.Name == "" || len(f.Name) > 10 {
+			return nil, fmt.Errorf("dbf field name %q must be 1 to 10 characters", f.Name)
+		}
+		if f.Length < 1 || f.Length > 254 {
+			return nil, fmt.Errorf("dbf field %v length %d not in [1, 254]", f.Name, f.Length)
+		}
+	}
+
+	w := &Writer{
+		fields: fields,
+		xmin:   math.Inf(1), ymin: math.Inf(1), xmax: math.Inf(-1), ymax: math.Inf(-1),
+	}
+	for _, companion := range []struct {
+		ext     string
+		content string
+	}{{".prj", WGS84}, {".cpg", "UTF-8"}} {
+		if err := os.WriteFile(base+companion.ext, []byte(companion.content), 0644); err != nil {
+			return nil, err
+		}
+	}
+
+	var err error
+	if w.shp, err = create(base+".shp", headerLength); err != nil {
+		return nil, err
+	}
+	if w.shx, err = create(base+".shx", headerLength); err != nil {
+		w.shp.f.Close()
+		return nil, err
+	}
+	if w.dbf, err = create(base+".dbf", dbfHeaderLength+dbfFieldLength*len(fields)+1); err != nil {
+		w.shp.f.Close()
+		w.shx.f.Close()
+		return nil, err
+	}
+	return w, nil
+}
+
+// create создаёт файл и пропускает место под заголовок
+func create(name string, headerSize int) (*output, error) {
+	f, err := os.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	o := &output{f: f, w: bufio.NewWriter(f)}
+	if err = o.write(make([]byte, headerSize)); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return o, nil
+}
+
+// Write записывает точку и значения её атрибутов в порядке полей.
+// Значения: строки, числа, bool и time.Time, nil — пустое значение.
+func (w *Writer) Write(lon float64, lat float64, values []interface{}) error {
+	if len(values) != len(w.fields) {
+		return fmt.Errorf("shapefile record has %d values, expected %d", len(values), len(w.fields))
+	}
+	w.count++
+
+	// запись shp: номер, длина содержимого в словах, тип и координаты точки
+	var rec [28]byte
+	binary.BigEndian.PutUint32(rec[0:], uint32(w.count))
+	binary.BigEndian.PutUint32(rec[4:], 10)
+	binary.LittleEndian.PutUint32(rec[8:], uint32(Point))
+	binary.LittleEndian.PutUint64(rec[12:], math.Float64bits(lon))
+	binary.LittleEndian.PutUint64(rec[20:], math.Float64bits(lat))
+
+	var idx [8]byte
+	binary.BigEndian.PutUint32(idx[0:], uint32(w.shp.size/2))
+	binary.BigEndian.PutUint32(idx[4:], 10)
+
+	if err := w.shp.write(rec[:]); err != nil {
+		return err
+	}
+	if err := w.shx.write(idx[:]); err != nil {
+		return err
+	}
+	w.xmin, w.xmax = math.Min(w.xmin, lon), math.Max(w.xmax, lon)
+	w.ymin, w.ymax = math.Min(w.ymin, lat), math.Max(w.ymax, lat)
+
+	record := make([]byte, 1, 1+w.recordLength())
+	record[0] = ' '
+	for i, f := range w.fields {
+		record = append(record, format(f, values[i])...)
+	}
+	return w.dbf.write(record)
+}
+
+// Close дописывает заголовки и закрывает файлы
+func (w *Writer) Close() error {
+	if w.count == 0 {
+		w.xmin, w.ymin, w.xmax, w.ymax = 0, 0, 0, 0
+	}
+	errs := []error{
+		w.dbf.write([]byte{dbfEOF}),
+		w.shp.close(w.shpHeader(w.shp.size)),
+		w.shx.close(w.shpHeader(w.shx.size)),
+		w.dbf.close(w.dbfHeader()),
+	}
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Count количество записанных точек
+func (w *Writer) Count() int {
+	return w.count
+}
+
+func (o *output) close(header []byte) error {
+	err := o.w.Flush()
+	if err == nil {
+		_, err = o.f.WriteAt(header, 0)
+	}
+	if cerr := o.f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func (w *Writer) shpHeader(size int64) []byte {
+	h := make([]byte, headerLength)
+	binary.BigEndian.PutUint32(h[0:], fileCode)
+	binary.BigEndian.PutUint32(h[24:], uint32(size/2))
+	binary.LittleEndian.PutUint32(h[28:], 1000)
+	binary.LittleEndian.PutUint32(h[32:], uint32(Point))
+	for i, v := range []float64{w.xmin, w.ymin, w.xmax, w.ymax} {
+		binary.LittleEndian.PutUint64(h[36+i*8:], math.Float64bits(v))
+	}
+	return h
+}
+
+func (w *Writer) recordLength() int {
+	length := 1
+	for _, f := range w.fields {
+		length += f.Length
+	}
+	return length
+}
+
+func (w *Writer) dbfHeader() []byte {
+	headerLen := dbfHeaderLength + dbfFieldLength*len(w.fields) + 1
+	h := make([]byte, headerLen)
+	now := time.Now()
+	h[0] = 0x03
+	h[1], h[2], h[3] = byte(now.Year()-1900), byte(now.Month()), byte(now.Day())
+	binary.LittleEndian.PutUint32(h[4:], uint32(w.count))
+	binary.LittleEndian.PutUint16(h[8:], uint16(headerLen))
+	binary.LittleEndian.PutUint16(h[10:], uint16(w.recordLength()))
+	for i, f := range w.fields {
+		fd := h[dbfHeaderLength+i*dbfFieldLength:]
+		copy(fd[:10], strings.ToUpper(f.Name))
+		fd[11] = f.Type
+		fd[16] = byte(f.Length)
+		fd[17] = byte(f.Decimals)
+	}
+	h[headerLen-1] = dbfTerminator
+	return h
+}
+
+// lineBreaks переводы строк, которые ломают строку таблицы в ГИС
+var lineBreaks = strings.NewReplacer("\r\n", " ", "\r", " ", "\n", " ")
+
+// format записывает значение в поле фиксированной ширины: строки
+// выравниваются влево и обрезаются по границе символа, числа — вправо
+func format(f Field, value interface{}) []byte {
+	var text string
+	switch v := value.(type) {
+	case nil:
+	case string:
+		text = v
+	case bool:
+		text = "F"
+		if v {
+			text = "T"
+		}
+	case time.Time:
+		text = v.Format("20060102")
+	case float64:
+		if !math.IsNaN(v) && !math.IsInf(v, 0) {
+			text = strconv.FormatFloat(v, 'f', f.Decimals, 64)
+		}
+	case int:
+		text = strconv.Itoa(v)
+	case int64:
+		text = strconv.FormatInt(v, 10)
+	case uint64:
+		text = strconv.FormatUint(v, 10)
+	default:
+		text = fmt.Sprint(v)
+	}
+
+	b := make([]byte, 0, f.Length)
+	switch f.Type {
+	case Numeric, Float:
+		if len(text) > f.Length {
+			// число не помещается в поле
+			text = strings.Repeat("*", f.Length)
+		}
+		b = append(b, strings.Repeat(" ", f.Length-len(text))...)
+		b = append(b, text...)
+	default:
+		text = lineBreaks.Replace(text)
+		for len(text) > f.Length {
+			_, size := utf8.DecodeLastRuneInString(text)
+			text = text[:len(text)-size]
+		}
+		b = append(b, text...)
+		b = append(b, strings.Repeat(" ", f.Length-len(text))...)
+	}
+	return b
+}
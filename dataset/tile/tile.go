@@ -0,0 +1,104 @@
+// Package tile вычисляет тайлы карты в проекции Web Mercator (схема slippy map z/x/y,
+// как у OpenStreetMap) и их quadkey, как у Bing Maps.
+package tile
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// MaxZoom наибольший масштаб: quadkey Bing Maps не длиннее 23 символов
+const MaxZoom = 23
+
+// MaxLatitude граница проекции Web Mercator, точки севернее и южнее попадают в крайние тайлы
+const MaxLatitude = 85.05112877980659
+
+// Tile тайл карты: X растёт на восток от 180-го меридиана, Y — на юг от MaxLatitude
+type Tile struct {
+	Zoom int
+	X    int
+	Y    int
+}
+
+// At возвращает тайл масштаба zoom, в который попадает точка
+func At(lat float64, lon float64, zoom int) Tile {
+	n := math.Exp2(float64(zoom))
+	lat = math.Max(-MaxLatitude, math.Min(MaxLatitude, lat))
+	phi := lat * math.Pi / 180
+
+	x := int(math.Floor((lon + 180) / 360 * n))
+	y := int(math.Floor((1 - math.Log(math.Tan(phi)+1/math.Cos(phi))/math.Pi) / 2 * n))
+	return Tile{Zoom: zoom, X: clamp(x, int(n)-1), Y: clamp(y, int(n)-1)}
+}
+
+func clamp(v int, max int) int {
+	if v < 0 {
+		return 0
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
+
+// String возвращает тайл в виде «z/x/y»
+func (t Tile) String() string {
+	return fmt.Sprintf("%d/%d/%d", t.Zoom, t.X, t.Y)
+}
+
+// Quadkey возвращает quadkey тайла: по цифре 0–3 на каждый масштаб, у тайла масштаба 0 он пустой
+func (t Tile) Quadkey() string {
+	var b strings.Builder
+	b.Grow(t.Zoom)
+	for i := t.Zoom; i > 0; i-- {
+		digit := byte('0')
+		mask := 1 << (i - 1)
+		if t.X&mask != 0 {
+			digit++
+		}
+		if t.Y&mask != 0 {
+			digit += 2
+		}
+		b.WriteByte(digit)
+	}
+	return b.String()
+}
+
+// ParseQuadkey возвращает тайл по quadkey
+func ParseQuadkey(quadkey string) (Tile, error) {
+	if len(quadkey) > MaxZoom {
+		return Tile{}, fmt.Errorf("quadkey %q is longer than %d", quadkey, MaxZoom)
+	}
+	t := Tile{Zoom: len(quadkey)}
+	for i := 0; i < len(quadkey); i++ {
+		mask := 1 << (t.Zoom - i - 1)
+		switch quadkey[i] {
+		case '0':
+		case '1':
+			t.X |= mask
+		case '2':
+			t.Y |= mask
+		case '3':
+			t.X |= mask
+			t.Y |= mask
+		default:
+			return Tile{}, fmt.Errorf("invalid quadkey %q", quadkey)
+		}
+	}
+	return t, nil
+}
+
+// Bounds возвращает границы тайла в градусах: запад, юг, восток, север
+func (t Tile) Bounds() (west float64, south float64, east float64, north float64) {
+	n := math.Exp2(float64(t.Zoom))
+	west = float64(t.X)/n*360 - 180
+	east = float64(t.X+1)/n*360 - 180
+	north = latitude(float64(t.Y), n)
+	south = latitude(float64(t.Y+1), n)
+	return west, south, east, north
+}
+
+func latitude(y float64, n float64) float64 {
+	return math.Atan(math.Sinh(math.Pi*(1-2*y/n))) * 180 / math.Pi
+}
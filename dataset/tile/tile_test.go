@@ -0,0 +1,71 @@
+package tile
+
+import (
+	"math"
+	"testing"
+)
+
+func TestAt(t *testing.T) {
+	tests := []struct {
+		lat, lon float64
+		zoom     int
+		want     Tile
+	}{
+		{0, 0, 0, Tile{0, 0, 0}},
+		{0, 0, 1, Tile{1, 1, 1}},
+		{55.7539, 37.6204, 12, Tile{12, 2476, 1280}},
+		{55.7539, 37.6204, 8, Tile{8, 154, 80}},
+		// точки за границей проекции и на 180-м меридиане попадают в крайние тайлы
+		{89.9, -180, 3, Tile{3, 0, 0}},
+		{-90, 180, 3, Tile{3, 7, 7}},
+	}
+	for _, tt := range tests {
+		if got := At(tt.lat, tt.lon, tt.zoom); got != tt.want {
+			t.Errorf("At(%v, %v, %d) = %v, want %v", tt.lat, tt.lon, tt.zoom, got, tt.want)
+		}
+	}
+}
+
+func TestQuadkey(t *testing.T) {
+	tests := []struct {
+		tile Tile
+		want string
+	}{
+		{Tile{0, 0, 0}, ""},
+		{Tile{1, 1, 0}, "1"},
+		{Tile{1, 0, 1}, "2"},
+		// пример из документации Bing Maps
+		{Tile{3, 3, 5}, "213"},
+		{Tile{12, 2476, 1280}, "120310101100"},
+	}
+	for _, tt := range tests {
+		if got := tt.tile.Quadkey(); got != tt.want {
+			t.Errorf("%v quadkey %q, want %q", tt.tile, got, tt.want)
+		}
+		if got, err := ParseQuadkey(tt.want); err != nil || got != tt.tile {
+			t.Errorf("ParseQuadkey(%q) = %v, %v, want %v", tt.want, got, err, tt.tile)
+		}
+	}
+	for _, q := range []string{"124", "012301230123012301230123"} {
+		if _, err := ParseQuadkey(q); err == nil {
+			t.Errorf("%q accepted", q)
+		}
+	}
+}
+
+func TestBounds(t *testing.T) {
+	west, south, east, north := Tile{1, 1, 0}.Bounds()
+	if west != 0 || east != 180 || south != 0 || math.Abs(north-MaxLatitude) > 1e-9 {
+		t.Errorf("bounds %v %v %v %v", west, south, east, north)
+	}
+
+	// точка внутри своего тайла
+	tile := At(55.7539, 37.6204, 12)
+	west, south, east, north = tile.Bounds()
+	if 37.6204 < west || 37.6204 > east || 55.7539 < south || 55.7539 > north {
+		t.Errorf("%v bounds %v %v %v %v", tile, west, south, east, north)
+	}
+	if tile.String() != "12/2476/1280" {
+		t.Errorf("string %q", tile.String())
+	}
+}
@@ -0,0 +1,200 @@
+package xlsx
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Типы связей частей книги, окончания одинаковы в transitional и strict вариантах формата
+const (
+	typeOfficeDocument = "/officeDocument"
+	typeSharedStrings  = "/sharedStrings"
+	typeStyles         = "/styles"
+	typeWorksheet      = "/worksheet"
+)
+
+type xmlRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Type   string `xml:"Type,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+// rels связи части книги с путями целей внутри архива
+type rels struct {
+	ids     map[string]string
+	types   map[string]string
+	targets map[string]string
+}
+
+// relationships читает связи части part из «_rels/<part>.rels» рядом с ней
+func (w *Workbook) relationships(part string) (*rels, error) {
+	dir, file := path.Split(part)
+	var x xmlRelationships
+	if err := w.decode(path.Join(dir, "_rels", file+".rels"), &x); err != nil {
+		return nil, fmt.Errorf("xlsx: %w", err)
+	}
+	r := &rels{ids: make(map[string]string), types: make(map[string]string), targets: make(map[string]string)}
+	for _, rel := range x.Relationships {
+		target := rel.Target
+		if strings.HasPrefix(target, "/") {
+			target = strings.TrimPrefix(target, "/")
+		} else {
+			target = path.Join(dir, target)
+		}
+		r.targets[rel.ID] = target
+		r.types[rel.ID] = rel.Type
+		for _, t := range []string{typeOfficeDocument, typeSharedStrings, typeStyles, typeWorksheet} {
+			if strings.HasSuffix(rel.Type, t) {
+				if _, ok := r.ids[t]; !ok {
+					r.ids[t] = rel.ID
+				}
+			}
+		}
+	}
+	return r, nil
+}
+
+// target возвращает путь первой части с типом связи kind
+func (r *rels) target(kind string) (string, bool) {
+	id, ok := r.ids[kind]
+	if !ok {
+		return "", false
+	}
+	return r.targets[id], true
+}
+
+// sheet возвращает путь листа по идентификатору связи
+func (r *rels) sheet(id string) (string, error) {
+	target, ok := r.targets[id]
+	if !ok || !strings.HasSuffix(r.types[id], typeWorksheet) {
+		return "", fmt.Errorf("worksheet relationship %q not found", id)
+	}
+	return target, nil
+}
+
+// mainPart возвращает путь основной части книги, обычно «xl/workbook.xml»
+func (w *Workbook) mainPart() (string, error) {
+	r, err := w.relationships("")
+	if err != nil {
+		return "", err
+	}
+	part, ok := r.target(typeOfficeDocument)
+	if !ok {
+		return "", fmt.Errorf("xlsx: workbook part not found, not an Excel workbook")
+	}
+	return part, nil
+}
+
+type xmlWorkbook struct {
+	Properties struct {
+		Date1904 string `xml:"date1904,attr"`
+	} `xml:"workbookPr"`
+	Sheets []struct {
+		Name  string `xml:"name,attr"`
+		State string `xml:"state,attr"`
+		// ID идентификатор связи r:id
+		ID string `xml:"id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+// readWorkbook читает листы книги и систему дат. Вместо пути листа
+// запоминается идентификатор связи, путь находится по связям книги.
+func (w *Workbook) readWorkbook(part string) error {
+	var x xmlWorkbook
+	if err := w.decode(part, &x); err != nil {
+		return fmt.Errorf("xlsx: %w", err)
+	}
+	w.date1904 = x.Properties.Date1904 == "1" || x.Properties.Date1904 == "true"
+	for _, s := range x.Sheets {
+		w.Sheets = append(w.Sheets, Sheet{Name: s.Name, Hidden: s.State != "" && s.State != "visible", part: s.ID})
+	}
+	if len(w.Sheets) == 0 {
+		return fmt.Errorf("xlsx: workbook has no sheets")
+	}
+	return nil
+}
+
+// xmlText строка с форматированием: простой текст t или фрагменты r,
+// фонетические подсказки rPh не читаются
+type xmlText struct {
+	T    string `xml:"t"`
+	Runs []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t xmlText) String() string {
+	if len(t.Runs) == 0 {
+		return t.T
+	}
+	var b strings.Builder
+	b.WriteString(t.T)
+	for _, r := range t.Runs {
+		b.WriteString(r.T)
+	}
+	return b.String()
+}
+
+func (w *Workbook) readSharedStrings(part string) error {
+	var x struct {
+		Items []xmlText `xml:"si"`
+	}
+	if err := w.decode(part, &x); err != nil {
+		return fmt.Errorf("xlsx: %w", err)
+	}
+	w.shared = make([]string, len(x.Items))
+	for i, si := range x.Items {
+		w.shared[i] = si.String()
+	}
+	return nil
+}
+
+type xmlStyles struct {
+	NumFmts []struct {
+		ID   int    `xml:"numFmtId,attr"`
+		Code string `xml:"formatCode,attr"`
+	} `xml:"numFmts>numFmt"`
+	CellXfs []struct {
+		NumFmtID int `xml:"numFmtId,attr"`
+	} `xml:"cellXfs>xf"`
+}
+
+// readStyles отмечает стили ячеек с форматом даты или времени
+func (w *Workbook) readStyles(part string) error {
+	var x xmlStyles
+	if err := w.decode(part, &x); err != nil {
+		return fmt.Errorf("xlsx: %w", err)
+	}
+	codes := make(map[int]string, len(x.NumFmts))
+	for _, f := range x.NumFmts {
+		codes[f.ID] = f.Code
+	}
+	w.dates = make([]bool, len(x.CellXfs))
+	for i, xf := range x.CellXfs {
+		if code, ok := codes[xf.NumFmtID]; ok {
+			w.dates[i] = isDateFormat(code)
+		} else {
+			w.dates[i] = isDateFormatID(xf.NumFmtID)
+		}
+	}
+	return nil
+}
+
+type xmlRow struct {
+	R     int       `xml:"r,attr"`
+	Cells []xmlCell `xml:"c"`
+}
+
+type xmlCell struct {
+	R string `xml:"r,attr"`
+	// S номер стиля ячейки в cellXfs
+	S int `xml:"s,attr"`
+	// T тип значения: s — общая строка, inlineStr, str — результат формулы,
+	// b — логическое, e — ошибка, d — дата ISO 8601, n или пусто — число
+	T  string  `xml:"t,attr"`
+	V  string  `xml:"v"`
+	Is xmlText `xml:"is"`
+}
@@ -0,0 +1,118 @@
+package xlsx
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// value возвращает значение ячейки текстом так, как его показал бы Excel
+// при сохранении в csv: числа без артефактов двоичного представления,
+// даты в ISO 8601
+func (w *Workbook) value(c xmlCell) (string, error) {
+	switch c.T {
+	case "s":
+		i, err := strconv.Atoi(strings.TrimSpace(c.V))
+		if err != nil || i < 0 || i >= len(w.shared) {
+			return "", fmt.Errorf("bad shared string index %q", c.V)
+		}
+		return w.shared[i], nil
+	case "inlineStr":
+		return c.Is.String(), nil
+	case "str", "e", "d":
+		return c.V, nil
+	case "b":
+		if strings.TrimSpace(c.V) == "1" {
+			return "true", nil
+		}
+		return "false", nil
+	}
+	v := strings.TrimSpace(c.V)
+	if v == "" {
+		return "", nil
+	}
+	if c.S >= 0 && c.S < len(w.dates) && w.dates[c.S] {
+		return w.date(v), nil
+	}
+	return number(v), nil
+}
+
+// number убирает хвосты двоичного представления: Excel хранит
+// 55.7539 как 55.753900000000002, а показывает 15 значащих цифр
+func number(v string) string {
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return v
+	}
+	f, _ = strconv.ParseFloat(strconv.FormatFloat(f, 'g', 15, 64), 64)
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// date переводит серийный номер даты Excel в дату, дату и время или время
+func (w *Workbook) date(v string) string {
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || f < 0 {
+		return v
+	}
+	days := math.Floor(f)
+	seconds := math.Round((f - days) * 86400)
+	clock := time.Duration(seconds) * time.Second
+	if days == 0 && !w.date1904 {
+		return time.Time{}.Add(clock).Format("15:04:05")
+	}
+
+	epoch := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	if w.date1904 {
+		epoch = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+	} else if days < 60 {
+		// Excel считает 1900 год високосным, номера до несуществующего 29.02.1900 сдвинуты на день
+		days++
+	}
+	t := epoch.AddDate(0, 0, int(days)).Add(clock)
+	if seconds == 0 {
+		return t.Format("2006-01-02")
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
+
+// isDateFormatID сообщает, что встроенный формат числа — дата или время
+func isDateFormatID(id int) bool {
+	return id >= 14 && id <= 22 || id >= 27 && id <= 36 || id >= 45 && id <= 47 || id >= 50 && id <= 58
+}
+
+// isDateFormat сообщает, что пользовательский формат числа — дата или время:
+// в первой секции формата вне кавычек и скобок есть d, m, y или h
+func isDateFormat(code string) bool {
+	section, _, _ := strings.Cut(code, ";")
+	quoted, bracket := false, false
+	for i := 0; i < len(section); i++ {
+		c := section[i]
+		switch {
+		case quoted:
+			quoted = c != '"'
+		case bracket:
+			// [h], [mm] и [ss] — прошедшее время
+			if c == 'h' || c == 'H' || c == 'm' || c == 'M' || c == 's' || c == 'S' {
+				if i > 0 && section[i-1] == '[' {
+					return true
+				}
+			}
+			bracket = c != ']'
+		case c == '"':
+			quoted = true
+		case c == '[':
+			bracket = true
+		case c == '\\' || c == '_' || c == '*':
+			// следующий символ выводится как есть
+			i++
+		default:
+			switch c | 0x20 {
+			case 'd', 'm', 'y', 'h':
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,263 @@
+// Package xlsx читает листы книг Excel (.xlsx) построчно: книга — zip архив
+// с XML частями Office Open XML, общими строками и стилями ячеек.
+package xlsx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// ErrEmptySheet на листе нет ни одной непустой строки
+var ErrEmptySheet = errors.New("sheet is empty")
+
+// Sheet лист книги
+type Sheet struct {
+	Name string
+	// Hidden лист скрыт в Excel и не читается, если не выбран явно
+	Hidden bool
+	part   string
+}
+
+// Workbook книга Excel, целиком прочитанная в память
+type Workbook struct {
+	Sheets []Sheet
+	files  map[string]*zip.File
+	// shared общие строки, на которые ссылаются ячейки с t="s"
+	shared []string
+	// dates стили ячеек (cellXfs), числа в которых — даты
+	dates    []bool
+	date1904 bool
+}
+
+// Read читает книгу из потока. Zip архиву нужен произвольный доступ,
+// поэтому книга читается в память целиком.
+func Read(r io.Reader) (*Workbook, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, fmt.Errorf("xlsx: %w", err)
+	}
+
+	w := &Workbook{files: make(map[string]*zip.File, len(zr.File))}
+	for _, f := range zr.File {
+		w.files[strings.TrimPrefix(f.Name, "/")] = f
+	}
+
+	book, err := w.mainPart()
+	if err != nil {
+		return nil, err
+	}
+	if err = w.readWorkbook(book); err != nil {
+		return nil, err
+	}
+	rels, err := w.relationships(book)
+	if err != nil {
+		return nil, err
+	}
+	if part, ok := rels.target(typeSharedStrings); ok {
+		if err = w.readSharedStrings(part); err != nil {
+			return nil, err
+		}
+	}
+	if part, ok := rels.target(typeStyles); ok {
+		if err = w.readStyles(part); err != nil {
+			return nil, err
+		}
+	}
+	for i := range w.Sheets {
+		if w.Sheets[i].part, err = rels.sheet(w.Sheets[i].part); err != nil {
+			return nil, fmt.Errorf("xlsx: sheet %q: %w", w.Sheets[i].Name, err)
+		}
+	}
+	return w, nil
+}
+
+// Select возвращает листы, имена которых подходят под glob шаблоны patterns,
+// в порядке книги. Без шаблонов возвращаются все видимые листы.
+// Шаблон, под который не подходит ни один лист, — ошибка.
+func (w *Workbook) Select(patterns []string) ([]Sheet, error) {
+	if len(patterns) == 0 {
+		var sheets []Sheet
+		for _, s := range w.Sheets {
+			if !s.Hidden {
+				sheets = append(sheets, s)
+			}
+		}
+		if len(sheets) == 0 {
+			return nil, fmt.Errorf("xlsx: workbook has no visible sheets")
+		}
+		return sheets, nil
+	}
+
+	selected := make([]bool, len(w.Sheets))
+	for _, p := range patterns {
+		found := false
+		for i, s := range w.Sheets {
+			if ok, _ := path.Match(p, s.Name); ok || p == s.Name {
+				selected[i], found = true, true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("xlsx: no sheet matches %q, workbook sheets: %v", p, strings.Join(w.names(), ", "))
+		}
+	}
+	var sheets []Sheet
+	for i, s := range w.Sheets {
+		if selected[i] {
+			sheets = append(sheets, s)
+		}
+	}
+	return sheets, nil
+}
+
+func (w *Workbook) names() []string {
+	names := make([]string, len(w.Sheets))
+	for i, s := range w.Sheets {
+		names[i] = s.Name
+	}
+	return names
+}
+
+// Rows передаёт в fn непустые строки листа: номер строки, начиная с 1,
+// и значения ячеек по номеру колонки. fn возвращает false, если чтение надо прекратить.
+func (w *Workbook) Rows(sheet Sheet, fn func(row int, cells []string) bool) error {
+	rc, err := w.open(sheet.part)
+	if err != nil {
+		return fmt.Errorf("xlsx: sheet %q: %w", sheet.Name, err)
+	}
+	defer rc.Close()
+
+	d := xml.NewDecoder(rc)
+	row := 0
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("xlsx: sheet %q: %w", sheet.Name, err)
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "row" {
+			continue
+		}
+		var r xmlRow
+		if err = d.DecodeElement(&r, &start); err != nil {
+			return fmt.Errorf("xlsx: sheet %q: %w", sheet.Name, err)
+		}
+		// номер строки может быть не указан, тогда она идёт следом за предыдущей
+		if r.R > 0 {
+			row = r.R
+		} else {
+			row++
+		}
+		cells, err := w.cells(r.Cells)
+		if err != nil {
+			return fmt.Errorf("xlsx: sheet %q row %d: %w", sheet.Name, row, err)
+		}
+		if cells == nil {
+			continue
+		}
+		if !fn(row, cells) {
+			return nil
+		}
+	}
+}
+
+// Header возвращает первую непустую строку листа и её номер
+func (w *Workbook) Header(sheet Sheet) (int, []string, error) {
+	var row int
+	var header []string
+	err := w.Rows(sheet, func(r int, cells []string) bool {
+		row, header = r, cells
+		return false
+	})
+	if err == nil && header == nil {
+		err = fmt.Errorf("xlsx: sheet %q: %w", sheet.Name, ErrEmptySheet)
+	}
+	return row, header, err
+}
+
+// cells возвращает значения ячеек строки по номерам колонок, nil если строка пустая
+func (w *Workbook) cells(cs []xmlCell) ([]string, error) {
+	var cells []string
+	empty := true
+	col := -1
+	for _, c := range cs {
+		// ссылка на ячейку тоже может быть пропущена
+		if c.R != "" {
+			n, err := column(c.R)
+			if err != nil {
+				return nil, err
+			}
+			col = n
+		} else {
+			col++
+		}
+		v, err := w.value(c)
+		if err != nil {
+			return nil, fmt.Errorf("cell %v: %w", c.R, err)
+		}
+		if v == "" {
+			continue
+		}
+		for len(cells) <= col {
+			cells = append(cells, "")
+		}
+		cells[col] = v
+		empty = false
+	}
+	if empty {
+		return nil, nil
+	}
+	return cells, nil
+}
+
+// column возвращает номер колонки ячейки «AB12», начиная с 0
+func column(ref string) (int, error) {
+	n := 0
+	i := 0
+	for ; i < len(ref); i++ {
+		c := ref[i] | 0x20
+		if c < 'a' || c > 'z' {
+			break
+		}
+		n = n*26 + int(c-'a'+1)
+	}
+	if i == 0 || n > 16384 {
+		return 0, fmt.Errorf("bad cell reference %q", ref)
+	}
+	return n - 1, nil
+}
+
+// open открывает часть книги по пути внутри архива
+func (w *Workbook) open(part string) (io.ReadCloser, error) {
+	f, ok := w.files[part]
+	if !ok {
+		return nil, fmt.Errorf("%v: %w", part, os.ErrNotExist)
+	}
+	return f.Open()
+}
+
+// decode разбирает XML часть книги в v
+func (w *Workbook) decode(part string, v interface{}) error {
+	rc, err := w.open(part)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	if err = xml.NewDecoder(rc).Decode(v); err != nil {
+		return fmt.Errorf("%v: %w", part, err)
+	}
+	return nil
+}
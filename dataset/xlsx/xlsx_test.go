@@ -0,0 +1,144 @@
+package xlsx
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// places.xlsx: лист «Места» с заголовком во второй строке, общими и встроенными строками,
+// форматированным текстом, строкой и ячейками без ссылок, пропусками строк и колонок,
+// стилями дат, времени и чисел; скрытый лист «Скрытый» и пустой лист «Пустой»
+func readFixture(t *testing.T) *Workbook {
+	f, err := os.Open("testdata/places.xlsx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w, err := Read(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+func TestRows(t *testing.T) {
+	w := readFixture(t)
+	sheets, err := w.Select(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sheets) != 2 || sheets[0].Name != "Места" || sheets[1].Name != "Пустой" {
+		t.Fatalf("visible sheets %v", sheets)
+	}
+
+	type row struct {
+		n     int
+		cells []string
+	}
+	var rows []row
+	err = w.Rows(sheets[0], func(n int, cells []string) bool {
+		rows = append(rows, row{n, cells})
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []row{
+		{2, []string{"name", "lat", "lon", "note", "founded"}},
+		{3, []string{"Москва", "55.7539", "37.6204", "столица", "2017-01-01"}},
+		{4, []string{"Санкт-Петербург", "59.9386", "30.3141", "", "2003-06-28 12:00:00"}},
+		{6, []string{"Казань", "55.7963", "49.1088", "", "18:00:00", "", "true"}},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows\n%q\nwant\n%q", rows, want)
+	}
+
+	n, header, err := w.Header(sheets[0])
+	if err != nil || n != 2 || !reflect.DeepEqual(header, want[0].cells) {
+		t.Errorf("header %d %q %v", n, header, err)
+	}
+	if _, _, err = w.Header(sheets[1]); !errors.Is(err, ErrEmptySheet) {
+		t.Errorf("empty sheet: %v", err)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	w := readFixture(t)
+	sheets, err := w.Select([]string{"Скр*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sheets) != 1 || sheets[0].Name != "Скрытый" || !sheets[0].Hidden {
+		t.Errorf("hidden sheet selected by pattern: %v", sheets)
+	}
+	_, header, err := w.Header(sheets[0])
+	if err != nil || !reflect.DeepEqual(header, []string{"", "other"}) {
+		t.Errorf("header of hidden sheet %q %v", header, err)
+	}
+	if _, err = w.Select([]string{"Места", "Лист1"}); err == nil {
+		t.Error("pattern without sheets is not reported")
+	}
+}
+
+func TestColumn(t *testing.T) {
+	tests := map[string]int{"A1": 0, "Z9": 25, "AA10": 26, "ab3": 27, "XFD1048576": 16383}
+	for ref, want := range tests {
+		if got, err := column(ref); err != nil || got != want {
+			t.Errorf("column(%q) = %d, %v, want %d", ref, got, err, want)
+		}
+	}
+	for _, ref := range []string{"", "12", "XFE1"} {
+		if _, err := column(ref); err == nil {
+			t.Errorf("column(%q) is not an error", ref)
+		}
+	}
+}
+
+func TestValue(t *testing.T) {
+	w := &Workbook{dates: []bool{false, true}}
+	tests := []struct {
+		v    string
+		s    int
+		want string
+	}{
+		{"55.753900000000002", 0, "55.7539"},
+		{"1E-3", 0, "0.001"},
+		{"42736", 1, "2017-01-01"},
+		{"42736.25", 1, "2017-01-01 06:00:00"},
+		{"0.5", 1, "12:00:00"},
+		// до несуществующего 29.02.1900 Excel сдвигает даты на день
+		{"59", 1, "1900-02-28"},
+		{"61", 1, "1900-03-01"},
+	}
+	for _, tt := range tests {
+		if got, err := w.value(xmlCell{V: tt.v, S: tt.s}); err != nil || got != tt.want {
+			t.Errorf("value %v style %d = %q, %v, want %q", tt.v, tt.s, got, err, tt.want)
+		}
+	}
+	w.date1904 = true
+	if got, _ := w.value(xmlCell{V: "0", S: 1}); got != "1904-01-01" {
+		t.Errorf("1904 date system: %v", got)
+	}
+}
+
+func TestIsDateFormat(t *testing.T) {
+	tests := map[string]bool{
+		"dd.mm.yyyy":         true,
+		`dd\.mm\.yyyy`:       true,
+		"[h]:mm:ss":          true,
+		"[$-419]d mmmm yyyy": true,
+		"0.00":               false,
+		`0.00" m"`:           false,
+		`#,##0" дней"`:       false,
+		"[Red]0.00;[Blue]-0": false,
+		"General":            false,
+		`0.0_m`:              false,
+	}
+	for code, want := range tests {
+		if got := isDateFormat(code); got != want {
+			t.Errorf("isDateFormat(%q) = %v, want %v", code, got, want)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package entitystore
+
+import (
+	"context"
+	"fmt"
+	"github.com/audetv/datasets-parser/app/repos/entity"
+	"github.com/golang/geo/s2"
+	"github.com/google/uuid"
+	"log"
+)
+
+// DBS2Cell родительская ячейка S2 сущности. Ячейки одного уровня находятся запросом
+// на равенство level и cell_id или token.
+type DBS2Cell struct {
+	EntityID uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Level    int       `gorm:"primaryKey;index:idx_s2_cell_level_cell,priority:1;index:idx_s2_cell_level_token,priority:1"`
+	CellID   uint64    `gorm:"type:numeric;index:idx_s2_cell_level_cell,priority:2"`
+	Token    string    `gorm:"type:varchar(16);index:idx_s2_cell_level_token,priority:2"`
+}
+
+func (DBS2Cell) TableName() string {
+	return "db_entity_s2_cells"
+}
+
+// DBTile тайл карты, в который попадает сущность
+type DBTile struct {
+	EntityID uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Zoom     int       `gorm:"primaryKey;index:idx_tile_zoom_xy,priority:1"`
+	X        int       `gorm:"index:idx_tile_zoom_xy,priority:2"`
+	Y        int       `gorm:"index:idx_tile_zoom_xy,priority:3"`
+	Quadkey  string    `gorm:"type:varchar(23);index"`
+}
+
+func (DBTile) TableName() string {
+	return "db_entity_tiles"
+}
+
+func newDBCells(entities []entity.Entity) ([]DBS2Cell, []DBTile) {
+	var cells []DBS2Cell
+	var tiles []DBTile
+	for _, e := range entities {
+		for _, c := range e.S2Cells {
+			cells = append(cells, DBS2Cell{EntityID: e.ID, Level: c.Level, CellID: c.ID, Token: s2.CellID(c.ID).ToToken()})
+		}
+		for _, t := range e.Tiles {
+			tiles = append(tiles, DBTile{EntityID: e.ID, Zoom: t.Zoom, X: t.X, Y: t.Y, Quadkey: t.Quadkey})
+		}
+	}
+	return cells, tiles
+}
+
+// insertCells записывает ячейки S2 и тайлы сущностей
+func (es *Entities) insertCells(ctx context.Context, entities []entity.Entity, batchSize int) error {
+	cells, tiles := newDBCells(entities)
+	if len(cells) > 0 {
+		if err := es.createInBatches(ctx, cells, batchSize); err != nil {
+			return err
+		}
+	}
+	if len(tiles) > 0 {
+		if err := es.createInBatches(ctx, tiles, batchSize); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reindexCells вычисляет ячейки S2 и тайлы уровней ix, которых нет у записанных сущностей,
+// например после добавления уровня в --s2-levels или --tile-zooms
+func (es *Entities) reindexCells(ctx context.Context, ix entity.Indexer) error {
+	// уровни и масштабы ix видны по ячейкам и тайлам любой точки
+	var probe entity.Entity
+	ix.Index(&probe)
+	for _, c := range probe.S2Cells {
+		level := c.Level
+		err := es.reindexMissing(ctx, fmt.Sprintf("ячейки S2 уровня %d", level),
+			"db_entity_s2_cells", "level", level, ix, func(e *entity.Entity) {
+				e.S2Cells, e.Tiles = cellsAt(e.S2Cells, level), nil
+			})
+		if err != nil {
+			return err
+		}
+	}
+	for _, t := range probe.Tiles {
+		zoom := t.Zoom
+		err := es.reindexMissing(ctx, fmt.Sprintf("тайлы масштаба %d", zoom),
+			"db_entity_tiles", "zoom", zoom, ix, func(e *entity.Entity) {
+				e.S2Cells, e.Tiles = nil, tilesAt(e.Tiles, zoom)
+			})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func cellsAt(cells []entity.S2Cell, level int) []entity.S2Cell {
+	for _, c := range cells {
+		if c.Level == level {
+			return []entity.S2Cell{c}
+		}
+	}
+	return nil
+}
+
+func tilesAt(tiles []entity.Tile, zoom int) []entity.Tile {
+	for _, t := range tiles {
+		if t.Zoom == zoom {
+			return []entity.Tile{t}
+		}
+	}
+	return nil
+}
+
+// reindexMissing пакетами по reindexBatch индексирует через ix сущности,
+// у которых в таблице table нет строки с column = value. keep оставляет
+// у сущности только недостающие ячейки или тайлы.
+func (es *Entities) reindexMissing(ctx context.Context, what string, table string, column string, value int,
+	ix entity.Indexer, keep func(e *entity.Entity)) error {
+	total := 0
+	for {
+		var rows []DBEntity
+		err := es.db.WithContext(ctx).Select("id, latitude, longitude").
+			Where("NOT EXISTS (SELECT 1 FROM "+table+" AS c WHERE c.entity_id = db_entities.id AND c."+column+" = ?)", value).
+			Limit(reindexBatch).Find(&rows).Error
+		if err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			break
+		}
+		entities := make([]entity.Entity, len(rows))
+		for i, row := range rows {
+			entities[i] = entity.Entity{ID: row.ID, Latitude: row.Latitude, Longitude: row.Longitude}
+			ix.Index(&entities[i])
+			keep(&entities[i])
+		}
+		if err = es.insertCells(ctx, entities, reindexBatch); err != nil {
+			return fmt.Errorf("reindex %v %d: %w", column, value, err)
+		}
+		total += len(rows)
+		log.Printf("%v вычислены у записей %d\n", what, total)
+	}
+	return nil
+}
@@ -2,10 +2,17 @@ package entitystore
 
 import (
 	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
 	"github.com/audetv/datasets-parser/app/repos/entity"
+	"github.com/golang/geo/s2"
 	"github.com/google/uuid"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -20,8 +27,18 @@ type DBEntity struct {
 	Latitude        float64     `gorm:"type:double precision"`
 	Height          float64     `gorm:"type:double precision"`
 	DescriptionJson interface{} `gorm:"type:json"`
-	CellID          uint64      `gorm:"type:numeric"`
-	Geohash         string      `gorm:"type:char(16)"`
+	CellID          uint64      `gorm:"type:numeric;index"`
+	S2Token         string      `gorm:"type:varchar(16)"`
+	Geohash         string      `gorm:"type:varchar(12);index"`
+	GeohashCell     string      `gorm:"type:varchar(12);index"`
+	GeohashN        string      `gorm:"type:varchar(12)"`
+	GeohashNe       string      `gorm:"type:varchar(12)"`
+	GeohashE        string      `gorm:"type:varchar(12)"`
+	GeohashSe       string      `gorm:"type:varchar(12)"`
+	GeohashS        string      `gorm:"type:varchar(12)"`
+	GeohashSw       string      `gorm:"type:varchar(12)"`
+	GeohashW        string      `gorm:"type:varchar(12)"`
+	GeohashNw       string      `gorm:"type:varchar(12)"`
 	CreatedAt       time.Time
 	UpdatedAt       time.Time
 	DeletedAt       *time.Time
@@ -32,26 +49,24 @@ type Entities struct {
 }
 
 var _ entity.Store = &Entities{}
+var _ entity.Transactor = &Entities{}
+var _ entity.ReadStore = &Entities{}
 
+// NewEntities подключается к базе данных. Схема таблиц не меняется,
+// перед записью сущностей её обновляет Migrate.
 func NewEntities(dsn string) (*Entities, error) {
 	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
-
-	err = db.AutoMigrate(&DBEntity{})
-	if err != nil {
-		return nil, err
-	}
-
 	if err != nil {
 		return nil, err
 	}
-	bs := &Entities{
+	return &Entities{
 		db: db,
-	}
-	return bs, nil
+	}, nil
 }
 
-func (es *Entities) Create(ctx context.Context, e entity.Entity) error {
-	dbEntity := DBEntity{
+func newDBEntity(e entity.Entity) *DBEntity {
+	n := e.GeohashNeighbours
+	return &DBEntity{
 		ID:              e.ID,
 		Name:            e.Name,
 		Filename:        e.Filename,
@@ -61,37 +76,160 @@ func (es *Entities) Create(ctx context.Context, e entity.Entity) error {
 		Height:          e.Height,
 		DescriptionJson: e.DescriptionJson,
 		CellID:          e.CellID,
+		S2Token:         e.S2Token,
 		Geohash:         e.Geohash,
+		GeohashCell:     e.GeohashCell,
+		GeohashN:        n[0],
+		GeohashNe:       n[1],
+		GeohashE:        n[2],
+		GeohashSe:       n[3],
+		GeohashS:        n[4],
+		GeohashSw:       n[5],
+		GeohashW:        n[6],
+		GeohashNw:       n[7],
 		CreatedAt:       time.Now(),
 		UpdatedAt:       time.Now(),
 		DeletedAt:       nil,
 	}
+}
 
-	result := es.db.WithContext(ctx).Create(&dbEntity)
-
-	return result.Error
+func (es *Entities) Create(ctx context.Context, e entity.Entity) error {
+	result := es.db.WithContext(ctx).Create(newDBEntity(e))
+	if result.Error != nil {
+		return result.Error
+	}
+	return es.insertCells(ctx, []entity.Entity{e}, 100)
 }
 
 func (es *Entities) BulkInsert(ctx context.Context, entities []entity.Entity, batchSize int) error {
 	var dbEnts DBEntities
 	for _, e := range entities {
-		dbEntity := DBEntity{
-			ID:              e.ID,
-			Name:            e.Name,
-			Filename:        e.Filename,
-			Description:     e.Description,
-			Longitude:       e.Longitude,
-			Latitude:        e.Latitude,
-			Height:          e.Height,
-			DescriptionJson: e.DescriptionJson,
-			CellID:          e.CellID,
-			Geohash:         e.Geohash,
-			CreatedAt:       time.Now(),
-			UpdatedAt:       time.Now(),
-			DeletedAt:       nil,
+		dbEnts = append(dbEnts, newDBEntity(e))
+	}
+	if err := es.createInBatches(ctx, dbEnts, batchSize); err != nil {
+		return err
+	}
+	return es.insertCells(ctx, entities, batchSize)
+}
+
+// maxParams наибольшее число параметров одного запроса в протоколе PostgreSQL
+const maxParams = 65535
+
+// createInBatches записывает строки rows пакетами не больше batchSize строк и не больше,
+// чем помещается в один запрос: каждый столбец строки — отдельный параметр
+func (es *Entities) createInBatches(ctx context.Context, rows interface{}, batchSize int) error {
+	stmt := &gorm.Statement{DB: es.db}
+	if err := stmt.Parse(rows); err != nil {
+		return err
+	}
+	if limit := maxParams / len(stmt.Schema.DBNames); batchSize <= 0 || batchSize > limit {
+		batchSize = limit
+	}
+	return es.db.WithContext(ctx).CreateInBatches(rows, batchSize).Error
+}
+
+// Transaction выполняет fn в транзакции базы данных,
+// если fn возвращает ошибку, все записи fn откатываются
+func (es *Entities) Transaction(ctx context.Context, fn func(store entity.Store) error) error {
+	return es.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(&Entities{db: tx})
+	})
+}
+
+// entityColumns столбцы DBEntity, которые читает scanEntity
+const entityColumns = "id, filename, name, description, longitude, latitude, height, description_json, cell_id, s2_token, " +
+	"geohash, geohash_cell, geohash_n, geohash_ne, geohash_e, geohash_se, geohash_s, geohash_sw, geohash_w, geohash_nw"
+
+// All читает сущности, подходящие под filter, потоком, не загружая таблицу в память,
+// в порядке файлов и идентификаторов. S2Cells и Tiles не читаются.
+func (es *Entities) All(ctx context.Context, filter entity.Filter, fn func(e entity.Entity) error) error {
+	query := es.db.WithContext(ctx).Model(&DBEntity{}).
+		Select(entityColumns).
+		Where("deleted_at IS NULL")
+	if len(filter.Filenames) > 0 {
+		query = query.Where("filename IN ?", filter.Filenames)
+	}
+	if b := filter.BBox; b != nil {
+		query = query.Where("latitude BETWEEN ? AND ?", b.South, b.North)
+		if b.West <= b.East {
+			query = query.Where("longitude BETWEEN ? AND ?", b.West, b.East)
+		} else {
+			query = query.Where("(longitude >= ? OR longitude <= ?)", b.West, b.East)
+		}
+	}
+	if c := filter.Cell; c != 0 {
+		query = query.Where(cellRanges(s2.CellUnion{c}))
+	}
+	if len(filter.Cells) > 0 {
+		query = query.Where(cellRanges(filter.Cells))
+	}
+	rows, err := query.Order("filename, id").Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		e, err := scanEntity(rows)
+		if err != nil {
+			return err
+		}
+		if err = fn(e); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Get читает сущность по идентификатору, S2Cells и Tiles не читаются
+func (es *Entities) Get(ctx context.Context, id uuid.UUID) (entity.Entity, error) {
+	rows, err := es.db.WithContext(ctx).Model(&DBEntity{}).
+		Select(entityColumns).
+		Where("id = ? AND deleted_at IS NULL", id).
+		Limit(1).Rows()
+	if err != nil {
+		return entity.Entity{}, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			return entity.Entity{}, err
+		}
+		return entity.Entity{}, fmt.Errorf("%w: %v", entity.ErrNotFound, id)
+	}
+	return scanEntity(rows)
+}
+
+// cellRanges возвращает условие попадания cell_id в одну из ячеек cells. Потомки ячейки
+// занимают непрерывный диапазон CellID, uint64 передаётся строкой, так как не помещается в bigint.
+func cellRanges(cells s2.CellUnion) clause.Expr {
+	ranges := make([]string, len(cells))
+	vars := make([]interface{}, 0, 2*len(cells))
+	for i, c := range cells {
+		ranges[i] = "cell_id BETWEEN ?::numeric AND ?::numeric"
+		vars = append(vars, strconv.FormatUint(uint64(c.RangeMin()), 10), strconv.FormatUint(uint64(c.RangeMax()), 10))
+	}
+	return gorm.Expr("("+strings.Join(ranges, " OR ")+")", vars...)
+}
+
+// scanEntity читает сущность из строки выборки столбцов entityColumns
+func scanEntity(rows *sql.Rows) (entity.Entity, error) {
+	var e entity.Entity
+	var description []byte
+	n := &e.GeohashNeighbours
+	err := rows.Scan(&e.ID, &e.Filename, &e.Name, &e.Description, &e.Longitude, &e.Latitude, &e.Height,
+		&description, &e.CellID, &e.S2Token,
+		&e.Geohash, &e.GeohashCell, &n[0], &n[1], &n[2], &n[3], &n[4], &n[5], &n[6], &n[7])
+	if err != nil {
+		return e, err
+	}
+	if len(description) > 0 {
+		var dj interface{}
+		if err = json.Unmarshal(description, &dj); err != nil {
+			return e, fmt.Errorf("entity %v description_json: %w", e.ID, err)
 		}
-		dbEnts = append(dbEnts, &dbEntity)
+		e.DescriptionJson = dj
 	}
-	result := es.db.WithContext(ctx).CreateInBatches(dbEnts, batchSize)
-	return result.Error
+	return e, nil
 }
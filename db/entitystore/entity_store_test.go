@@ -0,0 +1,97 @@
+package entitystore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"github.com/audetv/datasets-parser/app/geoindex"
+	"github.com/audetv/datasets-parser/app/repos/entity"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"strings"
+	"testing"
+)
+
+// recorder соединение, которое запоминает запросы вместо выполнения
+type recorder struct {
+	inserts map[string][]int
+}
+
+type result int64
+
+func (r result) LastInsertId() (int64, error) { return 0, nil }
+func (r result) RowsAffected() (int64, error) { return int64(r), nil }
+
+func (r *recorder) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	return nil, errors.New("prepare is not supported")
+}
+
+func (r *recorder) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if len(args) > maxParams {
+		return nil, errors.New("extended protocol limited to 65535 parameters")
+	}
+	if f := strings.Fields(query); len(f) > 2 && f[0] == "INSERT" {
+		r.inserts[f[2]] = append(r.inserts[f[2]], len(args))
+	}
+	return result(strings.Count(query, "),(") + 1), nil
+}
+
+func (r *recorder) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return nil, errors.New("query is not supported")
+}
+
+func (r *recorder) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return nil
+}
+
+func (r *recorder) BeginTx(ctx context.Context, opts *sql.TxOptions) (gorm.ConnPool, error) {
+	return &tx{r}, nil
+}
+
+// tx транзакция recorder
+type tx struct {
+	*recorder
+}
+
+func (*tx) Commit() error   { return nil }
+func (*tx) Rollback() error { return nil }
+
+func TestBulkInsertSplitsBatchesByParameterLimit(t *testing.T) {
+	rec := &recorder{inserts: make(map[string][]int)}
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: rec}), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+	es := &Entities{db: db}
+
+	// размер пакета импорта: больше строк, чем помещается в один запрос
+	const n = 3499
+	ix := geoindex.Indexer{S2Levels: []int{10, 20}, TileZooms: []int{5}}
+	entities := make([]entity.Entity, n)
+	for i := range entities {
+		entities[i] = entity.Entity{Name: "e", Latitude: float64(i%170) - 85, Longitude: float64(i%350) - 175}
+		ix.Index(&entities[i])
+	}
+	if err = es.BulkInsert(context.Background(), entities, n); err != nil {
+		t.Fatal(err)
+	}
+
+	for table, want := range map[string]int{"db_entities": n, "db_entity_s2_cells": 2 * n, "db_entity_tiles": n} {
+		stmts := rec.inserts[`"`+table+`"`]
+		if len(stmts) == 0 {
+			t.Errorf("%v: no inserts", table)
+			continue
+		}
+		total := 0
+		for _, params := range stmts {
+			total += params
+		}
+		if columns := total / want; total%want != 0 || columns == 0 {
+			t.Errorf("%v: %d parameters for %d rows", table, total, want)
+		}
+	}
+	if len(rec.inserts[`"db_entities"`]) < 2 {
+		t.Errorf("db_entities: %d rows sent in one insert of %v parameters", n, rec.inserts[`"db_entities"`])
+	}
+}
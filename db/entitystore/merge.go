@@ -0,0 +1,61 @@
+package entitystore
+
+import (
+	"context"
+	"fmt"
+	"github.com/audetv/datasets-parser/app/repos/entity"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"time"
+)
+
+// DBClusterMember сущность, объединённая в каноническую сущность ClusterID.
+// Сама сущность остаётся в db_entities с заполненным deleted_at.
+type DBClusterMember struct {
+	ClusterID  uuid.UUID `gorm:"type:uuid;index"`
+	EntityID   uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Filename   string
+	Distance   float64 `gorm:"type:double precision"`
+	Similarity float64 `gorm:"type:double precision"`
+	CreatedAt  time.Time
+}
+
+func (DBClusterMember) TableName() string {
+	return "db_cluster_members"
+}
+
+var _ entity.Merger = &Entities{}
+
+// Merge в одной транзакции скрывает объединённые сущности, записывает каноническую сущность
+// с ячейками S2 и тайлами и состав объединения в db_cluster_members
+func (es *Entities) Merge(ctx context.Context, m entity.Merge) error {
+	ids := make([]uuid.UUID, len(m.Members))
+	members := make([]DBClusterMember, len(m.Members))
+	for i, mm := range m.Members {
+		ids[i] = mm.ID
+		members[i] = DBClusterMember{
+			ClusterID:  m.Canonical.ID,
+			EntityID:   mm.ID,
+			Filename:   mm.Filename,
+			Distance:   mm.Distance,
+			Similarity: mm.Similarity,
+			CreatedAt:  time.Now(),
+		}
+	}
+	return es.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&DBEntity{}).
+			Where("id IN ? AND deleted_at IS NULL", ids).
+			Update("deleted_at", time.Now())
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected != int64(len(ids)) {
+			return fmt.Errorf("merge %v: %d of %d entities: %w", m.Canonical.Name, int64(len(ids))-result.RowsAffected, len(ids), entity.ErrMerged)
+		}
+		store := &Entities{db: tx}
+		if err := store.Create(ctx, m.Canonical); err != nil {
+			return err
+		}
+		return tx.Create(&members).Error
+	})
+}
@@ -0,0 +1,92 @@
+package entitystore
+
+import (
+	"context"
+	"fmt"
+	"github.com/audetv/datasets-parser/app/repos/entity"
+	"gorm.io/gorm"
+	"log"
+	"strings"
+)
+
+// reindexBatch сколько сущностей индексируется заново одним запросом
+const reindexBatch = 5000
+
+// Migrate обновляет схему таблиц сущностей. Сущностям, записанным до появления
+// колонок geohash или без ячеек S2 и тайлов уровней ix, они вычисляются через ix.
+func (es *Entities) Migrate(ctx context.Context, ix entity.Indexer) error {
+	if err := migrateS2Token(es.db); err != nil {
+		return err
+	}
+	if err := es.db.AutoMigrate(&DBEntity{}, &DBS2Cell{}, &DBTile{}, &DBClusterMember{}); err != nil {
+		return err
+	}
+	if err := es.reindexGeohash(ctx, ix); err != nil {
+		return err
+	}
+	return es.reindexCells(ctx, ix)
+}
+
+// migrateS2Token переносит токены S2, которые раньше записывались в колонку geohash,
+// в колонку s2_token. Колонка geohash очищается и заполняется в reindexGeohash.
+func migrateS2Token(db *gorm.DB) error {
+	m := db.Migrator()
+	if !m.HasTable(&DBEntity{}) || m.HasColumn(&DBEntity{}, "S2Token") {
+		return nil
+	}
+	log.Println("перенос токенов S2 из колонки geohash в s2_token")
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, sql := range []string{
+			"ALTER TABLE db_entities ADD COLUMN s2_token varchar(16)",
+			"UPDATE db_entities SET s2_token = trim(geohash), geohash = NULL",
+			"ALTER TABLE db_entities ALTER COLUMN geohash TYPE varchar(12)",
+		} {
+			if err := tx.Exec(sql).Error; err != nil {
+				return fmt.Errorf("migrate s2_token: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// reindexGeohash вычисляет geohash и соседние ячейки сущностей, у которых их нет,
+// пакетами по reindexBatch
+func (es *Entities) reindexGeohash(ctx context.Context, ix entity.Indexer) error {
+	total := 0
+	for {
+		var rows []DBEntity
+		err := es.db.WithContext(ctx).Select("id, latitude, longitude").
+			Where("geohash_cell IS NULL").Limit(reindexBatch).Find(&rows).Error
+		if err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			break
+		}
+
+		values := make([]string, len(rows))
+		args := make([]interface{}, 0, len(rows)*12)
+		for i, row := range rows {
+			e := entity.Entity{Latitude: row.Latitude, Longitude: row.Longitude}
+			ix.Index(&e)
+			values[i] = "(?::uuid, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+			args = append(args, row.ID, e.S2Token, e.Geohash, e.GeohashCell)
+			for _, n := range e.GeohashNeighbours {
+				args = append(args, n)
+			}
+		}
+		err = es.db.WithContext(ctx).Exec(`UPDATE db_entities AS e SET s2_token = v.s2_token,
+			geohash = v.geohash, geohash_cell = v.geohash_cell,
+			geohash_n = v.n, geohash_ne = v.ne, geohash_e = v.e, geohash_se = v.se,
+			geohash_s = v.s, geohash_sw = v.sw, geohash_w = v.w, geohash_nw = v.nw
+			FROM (VALUES `+strings.Join(values, ", ")+`)
+			AS v(id, s2_token, geohash, geohash_cell, n, ne, e, se, s, sw, w, nw)
+			WHERE e.id = v.id`, args...).Error
+		if err != nil {
+			return fmt.Errorf("reindex geohash: %w", err)
+		}
+		total += len(rows)
+		log.Printf("geohash пересчитан у записей %d\n", total)
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package entitystore
+
+import (
+	"container/heap"
+	"context"
+	"github.com/audetv/datasets-parser/app/repos/entity"
+	"github.com/audetv/datasets-parser/dataset/geodesy"
+	"github.com/golang/geo/s1"
+	"github.com/golang/geo/s2"
+	"math"
+	"sort"
+)
+
+const (
+	// nearStartRadius радиус первого круга поиска соседей в метрах
+	nearStartRadius = 1000
+	// nearGrowth во сколько раз растёт радиус круга, если в нём меньше K сущностей
+	nearGrowth = 4
+	// nearMaxCells сколько ячеек S2 покрывают круг, столько диапазонов cell_id в запросе
+	nearMaxCells = 16
+	// nearMargin запас круга на сфере: расстояние по эллипсоиду отличается от сферического до 0,5 %
+	nearMargin = 1.01
+)
+
+// Near находит K ближайших к точке сущностей. Кандидаты выбираются по индексу cell_id
+// в круге, покрытом ячейками S2, и ранжируются по расстоянию на эллипсоиде WGS 84.
+// Если в круге меньше K сущностей, радиус увеличивается в nearGrowth раз, пока круг
+// не достигнет MaxDistance или не покроет всю Землю. S2Cells и Tiles не читаются.
+func (es *Entities) Near(ctx context.Context, q entity.NearQuery) ([]entity.Neighbour, error) {
+	return near(ctx, es.All, q)
+}
+
+// near ищет соседей, читая сущности кругов поиска через all
+func near(ctx context.Context, all func(ctx context.Context, filter entity.Filter, fn func(e entity.Entity) error) error,
+	q entity.NearQuery) ([]entity.Neighbour, error) {
+	if err := q.Validate(); err != nil {
+		return nil, err
+	}
+	center := s2.PointFromLatLng(s2.LatLngFromDegrees(q.Lat, q.Lon))
+	coverer := &s2.RegionCoverer{MaxLevel: s2.MaxLevel, MaxCells: nearMaxCells}
+
+	radius := float64(nearStartRadius)
+	if q.MaxDistance > 0 && q.MaxDistance < radius {
+		radius = q.MaxDistance
+	}
+	for {
+		angle := radius * nearMargin / geodesy.Earth.Radius
+		whole := angle >= math.Pi
+		filter := entity.Filter{Filenames: q.Filenames}
+		limit := radius
+		if whole {
+			// круг покрывает всю Землю, самые далёкие точки могут оказаться дальше radius
+			if q.MaxDistance == 0 {
+				limit = math.Inf(1)
+			}
+		} else {
+			filter.Cells = coverer.Covering(s2.CapFromCenterAngle(center, s1.Angle(angle)))
+		}
+
+		found := &nearest{k: q.K}
+		err := all(ctx, filter, func(e entity.Entity) error {
+			if e.ID == q.Exclude {
+				return nil
+			}
+			l := geodesy.WGS84.Inverse(q.Lat, q.Lon, e.Latitude, e.Longitude)
+			if l.Distance <= limit {
+				found.add(entity.Neighbour{Entity: e, Distance: l.Distance, Azimuth: geodesy.Azimuth360(l.Azimuth1)})
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		if len(found.items) == q.K || whole || (q.MaxDistance > 0 && radius >= q.MaxDistance) {
+			return found.sorted(), nil
+		}
+		radius *= nearGrowth
+		if q.MaxDistance > 0 && radius > q.MaxDistance {
+			radius = q.MaxDistance
+		}
+	}
+}
+
+// nearest хранит k ближайших соседей в куче, на вершине которой самый далёкий
+type nearest struct {
+	k     int
+	items []entity.Neighbour
+}
+
+func (n *nearest) add(nb entity.Neighbour) {
+	if len(n.items) < n.k {
+		heap.Push(n, nb)
+		return
+	}
+	if closer(nb, n.items[0]) {
+		n.items[0] = nb
+		heap.Fix(n, 0)
+	}
+}
+
+// sorted возвращает соседей от ближнего к дальнему
+func (n *nearest) sorted() []entity.Neighbour {
+	sort.Slice(n.items, func(i, j int) bool {
+		return closer(n.items[i], n.items[j])
+	})
+	return n.items
+}
+
+// closer сравнивает соседей по расстоянию, равные — по ID, чтобы порядок не зависел от порядка чтения
+func closer(a, b entity.Neighbour) bool {
+	if a.Distance != b.Distance {
+		return a.Distance < b.Distance
+	}
+	return a.ID.String() < b.ID.String()
+}
+
+func (n *nearest) Len() int           { return len(n.items) }
+func (n *nearest) Less(i, j int) bool { return closer(n.items[j], n.items[i]) }
+func (n *nearest) Swap(i, j int)      { n.items[i], n.items[j] = n.items[j], n.items[i] }
+func (n *nearest) Push(x interface{}) { n.items = append(n.items, x.(entity.Neighbour)) }
+func (n *nearest) Pop() interface{} {
+	last := n.items[len(n.items)-1]
+	n.items = n.items[:len(n.items)-1]
+	return last
+}
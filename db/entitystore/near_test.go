@@ -0,0 +1,112 @@
+package entitystore
+
+import (
+	"context"
+	"fmt"
+	"github.com/audetv/datasets-parser/app/repos/entity"
+	"github.com/golang/geo/s2"
+	"github.com/google/uuid"
+	"testing"
+)
+
+// point сущность с cell_id, вычисленным по координатам, как при импорте
+func point(name string, lat, lon float64) entity.Entity {
+	return entity.Entity{
+		ID:        uuid.NewSHA1(uuid.NameSpaceOID, []byte(name)),
+		Filename:  "cities.csv",
+		Name:      name,
+		Latitude:  lat,
+		Longitude: lon,
+		CellID:    uint64(s2.CellIDFromLatLng(s2.LatLngFromDegrees(lat, lon))),
+	}
+}
+
+var cities = []entity.Entity{
+	point("Москва", 55.7539, 37.6204),
+	point("Химки", 55.8970, 37.4297),
+	point("Тверь", 56.8587, 35.9176),
+	point("Владивосток", 43.1155, 131.8855),
+	point("Уэлен", 66.1580, -169.8060),
+}
+
+// all читает cities с отбором, как запрос к базе данных; queries считает запросы
+func all(queries *int) func(ctx context.Context, filter entity.Filter, fn func(e entity.Entity) error) error {
+	return func(ctx context.Context, filter entity.Filter, fn func(e entity.Entity) error) error {
+		*queries++
+		for _, e := range cities {
+			if filter.Match(e) {
+				if err := fn(e); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+}
+
+func names(neighbours []entity.Neighbour) []string {
+	var ns []string
+	for _, n := range neighbours {
+		ns = append(ns, n.Name)
+	}
+	return ns
+}
+
+func TestNear(t *testing.T) {
+	tests := []struct {
+		name  string
+		query entity.NearQuery
+		want  []string
+	}{
+		{"ближайшие", entity.NearQuery{Lat: 55.75, Lon: 37.62, K: 3},
+			[]string{"Москва", "Химки", "Тверь"}},
+		{"без самой сущности", entity.NearQuery{Lat: 55.7539, Lon: 37.6204, K: 1, Exclude: cities[0].ID},
+			[]string{"Химки"}},
+		{"в радиусе", entity.NearQuery{Lat: 55.75, Lon: 37.62, K: 10, MaxDistance: 50000},
+			[]string{"Москва", "Химки"}},
+		// круг растёт, пока не покроет всю Землю; через полюс до Уэлена ближе, чем до Владивостока
+		{"все", entity.NearQuery{Lat: 55.75, Lon: 37.62, K: 10},
+			[]string{"Москва", "Химки", "Тверь", "Уэлен", "Владивосток"}},
+		// Уэлен по другую сторону 180-го меридиана
+		{"через антимеридиан", entity.NearQuery{Lat: 65, Lon: 179, K: 1},
+			[]string{"Уэлен"}},
+		{"другой файл", entity.NearQuery{Lat: 55.75, Lon: 37.62, K: 1, Filenames: []string{"temples.kml"}},
+			nil},
+	}
+	for _, tt := range tests {
+		var queries int
+		got, err := near(context.Background(), all(&queries), tt.query)
+		if err != nil {
+			t.Fatalf("%v: %v", tt.name, err)
+		}
+		if g := names(got); len(g) != len(tt.want) || (len(g) > 0 && fmt.Sprint(g) != fmt.Sprint(tt.want)) {
+			t.Errorf("%v: %q, want %q", tt.name, g, tt.want)
+		}
+		for i := 1; i < len(got); i++ {
+			if got[i].Distance < got[i-1].Distance {
+				t.Errorf("%v: not sorted by distance %v", tt.name, names(got))
+			}
+		}
+	}
+}
+
+func TestNearDistance(t *testing.T) {
+	var queries int
+	got, err := near(context.Background(), all(&queries), entity.NearQuery{Lat: 55.7539, Lon: 37.6204, K: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Химки к северо-западу от Москвы, около 20 км
+	if len(got) != 2 || got[0].Distance != 0 || got[1].Distance < 19000 || got[1].Distance > 21000 ||
+		got[1].Azimuth < 270 || got[1].Azimuth > 360 {
+		t.Errorf("neighbours %+v", got)
+	}
+	// первый круг 1 км, соседи найдены в круге 16 или 64 км
+	if queries < 2 || queries > 4 {
+		t.Errorf("queries %d", queries)
+	}
+
+	if _, err = near(context.Background(), all(&queries), entity.NearQuery{Lat: 0, Lon: 0, K: 0}); err == nil {
+		t.Error("k 0 accepted")
+	}
+}
@@ -0,0 +1,164 @@
+package searchstore
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"github.com/audetv/datasets-parser/app/repos/entity"
+	"github.com/golang/geo/s2"
+	"github.com/google/uuid"
+	"strconv"
+)
+
+// document документ индекса. Name и Description индексируются для полнотекстового поиска,
+// DescriptionJson записывается плоским объектом с ключами вида «a.b», в GeohashNeighbours
+// не попадают пустые соседи ячеек за полюсом. Родительские ячейки S2 записываются
+// в S2Cells строками «уровень/токен», тайлы — в Tiles строками «z/x/y» и в Quadkeys.
+type document struct {
+	UUID              string                 `json:"uuid"`
+	Filename          string                 `json:"filename"`
+	Name              string                 `json:"name"`
+	Description       string                 `json:"description"`
+	Latitude          float64                `json:"latitude"`
+	Longitude         float64                `json:"longitude"`
+	Height            float64                `json:"height"`
+	Location          *geoPoint              `json:"location,omitempty"`
+	CellID            string                 `json:"cell_id"`
+	S2Token           string                 `json:"s2_token"`
+	Geohash           string                 `json:"geohash"`
+	GeohashCell       string                 `json:"geohash_cell"`
+	GeohashNeighbours []string               `json:"geohash_neighbours"`
+	S2Cells           []string               `json:"s2_cells"`
+	Tiles             []string               `json:"tiles"`
+	Quadkeys          []string               `json:"quadkeys"`
+	DescriptionJson   map[string]interface{} `json:"description_json"`
+}
+
+// geoPoint поле geo_point Elasticsearch
+type geoPoint struct {
+	Lat float64 `json:"lat"`
+	Lon float64 `json:"lon"`
+}
+
+// action возвращает две строки NDJSON запроса _bulk: действие index и документ.
+// Действие index заменяет документ с тем же идентификатором, поэтому повтор запроса не создаёт дублей.
+func (es *Entities) action(e entity.Entity) ([]byte, error) {
+	flat, err := flatten(e.DescriptionJson)
+	if err != nil {
+		return nil, fmt.Errorf("search: entity %v description_json: %w", e.ID, err)
+	}
+	doc := document{
+		UUID:            e.ID.String(),
+		Filename:        e.Filename,
+		Name:            e.Name,
+		Description:     e.Description,
+		Latitude:        e.Latitude,
+		Longitude:       e.Longitude,
+		Height:          e.Height,
+		CellID:          strconv.FormatUint(e.CellID, 10),
+		S2Token:         e.S2Token,
+		Geohash:         e.Geohash,
+		GeohashCell:     e.GeohashCell,
+		DescriptionJson: flat,
+	}
+
+	doc.GeohashNeighbours = make([]string, 0, len(e.GeohashNeighbours))
+	for _, n := range e.GeohashNeighbours {
+		if n != "" {
+			doc.GeohashNeighbours = append(doc.GeohashNeighbours, n)
+		}
+	}
+
+	doc.S2Cells = make([]string, len(e.S2Cells))
+	for i, c := range e.S2Cells {
+		doc.S2Cells[i] = strconv.Itoa(c.Level) + "/" + s2.CellID(c.ID).ToToken()
+	}
+	doc.Tiles = make([]string, len(e.Tiles))
+	doc.Quadkeys = make([]string, len(e.Tiles))
+	for i, t := range e.Tiles {
+		doc.Tiles[i] = t.String()
+		doc.Quadkeys[i] = t.Quadkey
+	}
+
+	meta := map[string]interface{}{"_index": es.cfg.Index}
+	if es.cfg.Engine == EngineElasticsearch {
+		meta["_id"] = doc.UUID
+		doc.Location = &geoPoint{Lat: e.Latitude, Lon: e.Longitude}
+	} else {
+		meta["_id"] = manticoreID(e.ID)
+	}
+
+	head, err := json.Marshal(map[string]interface{}{"index": meta})
+	if err != nil {
+		return nil, err
+	}
+	body, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("search: entity %v: %w", e.ID, err)
+	}
+	line := make([]byte, 0, len(head)+len(body)+2)
+	line = append(append(head, '\n'), body...)
+	return append(line, '\n'), nil
+}
+
+// manticoreID идентификатор документа Manticore: положительное int64 из первых 8 байт uuid.
+// Сам uuid хранится в атрибуте uuid.
+func manticoreID(id uuid.UUID) uint64 {
+	n := binary.BigEndian.Uint64(id[:8]) & (1<<63 - 1)
+	if n == 0 {
+		n = 1
+	}
+	return n
+}
+
+// flatten переводит DescriptionJson в плоский объект: ключи вложенных объектов
+// соединяются точкой, значения из массивов объектов собираются в массив под общим ключом.
+// Значение, которое не является объектом, записывается под ключом value.
+func flatten(dj interface{}) (map[string]interface{}, error) {
+	if dj == nil {
+		return map[string]interface{}{}, nil
+	}
+	// значение приводится к типам json, в том числе структуры и числа любых типов
+	data, err := json.Marshal(dj)
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	if err = json.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	out := make(map[string]interface{})
+	if m, ok := v.(map[string]interface{}); ok {
+		for key, value := range m {
+			flattenValue(key, value, out)
+		}
+	} else {
+		flattenValue("value", v, out)
+	}
+	return out, nil
+}
+
+func flattenValue(key string, v interface{}, out map[string]interface{}) {
+	switch v := v.(type) {
+	case nil:
+	case map[string]interface{}:
+		for k, value := range v {
+			flattenValue(key+"."+k, value, out)
+		}
+	case []interface{}:
+		for _, value := range v {
+			flattenValue(key, value, out)
+		}
+	default:
+		prev, ok := out[key]
+		if !ok {
+			out[key] = v
+			return
+		}
+		if values, ok := prev.([]interface{}); ok {
+			out[key] = append(values, v)
+			return
+		}
+		out[key] = []interface{}{prev, v}
+	}
+}
@@ -0,0 +1,96 @@
+package searchstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// manticoreTable схема таблицы Manticore. Координаты хранятся во float,
+// точность которого около метра; description_json — атрибут json.
+const manticoreTable = `CREATE TABLE IF NOT EXISTS %v (
+	name text, description text,
+	uuid string attribute, filename string attribute,
+	latitude float, longitude float, height float,
+	cell_id string attribute, s2_token string attribute,
+	geohash string attribute, geohash_cell string attribute, geohash_neighbours json,
+	s2_cells json, tiles json, quadkeys json,
+	description_json json
+) morphology='stem_enru'`
+
+// elasticsearchMappings схема индекса Elasticsearch, description_json — поле flattened
+var elasticsearchMappings = map[string]interface{}{
+	"mappings": map[string]interface{}{
+		"dynamic": "strict",
+		"properties": map[string]interface{}{
+			"uuid":               map[string]string{"type": "keyword"},
+			"filename":           map[string]string{"type": "keyword"},
+			"name":               map[string]string{"type": "text"},
+			"description":        map[string]string{"type": "text"},
+			"latitude":           map[string]string{"type": "double"},
+			"longitude":          map[string]string{"type": "double"},
+			"height":             map[string]string{"type": "double"},
+			"location":           map[string]string{"type": "geo_point"},
+			"cell_id":            map[string]string{"type": "keyword"},
+			"s2_token":           map[string]string{"type": "keyword"},
+			"geohash":            map[string]string{"type": "keyword"},
+			"geohash_cell":       map[string]string{"type": "keyword"},
+			"geohash_neighbours": map[string]string{"type": "keyword"},
+			"s2_cells":           map[string]string{"type": "keyword"},
+			"tiles":              map[string]string{"type": "keyword"},
+			"quadkeys":           map[string]string{"type": "keyword"},
+			"description_json":   map[string]string{"type": "flattened"},
+		},
+	},
+}
+
+// createIndex создаёт индекс, если его нет
+func (es *Entities) createIndex(ctx context.Context) error {
+	if es.cfg.Engine == EngineElasticsearch {
+		return es.createElasticsearchIndex(ctx)
+	}
+	return es.createManticoreTable(ctx)
+}
+
+func (es *Entities) createManticoreTable(ctx context.Context) error {
+	form := url.Values{"query": {fmt.Sprintf(manticoreTable, es.cfg.Index)}}
+	data, err := es.do(ctx, http.MethodPost, "/sql?mode=raw", "application/x-www-form-urlencoded", []byte(form.Encode()))
+	if err != nil {
+		return err
+	}
+	// в режиме raw ответ — массив результатов запросов, ошибка запроса — в поле error
+	var results []struct {
+		Error string `json:"error"`
+	}
+	if err = json.Unmarshal(data, &results); err != nil {
+		return fmt.Errorf("sql response: %w", err)
+	}
+	for _, r := range results {
+		if r.Error != "" {
+			return errors.New(r.Error)
+		}
+	}
+	return nil
+}
+
+func (es *Entities) createElasticsearchIndex(ctx context.Context) error {
+	_, err := es.do(ctx, http.MethodHead, "/"+es.cfg.Index, "", nil)
+	var se *statusError
+	if err == nil || !errors.As(err, &se) || se.status != http.StatusNotFound {
+		return err
+	}
+	body, err := json.Marshal(elasticsearchMappings)
+	if err != nil {
+		return err
+	}
+	_, err = es.do(ctx, http.MethodPut, "/"+es.cfg.Index, "application/json", body)
+	// индекс мог создать другой процесс между запросами
+	if errors.As(err, &se) && strings.Contains(se.body, "resource_already_exists_exception") {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,329 @@
+// Package searchstore записывает сущности в поисковый индекс Manticore Search
+// или Elasticsearch через HTTP API _bulk (NDJSON).
+package searchstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/audetv/datasets-parser/app/repos/entity"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Engine поисковый движок
+type Engine string
+
+const (
+	EngineManticore     Engine = "manticore"
+	EngineElasticsearch Engine = "elasticsearch"
+)
+
+// ParseEngine разбирает название поискового движка
+func ParseEngine(s string) (Engine, error) {
+	switch e := Engine(strings.ToLower(strings.TrimSpace(s))); e {
+	case EngineManticore, EngineElasticsearch:
+		return e, nil
+	case "es":
+		return EngineElasticsearch, nil
+	}
+	return "", fmt.Errorf("search: unsupported engine %q, expected %v or %v", s, EngineManticore, EngineElasticsearch)
+}
+
+// DefaultURL адрес HTTP API движка по умолчанию
+func (e Engine) DefaultURL() string {
+	if e == EngineElasticsearch {
+		return "http://localhost:9200"
+	}
+	return "http://localhost:9308"
+}
+
+// Config параметры подключения к поисковому индексу
+type Config struct {
+	Engine Engine
+	// URL адрес HTTP API, пустой — адрес движка по умолчанию
+	URL string
+	// Index имя индекса, создаётся, если его нет
+	Index string
+	// BatchSize наибольшее количество документов в одном запросе _bulk, 0 — 500
+	BatchSize int
+	// Retries сколько раз повторить запрос при ошибке сети, ответе 429 или 5xx, 0 — 3
+	Retries int
+	// Backoff пауза перед первым повтором, удваивается с каждым повтором, 0 — 500ms
+	Backoff time.Duration
+	// Client HTTP клиент, nil — http.DefaultClient
+	Client *http.Client
+}
+
+var indexName = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+func (c Config) withDefaults() (Config, error) {
+	if c.Engine == "" {
+		c.Engine = EngineManticore
+	}
+	if _, err := ParseEngine(string(c.Engine)); err != nil {
+		return c, err
+	}
+	if c.URL == "" {
+		c.URL = c.Engine.DefaultURL()
+	}
+	c.URL = strings.TrimRight(c.URL, "/")
+	if !indexName.MatchString(c.Index) {
+		return c, fmt.Errorf("search: invalid index name %q, expected lowercase letters, digits and _", c.Index)
+	}
+	if c.BatchSize <= 0 {
+		c.BatchSize = 500
+	}
+	if c.Retries <= 0 {
+		c.Retries = 3
+	}
+	if c.Backoff <= 0 {
+		c.Backoff = 500 * time.Millisecond
+	}
+	if c.Client == nil {
+		c.Client = http.DefaultClient
+	}
+	return c, nil
+}
+
+// Entities хранилище сущностей в поисковом индексе. Записи не транзакционны:
+// документы, записанные до ошибки, остаются в индексе.
+type Entities struct {
+	cfg Config
+}
+
+var _ entity.Store = &Entities{}
+
+// NewEntities проверяет параметры и создаёт индекс cfg.Index, если его нет
+func NewEntities(ctx context.Context, cfg Config) (*Entities, error) {
+	cfg, err := cfg.withDefaults()
+	if err != nil {
+		return nil, err
+	}
+	es := &Entities{cfg: cfg}
+	if err = es.createIndex(ctx); err != nil {
+		return nil, fmt.Errorf("search: create index %v: %w", cfg.Index, err)
+	}
+	return es, nil
+}
+
+func (es *Entities) Create(ctx context.Context, e entity.Entity) error {
+	return es.BulkInsert(ctx, []entity.Entity{e}, 1)
+}
+
+// BulkInsert записывает сущности пакетами не больше batchSize и Config.BatchSize документов
+func (es *Entities) BulkInsert(ctx context.Context, entities []entity.Entity, batchSize int) error {
+	if batchSize <= 0 || batchSize > es.cfg.BatchSize {
+		batchSize = es.cfg.BatchSize
+	}
+	for start := 0; start < len(entities); start += batchSize {
+		end := start + batchSize
+		if end > len(entities) {
+			end = len(entities)
+		}
+		if err := es.bulk(ctx, entities[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// bulk отправляет пакет документов. Документы, отклонённые с кодом 429 или 5xx,
+// отправляются повторно, остальные отказы возвращаются ошибкой.
+func (es *Entities) bulk(ctx context.Context, entities []entity.Entity) error {
+	lines := make([][]byte, len(entities))
+	for i, e := range entities {
+		line, err := es.action(e)
+		if err != nil {
+			return err
+		}
+		lines[i] = line
+	}
+
+	pending := make([]int, len(entities))
+	for i := range pending {
+		pending[i] = i
+	}
+	for attempt := 0; ; attempt++ {
+		var body bytes.Buffer
+		for _, i := range pending {
+			body.Write(lines[i])
+		}
+		data, err := es.do(ctx, http.MethodPost, "/_bulk", "application/x-ndjson", body.Bytes())
+		if err != nil {
+			return err
+		}
+		failed, err := parseBulk(data, len(pending))
+		if err != nil {
+			return err
+		}
+
+		var retry []int
+		var rejected []string
+		status := 0
+		for j, f := range failed {
+			if f == nil {
+				continue
+			}
+			e := entities[pending[j]]
+			if retryable(f.status) {
+				retry = append(retry, pending[j])
+				status = f.status
+				continue
+			}
+			rejected = append(rejected, fmt.Sprintf("%v (%v): %v", e.ID, e.Filename, f.reason))
+		}
+		if len(rejected) > 0 {
+			return fmt.Errorf("search: %d of %d documents rejected, first %v", len(rejected), len(entities), rejected[0])
+		}
+		if len(retry) == 0 {
+			return nil
+		}
+		if attempt == es.cfg.Retries {
+			return fmt.Errorf("search: %d of %d documents not indexed after %d retries, last status %d",
+				len(retry), len(entities), attempt, status)
+		}
+		delay := es.cfg.Backoff << attempt
+		log.Printf("поиск: документов не принято %d, повтор через %v\n", len(retry), delay)
+		if err = sleep(ctx, delay); err != nil {
+			return err
+		}
+		pending = retry
+	}
+}
+
+// itemError отказ в записи одного документа пакета
+type itemError struct {
+	status int
+	reason string
+}
+
+// parseBulk разбирает ответ _bulk и возвращает отказы по порядку документов,
+// nil для записанных документов
+func parseBulk(data []byte, n int) ([]*itemError, error) {
+	var resp struct {
+		Errors bool                         `json:"errors"`
+		Items  []map[string]json.RawMessage `json:"items"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("search: bulk response: %w", err)
+	}
+	failed := make([]*itemError, n)
+	if !resp.Errors {
+		return failed, nil
+	}
+	if len(resp.Items) != n {
+		return nil, fmt.Errorf("search: bulk response has %d items for %d documents", len(resp.Items), n)
+	}
+	for i, item := range resp.Items {
+		// у элемента один ключ — действие: index, create или bulk у Manticore
+		for _, raw := range item {
+			var result struct {
+				Status int             `json:"status"`
+				Error  json.RawMessage `json:"error"`
+			}
+			if err := json.Unmarshal(raw, &result); err != nil {
+				return nil, fmt.Errorf("search: bulk response item %d: %w", i, err)
+			}
+			if result.Status >= 300 || len(result.Error) > 0 && string(result.Error) != "null" {
+				failed[i] = &itemError{status: result.Status, reason: errorReason(result.Error)}
+			}
+		}
+	}
+	return failed, nil
+}
+
+// errorReason текст ошибки: Elasticsearch возвращает объект с reason, Manticore — строку
+func errorReason(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	var e struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	}
+	if json.Unmarshal(raw, &e) == nil && e.Reason != "" {
+		return e.Type + ": " + e.Reason
+	}
+	return string(raw)
+}
+
+func retryable(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+// statusError ответ HTTP API с кодом ошибки
+type statusError struct {
+	method, path string
+	status       int
+	body         string
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("search: %v %v: status %d: %v", e.method, e.path, e.status, e.body)
+}
+
+// do выполняет запрос и возвращает тело ответа с кодом 2xx. Запрос повторяется
+// при ошибке сети, ответе 429 или 5xx.
+func (es *Entities) do(ctx context.Context, method string, path string, contentType string, body []byte) ([]byte, error) {
+	for attempt := 0; ; attempt++ {
+		data, err := es.request(ctx, method, path, contentType, body)
+		if err == nil || ctx.Err() != nil {
+			return data, err
+		}
+		var se *statusError
+		if errors.As(err, &se) && !retryable(se.status) || attempt == es.cfg.Retries {
+			return nil, err
+		}
+		delay := es.cfg.Backoff << attempt
+		log.Printf("%v, повтор через %v\n", err, delay)
+		if err = sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (es *Entities) request(ctx context.Context, method string, path string, contentType string, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, es.cfg.URL+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	resp, err := es.cfg.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		text := strings.TrimSpace(string(data))
+		if len(text) > 500 {
+			text = text[:500] + "…"
+		}
+		return nil, &statusError{method: method, path: path, status: resp.StatusCode, body: text}
+	}
+	return data, nil
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,355 @@
+package searchstore
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/audetv/datasets-parser/app/repos/entity"
+	"github.com/google/uuid"
+)
+
+// request запрос к тестовому серверу
+type request struct {
+	method, path string
+	body         string
+}
+
+// server тестовый HTTP API поискового движка: запоминает запросы
+// и отвечает на них обработчиком handle
+type server struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []request
+	handle   func(n int, r request) (int, string)
+}
+
+func newServer(t *testing.T, handle func(n int, r request) (int, string)) *server {
+	s := &server{handle: handle}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		req := request{method: r.Method, path: r.URL.RequestURI(), body: string(body)}
+		s.mu.Lock()
+		n := len(s.requests)
+		s.requests = append(s.requests, req)
+		s.mu.Unlock()
+		status, resp := s.handle(n, req)
+		w.WriteHeader(status)
+		io.WriteString(w, resp)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *server) config(engine Engine) Config {
+	return Config{Engine: engine, URL: s.URL, Index: "places", Backoff: time.Millisecond}
+}
+
+// documents возвращает uuid документов запроса _bulk по порядку
+func documents(t *testing.T, body string) []string {
+	var ids []string
+	sc := bufio.NewScanner(strings.NewReader(body))
+	sc.Buffer(nil, 1<<20)
+	for line := 0; sc.Scan(); line++ {
+		if line%2 == 0 {
+			continue
+		}
+		var doc document
+		if err := json.Unmarshal(sc.Bytes(), &doc); err != nil {
+			t.Fatalf("bulk document %q: %v", sc.Text(), err)
+		}
+		ids = append(ids, doc.UUID)
+	}
+	return ids
+}
+
+func testEntities(n int) []entity.Entity {
+	es := make([]entity.Entity, n)
+	for i := range es {
+		es[i] = entity.Entity{ID: uuid.New(), Filename: "test.csv", Name: "e", Latitude: 55.75, Longitude: 37.62}
+	}
+	return es
+}
+
+func TestNewEntitiesCreatesManticoreTable(t *testing.T) {
+	s := newServer(t, func(n int, r request) (int, string) {
+		return http.StatusOK, `[{"total":0,"error":"","warning":""}]`
+	})
+	if _, err := NewEntities(context.Background(), s.config(EngineManticore)); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.requests) != 1 {
+		t.Fatalf("requests %v, want one", s.requests)
+	}
+	r := s.requests[0]
+	if r.method != http.MethodPost || r.path != "/sql?mode=raw" {
+		t.Errorf("request %v %v, want POST /sql?mode=raw", r.method, r.path)
+	}
+	if !strings.Contains(r.body, "CREATE+TABLE+IF+NOT+EXISTS+places") {
+		t.Errorf("query %v does not create table places", r.body)
+	}
+
+	s.handle = func(n int, r request) (int, string) {
+		return http.StatusOK, `[{"total":0,"error":"index places: syntax error","warning":""}]`
+	}
+	if _, err := NewEntities(context.Background(), s.config(EngineManticore)); err == nil || !strings.Contains(err.Error(), "syntax error") {
+		t.Errorf("error %v, want sql error", err)
+	}
+}
+
+func TestNewEntitiesCreatesElasticsearchIndex(t *testing.T) {
+	s := newServer(t, func(n int, r request) (int, string) {
+		if r.method == http.MethodHead {
+			return http.StatusNotFound, ""
+		}
+		return http.StatusOK, `{"acknowledged":true}`
+	})
+	if _, err := NewEntities(context.Background(), s.config(EngineElasticsearch)); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.requests) != 2 || s.requests[0].method != http.MethodHead || s.requests[1].method != http.MethodPut {
+		t.Fatalf("requests %v, want HEAD and PUT", s.requests)
+	}
+	if s.requests[1].path != "/places" {
+		t.Errorf("index created at %v, want /places", s.requests[1].path)
+	}
+	var body struct {
+		Mappings struct {
+			Properties map[string]struct {
+				Type string `json:"type"`
+			} `json:"properties"`
+		} `json:"mappings"`
+	}
+	if err := json.Unmarshal([]byte(s.requests[1].body), &body); err != nil {
+		t.Fatal(err)
+	}
+	if p := body.Mappings.Properties; p["location"].Type != "geo_point" || p["description_json"].Type != "flattened" {
+		t.Errorf("mappings %v", p)
+	}
+
+	// индекс уже есть
+	s.requests = nil
+	s.handle = func(n int, r request) (int, string) {
+		return http.StatusOK, ""
+	}
+	if _, err := NewEntities(context.Background(), s.config(EngineElasticsearch)); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.requests) != 1 {
+		t.Errorf("requests %v, want only HEAD", s.requests)
+	}
+
+	// индекс создан другим процессом между HEAD и PUT
+	s.handle = func(n int, r request) (int, string) {
+		if r.method == http.MethodHead {
+			return http.StatusNotFound, ""
+		}
+		return http.StatusBadRequest, `{"error":{"type":"resource_already_exists_exception"},"status":400}`
+	}
+	if _, err := NewEntities(context.Background(), s.config(EngineElasticsearch)); err != nil {
+		t.Errorf("existing index: %v", err)
+	}
+}
+
+func TestBulkRetriesItemsWithBackoff(t *testing.T) {
+	var bulks [][]string
+	s := newServer(t, func(n int, r request) (int, string) {
+		if r.path != "/_bulk" {
+			return http.StatusOK, ""
+		}
+		ids := documents(t, r.body)
+		bulks = append(bulks, ids)
+		switch len(bulks) {
+		case 1:
+			// весь запрос отклонён — повторяется целиком
+			return http.StatusServiceUnavailable, "unavailable"
+		case 2:
+			return http.StatusOK, `{"errors":true,"items":[
+				{"index":{"status":201}},
+				{"index":{"status":429,"error":{"type":"es_rejected_execution_exception","reason":"queue full"}}},
+				{"index":{"status":503,"error":"busy"}}]}`
+		}
+		items := strings.Repeat(`{"index":{"status":201}},`, len(ids))
+		return http.StatusOK, `{"errors":false,"items":[` + strings.TrimSuffix(items, ",") + `]}`
+	})
+	store, err := NewEntities(context.Background(), s.config(EngineElasticsearch))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entities := testEntities(3)
+	start := time.Now()
+	if err = store.BulkInsert(context.Background(), entities, 0); err != nil {
+		t.Fatal(err)
+	}
+	if len(bulks) != 3 {
+		t.Fatalf("bulk requests %v, want 3", bulks)
+	}
+	all := []string{entities[0].ID.String(), entities[1].ID.String(), entities[2].ID.String()}
+	if !reflect.DeepEqual(bulks[0], all) || !reflect.DeepEqual(bulks[1], all) {
+		t.Errorf("first requests %v, want all documents %v", bulks[:2], all)
+	}
+	if want := all[1:]; !reflect.DeepEqual(bulks[2], want) {
+		t.Errorf("retried documents %v, want %v", bulks[2], want)
+	}
+	// пауза перед повтором запроса и перед повтором документов
+	if d := time.Since(start); d < 2*time.Millisecond {
+		t.Errorf("retries took %v, want backoff", d)
+	}
+}
+
+func TestBulkGivesUpAfterRetries(t *testing.T) {
+	bulks := 0
+	s := newServer(t, func(n int, r request) (int, string) {
+		if r.path != "/_bulk" {
+			return http.StatusOK, ""
+		}
+		bulks++
+		return http.StatusOK, `{"errors":true,"items":[{"index":{"status":429,"error":"queue full"}}]}`
+	})
+	cfg := s.config(EngineElasticsearch)
+	cfg.Retries = 2
+	store, err := NewEntities(context.Background(), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.BulkInsert(context.Background(), testEntities(1), 0)
+	if err == nil || !strings.Contains(err.Error(), "1 of 1 documents not indexed after 2 retries, last status 429") {
+		t.Errorf("error %v", err)
+	}
+	if bulks != 3 {
+		t.Errorf("bulk requests %d, want 3", bulks)
+	}
+}
+
+func TestBulkReportsRejectedItems(t *testing.T) {
+	bulks := 0
+	s := newServer(t, func(n int, r request) (int, string) {
+		if r.path != "/_bulk" {
+			return http.StatusOK, ""
+		}
+		bulks++
+		return http.StatusOK, `{"errors":true,"items":[
+			{"index":{"status":201}},
+			{"index":{"status":400,"error":{"type":"mapper_parsing_exception","reason":"failed to parse field [location]"}}}]}`
+	})
+	store, err := NewEntities(context.Background(), s.config(EngineElasticsearch))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entities := testEntities(2)
+	err = store.BulkInsert(context.Background(), entities, 0)
+	if err == nil {
+		t.Fatal("rejected document is not reported")
+	}
+	for _, want := range []string{"1 of 2 documents rejected", entities[1].ID.String(), "mapper_parsing_exception: failed to parse field [location]"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not contain %q", err, want)
+		}
+	}
+	if bulks != 1 {
+		t.Errorf("bulk requests %d, rejected documents must not be retried", bulks)
+	}
+}
+
+func TestManticoreBulkUsesNumericIDs(t *testing.T) {
+	var body string
+	s := newServer(t, func(n int, r request) (int, string) {
+		if r.path == "/_bulk" {
+			body = r.body
+			return http.StatusOK, `{"errors":false,"items":[{"bulk":{"status":201}}]}`
+		}
+		return http.StatusOK, `[{"total":0,"error":"","warning":""}]`
+	})
+	store, err := NewEntities(context.Background(), s.config(EngineManticore))
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := testEntities(1)[0]
+	if err = store.Create(context.Background(), e); err != nil {
+		t.Fatal(err)
+	}
+	var action struct {
+		Index struct {
+			Index string `json:"_index"`
+			ID    uint64 `json:"_id"`
+		} `json:"index"`
+	}
+	head, _, _ := strings.Cut(body, "\n")
+	if err = json.Unmarshal([]byte(head), &action); err != nil {
+		t.Fatal(err)
+	}
+	if action.Index.Index != "places" || action.Index.ID != manticoreID(e.ID) {
+		t.Errorf("action %v, want _index places and _id %d", head, manticoreID(e.ID))
+	}
+	if strings.Contains(body, `"location"`) {
+		t.Errorf("manticore document has geo_point location: %v", body)
+	}
+}
+
+func TestManticoreID(t *testing.T) {
+	tests := []struct {
+		id   string
+		want uint64
+	}{
+		{"01020304-0506-0708-090a-0b0c0d0e0f10", 0x0102030405060708},
+		// старший бит сбрасывается: идентификатор Manticore — положительное int64
+		{"ffffffff-ffff-ffff-0000-000000000000", 1<<63 - 1},
+		{"80000000-0000-0000-ffff-ffffffffffff", 1},
+		{"00000000-0000-0000-ffff-ffffffffffff", 1},
+	}
+	for _, tt := range tests {
+		if got := manticoreID(uuid.MustParse(tt.id)); got != tt.want {
+			t.Errorf("manticoreID(%v) = %#x, want %#x", tt.id, got, tt.want)
+		}
+	}
+}
+
+func TestFlatten(t *testing.T) {
+	tests := []struct {
+		name string
+		dj   interface{}
+		want map[string]interface{}
+	}{
+		{"nil", nil, map[string]interface{}{}},
+		{"scalar", "text", map[string]interface{}{"value": "text"}},
+		{"nested objects", map[string]interface{}{
+			"a": map[string]interface{}{"b": 1, "c": map[string]interface{}{"d": "x"}},
+			"e": nil,
+		}, map[string]interface{}{"a.b": 1.0, "a.c.d": "x"}},
+		{"arrays of objects", map[string]interface{}{
+			"tags": []map[string]interface{}{{"k": "name", "v": "a"}, {"k": "ref", "v": "b"}, {"k": "x"}},
+			"ids":  []int{1, 2},
+		}, map[string]interface{}{
+			"tags.k": []interface{}{"name", "ref", "x"},
+			"tags.v": []interface{}{"a", "b"},
+			"ids":    []interface{}{1.0, 2.0},
+		}},
+		{"struct", struct {
+			Name  string `json:"name"`
+			Count int    `json:"count"`
+		}{"a", 2}, map[string]interface{}{"name": "a", "count": 2.0}},
+	}
+	for _, tt := range tests {
+		got, err := flatten(tt.dj)
+		if err != nil {
+			t.Errorf("%v: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%v: flatten = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := flatten(map[string]interface{}{"f": func() {}}); err == nil {
+		t.Error("unsupported value is not reported")
+	}
+}